@@ -3,6 +3,9 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"sort"
+
+	"github.com/bytebase/bytebase/plugin/db"
 )
 
 // AnomalyType is the type of a task.
@@ -13,14 +16,83 @@ const (
 	AnomalyInstanceConnection AnomalyType = "bb.anomaly.instance.connection"
 	// AnomalyInstanceMigrationSchema is the anomaly type for schema migrations.
 	AnomalyInstanceMigrationSchema AnomalyType = "bb.anomaly.instance.migration-schema"
+	// AnomalyInstanceDiskSpaceLow is the anomaly type for the instance running out of disk space.
+	AnomalyInstanceDiskSpaceLow AnomalyType = "bb.anomaly.instance.disk-space-low"
 	// AnomalyDatabaseBackupPolicyViolation is the anomaly type for backup policy violations.
 	AnomalyDatabaseBackupPolicyViolation AnomalyType = "bb.anomaly.database.backup.policy-violation"
 	// AnomalyDatabaseBackupMissing is the anomaly type for missing backups.
 	AnomalyDatabaseBackupMissing AnomalyType = "bb.anomaly.database.backup.missing"
+	// AnomalyDatabaseBackupPruneFailed is the anomaly type for failing to prune the backups past the retention.
+	AnomalyDatabaseBackupPruneFailed AnomalyType = "bb.anomaly.database.backup.prune-failed"
+	// AnomalyDatabaseBackupCorrupt is the anomaly type for the latest backup file not matching its checksum.
+	AnomalyDatabaseBackupCorrupt AnomalyType = "bb.anomaly.database.backup.corrupt"
+	// AnomalyDatabaseBackupSizeAnomalous is the anomaly type for the latest backup shrinking or growing sharply from the backups before,
+	// e.g. a truncated backup marked as done.
+	AnomalyDatabaseBackupSizeAnomalous AnomalyType = "bb.anomaly.database.backup.size-anomalous"
 	// AnomalyDatabaseConnection is the anomaly type for database connections.
 	AnomalyDatabaseConnection AnomalyType = "bb.anomaly.database.connection"
 	// AnomalyDatabaseSchemaDrift is the anomaly type for database schema drifts.
 	AnomalyDatabaseSchemaDrift AnomalyType = "bb.anomaly.database.schema.drift"
+	// AnomalyDatabaseIndexMissing is the anomaly type for foreign keys lacking a supporting index.
+	AnomalyDatabaseIndexMissing AnomalyType = "bb.anomaly.database.index.missing"
+	// AnomalyDatabaseConnectionCountHigh is the anomaly type for the connection usage approaching the max connections.
+	AnomalyDatabaseConnectionCountHigh AnomalyType = "bb.anomaly.database.connection.count-high"
+	// AnomalyDatabaseLongRunningTransaction is the anomaly type for transactions running longer than the threshold.
+	AnomalyDatabaseLongRunningTransaction AnomalyType = "bb.anomaly.database.transaction.long-running"
+	// AnomalyDatabaseReplicationLag is the anomaly type for the replica falling behind its primary longer than the threshold.
+	AnomalyDatabaseReplicationLag AnomalyType = "bb.anomaly.database.replication.lag"
+	// AnomalyDatabaseTableBloat is the anomaly type for tables whose dead tuple ratio exceeds the threshold.
+	AnomalyDatabaseTableBloat AnomalyType = "bb.anomaly.database.table.bloat"
+	// AnomalyDatabaseScanTimeout is the anomaly type for the database scan not finishing within the timeout.
+	AnomalyDatabaseScanTimeout AnomalyType = "bb.anomaly.database.scan.timeout"
+	// AnomalyDatabaseVersionOutdated is the anomaly type for the database engine running a version older than the minimum recommended version.
+	AnomalyDatabaseVersionOutdated AnomalyType = "bb.anomaly.database.version.outdated"
+	// AnomalyDatabaseTableRowCountGrowth is the anomaly type for tables whose row count grows beyond the ratio between two scan rounds.
+	AnomalyDatabaseTableRowCountGrowth AnomalyType = "bb.anomaly.database.table.row-count-growth"
+	// AnomalyDatabaseUntracked is the anomaly type for databases without any migration history, i.e. not under migration management.
+	AnomalyDatabaseUntracked AnomalyType = "bb.anomaly.database.untracked"
+	// AnomalyDatabaseNoMigrationHistory is the anomaly type for managed databases without any migration history,
+	// e.g. the databases created out-of-band.
+	AnomalyDatabaseNoMigrationHistory AnomalyType = "bb.anomaly.database.no-migration-history"
+	// AnomalyDatabaseConnectionUnencrypted is the anomaly type for the instance connection not using TLS in the environment requiring encryption.
+	AnomalyDatabaseConnectionUnencrypted AnomalyType = "bb.anomaly.database.connection.unencrypted"
+	// AnomalyDatabaseInsecureConnection is the anomaly type for the instance connection falling back to plaintext though configured to use TLS,
+	// or the server certificate being self-signed or expired.
+	AnomalyDatabaseInsecureConnection AnomalyType = "bb.anomaly.database.connection.insecure"
+	// AnomalyDatabaseMissingIndex is the anomaly type for tables scanned sequentially far more often than through an index.
+	AnomalyDatabaseMissingIndex AnomalyType = "bb.anomaly.database.table.missing-index"
+	// AnomalyInstanceEngineVersionEOL is the anomaly type for the engine release line of the instance being past or near its end of life.
+	AnomalyInstanceEngineVersionEOL AnomalyType = "bb.anomaly.instance.engine-version-eol"
+)
+
+var (
+	// AnomalyTypes is a set of all anomaly types.
+	AnomalyTypes = map[AnomalyType]bool{
+		AnomalyInstanceConnection:             true,
+		AnomalyInstanceMigrationSchema:        true,
+		AnomalyInstanceDiskSpaceLow:           true,
+		AnomalyDatabaseBackupPolicyViolation:  true,
+		AnomalyDatabaseBackupMissing:          true,
+		AnomalyDatabaseBackupPruneFailed:      true,
+		AnomalyDatabaseBackupCorrupt:          true,
+		AnomalyDatabaseBackupSizeAnomalous:    true,
+		AnomalyDatabaseConnection:             true,
+		AnomalyDatabaseSchemaDrift:            true,
+		AnomalyDatabaseIndexMissing:           true,
+		AnomalyDatabaseConnectionCountHigh:    true,
+		AnomalyDatabaseLongRunningTransaction: true,
+		AnomalyDatabaseReplicationLag:         true,
+		AnomalyDatabaseTableBloat:             true,
+		AnomalyDatabaseScanTimeout:            true,
+		AnomalyDatabaseVersionOutdated:        true,
+		AnomalyDatabaseTableRowCountGrowth:    true,
+		AnomalyDatabaseUntracked:              true,
+		AnomalyDatabaseNoMigrationHistory:     true,
+		AnomalyInstanceEngineVersionEOL:       true,
+		AnomalyDatabaseMissingIndex:           true,
+		AnomalyDatabaseConnectionUnencrypted:  true,
+		AnomalyDatabaseInsecureConnection:     true,
+	}
 )
 
 // AnomalySeverity is the severity of anamoly.
@@ -40,8 +112,44 @@ func AnomalySeverityFromType(anomalyType AnomalyType) AnomalySeverity {
 	switch anomalyType {
 	case AnomalyDatabaseBackupPolicyViolation:
 		return AnomalySeverityMedium
+	case AnomalyDatabaseIndexMissing:
+		return AnomalySeverityMedium
+	case AnomalyDatabaseBackupPruneFailed:
+		return AnomalySeverityMedium
+	case AnomalyDatabaseScanTimeout:
+		return AnomalySeverityMedium
+	case AnomalyDatabaseTableBloat:
+		return AnomalySeverityMedium
+	case AnomalyDatabaseVersionOutdated:
+		return AnomalySeverityMedium
+	case AnomalyDatabaseTableRowCountGrowth:
+		return AnomalySeverityMedium
+	case AnomalyDatabaseUntracked:
+		return AnomalySeverityMedium
+	case AnomalyDatabaseNoMigrationHistory:
+		return AnomalySeverityHigh
+	case AnomalyInstanceEngineVersionEOL:
+		return AnomalySeverityMedium
+	case AnomalyDatabaseMissingIndex:
+		return AnomalySeverityMedium
 	case AnomalyDatabaseBackupMissing:
 		return AnomalySeverityHigh
+	case AnomalyDatabaseBackupCorrupt:
+		return AnomalySeverityHigh
+	case AnomalyDatabaseBackupSizeAnomalous:
+		return AnomalySeverityHigh
+	case AnomalyDatabaseConnectionCountHigh:
+		return AnomalySeverityHigh
+	case AnomalyDatabaseLongRunningTransaction:
+		return AnomalySeverityHigh
+	case AnomalyDatabaseReplicationLag:
+		return AnomalySeverityHigh
+	case AnomalyInstanceDiskSpaceLow:
+		return AnomalySeverityHigh
+	case AnomalyDatabaseConnectionUnencrypted:
+		return AnomalySeverityHigh
+	case AnomalyDatabaseInsecureConnection:
+		return AnomalySeverityHigh
 	case AnomalyInstanceConnection:
 	case AnomalyInstanceMigrationSchema:
 	case AnomalyDatabaseConnection:
@@ -55,6 +163,22 @@ func AnomalySeverityFromType(anomalyType AnomalyType) AnomalySeverity {
 type AnomalyInstanceConnectionPayload struct {
 	// Connection failure detail
 	Detail string `json:"detail,omitempty"`
+	// Category of the connection failure, e.g. to route the authentication failures differently from the network timeouts
+	Category db.ConnectionErrorCategory `json:"category,omitempty"`
+	// The host and port of the instance failing to connect
+	Host string `json:"host,omitempty"`
+	Port string `json:"port,omitempty"`
+}
+
+// AnomalyInstanceDiskSpaceLowPayload is the API message for low disk space payloads.
+type AnomalyInstanceDiskSpaceLowPayload struct {
+	TotalBytes int64 `json:"totalBytes,omitempty"`
+	UsedBytes  int64 `json:"usedBytes,omitempty"`
+	FreeBytes  int64 `json:"freeBytes,omitempty"`
+	// The percentage of used disk space
+	Percentage int `json:"percentage,omitempty"`
+	// The percentage of used disk space above which the anomaly is raised
+	Threshold int `json:"threshold,omitempty"`
 }
 
 // AnomalyDatabaseBackupPolicyViolationPayload is the API message for backup policy violation payloads.
@@ -71,20 +195,192 @@ type AnomalyDatabaseBackupMissingPayload struct {
 	LastBackupTs int64 `json:"lastBackupTs,omitempty"`
 }
 
+// AnomalyDatabaseBackupPruneFailedPayload is the API message for backup prune failure payloads.
+type AnomalyDatabaseBackupPruneFailedPayload struct {
+	// The backup failed to be pruned
+	BackupID   int    `json:"backupId,omitempty"`
+	BackupName string `json:"backupName,omitempty"`
+	// Prune failure detail
+	Detail string `json:"detail,omitempty"`
+}
+
+// AnomalyDatabaseBackupCorruptPayload is the API message for corrupt backup payloads.
+type AnomalyDatabaseBackupCorruptPayload struct {
+	// The backup whose file doesn't match its checksum
+	BackupID   int    `json:"backupId,omitempty"`
+	BackupName string `json:"backupName,omitempty"`
+	// The hex SHA-256 recorded when the backup was taken, and the one of the stored file
+	ExpectedChecksum string `json:"expectedChecksum,omitempty"`
+	ActualChecksum   string `json:"actualChecksum,omitempty"`
+}
+
+// AnomalyDatabaseBackupSizeAnomalousPayload is the API message for anomalous backup size payloads.
+type AnomalyDatabaseBackupSizeAnomalousPayload struct {
+	// The latest backup whose size is anomalous
+	BackupID   int    `json:"backupId,omitempty"`
+	BackupName string `json:"backupName,omitempty"`
+	// The median size of the backups before, and the size of the latest backup
+	PreviousSizeBytes int64 `json:"previousSizeBytes,omitempty"`
+	CurrentSizeBytes  int64 `json:"currentSizeBytes,omitempty"`
+	// The ratio of the current size to the previous size
+	Ratio float64 `json:"ratio,omitempty"`
+	// The ratio above which, or below the reciprocal of which, the anomaly is raised
+	Threshold float64 `json:"threshold,omitempty"`
+}
+
+// AnomalyDatabaseUntrackedPayload is the API message for untracked database payloads.
+type AnomalyDatabaseUntrackedPayload struct {
+	// The instance and the database to put under migration management
+	InstanceName string `json:"instanceName,omitempty"`
+	DatabaseName string `json:"databaseName,omitempty"`
+}
+
+// AnomalyDatabaseNoMigrationHistoryPayload is the API message for no migration history payloads.
+type AnomalyDatabaseNoMigrationHistoryPayload struct {
+	// The managed instance and database missing the migration history
+	InstanceName string `json:"instanceName,omitempty"`
+	DatabaseName string `json:"databaseName,omitempty"`
+}
+
 // AnomalyDatabaseConnectionPayload is the API message for database connection payloads.
 type AnomalyDatabaseConnectionPayload struct {
 	// Connection failure detail
 	Detail string `json:"detail,omitempty"`
+	// Category of the connection failure, e.g. to route the authentication failures differently from the network timeouts
+	Category db.ConnectionErrorCategory `json:"category,omitempty"`
+	// The host and port of the instance failing to connect
+	Host string `json:"host,omitempty"`
+	Port string `json:"port,omitempty"`
 }
 
 // AnomalyDatabaseSchemaDriftPayload is the API message for database schema drift payloads.
 type AnomalyDatabaseSchemaDriftPayload struct {
 	// The schema version corresponds to the expected schema
 	Version string `json:"version,omitempty"`
-	// The expected latest schema stored in the migration history table
+	// The expected latest schema stored in the migration history table, omitted if the schemas are too large
 	Expect string `json:"expect,omitempty"`
-	// The actual schema dumped from the database
+	// The actual schema dumped from the database, omitted if the schemas are too large
 	Actual string `json:"actual,omitempty"`
+	// The unified diff from the expected to the actual schema
+	Diff string `json:"diff,omitempty"`
+}
+
+// AnomalyDatabaseIndexMissingPayload is the API message for missing foreign key index payloads.
+type AnomalyDatabaseIndexMissingPayload struct {
+	ForeignKeyList []IndexMissingForeignKey `json:"foreignKeyList,omitempty"`
+}
+
+// IndexMissingForeignKey is the API message for a foreign key lacking a supporting index.
+type IndexMissingForeignKey struct {
+	Table string `json:"table,omitempty"`
+	// The referencing columns of the foreign key
+	ColumnList []string `json:"columnList,omitempty"`
+	// The DDL to create an index covering the foreign key columns
+	SuggestedIndexDDL string `json:"suggestedIndexDDL,omitempty"`
+}
+
+// AnomalyDatabaseConnectionCountHighPayload is the API message for high connection count payloads.
+type AnomalyDatabaseConnectionCountHighPayload struct {
+	CurrentConnections int `json:"currentConnections,omitempty"`
+	MaxConnections     int `json:"maxConnections,omitempty"`
+	// The percentage of max connections in use
+	Percentage int `json:"percentage,omitempty"`
+}
+
+// AnomalyDatabaseLongRunningTransactionPayload is the API message for long-running transaction payloads.
+type AnomalyDatabaseLongRunningTransactionPayload struct {
+	// The process id of the most long-running transaction
+	PID int64 `json:"pid,omitempty"`
+	// How long the transaction has been running in seconds
+	DurationSeconds int64 `json:"durationSeconds,omitempty"`
+	// The statement running in the transaction
+	Query string `json:"query,omitempty"`
+	// The number of transactions running longer than the threshold
+	Count int `json:"count,omitempty"`
+}
+
+// AnomalyDatabaseReplicationLagPayload is the API message for replication lag payloads.
+type AnomalyDatabaseReplicationLagPayload struct {
+	// How far the replica falls behind its primary in seconds
+	LagSeconds int64 `json:"lagSeconds,omitempty"`
+	// The lag in seconds above which the anomaly is raised
+	ThresholdSeconds int64 `json:"thresholdSeconds,omitempty"`
+	// The "host:port" of the primary the replica replicates from, empty if unknown
+	Primary string `json:"primary,omitempty"`
+}
+
+// AnomalyDatabaseTableBloatPayload is the API message for table bloat payloads.
+type AnomalyDatabaseTableBloatPayload struct {
+	// The table with the highest dead tuple ratio
+	Table      string `json:"table,omitempty"`
+	DeadTuples int64  `json:"deadTuples,omitempty"`
+	LiveTuples int64  `json:"liveTuples,omitempty"`
+	// The percentage of dead tuples among all tuples of the table
+	Percentage int `json:"percentage,omitempty"`
+	// The percentage of dead tuples above which the anomaly is raised
+	Threshold int `json:"threshold,omitempty"`
+}
+
+// AnomalyDatabaseMissingIndexPayload is the API message for missing index payloads.
+type AnomalyDatabaseMissingIndexPayload struct {
+	// The table with the highest ratio of sequential to index scans
+	Table string `json:"table,omitempty"`
+	// The number of sequential scans and index scans of the table. On MySQL, they are the rows read by full table scans and through indexes
+	SequentialScans int64 `json:"sequentialScans,omitempty"`
+	IndexScans      int64 `json:"indexScans,omitempty"`
+	// The ratio of the sequential scans to the index scans
+	Ratio float64 `json:"ratio,omitempty"`
+	// The ratio above which the anomaly is raised
+	Threshold float64 `json:"threshold,omitempty"`
+}
+
+// AnomalyDatabaseScanTimeoutPayload is the API message for database scan timeout payloads.
+type AnomalyDatabaseScanTimeoutPayload struct {
+	// The timeout in seconds the database scan exceeds
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
+}
+
+// AnomalyDatabaseVersionOutdatedPayload is the API message for outdated database version payloads.
+type AnomalyDatabaseVersionOutdatedPayload struct {
+	// The version reported by the database engine
+	Version string `json:"version,omitempty"`
+	// The minimum recommended version of the database engine
+	MinimumVersion string `json:"minimumVersion,omitempty"`
+}
+
+// AnomalyInstanceEngineVersionEOLPayload is the API message for engine version end-of-life payloads.
+type AnomalyInstanceEngineVersionEOLPayload struct {
+	// The version reported by the database engine
+	Version string `json:"version,omitempty"`
+	// The end-of-life date of the release line of the version, in YYYY-MM-DD
+	EOLDate string `json:"eolDate,omitempty"`
+	// The days until the end of life, negative if the release line is past its end of life
+	DaysRemaining int `json:"daysRemaining"`
+}
+
+// AnomalyDatabaseInsecureConnectionPayload is the API message for insecure connection payloads.
+type AnomalyDatabaseInsecureConnectionPayload struct {
+	// Whether the connection uses TLS
+	Encrypted bool `json:"encrypted"`
+	// Whether the server certificate is self-signed
+	SelfSigned bool `json:"selfSigned"`
+	// The expiration time of the server certificate, 0 if unknown
+	CertificateExpireTs int64 `json:"certificateExpireTs,omitempty"`
+}
+
+// AnomalyDatabaseTableRowCountGrowthPayload is the API message for table row count growth payloads.
+type AnomalyDatabaseTableRowCountGrowthPayload struct {
+	// The table with the highest growth ratio
+	Table string `json:"table,omitempty"`
+	// The row count estimate observed in the previous round and when it was observed
+	PreviousRowCount int64 `json:"previousRowCount,omitempty"`
+	PreviousTs       int64 `json:"previousTs,omitempty"`
+	// The row count estimate observed in this round
+	CurrentRowCount int64 `json:"currentRowCount,omitempty"`
+	// The ratio of the current to the previous row count
+	GrowthRatio float64 `json:"growthRatio,omitempty"`
+	// The ratio above which the anomaly is raised
+	Threshold float64 `json:"threshold,omitempty"`
 }
 
 // Anomaly is the API message for an anomaly.
@@ -109,6 +405,11 @@ type Anomaly struct {
 	// Calculated field derived from type
 	Severity AnomalySeverity `jsonapi:"attr,severity"`
 	Payload  string          `jsonapi:"attr,payload"`
+	// LastSeenTs is the last time the anomaly is found, while CreatedTs is the first time.
+	// The age of an active anomaly is the time elapsed since CreatedTs.
+	LastSeenTs int64 `jsonapi:"attr,lastSeenTs"`
+	// ArchiveReason is why the anomaly is archived, empty for the active anomaly.
+	ArchiveReason AnomalyArchiveReason `jsonapi:"attr,archiveReason"`
 }
 
 // AnomalyUpsert is the API message for creating an anomaly.
@@ -118,6 +419,7 @@ type AnomalyUpsert struct {
 
 	// Related fields
 	InstanceID int
+	// DatabaseID is nil for instance anomaly, the anomaly is stored with a NULL database_id.
 	DatabaseID *int
 
 	// Domain specific fields
@@ -125,17 +427,36 @@ type AnomalyUpsert struct {
 	Payload string      `jsonapi:"attr,payload"`
 }
 
+// AnomalySortBy is the sort order of the anomaly list.
+type AnomalySortBy string
+
+const (
+	// AnomalySortByCreatedTs sorts the anomalies from the newest to the oldest.
+	AnomalySortByCreatedTs AnomalySortBy = "CREATED_TS"
+	// AnomalySortBySeverity sorts the anomalies from the most to the least severe, and then from the newest to the oldest.
+	AnomalySortBySeverity AnomalySortBy = "SEVERITY"
+)
+
 // AnomalyFind is the API message for finding anomalies.
 type AnomalyFind struct {
 	// Standard fields
 	RowStatus *RowStatus
 
 	// Related fields
-	InstanceID *int
-	DatabaseID *int
-	Type       *AnomalyType
+	// EnvironmentID matches the anomalies of the instances in the environment
+	EnvironmentID *int
+	InstanceID    *int
+	DatabaseID    *int
+	Type          *AnomalyType
 	// Only applicable if InstanceID is specified, if true, then we only return instance anomaly (database_id is NULL)
 	InstanceOnly bool
+	// Severity matches the anomaly types of the severity, see AnomalySeverityFromType
+	Severity *AnomalySeverity
+
+	// Pagination fields, the anomalies are ordered by ID if SortBy is not specified
+	SortBy AnomalySortBy
+	Limit  *int
+	Offset *int
 }
 
 func (find *AnomalyFind) String() string {
@@ -146,17 +467,112 @@ func (find *AnomalyFind) String() string {
 	return string(str)
 }
 
+// AnomalyGroup is the anomalies of a database, or those of an instance itself, grouped by type.
+type AnomalyGroup struct {
+	InstanceID int
+	// DatabaseID is nil for the group of the instance anomalies
+	DatabaseID *int
+	// AnomalyMap is the anomalies of each type, an active anomaly is the only one of its type in the group
+	AnomalyMap map[AnomalyType][]*Anomaly
+}
+
+// GroupAnomalyList groups the anomalies by the database and the type, the instance anomalies are grouped by the instance.
+// The groups are ordered by the instance ID and then the database ID, the group of the instance anomalies comes first.
+func GroupAnomalyList(anomalyList []*Anomaly) []*AnomalyGroup {
+	type groupKey struct {
+		instanceID int
+		databaseID int
+	}
+	groupMap := make(map[groupKey]*AnomalyGroup)
+	var groupList []*AnomalyGroup
+	for _, anomaly := range anomalyList {
+		// Database IDs are positive, so 0 stands for the instance anomalies.
+		key := groupKey{instanceID: anomaly.InstanceID}
+		if anomaly.DatabaseID != nil {
+			key.databaseID = *anomaly.DatabaseID
+		}
+		group, ok := groupMap[key]
+		if !ok {
+			group = &AnomalyGroup{
+				InstanceID: anomaly.InstanceID,
+				DatabaseID: anomaly.DatabaseID,
+				AnomalyMap: make(map[AnomalyType][]*Anomaly),
+			}
+			groupMap[key] = group
+			groupList = append(groupList, group)
+		}
+		group.AnomalyMap[anomaly.Type] = append(group.AnomalyMap[anomaly.Type], anomaly)
+	}
+	sort.Slice(groupList, func(i, j int) bool {
+		if groupList[i].InstanceID != groupList[j].InstanceID {
+			return groupList[i].InstanceID < groupList[j].InstanceID
+		}
+		if groupList[i].DatabaseID == nil || groupList[j].DatabaseID == nil {
+			return groupList[i].DatabaseID == nil && groupList[j].DatabaseID != nil
+		}
+		return *groupList[i].DatabaseID < *groupList[j].DatabaseID
+	})
+	return groupList
+}
+
+// AnomalyArchiveReason is the reason an anomaly is archived, i.e. resolved.
+type AnomalyArchiveReason string
+
+const (
+	// AnomalyArchiveReasonConnectionRestored is the reason for the connection to the instance or database succeeding again.
+	AnomalyArchiveReasonConnectionRestored AnomalyArchiveReason = "CONNECTION_RESTORED"
+	// AnomalyArchiveReasonConnectionSecure is the reason for the connection to the instance using TLS with a valid certificate.
+	AnomalyArchiveReasonConnectionSecure AnomalyArchiveReason = "CONNECTION_SECURE"
+	// AnomalyArchiveReasonMigrationSchemaSetUp is the reason for the migration schema of the instance being set up.
+	AnomalyArchiveReasonMigrationSchemaSetUp AnomalyArchiveReason = "MIGRATION_SCHEMA_SET_UP"
+	// AnomalyArchiveReasonBelowThreshold is the reason for the measurement, e.g. the disk usage or the replication lag, falling within the threshold.
+	AnomalyArchiveReasonBelowThreshold AnomalyArchiveReason = "BELOW_THRESHOLD"
+	// AnomalyArchiveReasonVersionSupported is the reason for the engine version being upgraded to a supported one.
+	AnomalyArchiveReasonVersionSupported AnomalyArchiveReason = "VERSION_SUPPORTED"
+	// AnomalyArchiveReasonDatabaseTracked is the reason for the database having the migration history.
+	AnomalyArchiveReasonDatabaseTracked AnomalyArchiveReason = "DATABASE_TRACKED"
+	// AnomalyArchiveReasonSchemaMatched is the reason for the schema matching the baseline, including the one adopted from the live schema.
+	AnomalyArchiveReasonSchemaMatched AnomalyArchiveReason = "SCHEMA_MATCHED"
+	// AnomalyArchiveReasonIndexPresent is the reason for the foreign keys being covered by the indexes.
+	AnomalyArchiveReasonIndexPresent AnomalyArchiveReason = "INDEX_PRESENT"
+	// AnomalyArchiveReasonBackupPolicySatisfied is the reason for the backup setting meeting the backup plan policy.
+	AnomalyArchiveReasonBackupPolicySatisfied AnomalyArchiveReason = "BACKUP_POLICY_SATISFIED"
+	// AnomalyArchiveReasonBackupPresent is the reason for the backup expected by the schedule being present.
+	AnomalyArchiveReasonBackupPresent AnomalyArchiveReason = "BACKUP_PRESENT"
+	// AnomalyArchiveReasonBackupVerified is the reason for the backup file matching its checksum.
+	AnomalyArchiveReasonBackupVerified AnomalyArchiveReason = "BACKUP_VERIFIED"
+	// AnomalyArchiveReasonBackupPruned is the reason for the expired backups being pruned.
+	AnomalyArchiveReasonBackupPruned AnomalyArchiveReason = "BACKUP_PRUNED"
+	// AnomalyArchiveReasonScanCompleted is the reason for the database scan completing within the timeout.
+	AnomalyArchiveReasonScanCompleted AnomalyArchiveReason = "SCAN_COMPLETED"
+	// AnomalyArchiveReasonCheckDisabled is the reason for the check being turned off or no longer applying, e.g. the scan disabled
+	// for the environment, the instance or the anomaly type, or the database excluded from the scan.
+	AnomalyArchiveReasonCheckDisabled AnomalyArchiveReason = "CHECK_DISABLED"
+	// AnomalyArchiveReasonDatabaseNotFound is the reason for the database no longer being found on the instance by the sync.
+	AnomalyArchiveReasonDatabaseNotFound AnomalyArchiveReason = "DATABASE_NOT_FOUND"
+)
+
 // AnomalyArchive is the API message for archiving an anomoly.
 type AnomalyArchive struct {
 	InstanceID *int
 	DatabaseID *int
 	Type       AnomalyType
+	// Reason is why the anomaly is archived, recorded on the archived anomaly.
+	Reason AnomalyArchiveReason
 }
 
 // AnomalyService is the service for anomaly.
 type AnomalyService interface {
 	// UpsertActiveAnomaly would update the existing active anomaly if both database id and type match, otherwise create a new one.
-	UpsertActiveAnomaly(ctx context.Context, upsert *AnomalyUpsert) (*Anomaly, error)
+	// Returns true if a new anomaly is created, i.e. the anomaly is newly opened rather than re-observed.
+	// Re-observing keeps the CreatedTs as the first seen time and only advances the LastSeenTs, while an anomaly
+	// found again after being archived is opened as a new one.
+	UpsertActiveAnomaly(ctx context.Context, upsert *AnomalyUpsert) (*Anomaly, bool, error)
 	FindAnomalyList(ctx context.Context, find *AnomalyFind) ([]*Anomaly, error)
+	// CountAnomaly returns the number of anomalies matching find regardless of its Limit and Offset, for paginating FindAnomalyList.
+	CountAnomaly(ctx context.Context, find *AnomalyFind) (int, error)
 	ArchiveAnomaly(ctx context.Context, archive *AnomalyArchive) error
+	// ArchiveAnomaliesForDatabase archives all active anomalies of the database regardless of their types, e.g. after the database
+	// is dropped from the instance. Returns the number of archived anomalies.
+	ArchiveAnomaliesForDatabase(ctx context.Context, databaseID int) (int, error)
 }
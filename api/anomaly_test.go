@@ -0,0 +1,93 @@
+package api
+
+import "testing"
+
+func TestAnomalySeverityFromType(t *testing.T) {
+	tests := []struct {
+		anomalyType AnomalyType
+		want        AnomalySeverity
+	}{
+		{AnomalyInstanceConnection, AnomalySeverityCritical},
+		{AnomalyInstanceMigrationSchema, AnomalySeverityCritical},
+		{AnomalyInstanceDiskSpaceLow, AnomalySeverityHigh},
+		{AnomalyDatabaseBackupPolicyViolation, AnomalySeverityMedium},
+		{AnomalyDatabaseBackupMissing, AnomalySeverityHigh},
+		{AnomalyDatabaseBackupPruneFailed, AnomalySeverityMedium},
+		{AnomalyDatabaseBackupCorrupt, AnomalySeverityHigh},
+		{AnomalyDatabaseBackupSizeAnomalous, AnomalySeverityHigh},
+		{AnomalyDatabaseConnection, AnomalySeverityCritical},
+		{AnomalyDatabaseSchemaDrift, AnomalySeverityCritical},
+		{AnomalyDatabaseIndexMissing, AnomalySeverityMedium},
+		{AnomalyDatabaseConnectionCountHigh, AnomalySeverityHigh},
+		{AnomalyDatabaseLongRunningTransaction, AnomalySeverityHigh},
+		{AnomalyDatabaseReplicationLag, AnomalySeverityHigh},
+		{AnomalyDatabaseTableBloat, AnomalySeverityMedium},
+		{AnomalyDatabaseScanTimeout, AnomalySeverityMedium},
+		{AnomalyDatabaseVersionOutdated, AnomalySeverityMedium},
+		{AnomalyInstanceEngineVersionEOL, AnomalySeverityMedium},
+		{AnomalyDatabaseMissingIndex, AnomalySeverityMedium},
+		{AnomalyDatabaseConnectionUnencrypted, AnomalySeverityHigh},
+		{AnomalyDatabaseInsecureConnection, AnomalySeverityHigh},
+		{AnomalyDatabaseTableRowCountGrowth, AnomalySeverityMedium},
+		{AnomalyDatabaseUntracked, AnomalySeverityMedium},
+		{AnomalyDatabaseNoMigrationHistory, AnomalySeverityHigh},
+	}
+	// Every anomaly type raised by the scanner must have its severity decided here.
+	covered := make(map[AnomalyType]bool)
+	for _, tt := range tests {
+		covered[tt.anomalyType] = true
+		if got := AnomalySeverityFromType(tt.anomalyType); got != tt.want {
+			t.Errorf("AnomalySeverityFromType(%s) = %s, want %s", tt.anomalyType, got, tt.want)
+		}
+	}
+	for anomalyType := range AnomalyTypes {
+		if !covered[anomalyType] {
+			t.Errorf("missing the severity of anomaly type %s", anomalyType)
+		}
+	}
+}
+
+func TestGroupAnomalyList(t *testing.T) {
+	db1, db2 := 1, 2
+	anomalyList := []*Anomaly{
+		{ID: 1, InstanceID: 1, DatabaseID: &db2, Type: AnomalyDatabaseBackupMissing},
+		{ID: 2, InstanceID: 1, DatabaseID: &db1, Type: AnomalyDatabaseConnection},
+		{ID: 3, InstanceID: 1, Type: AnomalyInstanceConnection},
+		{ID: 4, InstanceID: 1, DatabaseID: &db1, Type: AnomalyDatabaseSchemaDrift},
+		{ID: 5, InstanceID: 1, DatabaseID: &db2, Type: AnomalyDatabaseSchemaDrift},
+		{ID: 6, InstanceID: 1, DatabaseID: &db1, Type: AnomalyDatabaseBackupPolicyViolation},
+	}
+	groupList := GroupAnomalyList(anomalyList)
+
+	type group struct {
+		databaseID int
+		typeMap    map[AnomalyType]int
+	}
+	// The instance anomalies are in the group of database ID 0.
+	want := []group{
+		{0, map[AnomalyType]int{AnomalyInstanceConnection: 3}},
+		{1, map[AnomalyType]int{AnomalyDatabaseConnection: 2, AnomalyDatabaseSchemaDrift: 4, AnomalyDatabaseBackupPolicyViolation: 6}},
+		{2, map[AnomalyType]int{AnomalyDatabaseBackupMissing: 1, AnomalyDatabaseSchemaDrift: 5}},
+	}
+	if len(groupList) != len(want) {
+		t.Fatalf("got %d groups, want %d", len(groupList), len(want))
+	}
+	for i, g := range groupList {
+		databaseID := 0
+		if g.DatabaseID != nil {
+			databaseID = *g.DatabaseID
+		}
+		if g.InstanceID != 1 || databaseID != want[i].databaseID {
+			t.Errorf("group %d is of instance %d database %d, want instance 1 database %d", i, g.InstanceID, databaseID, want[i].databaseID)
+			continue
+		}
+		if len(g.AnomalyMap) != len(want[i].typeMap) {
+			t.Errorf("group %d has %d types, want %d", i, len(g.AnomalyMap), len(want[i].typeMap))
+		}
+		for anomalyType, id := range want[i].typeMap {
+			if list := g.AnomalyMap[anomalyType]; len(list) != 1 || list[0].ID != id {
+				t.Errorf("group %d type %s = %v, want anomaly %d", i, anomalyType, list, id)
+			}
+		}
+	}
+}
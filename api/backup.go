@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
+
+	"github.com/bytebase/bytebase/common"
 )
 
 // BackupStatus is the status of a backup.
@@ -56,16 +59,55 @@ type BackupStorageBackend string
 const (
 	// BackupStorageBackendLocal is the local storage backend for a backup.
 	BackupStorageBackendLocal BackupStorageBackend = "LOCAL"
+	// BackupStorageBackendS3 is the AWS S3 storage backend for a backup.
+	BackupStorageBackendS3 BackupStorageBackend = "S3"
 )
 
 func (e BackupStorageBackend) String() string {
 	switch e {
 	case BackupStorageBackendLocal:
 		return "LOCAL"
+	case BackupStorageBackendS3:
+		return "S3"
+	}
+	return "UNKNOWN"
+}
+
+// BackupCompression is the compression codec of a backup file.
+type BackupCompression string
+
+const (
+	// BackupCompressionNone is the codec for the uncompressed backup, which the backups taken before compression support use.
+	BackupCompressionNone BackupCompression = "NONE"
+	// BackupCompressionGzip is the gzip codec.
+	BackupCompressionGzip BackupCompression = "GZIP"
+	// BackupCompressionZstd is the zstd codec.
+	BackupCompressionZstd BackupCompression = "ZSTD"
+)
+
+func (e BackupCompression) String() string {
+	switch e {
+	case BackupCompressionNone:
+		return "NONE"
+	case BackupCompressionGzip:
+		return "GZIP"
+	case BackupCompressionZstd:
+		return "ZSTD"
 	}
 	return "UNKNOWN"
 }
 
+// FileExtension returns the extension appended to the backup file name for the codec.
+func (e BackupCompression) FileExtension() string {
+	switch e {
+	case BackupCompressionGzip:
+		return ".gz"
+	case BackupCompressionZstd:
+		return ".zst"
+	}
+	return ""
+}
+
 // Backup is the API message for a backup.
 type Backup struct {
 	ID int `jsonapi:"primary,backup"`
@@ -86,14 +128,23 @@ type Backup struct {
 	Status         BackupStatus         `jsonapi:"attr,status"`
 	Type           BackupType           `jsonapi:"attr,type"`
 	StorageBackend BackupStorageBackend `jsonapi:"attr,storageBackend"`
+	// Compression is the codec the backup file is compressed with, which the restore decompresses with.
+	Compression BackupCompression `jsonapi:"attr,compression"`
 	// Upon taking the database backup, we will also record the current migration history version if exists.
 	// And when restoring the backup, we will record this in the migration history.
 	MigrationHistoryVersion string `jsonapi:"attr,migrationHistoryVersion"`
-	Path                    string `jsonapi:"attr,path"`
-	Comment                 string `jsonapi:"attr,comment"`
+	// Path is relative to the data directory for the LOCAL backend,
+	// and is the object URL s3://<bucket>/<key> for the S3 backend.
+	Path    string `jsonapi:"attr,path"`
+	Comment string `jsonapi:"attr,comment"`
+	// Checksum is the hex SHA-256 of the backup file, it's empty for the backups not done or taken before checksum support.
+	Checksum string `jsonapi:"attr,checksum"`
+	// SizeBytes is the size of the stored backup file, it's 0 for the backups not done or taken before size support.
+	SizeBytes int64 `jsonapi:"attr,sizeBytes"`
 }
 
 // BackupCreate is the API message for creating a backup.
+// The storage backend of the backup is decided by the backup plan policy of the environment.
 type BackupCreate struct {
 	// Standard fields
 	// Value is assigned from the jwt subject field passed by the client.
@@ -103,12 +154,14 @@ type BackupCreate struct {
 	DatabaseID int `jsonapi:"attr,databaseId"`
 
 	// Domain specific fields
-	Name                    string               `jsonapi:"attr,name"`
-	Status                  BackupStatus         `jsonapi:"attr,status"`
-	Type                    BackupType           `jsonapi:"attr,type"`
-	StorageBackend          BackupStorageBackend `jsonapi:"attr,storageBackend"`
-	MigrationHistoryVersion string               `jsonapi:"attr,migrationHistoryVersion"`
-	Path                    string               `jsonapi:"attr,path"`
+	Name           string               `jsonapi:"attr,name"`
+	Status         BackupStatus         `jsonapi:"attr,status"`
+	Type           BackupType           `jsonapi:"attr,type"`
+	StorageBackend BackupStorageBackend `jsonapi:"attr,storageBackend"`
+	// Compression is optional, and defaults to GZIP if empty.
+	Compression             BackupCompression `jsonapi:"attr,compression"`
+	MigrationHistoryVersion string            `jsonapi:"attr,migrationHistoryVersion"`
+	Path                    string            `jsonapi:"attr,path"`
 }
 
 // BackupFind is the API message for finding backups.
@@ -142,6 +195,18 @@ type BackupPatch struct {
 	// Domain specific fields
 	Status  string
 	Comment string
+	// Checksum and SizeBytes are set when the backup is done.
+	Checksum  *string
+	SizeBytes *int64
+}
+
+// BackupDelete is the API message for deleting a backup.
+type BackupDelete struct {
+	ID int
+
+	// Standard fields
+	// Value is assigned from the jwt subject field passed by the client.
+	DeleterID int
 }
 
 // BackupSetting is the backup setting for a database.
@@ -163,13 +228,22 @@ type BackupSetting struct {
 	Database *Database
 
 	// Domain specific fields
-	Enabled   bool `jsonapi:"attr,enabled"`
-	Hour      int  `jsonapi:"attr,hour"`
-	DayOfWeek int  `jsonapi:"attr,dayOfWeek"`
+	Enabled bool `jsonapi:"attr,enabled"`
+	Hour    int  `jsonapi:"attr,hour"`
+	// Minute is the minute of the hour to take the backup, -1 means unset.
+	Minute    int `jsonapi:"attr,minute"`
+	DayOfWeek int `jsonapi:"attr,dayOfWeek"`
+	// DayOfMonth is the day of the month to take the monthly backup, -1 means unset.
+	DayOfMonth int `jsonapi:"attr,dayOfMonth"`
 	// HookURL is the callback url to be requested (using HTTP GET) after a successful backup.
 	HookURL string `jsonapi:"attr,hookUrl"`
 }
 
+// Validate returns a common.Invalid error if the schedule of the backup setting is out of range.
+func (bs *BackupSetting) Validate() error {
+	return validateBackupSchedule(bs.Hour, bs.Minute, bs.DayOfWeek, bs.DayOfMonth)
+}
+
 // BackupSettingFind is the message to get a backup settings.
 type BackupSettingFind struct {
 	ID *int
@@ -193,16 +267,52 @@ type BackupSettingUpsert struct {
 	EnvironmentID int
 
 	// Domain specific fields
-	Enabled   bool   `jsonapi:"attr,enabled"`
-	Hour      int    `jsonapi:"attr,hour"`
-	DayOfWeek int    `jsonapi:"attr,dayOfWeek"`
-	HookURL   string `jsonapi:"attr,hookUrl"`
+	Enabled bool `jsonapi:"attr,enabled"`
+	Hour    int  `jsonapi:"attr,hour"`
+	// Minute must be between 0 and 59, or -1 for unset which defaults to 0 if the backup is enabled.
+	Minute    int `jsonapi:"attr,minute"`
+	DayOfWeek int `jsonapi:"attr,dayOfWeek"`
+	// DayOfMonth must be between 1 and 28 for monthly backup, or -1 for unset, DayOfWeek must be -1 if it's set.
+	DayOfMonth int    `jsonapi:"attr,dayOfMonth"`
+	HookURL    string `jsonapi:"attr,hookUrl"`
+}
+
+// Validate returns a common.Invalid error if the schedule of the backup setting upsert is out of range.
+func (upsert *BackupSettingUpsert) Validate() error {
+	return validateBackupSchedule(upsert.Hour, upsert.Minute, upsert.DayOfWeek, upsert.DayOfMonth)
+}
+
+// validateBackupSchedule validates the backup time, the hour is between 0 and 23, the minute is between 0 and 59
+// or -1 for unset, the day of week is between 0 (Sunday) and 6 (Saturday) for weekly backup or -1 for daily backup,
+// and the day of month is between 1 and 28 for monthly backup or -1 for unset. The day of week and the day of month
+// can't be both set.
+func validateBackupSchedule(hour, minute, dayOfWeek, dayOfMonth int) error {
+	if hour < 0 || hour > 23 {
+		return &common.Error{Code: common.Invalid, Err: fmt.Errorf("backup setting Hour %d should be between 0 and 23", hour)}
+	}
+	if minute < -1 || minute > 59 {
+		return &common.Error{Code: common.Invalid, Err: fmt.Errorf("backup setting Minute %d should be between 0 and 59, or -1 for unset", minute)}
+	}
+	if dayOfWeek < -1 || dayOfWeek > 6 {
+		return &common.Error{Code: common.Invalid, Err: fmt.Errorf("backup setting DayOfWeek %d should be between 0 and 6, or -1 for daily backup", dayOfWeek)}
+	}
+	// The day of month stops at 28 so that the monthly backup runs in every month.
+	if dayOfMonth != -1 && (dayOfMonth < 1 || dayOfMonth > 28) {
+		return &common.Error{Code: common.Invalid, Err: fmt.Errorf("backup setting DayOfMonth %d should be between 1 and 28, or -1 for unset", dayOfMonth)}
+	}
+	if dayOfMonth != -1 && dayOfWeek != -1 {
+		return &common.Error{Code: common.Invalid, Err: fmt.Errorf("backup setting DayOfWeek %d should be -1 when DayOfMonth %d is set", dayOfWeek, dayOfMonth)}
+	}
+	return nil
 }
 
 // BackupSettingsMatch is the message to find backup settings matching the conditions.
 type BackupSettingsMatch struct {
-	Hour      int
-	DayOfWeek int
+	Hour int
+	// Minute is the current minute of the hour, the settings whose minute has been reached match.
+	Minute     int
+	DayOfWeek  int
+	DayOfMonth int
 }
 
 // BackupService is the service for backups.
@@ -212,6 +322,7 @@ type BackupService interface {
 	// Returns backup list in updated_ts descending order.
 	FindBackupList(ctx context.Context, find *BackupFind) ([]*Backup, error)
 	PatchBackup(ctx context.Context, patch *BackupPatch) (*Backup, error)
+	DeleteBackup(ctx context.Context, delete *BackupDelete) error
 	FindBackupSetting(ctx context.Context, find *BackupSettingFind) (*BackupSetting, error)
 	UpsertBackupSetting(ctx context.Context, upsert *BackupSettingUpsert) (*BackupSetting, error)
 	UpsertBackupSettingTx(ctx context.Context, tx *sql.Tx, upsert *BackupSettingUpsert) (*BackupSetting, error)
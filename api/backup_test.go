@@ -0,0 +1,48 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/bytebase/bytebase/common"
+)
+
+func TestBackupSettingValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		hour       int
+		minute     int
+		dayOfWeek  int
+		dayOfMonth int
+		wantErr    bool
+	}{
+		{"daily", 0, 0, -1, -1, false},
+		{"weeklySunday", 23, 59, 0, -1, false},
+		{"weeklySaturday", 12, 30, 6, -1, false},
+		{"minuteUnset", 12, -1, -1, -1, false},
+		{"hourTooSmall", -1, 0, -1, -1, true},
+		{"hourTooLarge", 24, 0, -1, -1, true},
+		{"minuteTooSmall", 0, -2, -1, -1, true},
+		{"minuteTooLarge", 0, 60, -1, -1, true},
+		{"dayOfWeekTooSmall", 0, 0, -2, -1, true},
+		{"dayOfWeekTooLarge", 0, 0, 7, -1, true},
+		{"monthlyFirstDay", 0, 0, -1, 1, false},
+		{"monthlyLastDay", 0, 0, -1, 28, false},
+		{"dayOfMonthTooSmall", 0, 0, -1, 0, true},
+		{"dayOfMonthTooLarge", 0, 0, -1, 29, true},
+		{"dayOfMonthWithDayOfWeek", 0, 0, 1, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting := &BackupSetting{Hour: tt.hour, Minute: tt.minute, DayOfWeek: tt.dayOfWeek, DayOfMonth: tt.dayOfMonth}
+			upsert := &BackupSettingUpsert{Hour: tt.hour, Minute: tt.minute, DayOfWeek: tt.dayOfWeek, DayOfMonth: tt.dayOfMonth}
+			for _, err := range []error{setting.Validate(), upsert.Validate()} {
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("Validate() error = %v, wantErr %t", err, tt.wantErr)
+				}
+				if err != nil && common.ErrorCode(err) != common.Invalid {
+					t.Errorf("ErrorCode() = %v, want %v", common.ErrorCode(err), common.Invalid)
+				}
+			}
+		})
+	}
+}
@@ -66,6 +66,8 @@ type Database struct {
 	Collation            string     `jsonapi:"attr,collation"`
 	SyncStatus           SyncStatus `jsonapi:"attr,syncStatus"`
 	LastSuccessfulSyncTs int64      `jsonapi:"attr,lastSuccessfulSyncTs"`
+	// Managed is whether the database is expected to be under migration control, i.e. to have migration history.
+	Managed bool `jsonapi:"attr,managed"`
 }
 
 // DatabaseCreate is the API message for creating a database.
@@ -97,6 +99,8 @@ type DatabaseFind struct {
 	// Domain specific fields
 	Name               *string
 	IncludeAllDatabase bool
+	// HasAnomalyType only returns the databases having an active anomaly of the type if specified.
+	HasAnomalyType *AnomalyType
 }
 
 func (find *DatabaseFind) String() string {
@@ -122,6 +126,7 @@ type DatabasePatch struct {
 	// Domain specific fields
 	SyncStatus           *SyncStatus
 	LastSuccessfulSyncTs *int64
+	Managed              *bool `jsonapi:"attr,managed"`
 }
 
 // DatabaseService is the service for databases.
@@ -34,6 +34,13 @@ type Instance struct {
 	Host          string  `jsonapi:"attr,host"`
 	Port          string  `jsonapi:"attr,port"`
 	Username      string  `jsonapi:"attr,username"`
+	// Replica is whether the instance is a read replica
+	Replica bool `jsonapi:"attr,replica"`
+	// EnableAnomalyScan is whether the anomaly scanner scans the instance, its anomalies are archived if disabled
+	EnableAnomalyScan bool `jsonapi:"attr,enableAnomalyScan"`
+	// ServiceName and SID identify the Oracle database to connect to, only one of them is set for Oracle
+	ServiceName string `jsonapi:"attr,serviceName"`
+	SID         string `jsonapi:"attr,sid"`
 	// Password is not returned to the client
 	Password string
 }
@@ -55,6 +62,11 @@ type InstanceCreate struct {
 	Port         string  `jsonapi:"attr,port"`
 	Username     string  `jsonapi:"attr,username"`
 	Password     string  `jsonapi:"attr,password"`
+	Replica      bool    `jsonapi:"attr,replica"`
+	// EnableAnomalyScan is true if not specified
+	EnableAnomalyScan *bool  `jsonapi:"attr,enableAnomalyScan"`
+	ServiceName       string `jsonapi:"attr,serviceName"`
+	SID               string `jsonapi:"attr,sid"`
 }
 
 // InstanceFind is the API message for finding instances.
@@ -63,6 +75,9 @@ type InstanceFind struct {
 
 	// Standard fields
 	RowStatus *RowStatus
+
+	// Domain specific fields
+	EnableAnomalyScan *bool
 }
 
 func (find *InstanceFind) String() string {
@@ -88,9 +103,16 @@ type InstancePatch struct {
 	ExternalLink     *string `jsonapi:"attr,externalLink"`
 	Host             *string `jsonapi:"attr,host"`
 	Port             *string `jsonapi:"attr,port"`
+	Replica          *bool   `jsonapi:"attr,replica"`
 	Username         *string `jsonapi:"attr,username"`
 	Password         *string `jsonapi:"attr,password"`
 	UseEmptyPassword bool    `jsonapi:"attr,useEmptyPassword"`
+
+	// EnableAnomalyScan is whether the anomaly scanner scans the instance
+	EnableAnomalyScan *bool `jsonapi:"attr,enableAnomalyScan"`
+	// ServiceName and SID are set to empty to clear
+	ServiceName *string `jsonapi:"attr,serviceName"`
+	SID         *string `jsonapi:"attr,sid"`
 }
 
 // InstanceMigrationSchemaStatus is the schema status for instance migration.
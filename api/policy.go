@@ -3,7 +3,12 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bytebase/bytebase/common"
 )
 
 // PolicyType is the type or name of a policy.
@@ -15,11 +20,25 @@ type PipelineApprovalValue string
 // BackupPlanPolicySchedule is value for backup plan policy.
 type BackupPlanPolicySchedule string
 
+// DataMaskingAlgorithm is the algorithm to mask the column data.
+type DataMaskingAlgorithm string
+
+// SQLReviewRuleLevel is the error level of a SQL review rule.
+type SQLReviewRuleLevel string
+
 const (
 	// PolicyTypePipelineApproval is the approval policy type.
 	PolicyTypePipelineApproval PolicyType = "bb.policy.pipeline-approval"
 	// PolicyTypeBackupPlan is the backup plan policy type.
 	PolicyTypeBackupPlan PolicyType = "bb.policy.backup-plan"
+	// PolicyTypeAnomaly is the anomaly scan policy type.
+	PolicyTypeAnomaly PolicyType = "bb.policy.anomaly"
+	// PolicyTypeSchemaDrift is the schema drift policy type.
+	PolicyTypeSchemaDrift PolicyType = "bb.policy.schema-drift"
+	// PolicyTypeDataMasking is the data masking policy type.
+	PolicyTypeDataMasking PolicyType = "bb.policy.data-masking"
+	// PolicyTypeSQLReview is the SQL review policy type.
+	PolicyTypeSQLReview PolicyType = "bb.policy.sql-review"
 
 	// PipelineApprovalValueManualNever is MANUAL_APPROVAL_NEVER approval policy value.
 	PipelineApprovalValueManualNever PipelineApprovalValue = "MANUAL_APPROVAL_NEVER"
@@ -32,13 +51,47 @@ const (
 	BackupPlanPolicyScheduleDaily BackupPlanPolicySchedule = "DAILY"
 	// BackupPlanPolicyScheduleWeekly is WEEKLY backup plan policy value.
 	BackupPlanPolicyScheduleWeekly BackupPlanPolicySchedule = "WEEKLY"
+	// BackupPlanPolicyScheduleMonthly is MONTHLY backup plan policy value.
+	BackupPlanPolicyScheduleMonthly BackupPlanPolicySchedule = "MONTHLY"
+
+	// DataMaskingAlgorithmFull is FULL data masking algorithm value, which masks the whole value.
+	DataMaskingAlgorithmFull DataMaskingAlgorithm = "FULL"
+	// DataMaskingAlgorithmPartial is PARTIAL data masking algorithm value, which keeps part of the value visible.
+	DataMaskingAlgorithmPartial DataMaskingAlgorithm = "PARTIAL"
+	// DataMaskingAlgorithmHash is HASH data masking algorithm value, which replaces the value with its hash.
+	DataMaskingAlgorithmHash DataMaskingAlgorithm = "HASH"
+
+	// SQLReviewRuleLevelError is ERROR SQL review rule level value, the violation blocks the migration.
+	SQLReviewRuleLevelError SQLReviewRuleLevel = "ERROR"
+	// SQLReviewRuleLevelWarning is WARNING SQL review rule level value, the violation is reported but doesn't block the migration.
+	SQLReviewRuleLevelWarning SQLReviewRuleLevel = "WARNING"
+	// SQLReviewRuleLevelDisabled is DISABLED SQL review rule level value, the rule is not checked.
+	SQLReviewRuleLevelDisabled SQLReviewRuleLevel = "DISABLED"
+
+	// SQLReviewRuleTableRequirePK is the rule ID requiring every table to have a primary key.
+	SQLReviewRuleTableRequirePK = "table.require-pk"
+	// SQLReviewRuleTableNoDrop is the rule ID disallowing DROP TABLE.
+	SQLReviewRuleTableNoDrop = "table.no-drop"
+	// SQLReviewRuleColumnRequireComment is the rule ID requiring every column to have a comment.
+	SQLReviewRuleColumnRequireComment = "column.require-comment"
 )
 
+// ErrMalformedPolicyPayload is wrapped by the error from ValidatePolicy when the payload isn't valid JSON of the policy type.
+var ErrMalformedPolicyPayload = errors.New("malformed policy payload")
+
+// GlobalPolicyEnvironmentID is the environment ID of the global policy.
+// The global policy applies to the environments without their own policy of the same type.
+const GlobalPolicyEnvironmentID = 0
+
 var (
 	// PolicyTypes is a set of all policy types.
 	PolicyTypes = map[PolicyType]bool{
 		PolicyTypePipelineApproval: true,
 		PolicyTypeBackupPlan:       true,
+		PolicyTypeAnomaly:          true,
+		PolicyTypeSchemaDrift:      true,
+		PolicyTypeDataMasking:      true,
+		PolicyTypeSQLReview:        true,
 	}
 )
 
@@ -58,6 +111,8 @@ type Policy struct {
 	// Related fields
 	EnvironmentID int
 	Environment   *Environment `jsonapi:"relation,environment"`
+	// InstanceID is the instance of the environment the policy overrides the environment policy for, it's 0 for the environment policy.
+	InstanceID int `jsonapi:"attr,instanceId"`
 
 	// Domain specific fields
 	Type    PolicyType `jsonapi:"attr,type"`
@@ -70,6 +125,8 @@ type PolicyFind struct {
 
 	// Related fields
 	EnvironmentID *int
+	// InstanceID finds the instance policy, the environment policy is found if nil.
+	InstanceID *int
 
 	// Domain specific fields
 	Type *PolicyType `jsonapi:"attr,type"`
@@ -85,6 +142,8 @@ type PolicyUpsert struct {
 
 	// Related fields
 	EnvironmentID int
+	// InstanceID upserts the instance policy, the environment policy is upserted if nil.
+	InstanceID *int
 
 	// Domain specific fields
 	Type    PolicyType
@@ -95,13 +154,68 @@ type PolicyUpsert struct {
 type PolicyService interface {
 	FindPolicy(ctx context.Context, find *PolicyFind) (*Policy, error)
 	UpsertPolicy(ctx context.Context, upsert *PolicyUpsert) (*Policy, error)
+	// DeletePolicy archives the policies matching find, so that the default policy takes effect again.
+	DeletePolicy(ctx context.Context, find *PolicyFind) error
+	// FindEffectivePolicy finds the policy taking effect for an environment, see GetEffectivePolicy for the precedence order.
+	FindEffectivePolicy(ctx context.Context, environmentID int, pType PolicyType) (*Policy, error)
+	// ResolvePolicy finds the policy taking effect for an instance of the environment, which is the instance policy if set,
+	// otherwise the effective policy of the environment.
+	ResolvePolicy(ctx context.Context, environmentID int, instanceID int, pType PolicyType) (*Policy, error)
 	GetBackupPlanPolicy(ctx context.Context, environmentID int) (*BackupPlanPolicy, error)
 	GetPipelineApprovalPolicy(ctx context.Context, environmentID int) (*PipelineApprovalPolicy, error)
+	GetAnomalyPolicy(ctx context.Context, environmentID int) (*AnomalyPolicy, error)
+	GetSchemaDriftPolicy(ctx context.Context, environmentID int) (*SchemaDriftPolicy, error)
 }
 
-// PipelineApprovalPolicy is the policy configuration for pipeline approval
+// PipelineApprovalPolicy is the policy configuration for pipeline approval.
+//
+// The precedence is:
+//  1. Value MANUAL_APPROVAL_NEVER needs no approval, the ApproverRoleList and the StepList are ignored.
+//  2. Otherwise, the StepList if not empty is the approval chain, and the ApproverRoleList is ignored.
+//  3. Otherwise, one approval from the ApproverRoleList, or from any member if it's empty.
+//
+// The chain form may omit Value, which is then MANUAL_APPROVAL_ALWAYS.
 type PipelineApprovalPolicy struct {
 	Value PipelineApprovalValue `json:"value"`
+	// ApproverRoleList is the workspace roles allowed to approve, empty means any member can approve as before.
+	ApproverRoleList []Role `json:"approverRoleList,omitempty"`
+	// StepList is the ordered approval chain, each step must be fully approved before the next one.
+	StepList []PipelineApprovalStep `json:"stepList,omitempty"`
+	// AssigneeGroup is the name of the group the approvals are required from, e.g. "DBA", nil means no group.
+	AssigneeGroup *string `json:"assigneeGroup,omitempty"`
+	// MinApprovals is the number of approvals required for manual approval.
+	// The payloads without it are parsed as 1, which is one approval as before.
+	MinApprovals int `json:"minApprovals,omitempty"`
+}
+
+// PipelineApprovalStep is a step of the approval chain.
+type PipelineApprovalStep struct {
+	// Role is the workspace role of the approvers of the step
+	Role Role `json:"role"`
+	// Count is the number of approvals needed from the role
+	Count int `json:"count"`
+}
+
+// CanApprove returns whether a member with the role can approve the tasks under the policy.
+// For the approval chain, it's whether the role approves any step of the chain.
+func (pa PipelineApprovalPolicy) CanApprove(role Role) bool {
+	if len(pa.StepList) > 0 {
+		for _, step := range pa.StepList {
+			if step.Role == role {
+				return true
+			}
+		}
+		return false
+	}
+	if len(pa.ApproverRoleList) == 0 {
+		return true
+	}
+	for _, approverRole := range pa.ApproverRoleList {
+		if approverRole == role {
+			return true
+		}
+	}
+	return false
 }
 
 func (pa PipelineApprovalPolicy) String() (string, error) {
@@ -118,12 +232,43 @@ func UnmarshalPipelineApprovalPolicy(payload string) (*PipelineApprovalPolicy, e
 	if err := json.Unmarshal([]byte(payload), &pa); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal pipeline approval policy %q: %q", payload, err)
 	}
+	// Tell the missing minApprovals apart from an explicit 0, which ValidatePolicy rejects.
+	var minApprovals struct {
+		MinApprovals *int `json:"minApprovals"`
+	}
+	if err := json.Unmarshal([]byte(payload), &minApprovals); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pipeline approval policy %q: %q", payload, err)
+	}
+	if minApprovals.MinApprovals == nil {
+		pa.MinApprovals = 1
+	}
+	// The chain form implies manual approval.
+	if pa.Value == "" && len(pa.StepList) > 0 {
+		pa.Value = PipelineApprovalValueManualAlways
+	}
 	return &pa, nil
 }
 
 // BackupPlanPolicy is the policy configuration for backup plan.
 type BackupPlanPolicy struct {
 	Schedule BackupPlanPolicySchedule `json:"schedule"`
+	// RetentionDays is the number of days to keep the backups, 0 means keeping the backups forever.
+	RetentionDays int `json:"retentionDays"`
+	// StorageBackend is where the new backups are stored, empty means LOCAL.
+	StorageBackend BackupStorageBackend `json:"storageBackend,omitempty"`
+	// S3 is the bucket for the S3 storage backend.
+	S3 *BackupS3Config `json:"s3,omitempty"`
+}
+
+// BackupS3Config is the AWS S3 bucket storing the backups.
+// The credentials are not part of the policy, they are loaded from the environment variables, the shared
+// credentials file or the IAM role of the server as the AWS SDK does.
+type BackupS3Config struct {
+	Bucket string `json:"bucket"`
+	// Prefix is prepended to the object keys of the backups, e.g. "bytebase/".
+	Prefix string `json:"prefix,omitempty"`
+	// Region is the region of the bucket, empty means looking it up from the bucket.
+	Region string `json:"region,omitempty"`
 }
 
 func (bp BackupPlanPolicy) String() (string, error) {
@@ -143,10 +288,137 @@ func UnmarshalBackupPlanPolicy(payload string) (*BackupPlanPolicy, error) {
 	return &bp, nil
 }
 
+// AnomalyPolicy is the policy configuration for anomaly scan.
+type AnomalyPolicy struct {
+	// Enabled is whether to scan anomalies for the instances and databases in the environment.
+	Enabled bool `json:"enabled"`
+	// DisabledTypeList is the list of anomaly types not checked for the environment,
+	// e.g. the backup anomalies for the ephemeral databases.
+	DisabledTypeList []AnomalyType `json:"disabledTypeList"`
+	// RequireEncryptedConnection is whether the instances in the environment must only be connected with TLS,
+	// the unencrypted connection anomaly is raised otherwise.
+	RequireEncryptedConnection bool `json:"requireEncryptedConnection,omitempty"`
+	// CheckInsecureConnection is whether to check the TLS state of the connections to the instances in the environment,
+	// the insecure connection anomaly is raised on the plaintext fallback or the self-signed or expired server certificate.
+	CheckInsecureConnection bool `json:"checkInsecureConnection,omitempty"`
+}
+
+func (ap AnomalyPolicy) String() (string, error) {
+	s, err := json.Marshal(ap)
+	if err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
+
+// UnmarshalAnomalyPolicy will unmarshal payload to anomaly policy.
+func UnmarshalAnomalyPolicy(payload string) (*AnomalyPolicy, error) {
+	var ap AnomalyPolicy
+	if err := json.Unmarshal([]byte(payload), &ap); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal anomaly policy %q: %q", payload, err)
+	}
+	// Tell the missing enabled apart from an explicit false, so that a policy only disabling some types keeps the scan on.
+	var enabled struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.Unmarshal([]byte(payload), &enabled); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal anomaly policy %q: %q", payload, err)
+	}
+	if enabled.Enabled == nil {
+		ap.Enabled = true
+	}
+	return &ap, nil
+}
+
+// SchemaDriftPolicy is the policy configuration for schema drift check.
+type SchemaDriftPolicy struct {
+	// IgnorePatternList is the list of regular expressions, the objects whose names match any of them are excluded
+	// from the schema drift comparison, e.g. auto-generated temporary tables.
+	IgnorePatternList []string `json:"ignorePatternList"`
+}
+
+func (sp SchemaDriftPolicy) String() (string, error) {
+	s, err := json.Marshal(sp)
+	if err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
+
+// UnmarshalSchemaDriftPolicy will unmarshal payload to schema drift policy.
+func UnmarshalSchemaDriftPolicy(payload string) (*SchemaDriftPolicy, error) {
+	var sp SchemaDriftPolicy
+	if err := json.Unmarshal([]byte(payload), &sp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal schema drift policy %q: %q", payload, err)
+	}
+	return &sp, nil
+}
+
+// DataMaskingPolicy is the policy configuration for data masking.
+type DataMaskingPolicy struct {
+	RuleList []DataMaskingRule `json:"ruleList"`
+}
+
+// DataMaskingRule is the rule to mask the columns whose names match the pattern.
+type DataMaskingRule struct {
+	// ColumnPattern is the regular expression matching the column names.
+	ColumnPattern string               `json:"columnPattern"`
+	Algorithm     DataMaskingAlgorithm `json:"algorithm"`
+}
+
+func (dp DataMaskingPolicy) String() (string, error) {
+	s, err := json.Marshal(dp)
+	if err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
+
+// UnmarshalDataMaskingPolicy will unmarshal payload to data masking policy.
+func UnmarshalDataMaskingPolicy(payload string) (*DataMaskingPolicy, error) {
+	var dp DataMaskingPolicy
+	if err := json.Unmarshal([]byte(payload), &dp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal data masking policy %q: %q", payload, err)
+	}
+	return &dp, nil
+}
+
+// SQLReviewPolicy is the policy configuration for SQL review.
+type SQLReviewPolicy struct {
+	RuleList []SQLReviewRule `json:"ruleList"`
+}
+
+// SQLReviewRule is the lint rule checked against the migration statements.
+type SQLReviewRule struct {
+	ID    string             `json:"id"`
+	Level SQLReviewRuleLevel `json:"level"`
+	// Payload is the rule specific configuration in JSON, e.g. the naming format.
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+func (sp SQLReviewPolicy) String() (string, error) {
+	s, err := json.Marshal(sp)
+	if err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
+
+// UnmarshalSQLReviewPolicy will unmarshal payload to SQL review policy.
+func UnmarshalSQLReviewPolicy(payload string) (*SQLReviewPolicy, error) {
+	var sp SQLReviewPolicy
+	if err := json.Unmarshal([]byte(payload), &sp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal SQL review policy %q: %q", payload, err)
+	}
+	return &sp, nil
+}
+
 // ValidatePolicy will validate the policy type and payload values.
+// Returns EINVALID for an unknown policy type, a malformed payload or an invalid payload value,
+// a malformed payload can be told apart with errors.Is(err, ErrMalformedPolicyPayload).
 func ValidatePolicy(pType PolicyType, payload string) error {
 	if !PolicyTypes[pType] {
-		return fmt.Errorf("invalid policy type: %s", pType)
+		return common.Errorf(common.Invalid, fmt.Errorf("invalid policy type: %s", pType))
 	}
 	if payload == "" {
 		return nil
@@ -156,23 +428,135 @@ func ValidatePolicy(pType PolicyType, payload string) error {
 	case PolicyTypePipelineApproval:
 		pa, err := UnmarshalPipelineApprovalPolicy(payload)
 		if err != nil {
-			return err
+			return malformedPolicyPayloadError(err)
 		}
 		if pa.Value != PipelineApprovalValueManualNever && pa.Value != PipelineApprovalValueManualAlways {
-			return fmt.Errorf("invalid approval policy value: %q", payload)
+			return common.Errorf(common.Invalid, fmt.Errorf("invalid approval policy value: %q", payload))
+		}
+		for _, role := range pa.ApproverRoleList {
+			if role != Owner && role != DBA && role != Developer {
+				return common.Errorf(common.Invalid, fmt.Errorf("invalid approval policy approver role: %q", role))
+			}
+		}
+		for i, step := range pa.StepList {
+			if step.Role != Owner && step.Role != DBA && step.Role != Developer {
+				return common.Errorf(common.Invalid, fmt.Errorf("invalid approval policy step %d role: %q", i+1, step.Role))
+			}
+			if step.Count <= 0 {
+				return common.Errorf(common.Invalid, fmt.Errorf("invalid approval policy step %d count: %d, must be positive", i+1, step.Count))
+			}
+		}
+		if pa.Value == PipelineApprovalValueManualAlways && pa.MinApprovals < 1 {
+			return common.Errorf(common.Invalid, fmt.Errorf("invalid approval policy min approvals: %d, must be at least 1", pa.MinApprovals))
+		}
+		if pa.AssigneeGroup != nil && strings.TrimSpace(*pa.AssigneeGroup) == "" {
+			return common.Errorf(common.Invalid, fmt.Errorf("invalid approval policy assignee group: must not be empty if set"))
 		}
 	case PolicyTypeBackupPlan:
 		bp, err := UnmarshalBackupPlanPolicy(payload)
 		if err != nil {
-			return err
+			return malformedPolicyPayloadError(err)
 		}
-		if bp.Schedule != BackupPlanPolicyScheduleUnset && bp.Schedule != BackupPlanPolicyScheduleDaily && bp.Schedule != BackupPlanPolicyScheduleWeekly {
-			return fmt.Errorf("invalid backup plan policy schedule: %q", bp.Schedule)
+		if bp.Schedule != BackupPlanPolicyScheduleUnset && bp.Schedule != BackupPlanPolicyScheduleDaily && bp.Schedule != BackupPlanPolicyScheduleWeekly && bp.Schedule != BackupPlanPolicyScheduleMonthly {
+			return common.Errorf(common.Invalid, fmt.Errorf("invalid backup plan policy schedule: %q", bp.Schedule))
+		}
+		if bp.RetentionDays < 0 {
+			return common.Errorf(common.Invalid, fmt.Errorf("invalid backup plan policy retention days: %d", bp.RetentionDays))
+		}
+		switch bp.StorageBackend {
+		case "", BackupStorageBackendLocal:
+		case BackupStorageBackendS3:
+			if bp.S3 == nil || bp.S3.Bucket == "" {
+				return common.Errorf(common.Invalid, fmt.Errorf("invalid backup plan policy: the S3 bucket is required for the S3 storage backend"))
+			}
+		default:
+			return common.Errorf(common.Invalid, fmt.Errorf("invalid backup plan policy storage backend: %q", bp.StorageBackend))
+		}
+	case PolicyTypeAnomaly:
+		ap, err := UnmarshalAnomalyPolicy(payload)
+		if err != nil {
+			return malformedPolicyPayloadError(err)
+		}
+		for _, anomalyType := range ap.DisabledTypeList {
+			if !AnomalyTypes[anomalyType] {
+				return common.Errorf(common.Invalid, fmt.Errorf("invalid anomaly policy disabled type: %q", anomalyType))
+			}
+		}
+	case PolicyTypeSchemaDrift:
+		sp, err := UnmarshalSchemaDriftPolicy(payload)
+		if err != nil {
+			return malformedPolicyPayloadError(err)
+		}
+		for _, pattern := range sp.IgnorePatternList {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return common.Errorf(common.Invalid, fmt.Errorf("invalid schema drift policy ignore pattern %q: %w", pattern, err))
+			}
+		}
+	case PolicyTypeDataMasking:
+		dp, err := UnmarshalDataMaskingPolicy(payload)
+		if err != nil {
+			return malformedPolicyPayloadError(err)
+		}
+		for _, rule := range dp.RuleList {
+			if _, err := regexp.Compile(rule.ColumnPattern); err != nil {
+				return common.Errorf(common.Invalid, fmt.Errorf("invalid data masking policy column pattern %q: %w", rule.ColumnPattern, err))
+			}
+			if rule.Algorithm != DataMaskingAlgorithmFull && rule.Algorithm != DataMaskingAlgorithmPartial && rule.Algorithm != DataMaskingAlgorithmHash {
+				return common.Errorf(common.Invalid, fmt.Errorf("invalid data masking policy algorithm: %q", rule.Algorithm))
+			}
+		}
+	case PolicyTypeSQLReview:
+		sp, err := UnmarshalSQLReviewPolicy(payload)
+		if err != nil {
+			return malformedPolicyPayloadError(err)
+		}
+		ruleIDSet := make(map[string]bool)
+		for _, rule := range sp.RuleList {
+			if rule.ID == "" {
+				return common.Errorf(common.Invalid, fmt.Errorf("empty SQL review policy rule ID: %q", payload))
+			}
+			if ruleIDSet[rule.ID] {
+				return common.Errorf(common.Invalid, fmt.Errorf("duplicate SQL review policy rule ID: %q", rule.ID))
+			}
+			ruleIDSet[rule.ID] = true
+			if rule.Level != SQLReviewRuleLevelError && rule.Level != SQLReviewRuleLevelWarning && rule.Level != SQLReviewRuleLevelDisabled {
+				return common.Errorf(common.Invalid, fmt.Errorf("invalid SQL review policy rule level: %q", rule.Level))
+			}
 		}
 	}
 	return nil
 }
 
+// malformedPolicyPayloadError returns the EINVALID error for a payload failing to be unmarshaled.
+func malformedPolicyPayloadError(err error) error {
+	return common.Errorf(common.Invalid, fmt.Errorf("%w: %v", ErrMalformedPolicyPayload, err))
+}
+
+// GetEffectivePolicy returns the policy taking effect for the environment given its environment policy and the global policy,
+// either of which is nil if not set. The precedence order is:
+//  1. The policy of the environment.
+//  2. The global policy, i.e. the policy of GlobalPolicyEnvironmentID.
+//  3. The default policy from GetDefaultPolicy, which is returned without an ID under the environment.
+func GetEffectivePolicy(environmentID int, pType PolicyType, environmentPolicy *Policy, globalPolicy *Policy) (*Policy, error) {
+	if environmentPolicy != nil {
+		return environmentPolicy, nil
+	}
+	if globalPolicy != nil {
+		return globalPolicy, nil
+	}
+	payload, err := GetDefaultPolicy(pType)
+	if err != nil {
+		return nil, err
+	}
+	return &Policy{
+		CreatorID:     SystemBotID,
+		UpdaterID:     SystemBotID,
+		EnvironmentID: environmentID,
+		Type:          pType,
+		Payload:       payload,
+	}, nil
+}
+
 // GetDefaultPolicy will return the default value for the given policy type.
 // The default policy can be empty when we don't have anything to enforce at runtime.
 func GetDefaultPolicy(pType PolicyType) (string, error) {
@@ -185,6 +569,27 @@ func GetDefaultPolicy(pType PolicyType) (string, error) {
 		return BackupPlanPolicy{
 			Schedule: BackupPlanPolicyScheduleUnset,
 		}.String()
+	case PolicyTypeAnomaly:
+		return AnomalyPolicy{
+			Enabled:          true,
+			DisabledTypeList: []AnomalyType{},
+		}.String()
+	case PolicyTypeSchemaDrift:
+		return SchemaDriftPolicy{
+			IgnorePatternList: []string{},
+		}.String()
+	case PolicyTypeDataMasking:
+		return DataMaskingPolicy{
+			RuleList: []DataMaskingRule{},
+		}.String()
+	case PolicyTypeSQLReview:
+		return SQLReviewPolicy{
+			RuleList: []SQLReviewRule{
+				{ID: SQLReviewRuleTableRequirePK, Level: SQLReviewRuleLevelError},
+				{ID: SQLReviewRuleTableNoDrop, Level: SQLReviewRuleLevelWarning},
+				{ID: SQLReviewRuleColumnRequireComment, Level: SQLReviewRuleLevelDisabled},
+			},
+		}.String()
 	}
 	return "", nil
 }
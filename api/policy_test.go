@@ -0,0 +1,344 @@
+package api
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bytebase/bytebase/common"
+)
+
+func TestDefaultPoliciesValidate(t *testing.T) {
+	for pType := range PolicyTypes {
+		t.Run(string(pType), func(t *testing.T) {
+			payload, err := GetDefaultPolicy(pType)
+			if err != nil {
+				t.Fatalf("GetDefaultPolicy() error = %v", err)
+			}
+			if err := ValidatePolicy(pType, payload); err != nil {
+				t.Errorf("ValidatePolicy() of the default policy %q error = %v", payload, err)
+			}
+		})
+	}
+}
+
+func TestValidateDataMaskingPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		wantErr bool
+	}{
+		{"empty", `{"ruleList":[]}`, false},
+		{"full", `{"ruleList":[{"columnPattern":"^email$","algorithm":"FULL"}]}`, false},
+		{"partialAndHash", `{"ruleList":[{"columnPattern":"phone","algorithm":"PARTIAL"},{"columnPattern":"ssn","algorithm":"HASH"}]}`, false},
+		{"unknownAlgorithm", `{"ruleList":[{"columnPattern":"email","algorithm":"SHUFFLE"}]}`, true},
+		{"missingAlgorithm", `{"ruleList":[{"columnPattern":"email"}]}`, true},
+		{"invalidPattern", `{"ruleList":[{"columnPattern":"(email","algorithm":"FULL"}]}`, true},
+		{"invalidJSON", `{"ruleList":`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePolicy(PolicyTypeDataMasking, tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSQLReviewPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		wantErr bool
+	}{
+		{"empty", `{"ruleList":[]}`, false},
+		{"levels", `{"ruleList":[{"id":"table.require-pk","level":"ERROR"},{"id":"table.no-drop","level":"WARNING"},{"id":"column.require-comment","level":"DISABLED"}]}`, false},
+		{"payload", `{"ruleList":[{"id":"naming.table","level":"WARNING","payload":{"format":"^[a-z_]+$"}}]}`, false},
+		{"unknownLevel", `{"ruleList":[{"id":"table.require-pk","level":"INFO"}]}`, true},
+		{"missingLevel", `{"ruleList":[{"id":"table.require-pk"}]}`, true},
+		{"missingID", `{"ruleList":[{"level":"ERROR"}]}`, true},
+		{"duplicateID", `{"ruleList":[{"id":"table.no-drop","level":"ERROR"},{"id":"table.no-drop","level":"WARNING"}]}`, true},
+		{"invalidJSON", `{"ruleList":`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePolicy(PolicyTypeSQLReview, tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePipelineApprovalPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		wantErr bool
+	}{
+		{"always", `{"value":"MANUAL_APPROVAL_ALWAYS"}`, false},
+		{"never", `{"value":"MANUAL_APPROVAL_NEVER"}`, false},
+		{"approverRole", `{"value":"MANUAL_APPROVAL_ALWAYS","approverRoleList":["OWNER","DBA"]}`, false},
+		{"emptyApproverRole", `{"value":"MANUAL_APPROVAL_NEVER","approverRoleList":[]}`, false},
+		{"unknownApproverRole", `{"value":"MANUAL_APPROVAL_ALWAYS","approverRoleList":["ADMIN"]}`, true},
+		{"approverRoleWithNever", `{"value":"MANUAL_APPROVAL_NEVER","approverRoleList":["DBA"]}`, false},
+		{"chain", `{"stepList":[{"role":"DEVELOPER","count":1},{"role":"DBA","count":2}]}`, false},
+		{"chainWithValue", `{"value":"MANUAL_APPROVAL_ALWAYS","stepList":[{"role":"OWNER","count":1}]}`, false},
+		{"chainUnknownRole", `{"stepList":[{"role":"SECURITY","count":1}]}`, true},
+		{"chainZeroCount", `{"stepList":[{"role":"DBA","count":0}]}`, true},
+		{"chainMissingCount", `{"stepList":[{"role":"DBA"}]}`, true},
+		{"assigneeGroup", `{"value":"MANUAL_APPROVAL_ALWAYS","assigneeGroup":"DBA","minApprovals":2}`, false},
+		{"emptyAssigneeGroup", `{"value":"MANUAL_APPROVAL_ALWAYS","assigneeGroup":" "}`, true},
+		{"zeroMinApprovals", `{"value":"MANUAL_APPROVAL_ALWAYS","minApprovals":0}`, true},
+		{"negativeMinApprovals", `{"value":"MANUAL_APPROVAL_ALWAYS","minApprovals":-1}`, true},
+		{"zeroMinApprovalsWithNever", `{"value":"MANUAL_APPROVAL_NEVER","minApprovals":0}`, false},
+		{"missingValue", `{}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePolicy(PolicyTypePipelineApproval, tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPipelineApprovalPolicyCanApprove(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		role    Role
+		want    bool
+	}{
+		// The payloads before approver roles let any member approve.
+		{"legacyPayload", `{"value":"MANUAL_APPROVAL_ALWAYS"}`, Developer, true},
+		{"approverRole", `{"value":"MANUAL_APPROVAL_ALWAYS","approverRoleList":["OWNER","DBA"]}`, DBA, true},
+		{"notApproverRole", `{"value":"MANUAL_APPROVAL_ALWAYS","approverRoleList":["OWNER","DBA"]}`, Developer, false},
+		// The chain takes precedence over the approver roles.
+		{"chainRole", `{"approverRoleList":["OWNER"],"stepList":[{"role":"DEVELOPER","count":1},{"role":"DBA","count":1}]}`, DBA, true},
+		{"chainOverApproverRole", `{"approverRoleList":["OWNER"],"stepList":[{"role":"DBA","count":1}]}`, Owner, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pa, err := UnmarshalPipelineApprovalPolicy(tt.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := pa.CanApprove(tt.role); got != tt.want {
+				t.Errorf("CanApprove(%s) = %v, want %v", tt.role, got, tt.want)
+			}
+		})
+	}
+
+	// The chain form without value needs manual approval.
+	pa, err := UnmarshalPipelineApprovalPolicy(`{"stepList":[{"role":"DBA","count":1}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pa.Value != PipelineApprovalValueManualAlways {
+		t.Errorf("Value = %s, want %s", pa.Value, PipelineApprovalValueManualAlways)
+	}
+
+	// The default payload is unchanged by the new fields.
+	s, err := PipelineApprovalPolicy{Value: PipelineApprovalValueManualAlways}.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"value":"MANUAL_APPROVAL_ALWAYS"}`; s != want {
+		t.Errorf("String() = %s, want %s", s, want)
+	}
+}
+
+func TestPipelineApprovalPolicyRoundTrip(t *testing.T) {
+	group := "DBA"
+	tests := []struct {
+		name string
+		// payload is the stored payload, and wantPayload is the payload after the round trip.
+		payload     string
+		want        PipelineApprovalPolicy
+		wantPayload string
+	}{
+		{
+			name:        "legacy",
+			payload:     `{"value":"MANUAL_APPROVAL_ALWAYS"}`,
+			want:        PipelineApprovalPolicy{Value: PipelineApprovalValueManualAlways, MinApprovals: 1},
+			wantPayload: `{"value":"MANUAL_APPROVAL_ALWAYS","minApprovals":1}`,
+		},
+		{
+			name:        "legacyNever",
+			payload:     `{"value":"MANUAL_APPROVAL_NEVER"}`,
+			want:        PipelineApprovalPolicy{Value: PipelineApprovalValueManualNever, MinApprovals: 1},
+			wantPayload: `{"value":"MANUAL_APPROVAL_NEVER","minApprovals":1}`,
+		},
+		{
+			name:        "assigneeGroup",
+			payload:     `{"value":"MANUAL_APPROVAL_ALWAYS","assigneeGroup":"DBA","minApprovals":2}`,
+			want:        PipelineApprovalPolicy{Value: PipelineApprovalValueManualAlways, AssigneeGroup: &group, MinApprovals: 2},
+			wantPayload: `{"value":"MANUAL_APPROVAL_ALWAYS","assigneeGroup":"DBA","minApprovals":2}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pa, err := UnmarshalPipelineApprovalPolicy(tt.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*pa, tt.want) {
+				t.Errorf("UnmarshalPipelineApprovalPolicy() = %+v, want %+v", *pa, tt.want)
+			}
+			s, err := pa.String()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s != tt.wantPayload {
+				t.Errorf("String() = %s, want %s", s, tt.wantPayload)
+			}
+			again, err := UnmarshalPipelineApprovalPolicy(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(again, pa) {
+				t.Errorf("UnmarshalPipelineApprovalPolicy(String()) = %+v, want %+v", *again, *pa)
+			}
+		})
+	}
+}
+
+func TestValidateBackupPlanPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		wantErr bool
+	}{
+		{"scheduleOnly", `{"schedule":"DAILY"}`, false},
+		{"keepForever", `{"schedule":"WEEKLY","retentionDays":0}`, false},
+		{"retention", `{"schedule":"MONTHLY","retentionDays":90}`, false},
+		{"negativeRetention", `{"schedule":"DAILY","retentionDays":-1}`, true},
+		{"unknownSchedule", `{"schedule":"HOURLY"}`, true},
+		{"local", `{"schedule":"DAILY","storageBackend":"LOCAL"}`, false},
+		{"s3", `{"schedule":"DAILY","storageBackend":"S3","s3":{"bucket":"backup","prefix":"bytebase/","region":"us-west-2"}}`, false},
+		{"s3WithoutRegion", `{"schedule":"DAILY","storageBackend":"S3","s3":{"bucket":"backup"}}`, false},
+		{"s3WithoutBucket", `{"schedule":"DAILY","storageBackend":"S3","s3":{"region":"us-west-2"}}`, true},
+		{"s3WithoutConfig", `{"schedule":"DAILY","storageBackend":"S3"}`, true},
+		{"unknownStorageBackend", `{"schedule":"DAILY","storageBackend":"GCS"}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePolicy(PolicyTypeBackupPlan, tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetEffectivePolicy(t *testing.T) {
+	environmentPolicy := &Policy{ID: 101, EnvironmentID: 5001, Type: PolicyTypeBackupPlan, Payload: `{"schedule":"DAILY"}`}
+	globalPolicy := &Policy{ID: 102, EnvironmentID: GlobalPolicyEnvironmentID, Type: PolicyTypeBackupPlan, Payload: `{"schedule":"WEEKLY"}`}
+	defaultPayload, err := GetDefaultPolicy(PolicyTypeBackupPlan)
+	if err != nil {
+		t.Fatalf("GetDefaultPolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		name              string
+		environmentPolicy *Policy
+		globalPolicy      *Policy
+		wantID            int
+		wantEnvironmentID int
+		wantPayload       string
+	}{
+		{"environment", environmentPolicy, globalPolicy, 101, 5001, `{"schedule":"DAILY"}`},
+		{"environmentWithoutGlobal", environmentPolicy, nil, 101, 5001, `{"schedule":"DAILY"}`},
+		{"global", nil, globalPolicy, 102, GlobalPolicyEnvironmentID, `{"schedule":"WEEKLY"}`},
+		{"default", nil, nil, 0, 5001, defaultPayload},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := GetEffectivePolicy(5001, PolicyTypeBackupPlan, tt.environmentPolicy, tt.globalPolicy)
+			if err != nil {
+				t.Fatalf("GetEffectivePolicy() error = %v", err)
+			}
+			if policy.ID != tt.wantID || policy.EnvironmentID != tt.wantEnvironmentID || policy.Payload != tt.wantPayload {
+				t.Errorf("GetEffectivePolicy() = {ID: %d, EnvironmentID: %d, Payload: %s}, want {ID: %d, EnvironmentID: %d, Payload: %s}",
+					policy.ID, policy.EnvironmentID, policy.Payload, tt.wantID, tt.wantEnvironmentID, tt.wantPayload)
+			}
+		})
+	}
+}
+
+func TestValidatePolicyErrorCode(t *testing.T) {
+	tests := []struct {
+		name          string
+		pType         PolicyType
+		payload       string
+		wantMalformed bool
+	}{
+		{"unknownType", PolicyType("bb.policy.unknown"), "", false},
+		{"approvalMalformed", PolicyTypePipelineApproval, `{"value":`, true},
+		{"approvalValue", PolicyTypePipelineApproval, `{"value":"MANUAL_APPROVAL_SOMETIMES"}`, false},
+		{"approvalApproverRole", PolicyTypePipelineApproval, `{"value":"MANUAL_APPROVAL_ALWAYS","approverRoleList":["ADMIN"]}`, false},
+		{"backupPlanMalformed", PolicyTypeBackupPlan, `{"schedule":1}`, true},
+		{"backupPlanSchedule", PolicyTypeBackupPlan, `{"schedule":"HOURLY"}`, false},
+		{"backupPlanRetention", PolicyTypeBackupPlan, `{"schedule":"DAILY","retentionDays":-1}`, false},
+		{"anomalyMalformed", PolicyTypeAnomaly, `{"enabled":"yes"}`, true},
+		{"anomalyDisabledType", PolicyTypeAnomaly, `{"enabled":true,"disabledTypeList":["bb.anomaly.database.unknown"]}`, false},
+		{"schemaDriftMalformed", PolicyTypeSchemaDrift, `[]`, true},
+		{"schemaDriftPattern", PolicyTypeSchemaDrift, `{"ignorePatternList":["("]}`, false},
+		{"dataMaskingMalformed", PolicyTypeDataMasking, `{"ruleList":{}}`, true},
+		{"dataMaskingPattern", PolicyTypeDataMasking, `{"ruleList":[{"columnPattern":"(","algorithm":"FULL"}]}`, false},
+		{"dataMaskingAlgorithm", PolicyTypeDataMasking, `{"ruleList":[{"columnPattern":"email","algorithm":"ROT13"}]}`, false},
+		{"sqlReviewMalformed", PolicyTypeSQLReview, `{"ruleList":`, true},
+		{"sqlReviewMissingID", PolicyTypeSQLReview, `{"ruleList":[{"level":"ERROR"}]}`, false},
+		{"sqlReviewDuplicateID", PolicyTypeSQLReview, `{"ruleList":[{"id":"table.no-drop","level":"ERROR"},{"id":"table.no-drop","level":"ERROR"}]}`, false},
+		{"sqlReviewLevel", PolicyTypeSQLReview, `{"ruleList":[{"id":"table.no-drop","level":"INFO"}]}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePolicy(tt.pType, tt.payload)
+			if code := common.ErrorCode(err); code != common.Invalid {
+				t.Fatalf("ValidatePolicy() error code = %v, want %v, error = %v", code, common.Invalid, err)
+			}
+			if malformed := errors.Is(err, ErrMalformedPolicyPayload); malformed != tt.wantMalformed {
+				t.Errorf("errors.Is(err, ErrMalformedPolicyPayload) = %v, want %v, error = %v", malformed, tt.wantMalformed, err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalAnomalyPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    AnomalyPolicy
+	}{
+		{
+			name:    "missingEnabled",
+			payload: `{"disabledTypeList":["bb.anomaly.database.backup.missing"]}`,
+			want:    AnomalyPolicy{Enabled: true, DisabledTypeList: []AnomalyType{AnomalyDatabaseBackupMissing}},
+		},
+		{
+			name:    "disabled",
+			payload: `{"enabled":false}`,
+			want:    AnomalyPolicy{Enabled: false},
+		},
+		{
+			name:    "enabled",
+			payload: `{"enabled":true,"disabledTypeList":[]}`,
+			want:    AnomalyPolicy{Enabled: true, DisabledTypeList: []AnomalyType{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ap, err := UnmarshalAnomalyPolicy(tt.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*ap, tt.want) {
+				t.Errorf("UnmarshalAnomalyPolicy() = %+v, want %+v", *ap, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// RowCountBaseline is the API message for the row count of a table observed by the anomaly scanner in the previous round.
+type RowCountBaseline struct {
+	// Related fields
+	DatabaseID int
+
+	// Domain specific fields
+	TableName  string
+	RowCount   int64
+	ObservedTs int64
+}
+
+// RowCountBaselineFind is the API message for finding row count baselines.
+type RowCountBaselineFind struct {
+	// Related fields
+	DatabaseID *int
+}
+
+func (find *RowCountBaselineFind) String() string {
+	str, err := json.Marshal(*find)
+	if err != nil {
+		return err.Error()
+	}
+	return string(str)
+}
+
+// RowCountBaselineUpsert is the API message for replacing the row count baselines of a database.
+type RowCountBaselineUpsert struct {
+	// Related fields
+	DatabaseID int
+
+	// Domain specific fields
+	// RowCountMap is the row count by table name
+	RowCountMap map[string]int64
+}
+
+// RowCountBaselineService is the service for row count baselines.
+type RowCountBaselineService interface {
+	FindRowCountBaselineList(ctx context.Context, find *RowCountBaselineFind) ([]*RowCountBaseline, error)
+	// UpsertRowCountBaselineList replaces the baselines of the database, the baselines of the tables not in the upsert are deleted.
+	UpsertRowCountBaselineList(ctx context.Context, upsert *RowCountBaselineUpsert) error
+}
@@ -15,6 +15,9 @@ type ConnectionInfo struct {
 	Password         string  `jsonapi:"attr,password"`
 	UseEmptyPassword bool    `jsonapi:"attr,useEmptyPassword"`
 	InstanceID       *int    `jsonapi:"attr,instanceId"`
+	// ServiceName and SID identify the Oracle database to connect to
+	ServiceName string `jsonapi:"attr,serviceName"`
+	SID         string `jsonapi:"attr,sid"`
 }
 
 // SQLSyncSchema is the API message for sync schemas.
@@ -67,6 +67,54 @@ var (
 	readonly bool
 	demo     bool
 	debug    bool
+	// anomalyScanInterval is the interval between anomaly scan rounds, 0 means using the default interval.
+	anomalyScanInterval time.Duration
+	// anomalyScanTimeout is the timeout for scanning a single instance, 0 means using the default timeout.
+	anomalyScanTimeout time.Duration
+	// anomalyScanDatabaseTimeout is the timeout for scanning a single database, 0 means using the default timeout.
+	anomalyScanDatabaseTimeout time.Duration
+	// anomalyScanConcurrency is the number of databases of an instance scanned concurrently, 0 means using the default concurrency.
+	anomalyScanConcurrency int
+	// anomalyConnectRetryCount is the number of attempts to connect before raising the connection anomaly, 0 means using the default count.
+	anomalyConnectRetryCount int
+	// anomalyConnectRetryBackoff is the backoff before the first connect retry, doubling for each following retry, 0 means using the default backoff.
+	anomalyConnectRetryBackoff time.Duration
+	// anomalyConnectionCountThreshold is the percentage of max connections in use to raise the connection count anomaly, 0 means using the default threshold.
+	anomalyConnectionCountThreshold int
+	// anomalyDiskUsageThreshold is the percentage of used disk space to raise the disk space low anomaly, 0 means using the default threshold.
+	anomalyDiskUsageThreshold int
+	// anomalyLongRunningTransactionThreshold is the duration above which a transaction is reported as long-running, 0 means using the default threshold.
+	anomalyLongRunningTransactionThreshold time.Duration
+	// anomalySlowInstanceScanThreshold is the duration of an instance scan above which a warning is logged, 0 means using the default threshold.
+	anomalySlowInstanceScanThreshold time.Duration
+	// anomalyReplicationLagThreshold is the replication lag above which the replication lag anomaly is raised, 0 means using the default threshold.
+	anomalyReplicationLagThreshold time.Duration
+	// anomalyTableBloatThreshold is the percentage of dead tuples of a table to raise the table bloat anomaly, 0 means using the default threshold.
+	anomalyTableBloatThreshold int
+	// anomalyRowCountGrowthThreshold is the ratio of a table's row count to the previous scan round's to raise the row count growth anomaly, 0 means using the default threshold.
+	anomalyRowCountGrowthThreshold float64
+	// anomalyBackupChecksumVerifyInterval is the interval between two checksum verifications of the same backup, 0 means using the default interval.
+	anomalyBackupChecksumVerifyInterval time.Duration
+	// anomalyBackupMaxAgeGraceMultiplier is applied to the max age of the last backup allowed by the backup schedule, 0 means using the default multiplier.
+	anomalyBackupMaxAgeGraceMultiplier float64
+	// anomalyBackupMissingGracePeriod is the period after the first scheduled backup since the backup setting changed before the backup missing anomaly is raised, 0 means one interval of the backup schedule.
+	anomalyBackupMissingGracePeriod time.Duration
+	// anomalyBackupSizeChangeThreshold is the ratio of the latest backup's size to the median size of the backups before to raise the backup size anomaly, 0 means using the default threshold.
+	anomalyBackupSizeChangeThreshold float64
+	// anomalyEngineVersionEOLWarningPeriod is the period before the end of life of the engine release line within which the anomaly is raised, 0 means using the default period.
+	anomalyEngineVersionEOLWarningPeriod time.Duration
+	// anomalySequentialScanRatioThreshold is the ratio of a table's sequential scans to its index scans to raise the missing index anomaly, 0 means using the default threshold.
+	anomalySequentialScanRatioThreshold float64
+	// anomalyExcludedDatabasePatterns is the regular expressions of the database names the anomaly scanner skips, in addition to the system databases.
+	anomalyExcludedDatabasePatterns []string
+	// anomalyDisabledTypes is the anomaly types the anomaly scanner skips in every environment, in addition to the types disabled by the anomaly policy.
+	anomalyDisabledTypes []string
+	// anomalyWebhookURL is the URL to POST to when an anomaly is created or resolved, empty means no webhook.
+	anomalyWebhookURL string
+	// anomalyReportPath is the file to write the JSON report of the active anomalies to after each scan round, empty means no report.
+	anomalyReportPath string
+	// anomalyScanDryRun runs the anomaly checks and logs the anomaly writes instead of applying them.
+	anomalyScanDryRun bool
 
 	logger *zap.Logger
 
@@ -124,6 +172,30 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&readonly, "readonly", false, "whether to run in read-only mode")
 	rootCmd.PersistentFlags().BoolVar(&demo, "demo", false, "whether to run using demo data")
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "whether to enable debug level logging")
+	rootCmd.PersistentFlags().DurationVar(&anomalyScanInterval, "anomaly-scan-interval", 0, "interval between anomaly scan rounds (e.g. 30m). Must be at least 1m. Default is 10m")
+	rootCmd.PersistentFlags().DurationVar(&anomalyScanTimeout, "anomaly-scan-timeout", 0, "timeout for the anomaly scan of a single instance (e.g. 5m). Default is 5m")
+	rootCmd.PersistentFlags().DurationVar(&anomalyScanDatabaseTimeout, "anomaly-scan-database-timeout", 0, "timeout for the anomaly scan of a single database (e.g. 2m). Default is 2m")
+	rootCmd.PersistentFlags().DurationVar(&anomalySlowInstanceScanThreshold, "anomaly-slow-instance-scan-threshold", 0, "duration of an instance scan above which the anomaly scanner logs a slow scan warning (e.g. 30s). Default is 1m")
+	rootCmd.PersistentFlags().IntVar(&anomalyScanConcurrency, "anomaly-scan-concurrency", 0, "number of databases of an instance scanned concurrently by the anomaly scanner. Default is 4")
+	rootCmd.PersistentFlags().IntVar(&anomalyConnectRetryCount, "anomaly-connect-retry-count", 0, "number of attempts to connect with exponential backoff before the anomaly scanner raises the connection anomaly. Default is 3")
+	rootCmd.PersistentFlags().DurationVar(&anomalyConnectRetryBackoff, "anomaly-connect-retry-backoff", 0, "backoff before the first connect retry of the anomaly scanner, doubling for each following retry (e.g. 500ms). Default is 1s")
+	rootCmd.PersistentFlags().IntVar(&anomalyConnectionCountThreshold, "anomaly-connection-count-threshold", 0, "percentage of max connections in use above which the connection count anomaly is raised. Must be between 1 and 100. Default is 80")
+	rootCmd.PersistentFlags().IntVar(&anomalyDiskUsageThreshold, "anomaly-disk-usage-threshold", 0, "percentage of used disk space above which the disk space low anomaly is raised. Must be between 1 and 100. Default is 90")
+	rootCmd.PersistentFlags().DurationVar(&anomalyLongRunningTransactionThreshold, "anomaly-long-running-transaction-threshold", 0, "duration above which a transaction is reported as long-running (e.g. 10m). Default is 10m")
+	rootCmd.PersistentFlags().DurationVar(&anomalyReplicationLagThreshold, "anomaly-replication-lag-threshold", 0, "replication lag of the replica instances above which the replication lag anomaly is raised (e.g. 5m). Default is 60s")
+	rootCmd.PersistentFlags().Float64Var(&anomalyRowCountGrowthThreshold, "anomaly-row-count-growth-threshold", 0, "ratio of a table's row count to the previous anomaly scan round's above which the table row count growth anomaly is raised. Must be larger than 1. Default is 10")
+	rootCmd.PersistentFlags().IntVar(&anomalyTableBloatThreshold, "anomaly-table-bloat-threshold", 0, "percentage of dead tuples of a Postgres table above which the table bloat anomaly is raised. Must be between 1 and 100. Default is 30")
+	rootCmd.PersistentFlags().DurationVar(&anomalyBackupChecksumVerifyInterval, "anomaly-backup-checksum-verify-interval", 0, "interval between two checksum verifications of the same latest backup by the anomaly scanner (e.g. 12h). Default is 24h")
+	rootCmd.PersistentFlags().Float64Var(&anomalyBackupMaxAgeGraceMultiplier, "anomaly-backup-max-age-grace-multiplier", 0, "multiplier applied to the max age of the last successful backup allowed by the backup schedule before the backup missing anomaly is raised. Must be at least 1. Default is 1.2")
+	rootCmd.PersistentFlags().DurationVar(&anomalyBackupMissingGracePeriod, "anomaly-backup-missing-grace-period", 0, "period after the first scheduled backup since the backup setting changed within which the backup missing anomaly isn't raised yet (e.g. 6h). Default is one interval of the backup schedule")
+	rootCmd.PersistentFlags().Float64Var(&anomalyBackupSizeChangeThreshold, "anomaly-backup-size-change-threshold", 0, "ratio of the latest backup's size to the median size of the backups before, above which or below the reciprocal of which the backup size anomaly is raised. Must be larger than 1. Default is 3")
+	rootCmd.PersistentFlags().DurationVar(&anomalyEngineVersionEOLWarningPeriod, "anomaly-engine-version-eol-warning-period", 0, "period before the end of life of the engine release line within which the anomaly scanner raises the engine version end-of-life anomaly (e.g. 720h). Default is 2160h (90 days)")
+	rootCmd.PersistentFlags().Float64Var(&anomalySequentialScanRatioThreshold, "anomaly-sequential-scan-ratio-threshold", 0, "ratio of a table's sequential scans to its index scans above which the missing index anomaly is raised. Must be positive. Default is 10")
+	rootCmd.PersistentFlags().StringSliceVar(&anomalyExcludedDatabasePatterns, "anomaly-excluded-database-patterns", nil, "comma separated regular expressions of the database names the anomaly scanner skips, each matching the whole name. The system databases of each engine are always skipped")
+	rootCmd.PersistentFlags().StringSliceVar(&anomalyDisabledTypes, "anomaly-disabled-types", nil, "comma separated anomaly types the anomaly scanner skips in every environment (e.g. bb.anomaly.database.backup.missing). Their active anomalies are archived")
+	rootCmd.PersistentFlags().StringVar(&anomalyWebhookURL, "anomaly-webhook-url", "", "URL to POST a JSON payload to when an anomaly is created or resolved")
+	rootCmd.PersistentFlags().StringVar(&anomalyReportPath, "anomaly-report-path", "", "file to write the JSON report of the active anomalies to after each anomaly scan round")
+	rootCmd.PersistentFlags().BoolVar(&anomalyScanDryRun, "anomaly-scan-dry-run", false, "whether to run the anomaly checks and only log the anomalies which would be created, updated or archived")
 }
 
 // -----------------------------------Command Line Config END--------------------------------------
@@ -173,6 +245,95 @@ func preStart() error {
 		dataDir = absDir
 	}
 
+	if anomalyScanInterval != 0 && anomalyScanInterval < server.MinAnomalyScanInterval {
+		error := fmt.Errorf("--anomaly-scan-interval %v must be at least %v", anomalyScanInterval, server.MinAnomalyScanInterval)
+		return error
+	}
+	if anomalyScanTimeout < 0 {
+		error := fmt.Errorf("--anomaly-scan-timeout %v must not be negative", anomalyScanTimeout)
+		return error
+	}
+	if anomalyScanDatabaseTimeout < 0 {
+		error := fmt.Errorf("--anomaly-scan-database-timeout %v must not be negative", anomalyScanDatabaseTimeout)
+		return error
+	}
+	if anomalyScanConcurrency < 0 {
+		error := fmt.Errorf("--anomaly-scan-concurrency %d must not be negative", anomalyScanConcurrency)
+		return error
+	}
+	if anomalyConnectRetryCount < 0 {
+		error := fmt.Errorf("--anomaly-connect-retry-count %d must not be negative", anomalyConnectRetryCount)
+		return error
+	}
+	if anomalyConnectRetryBackoff < 0 {
+		error := fmt.Errorf("--anomaly-connect-retry-backoff %v must not be negative", anomalyConnectRetryBackoff)
+		return error
+	}
+	if anomalyConnectionCountThreshold < 0 || anomalyConnectionCountThreshold > 100 {
+		error := fmt.Errorf("--anomaly-connection-count-threshold %d must be between 1 and 100", anomalyConnectionCountThreshold)
+		return error
+	}
+	if anomalyDiskUsageThreshold < 0 || anomalyDiskUsageThreshold > 100 {
+		error := fmt.Errorf("--anomaly-disk-usage-threshold %d must be between 1 and 100", anomalyDiskUsageThreshold)
+		return error
+	}
+	if anomalyLongRunningTransactionThreshold < 0 {
+		error := fmt.Errorf("--anomaly-long-running-transaction-threshold %v must not be negative", anomalyLongRunningTransactionThreshold)
+		return error
+	}
+	if anomalySlowInstanceScanThreshold < 0 {
+		error := fmt.Errorf("--anomaly-slow-instance-scan-threshold %v must not be negative", anomalySlowInstanceScanThreshold)
+		return error
+	}
+	if anomalyReplicationLagThreshold < 0 {
+		error := fmt.Errorf("--anomaly-replication-lag-threshold %v must not be negative", anomalyReplicationLagThreshold)
+		return error
+	}
+	if anomalyRowCountGrowthThreshold < 0 || (anomalyRowCountGrowthThreshold > 0 && anomalyRowCountGrowthThreshold <= 1) {
+		error := fmt.Errorf("--anomaly-row-count-growth-threshold %v must be larger than 1", anomalyRowCountGrowthThreshold)
+		return error
+	}
+	if anomalyTableBloatThreshold < 0 || anomalyTableBloatThreshold > 100 {
+		error := fmt.Errorf("--anomaly-table-bloat-threshold %d must be between 1 and 100", anomalyTableBloatThreshold)
+		return error
+	}
+	if anomalySequentialScanRatioThreshold < 0 {
+		error := fmt.Errorf("--anomaly-sequential-scan-ratio-threshold %v must be positive", anomalySequentialScanRatioThreshold)
+		return error
+	}
+	if anomalyEngineVersionEOLWarningPeriod < 0 {
+		error := fmt.Errorf("--anomaly-engine-version-eol-warning-period %v must not be negative", anomalyEngineVersionEOLWarningPeriod)
+		return error
+	}
+	for _, pattern := range anomalyExcludedDatabasePatterns {
+		if _, err := server.CompileExcludedDatabasePattern(pattern); err != nil {
+			error := fmt.Errorf("--anomaly-excluded-database-patterns %q is not a valid regular expression: %w", pattern, err)
+			return error
+		}
+	}
+	for _, anomalyType := range anomalyDisabledTypes {
+		if !api.AnomalyTypes[api.AnomalyType(anomalyType)] {
+			error := fmt.Errorf("--anomaly-disabled-types %q is not a valid anomaly type", anomalyType)
+			return error
+		}
+	}
+	if anomalyBackupMaxAgeGraceMultiplier < 0 || (anomalyBackupMaxAgeGraceMultiplier > 0 && anomalyBackupMaxAgeGraceMultiplier < 1) {
+		error := fmt.Errorf("--anomaly-backup-max-age-grace-multiplier %v must be at least 1", anomalyBackupMaxAgeGraceMultiplier)
+		return error
+	}
+	if anomalyBackupMissingGracePeriod < 0 {
+		error := fmt.Errorf("--anomaly-backup-missing-grace-period %v must not be negative", anomalyBackupMissingGracePeriod)
+		return error
+	}
+	if anomalyBackupChecksumVerifyInterval < 0 {
+		error := fmt.Errorf("--anomaly-backup-checksum-verify-interval %v must not be negative", anomalyBackupChecksumVerifyInterval)
+		return error
+	}
+	if anomalyBackupSizeChangeThreshold < 0 || (anomalyBackupSizeChangeThreshold > 0 && anomalyBackupSizeChangeThreshold <= 1) {
+		error := fmt.Errorf("--anomaly-backup-size-change-threshold %v must be larger than 1", anomalyBackupSizeChangeThreshold)
+		return error
+	}
+
 	// Trim trailing / in case user supplies
 	dataDir = strings.TrimRight(dataDir, "/")
 
@@ -225,6 +386,30 @@ func newMain() *main {
 	fmt.Printf("readonly=%t\n", readonly)
 	fmt.Printf("demo=%t\n", demo)
 	fmt.Printf("debug=%t\n", debug)
+	fmt.Printf("anomalyScanInterval=%v\n", anomalyScanInterval)
+	fmt.Printf("anomalyScanTimeout=%v\n", anomalyScanTimeout)
+	fmt.Printf("anomalyScanDatabaseTimeout=%v\n", anomalyScanDatabaseTimeout)
+	fmt.Printf("anomalySlowInstanceScanThreshold=%v\n", anomalySlowInstanceScanThreshold)
+	fmt.Printf("anomalyScanConcurrency=%d\n", anomalyScanConcurrency)
+	fmt.Printf("anomalyConnectRetryCount=%d\n", anomalyConnectRetryCount)
+	fmt.Printf("anomalyConnectRetryBackoff=%v\n", anomalyConnectRetryBackoff)
+	fmt.Printf("anomalyConnectionCountThreshold=%d\n", anomalyConnectionCountThreshold)
+	fmt.Printf("anomalyDiskUsageThreshold=%d\n", anomalyDiskUsageThreshold)
+	fmt.Printf("anomalyLongRunningTransactionThreshold=%v\n", anomalyLongRunningTransactionThreshold)
+	fmt.Printf("anomalyReplicationLagThreshold=%v\n", anomalyReplicationLagThreshold)
+	fmt.Printf("anomalyRowCountGrowthThreshold=%v\n", anomalyRowCountGrowthThreshold)
+	fmt.Printf("anomalyTableBloatThreshold=%d\n", anomalyTableBloatThreshold)
+	fmt.Printf("anomalyBackupChecksumVerifyInterval=%v\n", anomalyBackupChecksumVerifyInterval)
+	fmt.Printf("anomalyBackupMaxAgeGraceMultiplier=%v\n", anomalyBackupMaxAgeGraceMultiplier)
+	fmt.Printf("anomalyBackupMissingGracePeriod=%v\n", anomalyBackupMissingGracePeriod)
+	fmt.Printf("anomalyBackupSizeChangeThreshold=%v\n", anomalyBackupSizeChangeThreshold)
+	fmt.Printf("anomalyEngineVersionEOLWarningPeriod=%v\n", anomalyEngineVersionEOLWarningPeriod)
+	fmt.Printf("anomalySequentialScanRatioThreshold=%v\n", anomalySequentialScanRatioThreshold)
+	fmt.Printf("anomalyExcludedDatabasePatterns=%v\n", anomalyExcludedDatabasePatterns)
+	fmt.Printf("anomalyDisabledTypes=%v\n", anomalyDisabledTypes)
+	fmt.Printf("anomalyWebhookURLSet=%t\n", anomalyWebhookURL != "")
+	fmt.Printf("anomalyReportPath=%s\n", anomalyReportPath)
+	fmt.Printf("anomalyScanDryRun=%t\n", anomalyScanDryRun)
 	fmt.Println("-----Config END-------")
 
 	return &main{
@@ -279,7 +464,36 @@ func (m *main) Run(ctx context.Context) error {
 
 	m.db = db
 
-	s := server.NewServer(m.l, version, host, port, frontendHost, frontendPort, m.profile.mode, dataDir, m.profile.backupRunnerInterval, config.secret, readonly, demo, debug)
+	var anomalyDisabledTypeList []api.AnomalyType
+	for _, anomalyType := range anomalyDisabledTypes {
+		anomalyDisabledTypeList = append(anomalyDisabledTypeList, api.AnomalyType(anomalyType))
+	}
+	s := server.NewServer(m.l, version, host, port, frontendHost, frontendPort, m.profile.mode, dataDir, m.profile.backupRunnerInterval, server.AnomalyScannerConfig{
+		Interval:                        anomalyScanInterval,
+		Timeout:                         anomalyScanTimeout,
+		DatabaseTimeout:                 anomalyScanDatabaseTimeout,
+		SlowInstanceScanThreshold:       anomalySlowInstanceScanThreshold,
+		Concurrency:                     anomalyScanConcurrency,
+		ConnectRetryCount:               anomalyConnectRetryCount,
+		ConnectRetryBackoff:             anomalyConnectRetryBackoff,
+		ConnectionCountThreshold:        anomalyConnectionCountThreshold,
+		DiskUsageThreshold:              anomalyDiskUsageThreshold,
+		LongRunningTransactionThreshold: anomalyLongRunningTransactionThreshold,
+		ReplicationLagThreshold:         anomalyReplicationLagThreshold,
+		RowCountGrowthThreshold:         anomalyRowCountGrowthThreshold,
+		TableBloatThreshold:             anomalyTableBloatThreshold,
+		BackupChecksumVerifyInterval:    anomalyBackupChecksumVerifyInterval,
+		BackupMaxAgeGraceMultiplier:     anomalyBackupMaxAgeGraceMultiplier,
+		BackupMissingGracePeriod:        anomalyBackupMissingGracePeriod,
+		BackupSizeChangeThreshold:       anomalyBackupSizeChangeThreshold,
+		EngineVersionEOLWarningPeriod:   anomalyEngineVersionEOLWarningPeriod,
+		SequentialScanRatioThreshold:    anomalySequentialScanRatioThreshold,
+		ExcludedDatabasePatternList:     anomalyExcludedDatabasePatterns,
+		DisabledTypeList:                anomalyDisabledTypeList,
+		WebhookURL:                      anomalyWebhookURL,
+		ReportPath:                      anomalyReportPath,
+		DryRun:                          anomalyScanDryRun,
+	}, config.secret, readonly, demo, debug)
 	s.SettingService = settingService
 	s.PrincipalService = store.NewPrincipalService(m.l, db, s.CacheService)
 	s.MemberService = store.NewMemberService(m.l, db, s.CacheService)
@@ -309,6 +523,7 @@ func (m *main) Run(ctx context.Context) error {
 	s.VCSService = store.NewVCSService(m.l, db)
 	s.RepositoryService = store.NewRepositoryService(m.l, db, s.ProjectService)
 	s.AnomalyService = store.NewAnomalyService(m.l, db)
+	s.RowCountBaselineService = store.NewRowCountBaselineService(m.l, db)
 	s.LabelService = store.NewLabelService(m.l, db)
 	s.DeploymentConfigService = store.NewDeploymentConfigService(m.l, db)
 
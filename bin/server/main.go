@@ -11,8 +11,14 @@ import (
 
 	// Register clickhouse driver.
 	_ "github.com/bytebase/bytebase/plugin/db/clickhouse"
+	// Register mongodb driver.
+	_ "github.com/bytebase/bytebase/plugin/db/mongodb"
+	// Register mssql driver.
+	_ "github.com/bytebase/bytebase/plugin/db/mssql"
 	// Register mysql driver.
 	_ "github.com/bytebase/bytebase/plugin/db/mysql"
+	// Register oracle driver.
+	_ "github.com/bytebase/bytebase/plugin/db/oracle"
 	// Register postgres driver.
 	_ "github.com/bytebase/bytebase/plugin/db/pg"
 	_ "github.com/lib/pq"
@@ -0,0 +1,200 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffMaxEditCount is the max number of line edits the diff searches for, beyond which the changed lines
+// are reported as a whole replacement to bound the memory. The common leading and trailing lines are always kept out.
+const diffMaxEditCount = 1000
+
+// diffOp is a line of the edit script, kind is ' ' for an unchanged line, '-' for a deleted line and '+' for an inserted line.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// UnifiedDiff returns the line based unified diff from oldText to newText with contextLines unchanged lines
+// around each change, or an empty string if they are the same.
+func UnifiedDiff(oldName, newName, oldText, newText string, contextLines int) string {
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+	var changeList []int
+	for i, op := range ops {
+		if op.kind != ' ' {
+			changeList = append(changeList, i)
+		}
+	}
+	if len(changeList) == 0 {
+		return ""
+	}
+
+	// oldLine[i] and newLine[i] are the number of old and new lines before ops[i].
+	oldLine, newLine := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if op.kind != '+' {
+			oldLine[i+1]++
+		}
+		if op.kind != '-' {
+			newLine[i+1]++
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for k := 0; k < len(changeList); k++ {
+		start, end := changeList[k]-contextLines, changeList[k]
+		if start < 0 {
+			start = 0
+		}
+		// Merge the following changes whose context would overlap.
+		for k+1 < len(changeList) && changeList[k+1]-end-1 <= 2*contextLines {
+			k++
+			end = changeList[k]
+		}
+		end += contextLines + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n",
+			formatDiffRange(oldLine[start], oldLine[end]-oldLine[start]),
+			formatDiffRange(newLine[start], newLine[end]-newLine[start]))
+		for _, op := range ops[start:end] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+// formatDiffRange formats the hunk range starting after line `before` with count lines.
+func formatDiffRange(before, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", before)
+	case 1:
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines returns the edit script from a to b.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{kind: ' ', line: line})
+	}
+	ops = append(ops, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{kind: ' ', line: line})
+	}
+	return ops
+}
+
+// myersDiff returns the shortest edit script from a to b using the Myers' O(ND) algorithm.
+func myersDiff(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	// v[offset+k] is the furthest x reached on diagonal k = x - y.
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace[d] is v of diagonals -d to d after d edits, for backtracking.
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		if d > diffMaxEditCount {
+			return replaceDiff(a, b)
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrackDiff(trace, a, b, d)
+			}
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+	}
+	return nil
+}
+
+// backtrackDiff walks the trace back from the end of a and b after d edits.
+func backtrackDiff(trace [][]int, a, b []string, d int) []diffOp {
+	var ops []diffOp
+	x, y := len(a), len(b)
+	for ; d > 0; d-- {
+		prev := trace[d-1]
+		// get returns the x on diagonal k after d-1 edits.
+		get := func(k int) int {
+			return prev[k+d-1]
+		}
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && get(k-1) < get(k+1)) {
+			prevK = k + 1
+		}
+		prevX := get(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{kind: ' ', line: a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, diffOp{kind: '+', line: b[y-1]})
+			y--
+		} else {
+			ops = append(ops, diffOp{kind: '-', line: a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, diffOp{kind: ' ', line: a[x-1]})
+		x--
+		y--
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// replaceDiff returns the edit script deleting all of a and then inserting all of b.
+func replaceDiff(a, b []string) []diffOp {
+	var ops []diffOp
+	for _, line := range a {
+		ops = append(ops, diffOp{kind: '-', line: line})
+	}
+	for _, line := range b {
+		ops = append(ops, diffOp{kind: '+', line: line})
+	}
+	return ops
+}
@@ -0,0 +1,77 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldText string
+		newText string
+		want    string
+	}{
+		{
+			name:    "same",
+			oldText: "a\nb\n",
+			newText: "a\nb\n",
+			want:    "",
+		},
+		{
+			name:    "change",
+			oldText: "a\nb\nc\nd\ne\n",
+			newText: "a\nb\nC\nd\ne\n",
+			want:    "--- old\n+++ new\n@@ -2,3 +2,3 @@\n b\n-c\n+C\n d\n",
+		},
+		{
+			name:    "insert",
+			oldText: "a\nb\n",
+			newText: "a\nb\nc\n",
+			want:    "--- old\n+++ new\n@@ -2 +2,2 @@\n b\n+c\n",
+		},
+		{
+			name:    "fromEmpty",
+			oldText: "",
+			newText: "a\n",
+			want:    "--- old\n+++ new\n@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			name:    "mergeHunks",
+			oldText: "a\nb\nc\nd\n",
+			newText: "A\nb\nc\nD\n",
+			want:    "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-a\n+A\n b\n c\n-d\n+D\n",
+		},
+		{
+			name:    "splitHunks",
+			oldText: "a\nb\nc\nd\ne\nf\n",
+			newText: "A\nb\nc\nd\ne\nF\n",
+			want:    "--- old\n+++ new\n@@ -1,2 +1,2 @@\n-a\n+A\n b\n@@ -5,2 +5,2 @@\n e\n-f\n+F\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := UnifiedDiff("old", "new", test.oldText, test.newText, 1); got != test.want {
+				t.Errorf("UnifiedDiff() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiffReplace(t *testing.T) {
+	// Exceeding the max edit count reports the changed lines as a whole replacement.
+	var oldLines, newLines []string
+	for i := 0; i < diffMaxEditCount; i++ {
+		oldLines = append(oldLines, fmt.Sprintf("old %d", i))
+		newLines = append(newLines, fmt.Sprintf("new %d", i))
+	}
+	got := UnifiedDiff("old", "new", "same\n"+strings.Join(oldLines, "\n"), "same\n"+strings.Join(newLines, "\n"), 0)
+	if want := fmt.Sprintf("@@ -2,%d +2,%d @@\n", diffMaxEditCount, diffMaxEditCount); !strings.Contains(got, want) {
+		t.Fatalf("expect hunk header %q", want)
+	}
+	if count := strings.Count(got, "\n-old "); count != diffMaxEditCount {
+		t.Errorf("got %d deleted lines, want %d", count, diffMaxEditCount)
+	}
+}
@@ -64,6 +64,11 @@ func (e *Error) Error() string {
 	return e.Err.Error()
 }
 
+// Unwrap returns the embedded error, so that errors.Is and errors.As can inspect it.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
 // ErrorCode unwraps an application error and returns its code.
 // Non-application errors always return EINTERNAL.
 func ErrorCode(err error) Code {
@@ -99,6 +99,18 @@ const actions = {
 
     return policy;
   },
+
+  // Deleting the policy reverts it to the default, which is fetched afterwards.
+  async deletePolicyByEnvironmentAndType(
+    { dispatch }: any,
+    { environmentId, type }: { environmentId: EnvironmentId; type: PolicyType }
+  ): Promise<Policy> {
+    await axios.delete(`/api/policy/environment/${environmentId}?type=${type}`);
+    return dispatch("fetchPolicyByEnvironmentAndType", {
+      environmentId,
+      type,
+    });
+  },
 };
 
 const mutations = {
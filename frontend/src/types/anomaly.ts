@@ -12,13 +12,50 @@ import {
 export type AnomalyType =
   | "bb.anomaly.instance.connection"
   | "bb.anomaly.instance.migration-schema"
+  | "bb.anomaly.instance.disk-space-low"
   | "bb.anomaly.database.backup.policy-violation"
   | "bb.anomaly.database.backup.missing"
+  | "bb.anomaly.database.backup.prune-failed"
+  | "bb.anomaly.database.backup.corrupt"
+  | "bb.anomaly.database.backup.size-anomalous"
   | "bb.anomaly.database.connection"
-  | "bb.anomaly.database.schema.drift";
+  | "bb.anomaly.database.schema.drift"
+  | "bb.anomaly.database.index.missing"
+  | "bb.anomaly.database.connection.count-high"
+  | "bb.anomaly.database.transaction.long-running"
+  | "bb.anomaly.database.replication.lag"
+  | "bb.anomaly.database.table.bloat"
+  | "bb.anomaly.database.scan.timeout"
+  | "bb.anomaly.database.version.outdated"
+  | "bb.anomaly.database.table.row-count-growth"
+  | "bb.anomaly.database.untracked"
+  | "bb.anomaly.database.no-migration-history"
+  | "bb.anomaly.instance.engine-version-eol"
+  | "bb.anomaly.database.table.missing-index"
+  | "bb.anomaly.database.connection.unencrypted"
+  | "bb.anomaly.database.connection.insecure";
+
+export type ConnectionErrorCategory =
+  | "DNS"
+  | "REFUSED"
+  | "AUTH"
+  | "TLS"
+  | "TIMEOUT"
+  | "UNKNOWN";
 
 export type AnomalyInstanceConnectionPayload = {
   detail: string;
+  category?: ConnectionErrorCategory;
+  host?: string;
+  port?: string;
+};
+
+export type AnomalyInstanceDiskSpaceLowPayload = {
+  totalBytes: number;
+  usedBytes: number;
+  freeBytes: number;
+  percentage: number;
+  threshold: number;
 };
 
 export type AnomalyDatabaseBackupPolicyViolationPayload = {
@@ -34,22 +71,170 @@ export type AnomalyDatabaseBackupMissingPayload = {
 
 export type AnomalyDatabaseConnectionPayload = {
   detail: string;
+  category?: ConnectionErrorCategory;
+  host?: string;
+  port?: string;
 };
 
 export type AnomalyDatabaseSchemaDriftPayload = {
   version: string;
-  expect: string;
-  actual: string;
+  // The full schemas are omitted if they are too large.
+  expect?: string;
+  actual?: string;
+  diff: string;
+};
+
+export type IndexMissingForeignKey = {
+  table: string;
+  columnList: string[];
+  suggestedIndexDDL: string;
+};
+
+export type AnomalyDatabaseIndexMissingPayload = {
+  foreignKeyList: IndexMissingForeignKey[];
+};
+
+export type AnomalyDatabaseConnectionCountHighPayload = {
+  currentConnections: number;
+  maxConnections: number;
+  percentage: number;
+};
+
+export type AnomalyDatabaseLongRunningTransactionPayload = {
+  pid: number;
+  durationSeconds: number;
+  query: string;
+  count: number;
+};
+
+export type AnomalyDatabaseBackupPruneFailedPayload = {
+  backupId: number;
+  backupName: string;
+  detail: string;
+};
+
+export type AnomalyDatabaseBackupCorruptPayload = {
+  backupId: number;
+  backupName: string;
+  expectedChecksum: string;
+  actualChecksum: string;
+};
+
+export type AnomalyDatabaseBackupSizeAnomalousPayload = {
+  backupId: number;
+  backupName: string;
+  previousSizeBytes: number;
+  currentSizeBytes: number;
+  ratio: number;
+  threshold: number;
+};
+
+export type AnomalyDatabaseReplicationLagPayload = {
+  lagSeconds: number;
+  thresholdSeconds: number;
+  // Empty if the primary is unknown.
+  primary: string;
+};
+
+export type AnomalyDatabaseTableBloatPayload = {
+  table: string;
+  deadTuples: number;
+  liveTuples: number;
+  percentage: number;
+  threshold: number;
+};
+
+export type AnomalyDatabaseMissingIndexPayload = {
+  table: string;
+  sequentialScans: number;
+  indexScans: number;
+  ratio: number;
+  threshold: number;
+};
+
+export type AnomalyDatabaseScanTimeoutPayload = {
+  timeoutSeconds: number;
+};
+
+export type AnomalyDatabaseVersionOutdatedPayload = {
+  version: string;
+  minimumVersion: string;
+};
+
+export type AnomalyInstanceEngineVersionEOLPayload = {
+  version: string;
+  eolDate: string;
+  daysRemaining: number;
+};
+
+export type AnomalyDatabaseInsecureConnectionPayload = {
+  encrypted: boolean;
+  selfSigned: boolean;
+  certificateExpireTs?: number;
+};
+
+export type AnomalyDatabaseTableRowCountGrowthPayload = {
+  table: string;
+  previousRowCount: number;
+  previousTs: number;
+  currentRowCount: number;
+  growthRatio: number;
+  threshold: number;
+};
+
+export type AnomalyDatabaseUntrackedPayload = {
+  instanceName: string;
+  databaseName: string;
+};
+
+export type AnomalyDatabaseNoMigrationHistoryPayload = {
+  instanceName: string;
+  databaseName: string;
 };
 
 export type AnomalyPayload =
+  | AnomalyInstanceDiskSpaceLowPayload
   | AnomalyDatabaseBackupPolicyViolationPayload
   | AnomalyDatabaseBackupMissingPayload
+  | AnomalyDatabaseBackupPruneFailedPayload
+  | AnomalyDatabaseBackupCorruptPayload
+  | AnomalyDatabaseBackupSizeAnomalousPayload
   | AnomalyDatabaseConnectionPayload
-  | AnomalyDatabaseSchemaDriftPayload;
+  | AnomalyDatabaseSchemaDriftPayload
+  | AnomalyDatabaseIndexMissingPayload
+  | AnomalyDatabaseConnectionCountHighPayload
+  | AnomalyDatabaseLongRunningTransactionPayload
+  | AnomalyDatabaseReplicationLagPayload
+  | AnomalyDatabaseTableBloatPayload
+  | AnomalyDatabaseScanTimeoutPayload
+  | AnomalyDatabaseVersionOutdatedPayload
+  | AnomalyDatabaseTableRowCountGrowthPayload
+  | AnomalyDatabaseUntrackedPayload
+  | AnomalyDatabaseNoMigrationHistoryPayload
+  | AnomalyInstanceEngineVersionEOLPayload
+  | AnomalyDatabaseMissingIndexPayload
+  | AnomalyDatabaseInsecureConnectionPayload;
 
 export type AnomalySeverity = "MEDIUM" | "HIGH" | "CRITICAL";
 
+export type AnomalyArchiveReason =
+  | ""
+  | "CONNECTION_RESTORED"
+  | "CONNECTION_SECURE"
+  | "MIGRATION_SCHEMA_SET_UP"
+  | "BELOW_THRESHOLD"
+  | "VERSION_SUPPORTED"
+  | "DATABASE_TRACKED"
+  | "SCHEMA_MATCHED"
+  | "INDEX_PRESENT"
+  | "BACKUP_POLICY_SATISFIED"
+  | "BACKUP_PRESENT"
+  | "BACKUP_VERIFIED"
+  | "BACKUP_PRUNED"
+  | "SCAN_COMPLETED"
+  | "CHECK_DISABLED"
+  | "DATABASE_NOT_FOUND";
+
 export type Anomaly = {
   id: AnomalyId;
 
@@ -68,4 +253,8 @@ export type Anomaly = {
   type: AnomalyType;
   severity: AnomalySeverity;
   payload: AnomalyPayload;
+  // The last time the anomaly is found, while createdTs is the first time.
+  lastSeenTs: number;
+  // Why the anomaly is archived, empty for the active anomaly.
+  archiveReason: AnomalyArchiveReason;
 };
@@ -5,7 +5,9 @@ export type BackupStatus = "PENDING_CREATE" | "DONE" | "FAILED";
 
 export type BackupType = "MANUAL" | "AUTOMATIC";
 
-export type BackupStorageBackend = "LOCAL";
+export type BackupStorageBackend = "LOCAL" | "S3";
+
+export type BackupCompression = "NONE" | "GZIP" | "ZSTD";
 
 // Backup
 export type Backup = {
@@ -24,9 +26,12 @@ export type Backup = {
   status: BackupStatus;
   type: BackupType;
   storageBackend: BackupStorageBackend;
+  compression: BackupCompression;
   migrationHistoryVersion: string;
   path: string;
   comment: string;
+  checksum: string;
+  sizeBytes: number;
 };
 
 export type BackupCreate = {
@@ -38,6 +43,8 @@ export type BackupCreate = {
   status: BackupStatus;
   type: BackupType;
   storageBackend: BackupStorageBackend;
+  // Defaults to GZIP if unset.
+  compression?: BackupCompression;
 };
 
 // Backup setting.
@@ -55,7 +62,10 @@ export type BackupSetting = {
 
   enabled: boolean;
   hour: number;
+  minute: number;
   dayOfWeek: number;
+  // -1 means unset, otherwise the day of the month to take the monthly backup.
+  dayOfMonth: number;
   hookUrl: string;
 };
 
@@ -66,6 +76,9 @@ export type BackupSettingUpsert = {
   // Domain specific fields
   enabled: boolean;
   hour: number;
+  minute: number;
   dayOfWeek: number;
+  // -1 means unset, otherwise the day of the month to take the monthly backup.
+  dayOfMonth: number;
   hookUrl: string;
 };
@@ -236,6 +236,10 @@ export const unknown = (
     engine: "MYSQL",
     engineVersion: "",
     host: "",
+    replica: false,
+    enableAnomalyScan: true,
+    serviceName: "",
+    sid: "",
   };
 
   const UNKNOWN_DATABASE: Database = {
@@ -253,6 +257,7 @@ export const unknown = (
     collation: "",
     syncStatus: "NOT_FOUND",
     lastSuccessfulSyncTs: 0,
+    managed: false,
   };
 
   const UNKNOWN_DATA_SOURCE: DataSource = {
@@ -277,7 +282,9 @@ export const unknown = (
     updatedTs: 0,
     enabled: false,
     hour: 0,
+    minute: 0,
     dayOfWeek: 0,
+    dayOfMonth: -1,
     hookUrl: "",
   };
 
@@ -587,6 +594,10 @@ export const empty = (
     engine: "MYSQL",
     engineVersion: "",
     host: "",
+    replica: false,
+    enableAnomalyScan: true,
+    serviceName: "",
+    sid: "",
   };
 
   const EMPTY_DATABASE: Database = {
@@ -604,6 +615,7 @@ export const empty = (
     collation: "",
     syncStatus: "NOT_FOUND",
     lastSuccessfulSyncTs: 0,
+    managed: false,
   };
 
   const EMPTY_DATA_SOURCE: DataSource = {
@@ -628,7 +640,9 @@ export const empty = (
     updatedTs: 0,
     enabled: false,
     hour: 0,
+    minute: 0,
     dayOfWeek: 0,
+    dayOfMonth: -1,
     hookUrl: "",
   };
 
@@ -33,6 +33,7 @@ export type Database = {
   // Domain specific fields
   syncStatus: DatabaseSyncStatus;
   lastSuccessfulSyncTs: number;
+  managed: boolean;
   name: string;
   characterSet: string;
   collation: string;
@@ -7,7 +7,10 @@ import { VCSPushEvent } from "./vcs";
 
 export type EngineType =
   | "CLICKHOUSE"
+  | "MONGODB"
+  | "MSSQL"
   | "MYSQL"
+  | "ORACLE"
   | "POSTGRES"
   | "SNOWFLAKE"
   | "TIDB";
@@ -15,6 +18,9 @@ export type EngineType =
 export function defaultCharset(type: EngineType): string {
   switch (type) {
     case "CLICKHOUSE":
+    case "MONGODB":
+    case "MSSQL":
+    case "ORACLE":
     case "SNOWFLAKE":
       return "";
     case "MYSQL":
@@ -28,6 +34,9 @@ export function defaultCharset(type: EngineType): string {
 export function defaultCollation(type: EngineType): string {
   switch (type) {
     case "CLICKHOUSE":
+    case "MONGODB":
+    case "MSSQL":
+    case "ORACLE":
     case "SNOWFLAKE":
       return "";
     case "MYSQL":
@@ -65,6 +74,13 @@ export type Instance = {
   // In mysql, username can be empty which means anonymous user
   username?: string;
   password?: string;
+  // Whether the instance is a read replica
+  replica: boolean;
+  // Whether the anomaly scanner scans the instance
+  enableAnomalyScan: boolean;
+  // The Oracle database to connect to, only one of them is set for Oracle
+  serviceName: string;
+  sid: string;
 };
 
 export type InstanceCreate = {
@@ -80,6 +96,10 @@ export type InstanceCreate = {
   // In mysql, username can be empty which means anonymous user
   username?: string;
   password?: string;
+  replica: boolean;
+  enableAnomalyScan: boolean;
+  serviceName: string;
+  sid: string;
 };
 
 export type InstancePatch = {
@@ -94,6 +114,10 @@ export type InstancePatch = {
   username?: string;
   password?: string;
   useEmptyPassword: boolean;
+  replica?: boolean;
+  enableAnomalyScan?: boolean;
+  serviceName?: string;
+  sid?: string;
 };
 
 export type MigrationSchemaStatus = "UNKNOWN" | "OK" | "NOT_EXIST";
@@ -1,8 +1,20 @@
-import { Environment, PolicyId, Principal } from ".";
+import {
+  AnomalyType,
+  BackupStorageBackend,
+  Environment,
+  InstanceId,
+  PolicyId,
+  Principal,
+  RoleType,
+} from ".";
 
 export type PolicyType =
   | "bb.policy.pipeline-approval"
-  | "bb.policy.backup-plan";
+  | "bb.policy.backup-plan"
+  | "bb.policy.anomaly"
+  | "bb.policy.schema-drift"
+  | "bb.policy.data-masking"
+  | "bb.policy.sql-review";
 
 export type PipelineApprovalPolicyValue =
   | "MANUAL_APPROVAL_NEVER"
@@ -10,17 +22,87 @@ export type PipelineApprovalPolicyValue =
 
 export type PipelineApporvalPolicyPayload = {
   value: PipelineApprovalPolicyValue;
+  // The roles allowed to approve, empty means any member.
+  approverRoleList?: RoleType[];
+  // The ordered approval chain, takes precedence over approverRoleList.
+  stepList?: PipelineApprovalStep[];
+  // The group the approvals are required from.
+  assigneeGroup?: string;
+  // The number of required approvals, 1 if unset.
+  minApprovals?: number;
 };
 
-export type BackupPlanPolicySchedule = "UNSET" | "DAILY" | "WEEKLY";
+export type PipelineApprovalStep = {
+  role: RoleType;
+  count: number;
+};
+
+export type BackupPlanPolicySchedule =
+  | "UNSET"
+  | "DAILY"
+  | "WEEKLY"
+  | "MONTHLY";
 
 export type PolicyBackupPlanPolicyPayload = {
   schedule: BackupPlanPolicySchedule;
+  // 0 means keeping the backups forever.
+  retentionDays: number;
+  // LOCAL if unset.
+  storageBackend?: BackupStorageBackend;
+  s3?: BackupS3Config;
+};
+
+export type BackupS3Config = {
+  bucket: string;
+  prefix?: string;
+  // The region of the AWS configuration of the server if unset.
+  region?: string;
+};
+
+export type PolicyAnomalyPolicyPayload = {
+  enabled: boolean;
+  // The anomaly types not checked for the environment.
+  disabledTypeList: AnomalyType[];
+  // Whether the instances in the environment must only be connected with TLS.
+  requireEncryptedConnection?: boolean;
+  // Whether to check the TLS state and the server certificate of the connections to the instances in the environment.
+  checkInsecureConnection?: boolean;
+};
+
+export type PolicySchemaDriftPolicyPayload = {
+  ignorePatternList: string[];
+};
+
+export type DataMaskingAlgorithm = "FULL" | "PARTIAL" | "HASH";
+
+export type DataMaskingRule = {
+  columnPattern: string;
+  algorithm: DataMaskingAlgorithm;
+};
+
+export type PolicyDataMaskingPolicyPayload = {
+  ruleList: DataMaskingRule[];
+};
+
+export type SQLReviewRuleLevel = "ERROR" | "WARNING" | "DISABLED";
+
+export type SQLReviewRule = {
+  id: string;
+  level: SQLReviewRuleLevel;
+  payload?: any;
+};
+
+export type PolicySQLReviewPolicyPayload = {
+  ruleList: SQLReviewRule[];
 };
 
 export type PolicyPayload =
   | PipelineApporvalPolicyPayload
-  | PolicyBackupPlanPolicyPayload;
+  | PolicyBackupPlanPolicyPayload
+  | PolicyAnomalyPolicyPayload
+  | PolicySchemaDriftPolicyPayload
+  | PolicyDataMaskingPolicyPayload
+  | PolicySQLReviewPolicyPayload;
 
 export type Policy = {
   id: PolicyId;
@@ -33,6 +115,8 @@ export type Policy = {
 
   // Related fields
   environment: Environment;
+  // instanceId is 0 for the environment policy.
+  instanceId: InstanceId;
 
   // Domain specific fields
   type: PolicyType;
@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"time"
 
@@ -384,6 +385,40 @@ func (driver *Driver) Execute(ctx context.Context, statement string) error {
 	return err
 }
 
+// Capabilities returns the optional features supported for ClickHouse.
+func (driver *Driver) Capabilities() db.Capabilities {
+	return db.Capabilities{
+		SupportsDiskUsage:        true,
+		SupportsMigrationHistory: true,
+	}
+}
+
+// FindLongRunningTransactionList is not supported for ClickHouse.
+func (driver *Driver) FindLongRunningTransactionList(ctx context.Context, database string, threshold time.Duration) ([]*db.Transaction, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("listing transactions is not supported for ClickHouse"))
+}
+
+// GetDiskUsage gets the disk usage summed over the disks configured for the server.
+func (driver *Driver) GetDiskUsage(ctx context.Context) (*db.DiskUsage, error) {
+	query := "SELECT sum(total_space), sum(free_space) FROM system.disks"
+	var usage db.DiskUsage
+	if err := driver.db.QueryRowContext(ctx, query).Scan(&usage.TotalBytes, &usage.FreeBytes); err != nil {
+		return nil, util.FormatErrorWithQuery(err, query)
+	}
+	usage.UsedBytes = usage.TotalBytes - usage.FreeBytes
+	return &usage, nil
+}
+
+// ReplicationStatus is not supported for ClickHouse.
+func (driver *Driver) ReplicationStatus(ctx context.Context) (*db.ReplicationStatus, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("getting replication status is not supported for ClickHouse"))
+}
+
+// GetConnectionTLSState is not supported for ClickHouse.
+func (driver *Driver) GetConnectionTLSState(ctx context.Context) (*db.ConnectionTLSState, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("getting connection TLS state is not supported for ClickHouse"))
+}
+
 // NeedsSetupMigration returns whether it needs to setup migration.
 func (driver *Driver) NeedsSetupMigration(ctx context.Context) (bool, error) {
 	const query = `
@@ -521,6 +556,16 @@ const (
 		"-- View structure for `%s`\n" +
 		"--\n" +
 		"%s;\n"
+	materializedViewStmtFmt = "" +
+		"--\n" +
+		"-- Materialized view structure for `%s`\n" +
+		"--\n" +
+		"%s;\n"
+	dictionaryStmtFmt = "" +
+		"--\n" +
+		"-- Dictionary structure for `%s`\n" +
+		"--\n" +
+		"%s;\n"
 )
 
 // Dump dumps the database.
@@ -542,7 +587,7 @@ func (driver *Driver) Dump(ctx context.Context, database string, out io.Writer,
 	return nil
 }
 
-// dumpTxn will dump the input database. schemaOnly isn't supported yet and true by default.
+// dumpTxn will dump the input database. Dumping the data isn't supported yet, so only the schema is dumped regardless of schemaOnly.
 func dumpTxn(ctx context.Context, txn *sql.Tx, database string, out io.Writer, schemaOnly bool) error {
 	// Find all dumpable databases
 	dbNames, err := getDatabases(txn)
@@ -654,10 +699,10 @@ type tableSchema struct {
 	statement string
 }
 
-// getTables gets all tables of a database.
+// getTables gets all tables of a database, see formatTableList for the statements.
 func getTables(txn *sql.Tx, dbName string) ([]*tableSchema, error) {
 	var tables []*tableSchema
-	query := fmt.Sprintf("SELECT name, engine, create_table_query FROM system.tables WHERE database='%s';", dbName)
+	query := fmt.Sprintf("SELECT name, engine, create_table_query FROM system.tables WHERE database='%s' ORDER BY name;", dbName)
 	rows, err := txn.Query(query)
 	if err != nil {
 		return nil, err
@@ -671,17 +716,52 @@ func getTables(txn *sql.Tx, dbName string) ([]*tableSchema, error) {
 		}
 		tables = append(tables, &tbl)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return formatTableList(dbName, tables), nil
+}
 
+// formatTableList formats the statements of the tables from system.tables of the database, so that the dump of
+// the same schema is the same, which the schema drift check relies on.
+// The tables are ordered by name, and the inner tables storing the data of the materialized views are skipped,
+// since they are created by the materialized views and named after a random UUID in the Atomic databases.
+func formatTableList(dbName string, tables []*tableSchema) []*tableSchema {
+	var list []*tableSchema
 	for _, tbl := range tables {
-		// Remove the database prefix from statement.
-		tbl.statement = strings.ReplaceAll(tbl.statement, fmt.Sprintf(" %s.%s ", dbName, tbl.name), fmt.Sprintf(" %s ", tbl.name))
-		if tbl.tableType == "View" {
-			tbl.statement = fmt.Sprintf(viewStmtFmt, tbl.name, tbl.statement)
-		} else {
-			tbl.statement = fmt.Sprintf(tableStmtFmt, tbl.name, tbl.statement)
+		if strings.HasPrefix(tbl.name, ".inner.") || strings.HasPrefix(tbl.name, ".inner_id.") {
+			continue
 		}
+		// Remove the database prefix from the name of the created object, which is quoted if needed.
+		statement := tbl.statement
+		for _, name := range [][2]string{
+			{fmt.Sprintf(" %s.%s ", dbName, tbl.name), fmt.Sprintf(" %s ", tbl.name)},
+			{fmt.Sprintf(" `%s`.`%s` ", dbName, tbl.name), fmt.Sprintf(" `%s` ", tbl.name)},
+		} {
+			if strings.Contains(statement, name[0]) {
+				statement = strings.Replace(statement, name[0], name[1], 1)
+				break
+			}
+		}
+		stmtFmt := tableStmtFmt
+		switch tbl.tableType {
+		case "View":
+			stmtFmt = viewStmtFmt
+		case "MaterializedView":
+			stmtFmt = materializedViewStmtFmt
+		case "Dictionary":
+			stmtFmt = dictionaryStmtFmt
+		}
+		list = append(list, &tableSchema{
+			name:      tbl.name,
+			tableType: tbl.tableType,
+			statement: fmt.Sprintf(stmtFmt, tbl.name, statement),
+		})
 	}
-	return tables, nil
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].name < list[j].name
+	})
+	return list
 }
 
 // Restore restores a database.
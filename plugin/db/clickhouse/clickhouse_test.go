@@ -0,0 +1,78 @@
+package clickhouse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bytebase/bytebase/plugin/db"
+)
+
+func TestFormatTableList(t *testing.T) {
+	// The rows of system.tables of database db, as returned by an Atomic database.
+	tables := []*tableSchema{
+		{
+			name:      "user",
+			tableType: "MergeTree",
+			statement: "CREATE TABLE db.user (`id` UInt64, `name` String) ENGINE = MergeTree ORDER BY id SETTINGS index_granularity = 8192",
+		},
+		{
+			name:      "active_user",
+			tableType: "View",
+			statement: "CREATE VIEW db.active_user (`id` UInt64) AS SELECT id FROM db.user WHERE name != ''",
+		},
+		{
+			name:      "user_count",
+			tableType: "MaterializedView",
+			statement: "CREATE MATERIALIZED VIEW db.user_count (`count` UInt64) ENGINE = SummingMergeTree ORDER BY tuple() SETTINGS index_granularity = 8192 AS SELECT count() AS count FROM db.user",
+		},
+		{
+			name:      ".inner_id.5f1c3a4e-1b2d-4c5e-8f90-123456789abc",
+			tableType: "SummingMergeTree",
+			statement: "CREATE TABLE db.`.inner_id.5f1c3a4e-1b2d-4c5e-8f90-123456789abc` (`count` UInt64) ENGINE = SummingMergeTree ORDER BY tuple() SETTINGS index_granularity = 8192",
+		},
+		{
+			name:      "user-event",
+			tableType: "ReplacingMergeTree",
+			statement: "CREATE TABLE `db`.`user-event` (`id` UInt64, `ts` DateTime) ENGINE = ReplacingMergeTree(ts) PARTITION BY toYYYYMM(ts) ORDER BY id SETTINGS index_granularity = 8192",
+		},
+	}
+
+	want := "" +
+		"--\n" +
+		"-- View structure for `active_user`\n" +
+		"--\n" +
+		"CREATE VIEW active_user (`id` UInt64) AS SELECT id FROM db.user WHERE name != '';\n" +
+		"\n" +
+		"--\n" +
+		"-- Table structure for `user`\n" +
+		"--\n" +
+		"CREATE TABLE user (`id` UInt64, `name` String) ENGINE = MergeTree ORDER BY id SETTINGS index_granularity = 8192;\n" +
+		"\n" +
+		"--\n" +
+		"-- Table structure for `user-event`\n" +
+		"--\n" +
+		"CREATE TABLE `user-event` (`id` UInt64, `ts` DateTime) ENGINE = ReplacingMergeTree(ts) PARTITION BY toYYYYMM(ts) ORDER BY id SETTINGS index_granularity = 8192;\n" +
+		"\n" +
+		"--\n" +
+		"-- Materialized view structure for `user_count`\n" +
+		"--\n" +
+		"CREATE MATERIALIZED VIEW user_count (`count` UInt64) ENGINE = SummingMergeTree ORDER BY tuple() SETTINGS index_granularity = 8192 AS SELECT count() AS count FROM db.user;\n" +
+		"\n"
+	var sb strings.Builder
+	for _, tbl := range formatTableList("db", tables) {
+		sb.WriteString(tbl.statement + "\n")
+	}
+	if got := sb.String(); got != want {
+		t.Errorf("formatTableList() = %s, want %s", got, want)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	want := db.Capabilities{
+		SupportsDiskUsage:        true,
+		SupportsMigrationHistory: true,
+	}
+	if got := (&Driver{}).Capabilities(); got != want {
+		t.Errorf("Capabilities() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// ConnectionErrorCategory is the category of a connection failure.
+type ConnectionErrorCategory string
+
+const (
+	// ConnectionErrorCategoryDNS is the category for failing to resolve the host.
+	ConnectionErrorCategoryDNS ConnectionErrorCategory = "DNS"
+	// ConnectionErrorCategoryRefused is the category for the TCP connection being refused.
+	ConnectionErrorCategoryRefused ConnectionErrorCategory = "REFUSED"
+	// ConnectionErrorCategoryAuth is the category for the server rejecting the credentials.
+	ConnectionErrorCategoryAuth ConnectionErrorCategory = "AUTH"
+	// ConnectionErrorCategoryTLS is the category for the TLS handshake or certificate verification failure.
+	ConnectionErrorCategoryTLS ConnectionErrorCategory = "TLS"
+	// ConnectionErrorCategoryTimeout is the category for the connection timing out.
+	ConnectionErrorCategoryTimeout ConnectionErrorCategory = "TIMEOUT"
+	// ConnectionErrorCategoryUnknown is the category for the failures not falling into the other categories.
+	ConnectionErrorCategoryUnknown ConnectionErrorCategory = "UNKNOWN"
+)
+
+// connectionErrorPatternList maps the lower-cased messages of the driver errors to the categories.
+// The drivers don't always wrap the underlying errors, so the messages are matched as the fallback.
+// The patterns are checked in order and the first match wins.
+var connectionErrorPatternList = []struct {
+	pattern  string
+	category ConnectionErrorCategory
+}{
+	{"no such host", ConnectionErrorCategoryDNS},
+	{"server misbehaving", ConnectionErrorCategoryDNS},
+	{"connection refused", ConnectionErrorCategoryRefused},
+	{"i/o timeout", ConnectionErrorCategoryTimeout},
+	{"deadline exceeded", ConnectionErrorCategoryTimeout},
+	{"x509:", ConnectionErrorCategoryTLS},
+	{"tls:", ConnectionErrorCategoryTLS},
+	// MySQL: TLS requested but server does not support TLS
+	{"does not support tls", ConnectionErrorCategoryTLS},
+	// Postgres: pq: SSL is not enabled on the server
+	{"ssl is not enabled", ConnectionErrorCategoryTLS},
+	// SQL Server: TLS Handshake failed: cannot read handshake packet: EOF
+	{"tls handshake failed", ConnectionErrorCategoryTLS},
+	// MySQL: Error 1045: Access denied for user 'root'@'localhost' (using password: YES)
+	{"access denied", ConnectionErrorCategoryAuth},
+	// Postgres: pq: password authentication failed for user "postgres"
+	{"authentication failed", ConnectionErrorCategoryAuth},
+	// Postgres: pq: no pg_hba.conf entry for host "10.0.0.1", user "postgres", database "postgres", SSL off
+	{"pg_hba.conf", ConnectionErrorCategoryAuth},
+	// SQL Server: mssql: login error: Login failed for user 'sa'.
+	{"login failed", ConnectionErrorCategoryAuth},
+	// MongoDB: connection() error occurred during connection handshake: auth error: sasl conversation error: unable to authenticate using mechanism "SCRAM-SHA-256"
+	{"unable to authenticate", ConnectionErrorCategoryAuth},
+	// Snowflake: 390100 (08004): Incorrect username or password was specified.
+	{"incorrect username or password", ConnectionErrorCategoryAuth},
+}
+
+// ClassifyConnectionError returns the category of the connection failure.
+// It inspects the wrapped network errors first and falls back to matching the driver error messages.
+func ClassifyConnectionError(err error) ConnectionErrorCategory {
+	if err == nil {
+		return ConnectionErrorCategoryUnknown
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ConnectionErrorCategoryDNS
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ConnectionErrorCategoryRefused
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ConnectionErrorCategoryTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ConnectionErrorCategoryTimeout
+	}
+	var recordHeaderErr tls.RecordHeaderError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certificateInvalidErr x509.CertificateInvalidError
+	if errors.As(err, &recordHeaderErr) || errors.As(err, &unknownAuthorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &certificateInvalidErr) {
+		return ConnectionErrorCategoryTLS
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, p := range connectionErrorPatternList {
+		if strings.Contains(message, p.pattern) {
+			return p.category
+		}
+	}
+	return ConnectionErrorCategoryUnknown
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestClassifyConnectionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ConnectionErrorCategory
+	}{
+		{"nil", nil, ConnectionErrorCategoryUnknown},
+		{"dnsError", &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "db.invalid", IsNotFound: true}}, ConnectionErrorCategoryDNS},
+		{"refusedError", &net.OpError{Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}, ConnectionErrorCategoryRefused},
+		{"deadlineExceeded", fmt.Errorf("failed to ping: %w", context.DeadlineExceeded), ConnectionErrorCategoryTimeout},
+		{"mysqlDNS", errors.New("dial tcp: lookup db.invalid on 127.0.0.53:53: no such host"), ConnectionErrorCategoryDNS},
+		{"mysqlRefused", errors.New("dial tcp 127.0.0.1:3306: connect: connection refused"), ConnectionErrorCategoryRefused},
+		{"mysqlTimeout", errors.New("dial tcp 10.0.0.1:3306: i/o timeout"), ConnectionErrorCategoryTimeout},
+		{"mysqlAuth", errors.New("Error 1045: Access denied for user 'root'@'172.17.0.1' (using password: YES)"), ConnectionErrorCategoryAuth},
+		{"mysqlTLSUnsupported", errors.New("TLS requested but server does not support TLS"), ConnectionErrorCategoryTLS},
+		{"mysqlTLSCertificate", errors.New("x509: certificate signed by unknown authority"), ConnectionErrorCategoryTLS},
+		{"pgDNS", errors.New("dial tcp: lookup pg.invalid: no such host"), ConnectionErrorCategoryDNS},
+		{"pgRefused", errors.New("dial tcp [::1]:5432: connect: connection refused"), ConnectionErrorCategoryRefused},
+		{"pgAuth", errors.New(`pq: password authentication failed for user "postgres"`), ConnectionErrorCategoryAuth},
+		{"pgHBA", errors.New(`pq: no pg_hba.conf entry for host "10.0.0.1", user "postgres", database "postgres", SSL off`), ConnectionErrorCategoryAuth},
+		{"pgSSLDisabled", errors.New("pq: SSL is not enabled on the server"), ConnectionErrorCategoryTLS},
+		{"pgTLSHandshake", errors.New("tls: first record does not look like a TLS handshake"), ConnectionErrorCategoryTLS},
+		{"mssqlAuth", errors.New("mssql: login error: Login failed for user 'sa'."), ConnectionErrorCategoryAuth},
+		{"mssqlTLS", errors.New("TLS Handshake failed: cannot read handshake packet: EOF"), ConnectionErrorCategoryTLS},
+		{"mongodbAuth", errors.New(`connection() error occurred during connection handshake: auth error: sasl conversation error: unable to authenticate using mechanism "SCRAM-SHA-256": (AuthenticationFailed) Authentication failed.`), ConnectionErrorCategoryAuth},
+		{"snowflakeAuth", errors.New("390100 (08004): Incorrect username or password was specified."), ConnectionErrorCategoryAuth},
+		{"unknown", errors.New(`pq: database "foo" does not exist`), ConnectionErrorCategoryUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyConnectionError(tt.err); got != tt.want {
+				t.Errorf("ClassifyConnectionError(%v) = %s, want %s", tt.err, got, tt.want)
+			}
+		})
+	}
+}
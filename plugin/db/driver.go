@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/bytebase/bytebase/common"
 	"go.uber.org/zap"
@@ -20,8 +21,14 @@ type Type string
 const (
 	// ClickHouse is the database type for CLICKHOUSE.
 	ClickHouse Type = "CLICKHOUSE"
+	// MongoDB is the database type for MONGODB.
+	MongoDB Type = "MONGODB"
+	// MSSQL is the database type for MSSQL.
+	MSSQL Type = "MSSQL"
 	// MySQL is the database type for MYSQL.
 	MySQL Type = "MYSQL"
+	// Oracle is the database type for ORACLE.
+	Oracle Type = "ORACLE"
 	// Postgres is the database type for POSTGRES.
 	Postgres Type = "POSTGRES"
 	// Snowflake is the database type for SNOWFLAKE.
@@ -59,6 +66,68 @@ type Index struct {
 	Comment string
 }
 
+// ForeignKey is the database foreign key.
+type ForeignKey struct {
+	Name string
+	// ColumnList is the list of referencing columns in key order.
+	ColumnList      []string
+	ReferencedTable string
+}
+
+// Transaction is the active database transaction.
+type Transaction struct {
+	// PID is the process (connection) id running the transaction.
+	PID int64
+	// Duration is how long the transaction has been running.
+	Duration time.Duration
+	// Query is the statement currently running in the transaction, it's empty if the transaction is idle.
+	Query string
+}
+
+// DiskUsage is the disk usage of the instance.
+type DiskUsage struct {
+	TotalBytes int64
+	UsedBytes  int64
+	FreeBytes  int64
+}
+
+// ReplicationStatus is the replication status of the replica instance.
+type ReplicationStatus struct {
+	// Lag is how far the replica falls behind its primary.
+	Lag time.Duration
+	// Primary is the "host:port" of the primary the replica replicates from, empty if unknown.
+	Primary string
+}
+
+// ConnectionTLSState is the negotiated TLS state of the connection to the instance.
+type ConnectionTLSState struct {
+	// Required is whether the connection is configured to use TLS.
+	Required bool
+	// Encrypted is whether the connection uses TLS.
+	Encrypted bool
+	// SelfSigned is whether the server certificate is self-signed, false if the certificate is unknown.
+	SelfSigned bool
+	// CertificateNotAfter is the expiration time of the server certificate, zero if unknown.
+	CertificateNotAfter time.Time
+}
+
+// Capabilities is the optional features supported by a driver, the callers skip the features not supported instead of
+// calling the corresponding methods.
+type Capabilities struct {
+	// SupportsDiskUsage is whether GetDiskUsage is supported.
+	SupportsDiskUsage bool
+	// SupportsTxList is whether FindLongRunningTransactionList is supported.
+	SupportsTxList bool
+	// SupportsReplicationStatus is whether ReplicationStatus is supported.
+	SupportsReplicationStatus bool
+	// SupportsTLSState is whether GetConnectionTLSState is supported.
+	SupportsTLSState bool
+	// SupportsIndexInspection is whether SyncSchema reports both the indexes and the foreign keys of the tables.
+	SupportsIndexInspection bool
+	// SupportsMigrationHistory is whether the migration related methods are supported.
+	SupportsMigrationHistory bool
+}
+
 // Column the database table column.
 type Column struct {
 	Name     string
@@ -97,6 +166,8 @@ type Table struct {
 	ColumnList    []Column
 	// IndexList isn't supported for ClickHouse, Snowflake.
 	IndexList []Index
+	// ForeignKeyList isn't supported for ClickHouse, Snowflake.
+	ForeignKeyList []ForeignKey
 }
 
 // Schema is the database schema.
@@ -319,8 +390,13 @@ type MigrationHistoryFind struct {
 
 	Database *string
 	Version  *string
+	// VersionPrefix matches the migration histories whose version starts with it.
+	VersionPrefix *string
+	Status        *MigrationStatus
 	// If specified, then it will only fetch "Limit" most recent migration histories
 	Limit *int
+	// If specified, then it will skip the "Offset" most recent migration histories, for paginating with Limit.
+	Offset *int
 }
 
 // ConnectionConfig is the configuration for connections.
@@ -331,6 +407,9 @@ type ConnectionConfig struct {
 	Password  string
 	Database  string
 	TLSConfig TLSConfig
+	// ServiceName and SID identify the Oracle database to connect to, only one of them is set for Oracle.
+	ServiceName string
+	SID         string
 }
 
 // ConnectionContext is the context for connection.
@@ -349,9 +428,25 @@ type Driver interface {
 	Close(ctx context.Context) error
 	Ping(ctx context.Context) error
 	GetDbConnection(ctx context.Context, database string) (*sql.DB, error)
+	// Get the engine version of the instance.
+	// Drivers that can't determine the version return a common.NotImplemented error.
 	GetVersion(ctx context.Context) (string, error)
 	SyncSchema(ctx context.Context) ([]*User, []*Schema, error)
 	Execute(ctx context.Context, statement string) error
+	// Get the optional features supported by the driver for the engine.
+	Capabilities() Capabilities
+	// Find the transactions on the database running longer than the threshold, most long-running first.
+	// Drivers that can't list active transactions return a common.NotImplemented error.
+	FindLongRunningTransactionList(ctx context.Context, database string, threshold time.Duration) ([]*Transaction, error)
+	// Get the disk usage of the instance storage.
+	// Drivers that can't report disk usage return a common.NotImplemented error.
+	GetDiskUsage(ctx context.Context) (*DiskUsage, error)
+	// Get the replication status of the replica instance, including how far it falls behind its primary.
+	// Drivers that can't report the replication status return a common.NotImplemented error.
+	ReplicationStatus(ctx context.Context) (*ReplicationStatus, error)
+	// Get the negotiated TLS state of the connection to the instance.
+	// Drivers that can't tell the negotiated TLS state return a common.NotImplemented error.
+	GetConnectionTLSState(ctx context.Context) (*ConnectionTLSState, error)
 
 	// Migration related
 	// Check whether we need to setup migration (e.g. creating/upgrading the migration related tables)
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// engineVersionEOLMap is the end-of-life date of the release lines of each engine, after which the vendor stops
+// shipping fixes, including the security ones. The engines not listed don't have a known end of life.
+var engineVersionEOLMap = map[Type]map[string]time.Time{
+	MySQL: {
+		"5.5": eolDate(2018, time.December, 31),
+		"5.6": eolDate(2021, time.February, 28),
+		"5.7": eolDate(2023, time.October, 31),
+		"8.0": eolDate(2026, time.April, 30),
+		"8.4": eolDate(2032, time.April, 30),
+	},
+	Postgres: {
+		"9.4": eolDate(2020, time.February, 13),
+		"9.5": eolDate(2021, time.February, 11),
+		"9.6": eolDate(2021, time.November, 11),
+		"10":  eolDate(2022, time.November, 10),
+		"11":  eolDate(2023, time.November, 9),
+		"12":  eolDate(2024, time.November, 21),
+		"13":  eolDate(2025, time.November, 13),
+		"14":  eolDate(2026, time.November, 12),
+		"15":  eolDate(2027, time.November, 11),
+		"16":  eolDate(2028, time.November, 9),
+		"17":  eolDate(2029, time.November, 8),
+	},
+}
+
+func eolDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// releaseLineRegexp matches the major and the minor version at the beginning of a version string, e.g. "8" and "0" in "8.0.28-log".
+var releaseLineRegexp = regexp.MustCompile(`^(\d+)(?:\.(\d+))?`)
+
+// EngineVersionEOL is the end of life of the release line of an engine version.
+type EngineVersionEOL struct {
+	// ReleaseLine is the release line of the version, e.g. "5.7" of MySQL 5.7.38 and "14" of Postgres 14.2.
+	ReleaseLine string
+	// Date is the end-of-life date of the release line.
+	Date time.Time
+}
+
+// GetEngineVersionEOL returns the end of life of the release line of the version reported by the engine.
+// It returns nil if the engine doesn't have a known end of life, the release line isn't listed, or the version can't be parsed.
+func GetEngineVersionEOL(engine Type, version string) *EngineVersionEOL {
+	eolMap, ok := engineVersionEOLMap[engine]
+	if !ok {
+		return nil
+	}
+	match := releaseLineRegexp.FindStringSubmatch(version)
+	if match == nil {
+		return nil
+	}
+	releaseLine := match[1]
+	// Postgres 10 and later number the release lines by the major version only.
+	major, err := strconv.Atoi(match[1])
+	if err != nil {
+		return nil
+	}
+	if !(engine == Postgres && major >= 10) {
+		if match[2] == "" {
+			return nil
+		}
+		releaseLine += "." + match[2]
+	}
+	date, ok := eolMap[releaseLine]
+	if !ok {
+		return nil
+	}
+	return &EngineVersionEOL{
+		ReleaseLine: releaseLine,
+		Date:        date,
+	}
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetEngineVersionEOL(t *testing.T) {
+	tests := []struct {
+		engine          Type
+		version         string
+		wantReleaseLine string
+		wantDate        time.Time
+	}{
+		{MySQL, "5.7.38-log", "5.7", time.Date(2023, time.October, 31, 0, 0, 0, 0, time.UTC)},
+		{MySQL, "8.0.28-0ubuntu0.20.04.3", "8.0", time.Date(2026, time.April, 30, 0, 0, 0, 0, time.UTC)},
+		{Postgres, "9.6.24", "9.6", time.Date(2021, time.November, 11, 0, 0, 0, 0, time.UTC)},
+		{Postgres, "14.2 (Debian 14.2-1.pgdg110+1)", "14", time.Date(2026, time.November, 12, 0, 0, 0, 0, time.UTC)},
+		{Postgres, "16", "16", time.Date(2028, time.November, 9, 0, 0, 0, 0, time.UTC)},
+		// The engines or release lines without known end-of-life dates.
+		{MySQL, "9.0.1", "", time.Time{}},
+		{MySQL, "8", "", time.Time{}},
+		{Postgres, "99.0", "", time.Time{}},
+		{TiDB, "5.7.25-TiDB-v5.4.0", "", time.Time{}},
+		{MySQL, "unknown", "", time.Time{}},
+	}
+	for _, tt := range tests {
+		eol := GetEngineVersionEOL(tt.engine, tt.version)
+		if tt.wantReleaseLine == "" {
+			if eol != nil {
+				t.Errorf("GetEngineVersionEOL(%s, %q) = %+v, want nil", tt.engine, tt.version, eol)
+			}
+			continue
+		}
+		if eol == nil {
+			t.Errorf("GetEngineVersionEOL(%s, %q) = nil, want release line %s", tt.engine, tt.version, tt.wantReleaseLine)
+			continue
+		}
+		if eol.ReleaseLine != tt.wantReleaseLine || !eol.Date.Equal(tt.wantDate) {
+			t.Errorf("GetEngineVersionEOL(%s, %q) = %s %v, want %s %v", tt.engine, tt.version, eol.ReleaseLine, eol.Date, tt.wantReleaseLine, tt.wantDate)
+		}
+	}
+}
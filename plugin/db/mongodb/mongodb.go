@@ -0,0 +1,573 @@
+package mongodb
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bytebase/bytebase/common"
+	"github.com/bytebase/bytebase/plugin/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+var (
+	systemDatabases = map[string]bool{
+		"admin":  true,
+		"config": true,
+		"local":  true,
+	}
+	bytebaseDatabase = "bytebase"
+	// migrationHistoryCollection is the collection in the bytebase database keeping the migration history.
+	// The documents use the same field names as the migration_history table of the SQL drivers, with the id as _id.
+	migrationHistoryCollection = "migration_history"
+
+	_ db.Driver = (*Driver)(nil)
+)
+
+func init() {
+	db.Register(db.MongoDB, newDriver)
+}
+
+// Driver is the MongoDB driver.
+type Driver struct {
+	l             *zap.Logger
+	connectionCtx db.ConnectionContext
+	dbType        db.Type
+
+	client *mongo.Client
+}
+
+func newDriver(config db.DriverConfig) db.Driver {
+	return &Driver{
+		l: config.Logger,
+	}
+}
+
+// Open opens a MongoDB driver.
+func (driver *Driver) Open(ctx context.Context, dbType db.Type, config db.ConnectionConfig, connCtx db.ConnectionContext) (db.Driver, error) {
+	port := config.Port
+	if port == "" {
+		port = "27017"
+	}
+	uri := fmt.Sprintf("mongodb://%s:%s", config.Host, port)
+	opts := options.Client().ApplyURI(uri).SetAppName("bytebase")
+	if config.Username != "" {
+		opts.SetAuth(options.Credential{
+			Username: config.Username,
+			Password: config.Password,
+		})
+	}
+	// Set SSL configuration.
+	tlsConfig, err := config.TLSConfig.GetSslConfig()
+	if err != nil {
+		return nil, fmt.Errorf("mongodb: tls config error: %v", err)
+	}
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	driver.l.Debug("Opening MongoDB driver",
+		zap.String("uri", uri),
+		zap.String("username", config.Username),
+		zap.String("environment", connCtx.EnvironmentName),
+		zap.String("database", connCtx.InstanceName),
+	)
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	driver.dbType = dbType
+	driver.client = client
+	driver.connectionCtx = connCtx
+
+	return driver, nil
+}
+
+// Close closes the driver.
+func (driver *Driver) Close(ctx context.Context) error {
+	return driver.client.Disconnect(ctx)
+}
+
+// Ping pings the database.
+func (driver *Driver) Ping(ctx context.Context) error {
+	return driver.client.Ping(ctx, nil)
+}
+
+// GetDbConnection is not supported for MongoDB.
+func (driver *Driver) GetDbConnection(ctx context.Context, database string) (*sql.DB, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("SQL connection is not supported for MongoDB"))
+}
+
+// GetVersion gets the version.
+func (driver *Driver) GetVersion(ctx context.Context) (string, error) {
+	var buildInfo struct {
+		Version string `bson:"version"`
+	}
+	if err := driver.client.Database("admin").RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&buildInfo); err != nil {
+		return "", err
+	}
+	return buildInfo.Version, nil
+}
+
+// SyncSchema synces the schema.
+// The collections are synced as tables with their indexes and stats, and the views are synced with their pipelines.
+func (driver *Driver) SyncSchema(ctx context.Context) ([]*db.User, []*db.Schema, error) {
+	userList, err := driver.getUserList(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	databaseList, err := driver.getDatabaseList(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var schemaList []*db.Schema
+	for _, database := range databaseList {
+		collectionList, err := getCollectionList(ctx, driver.client.Database(database))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		schema := db.Schema{
+			Name: database,
+		}
+		for _, collection := range collectionList {
+			if strings.HasPrefix(collection.Name, "system.") {
+				continue
+			}
+			if collection.Type == "view" {
+				var viewOptions struct {
+					ViewOn   string   `bson:"viewOn"`
+					Pipeline bson.Raw `bson:"pipeline"`
+				}
+				if err := bson.Unmarshal(collection.Options, &viewOptions); err != nil {
+					return nil, nil, fmt.Errorf("failed to unmarshal options of view %q: %w", collection.Name, err)
+				}
+				pipeline, err := bson.MarshalExtJSON(viewOptions.Pipeline, false /* canonical */, false /* escapeHTML */)
+				if err != nil {
+					return nil, nil, err
+				}
+				schema.ViewList = append(schema.ViewList, db.View{
+					Name:       collection.Name,
+					Definition: fmt.Sprintf("%s: %s", viewOptions.ViewOn, pipeline),
+				})
+				continue
+			}
+
+			table, err := driver.getTable(ctx, database, collection)
+			if err != nil {
+				return nil, nil, err
+			}
+			schema.TableList = append(schema.TableList, *table)
+		}
+		schemaList = append(schemaList, &schema)
+	}
+
+	return userList, schemaList, nil
+}
+
+func (driver *Driver) getUserList(ctx context.Context) ([]*db.User, error) {
+	var usersInfo struct {
+		UserList []struct {
+			User     string   `bson:"user"`
+			Database string   `bson:"db"`
+			RoleList bson.Raw `bson:"roles"`
+		} `bson:"users"`
+	}
+	command := bson.D{{Key: "usersInfo", Value: bson.D{{Key: "forAllDBs", Value: true}}}}
+	if err := driver.client.Database("admin").RunCommand(ctx, command).Decode(&usersInfo); err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	var userList []*db.User
+	for _, user := range usersInfo.UserList {
+		grant, err := bson.MarshalExtJSON(user.RoleList, false /* canonical */, false /* escapeHTML */)
+		if err != nil {
+			return nil, err
+		}
+		userList = append(userList, &db.User{
+			Name:  fmt.Sprintf("%s@%s", user.User, user.Database),
+			Grant: string(grant),
+		})
+	}
+	return userList, nil
+}
+
+// getDatabaseList gets the user databases excluding the system databases and the bytebase database.
+func (driver *Driver) getDatabaseList(ctx context.Context) ([]string, error) {
+	nameList, err := driver.client.ListDatabaseNames(ctx, bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get databases: %w", err)
+	}
+	var databaseList []string
+	for _, name := range nameList {
+		if systemDatabases[name] || name == bytebaseDatabase {
+			continue
+		}
+		databaseList = append(databaseList, name)
+	}
+	sort.Strings(databaseList)
+	return databaseList, nil
+}
+
+func (driver *Driver) getTable(ctx context.Context, database string, collection *collectionSchema) (*db.Table, error) {
+	var collStats struct {
+		Count          int64 `bson:"count"`
+		Size           int64 `bson:"size"`
+		TotalIndexSize int64 `bson:"totalIndexSize"`
+	}
+	command := bson.D{{Key: "collStats", Value: collection.Name}}
+	if err := driver.client.Database(database).RunCommand(ctx, command).Decode(&collStats); err != nil {
+		return nil, fmt.Errorf("failed to get stats of collection %q: %w", collection.Name, err)
+	}
+
+	table := &db.Table{
+		Name:      collection.Name,
+		Type:      collection.Type,
+		RowCount:  collStats.Count,
+		DataSize:  collStats.Size,
+		IndexSize: collStats.TotalIndexSize,
+	}
+	for _, index := range collection.IndexList {
+		var spec struct {
+			Name   string `bson:"name"`
+			Key    bson.D `bson:"key"`
+			Unique bool   `bson:"unique"`
+		}
+		if err := bson.Unmarshal(index, &spec); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal index of collection %q: %w", collection.Name, err)
+		}
+		for i, key := range spec.Key {
+			table.IndexList = append(table.IndexList, db.Index{
+				Name:       spec.Name,
+				Expression: key.Key,
+				Position:   i + 1,
+				Type:       fmt.Sprintf("%v", key.Value),
+				Unique:     spec.Unique || spec.Name == "_id_",
+				Visible:    true,
+			})
+		}
+	}
+	return table, nil
+}
+
+// Execute is not supported for MongoDB.
+func (driver *Driver) Execute(ctx context.Context, statement string) error {
+	return common.Errorf(common.NotImplemented, fmt.Errorf("executing statement is not supported for MongoDB"))
+}
+
+// Capabilities returns the optional features supported for MongoDB.
+func (driver *Driver) Capabilities() db.Capabilities {
+	return db.Capabilities{
+		SupportsMigrationHistory: true,
+	}
+}
+
+// FindLongRunningTransactionList is not supported for MongoDB.
+func (driver *Driver) FindLongRunningTransactionList(ctx context.Context, database string, threshold time.Duration) ([]*db.Transaction, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("listing transactions is not supported for MongoDB"))
+}
+
+// GetDiskUsage is not supported for MongoDB.
+func (driver *Driver) GetDiskUsage(ctx context.Context) (*db.DiskUsage, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("getting disk usage is not supported for MongoDB"))
+}
+
+// ReplicationStatus is not supported for MongoDB.
+func (driver *Driver) ReplicationStatus(ctx context.Context) (*db.ReplicationStatus, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("getting replication status is not supported for MongoDB"))
+}
+
+// GetConnectionTLSState is not supported for MongoDB.
+func (driver *Driver) GetConnectionTLSState(ctx context.Context) (*db.ConnectionTLSState, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("getting connection TLS state is not supported for MongoDB"))
+}
+
+// NeedsSetupMigration returns false since the migration history collection is created on the first insert.
+func (driver *Driver) NeedsSetupMigration(ctx context.Context) (bool, error) {
+	return false, nil
+}
+
+// SetupMigrationIfNeeded is a no-op since the migration history collection is created on the first insert.
+func (driver *Driver) SetupMigrationIfNeeded(ctx context.Context) error {
+	return nil
+}
+
+// ExecuteMigration is not supported for MongoDB.
+func (driver *Driver) ExecuteMigration(ctx context.Context, m *db.MigrationInfo, statement string) (int64, string, error) {
+	return -1, "", common.Errorf(common.NotImplemented, fmt.Errorf("executing migration is not supported for MongoDB"))
+}
+
+// migrationHistory is the document of the migration history collection.
+type migrationHistory struct {
+	ID                int    `bson:"_id"`
+	Creator           string `bson:"created_by"`
+	CreatedTs         int64  `bson:"created_ts"`
+	Updater           string `bson:"updated_by"`
+	UpdatedTs         int64  `bson:"updated_ts"`
+	ReleaseVersion    string `bson:"release_version"`
+	Namespace         string `bson:"namespace"`
+	Sequence          int    `bson:"sequence"`
+	Engine            string `bson:"engine"`
+	Type              string `bson:"type"`
+	Status            string `bson:"status"`
+	Version           string `bson:"version"`
+	Description       string `bson:"description"`
+	Statement         string `bson:"statement"`
+	Schema            string `bson:"schema"`
+	SchemaPrev        string `bson:"schema_prev"`
+	ExecutionDuration int    `bson:"execution_duration"`
+	IssueID           string `bson:"issue_id"`
+	Payload           string `bson:"payload"`
+}
+
+// FindMigrationHistoryList finds the migration history list and returns most recent item first.
+func (driver *Driver) FindMigrationHistoryList(ctx context.Context, find *db.MigrationHistoryFind) ([]*db.MigrationHistory, error) {
+	filter, opts := findMigrationHistoryFilter(find)
+	cursor, err := driver.client.Database(bytebaseDatabase).Collection(migrationHistoryCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	list := make([]*db.MigrationHistory, 0)
+	for cursor.Next(ctx) {
+		var history migrationHistory
+		if err := cursor.Decode(&history); err != nil {
+			return nil, err
+		}
+		list = append(list, &db.MigrationHistory{
+			ID:                history.ID,
+			Creator:           history.Creator,
+			CreatedTs:         history.CreatedTs,
+			Updater:           history.Updater,
+			UpdatedTs:         history.UpdatedTs,
+			ReleaseVersion:    history.ReleaseVersion,
+			Namespace:         history.Namespace,
+			Sequence:          history.Sequence,
+			Engine:            db.MigrationEngine(history.Engine),
+			Type:              db.MigrationType(history.Type),
+			Status:            db.MigrationStatus(history.Status),
+			Version:           history.Version,
+			Description:       history.Description,
+			Statement:         history.Statement,
+			Schema:            history.Schema,
+			SchemaPrev:        history.SchemaPrev,
+			ExecutionDuration: history.ExecutionDuration,
+			IssueID:           history.IssueID,
+			Payload:           history.Payload,
+		})
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// findMigrationHistoryFilter returns the filter and the find options for the migration history list matching find, most recent first.
+func findMigrationHistoryFilter(find *db.MigrationHistoryFind) (bson.D, *options.FindOptions) {
+	filter := bson.D{}
+	if v := find.ID; v != nil {
+		filter = append(filter, bson.E{Key: "_id", Value: *v})
+	}
+	if v := find.Database; v != nil {
+		filter = append(filter, bson.E{Key: "namespace", Value: *v})
+	}
+	if v := find.Version; v != nil {
+		filter = append(filter, bson.E{Key: "version", Value: *v})
+	}
+	if v := find.VersionPrefix; v != nil {
+		// Both version and the prefix filter on the same field, so the conditions are combined with $and.
+		filter = append(filter, bson.E{Key: "$and", Value: bson.A{
+			bson.D{{Key: "version", Value: primitive.Regex{Pattern: "^" + regexp.QuoteMeta(*v)}}},
+		}})
+	}
+	if v := find.Status; v != nil {
+		filter = append(filter, bson.E{Key: "status", Value: string(*v)})
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_ts", Value: -1}})
+	if v := find.Limit; v != nil {
+		opts.SetLimit(int64(*v))
+	}
+	if v := find.Offset; v != nil {
+		opts.SetSkip(int64(*v))
+	}
+	return filter, opts
+}
+
+// Dump and restore
+
+// collectionSchema is the schema of a collection or view.
+type collectionSchema struct {
+	Name string `bson:"name"`
+	// Type is "collection" or "view".
+	Type string `bson:"type"`
+	// Options is the options of the collection including the validator, or the pipeline of the view.
+	Options bson.Raw `bson:"options"`
+	// IndexList is the index specifications of the collection.
+	IndexList []bson.Raw `bson:"-"`
+}
+
+// Dump dumps the schema of the database as JSON, if database is empty, then dump all databases.
+// Data dump isn't supported.
+func (driver *Driver) Dump(ctx context.Context, database string, out io.Writer, schemaOnly bool) error {
+	if !schemaOnly {
+		return common.Errorf(common.NotImplemented, fmt.Errorf("dumping data is not supported for MongoDB"))
+	}
+
+	databaseList, err := driver.getDatabaseList(ctx)
+	if err != nil {
+		return err
+	}
+	if database != "" {
+		exist := false
+		for _, name := range databaseList {
+			if name == database {
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			return common.Errorf(common.NotFound, fmt.Errorf("database %s not found", database))
+		}
+		databaseList = []string{database}
+	}
+
+	for _, name := range databaseList {
+		collectionList, err := getCollectionList(ctx, driver.client.Database(name))
+		if err != nil {
+			return err
+		}
+		if err := dumpDatabase(out, name, collectionList); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getCollectionList gets the collections and views of the database with the indexes of the collections.
+func getCollectionList(ctx context.Context, database *mongo.Database) ([]*collectionSchema, error) {
+	cursor, err := database.ListCollections(ctx, bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collections of database %q: %w", database.Name(), err)
+	}
+	var collectionList []*collectionSchema
+	if err := cursor.All(ctx, &collectionList); err != nil {
+		return nil, fmt.Errorf("failed to get collections of database %q: %w", database.Name(), err)
+	}
+
+	for _, collection := range collectionList {
+		if collection.Type == "view" || strings.HasPrefix(collection.Name, "system.") {
+			continue
+		}
+		cursor, err := database.Collection(collection.Name).Indexes().List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get indexes of collection %q: %w", collection.Name, err)
+		}
+		if err := cursor.All(ctx, &collection.IndexList); err != nil {
+			return nil, fmt.Errorf("failed to get indexes of collection %q: %w", collection.Name, err)
+		}
+	}
+	return collectionList, nil
+}
+
+// dumpCollection is the dumped collection.
+type dumpCollection struct {
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	Options   json.RawMessage   `json:"options,omitempty"`
+	IndexList []json.RawMessage `json:"indexList,omitempty"`
+}
+
+// dumpDatabase writes the schema of the database as an indented JSON document.
+// The collections and indexes are sorted by name, and the fields varying across instances are left out,
+// so that the same schema always dumps to the same output.
+func dumpDatabase(out io.Writer, database string, collectionList []*collectionSchema) error {
+	sort.Slice(collectionList, func(i, j int) bool {
+		return collectionList[i].Name < collectionList[j].Name
+	})
+
+	dumpList := []*dumpCollection{}
+	for _, collection := range collectionList {
+		if strings.HasPrefix(collection.Name, "system.") {
+			continue
+		}
+		dump := &dumpCollection{
+			Name: collection.Name,
+			Type: collection.Type,
+		}
+		if len(collection.Options) > 0 {
+			collectionOptions, err := marshalDocument(collection.Options, nil)
+			if err != nil {
+				return fmt.Errorf("failed to marshal options of collection %q: %w", collection.Name, err)
+			}
+			if string(collectionOptions) != "{}" {
+				dump.Options = collectionOptions
+			}
+		}
+
+		indexList := append([]bson.Raw(nil), collection.IndexList...)
+		sort.Slice(indexList, func(i, j int) bool {
+			return indexList[i].Lookup("name").StringValue() < indexList[j].Lookup("name").StringValue()
+		})
+		for _, index := range indexList {
+			// The namespace is only reported by the servers before 4.4, and the index version depends on the server version.
+			spec, err := marshalDocument(index, map[string]bool{"ns": true, "v": true})
+			if err != nil {
+				return fmt.Errorf("failed to marshal index of collection %q: %w", collection.Name, err)
+			}
+			dump.IndexList = append(dump.IndexList, spec)
+		}
+		dumpList = append(dumpList, dump)
+	}
+
+	content, err := json.MarshalIndent(struct {
+		Database       string            `json:"database"`
+		CollectionList []*dumpCollection `json:"collectionList"`
+	}{
+		Database:       database,
+		CollectionList: dumpList,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(content); err != nil {
+		return err
+	}
+	_, err = io.WriteString(out, "\n")
+	return err
+}
+
+// marshalDocument marshals the BSON document to the relaxed extended JSON without the excluded fields.
+func marshalDocument(doc bson.Raw, excludedFieldMap map[string]bool) (json.RawMessage, error) {
+	elementList, err := doc.Elements()
+	if err != nil {
+		return nil, err
+	}
+	d := bson.D{}
+	for _, element := range elementList {
+		if excludedFieldMap[element.Key()] {
+			continue
+		}
+		d = append(d, bson.E{Key: element.Key(), Value: element.Value()})
+	}
+	return bson.MarshalExtJSON(d, false /* canonical */, false /* escapeHTML */)
+}
+
+// Restore is not supported for MongoDB.
+func (driver *Driver) Restore(ctx context.Context, sc *bufio.Scanner) error {
+	return common.Errorf(common.NotImplemented, fmt.Errorf("restoring is not supported for MongoDB"))
+}
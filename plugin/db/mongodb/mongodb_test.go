@@ -0,0 +1,155 @@
+package mongodb
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bytebase/bytebase/plugin/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func mustMarshal(t *testing.T, doc bson.D) bson.Raw {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestDumpDatabase(t *testing.T) {
+	newCollectionList := func(reverse bool) []*collectionSchema {
+		idIndex := mustMarshal(t, bson.D{{Key: "v", Value: 2}, {Key: "key", Value: bson.D{{Key: "_id", Value: 1}}}, {Key: "name", Value: "_id_"}, {Key: "ns", Value: "db.user"}})
+		emailIndex := mustMarshal(t, bson.D{{Key: "v", Value: 2}, {Key: "key", Value: bson.D{{Key: "email", Value: 1}}}, {Key: "name", Value: "email_1"}, {Key: "unique", Value: true}})
+		user := &collectionSchema{
+			Name: "user",
+			Type: "collection",
+			Options: mustMarshal(t, bson.D{{Key: "validator", Value: bson.D{
+				{Key: "$jsonSchema", Value: bson.D{{Key: "required", Value: bson.A{"email"}}}},
+			}}}),
+			IndexList: []bson.Raw{idIndex, emailIndex},
+		}
+		activeUser := &collectionSchema{
+			Name: "active_user",
+			Type: "view",
+			Options: mustMarshal(t, bson.D{
+				{Key: "viewOn", Value: "user"},
+				{Key: "pipeline", Value: bson.A{bson.D{{Key: "$match", Value: bson.D{{Key: "active", Value: true}}}}}},
+			}),
+		}
+		profile := &collectionSchema{
+			Name:    "system.profile",
+			Type:    "collection",
+			Options: mustMarshal(t, bson.D{}),
+		}
+		if reverse {
+			user.IndexList = []bson.Raw{emailIndex, idIndex}
+			return []*collectionSchema{user, profile, activeUser}
+		}
+		return []*collectionSchema{activeUser, profile, user}
+	}
+
+	want := `{
+  "database": "db",
+  "collectionList": [
+    {
+      "name": "active_user",
+      "type": "view",
+      "options": {
+        "viewOn": "user",
+        "pipeline": [
+          {
+            "$match": {
+              "active": true
+            }
+          }
+        ]
+      }
+    },
+    {
+      "name": "user",
+      "type": "collection",
+      "options": {
+        "validator": {
+          "$jsonSchema": {
+            "required": [
+              "email"
+            ]
+          }
+        }
+      },
+      "indexList": [
+        {
+          "key": {
+            "_id": 1
+          },
+          "name": "_id_"
+        },
+        {
+          "key": {
+            "email": 1
+          },
+          "name": "email_1",
+          "unique": true
+        }
+      ]
+    }
+  ]
+}
+`
+	// The dump is the same regardless of the order the server lists the collections and indexes.
+	for _, reverse := range []bool{false, true} {
+		var sb strings.Builder
+		if err := dumpDatabase(&sb, "db", newCollectionList(reverse)); err != nil {
+			t.Fatal(err)
+		}
+		if got := sb.String(); got != want {
+			t.Errorf("dumpDatabase(reverse=%v) = %s, want %s", reverse, got, want)
+		}
+	}
+}
+
+func TestFindMigrationHistoryFilter(t *testing.T) {
+	database, version, prefix, status := "db", "1.2.3", "1.2.", db.Done
+	limit, offset := 10, 20
+	filter, opts := findMigrationHistoryFilter(&db.MigrationHistoryFind{
+		Database:      &database,
+		Version:       &version,
+		VersionPrefix: &prefix,
+		Status:        &status,
+		Limit:         &limit,
+		Offset:        &offset,
+	})
+
+	wantFilter := bson.D{
+		{Key: "namespace", Value: database},
+		{Key: "version", Value: version},
+		{Key: "$and", Value: bson.A{bson.D{{Key: "version", Value: primitive.Regex{Pattern: `^1\.2\.`}}}}},
+		{Key: "status", Value: "DONE"},
+	}
+	if !reflect.DeepEqual(filter, wantFilter) {
+		t.Errorf("filter = %v, want %v", filter, wantFilter)
+	}
+	if opts.Limit == nil || *opts.Limit != 10 {
+		t.Errorf("Limit = %v, want 10", opts.Limit)
+	}
+	if opts.Skip == nil || *opts.Skip != 20 {
+		t.Errorf("Skip = %v, want 20", opts.Skip)
+	}
+
+	// Without limit and offset, all matching histories are returned.
+	_, opts = findMigrationHistoryFilter(&db.MigrationHistoryFind{})
+	if opts.Limit != nil || opts.Skip != nil {
+		t.Errorf("Limit = %v, Skip = %v, want both unset", opts.Limit, opts.Skip)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	want := db.Capabilities{
+		SupportsMigrationHistory: true,
+	}
+	if got := (&Driver{}).Capabilities(); got != want {
+		t.Errorf("Capabilities() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,1003 @@
+package mssql
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bytebase/bytebase/common"
+	"github.com/bytebase/bytebase/plugin/db"
+	"github.com/bytebase/bytebase/plugin/db/util"
+
+	// Register the sqlserver database/sql driver.
+	_ "github.com/denisenkom/go-mssqldb"
+	"go.uber.org/zap"
+)
+
+var (
+	// systemDatabaseMaxID is the largest database_id of the system databases, i.e. master, tempdb, model and msdb.
+	systemDatabaseMaxID = 4
+
+	_ db.Driver = (*Driver)(nil)
+)
+
+func init() {
+	db.Register(db.MSSQL, newDriver)
+}
+
+// Driver is the SQL Server driver.
+type Driver struct {
+	l             *zap.Logger
+	connectionCtx db.ConnectionContext
+	dbType        db.Type
+	// tlsRequired is whether the connection is configured to use TLS.
+	tlsRequired bool
+
+	db *sql.DB
+}
+
+func newDriver(config db.DriverConfig) db.Driver {
+	return &Driver{
+		l: config.Logger,
+	}
+}
+
+// Open opens a SQL Server driver.
+func (driver *Driver) Open(ctx context.Context, dbType db.Type, config db.ConnectionConfig, connCtx db.ConnectionContext) (db.Driver, error) {
+	port := config.Port
+	if port == "" {
+		port = "1433"
+	}
+	query := url.Values{}
+	query.Set("app name", "bytebase")
+	if config.Database != "" {
+		query.Set("database", config.Database)
+	}
+	// Set SSL configuration.
+	if config.TLSConfig.SslCert != "" || config.TLSConfig.SslKey != "" {
+		return nil, fmt.Errorf("mssql: tls config error: client certificate is not supported for SQL Server")
+	}
+	if config.TLSConfig.SslCA != "" {
+		query.Set("encrypt", "true")
+		query.Set("certificate", config.TLSConfig.SslCA)
+	}
+	dsn := &url.URL{
+		Scheme:   "sqlserver",
+		User:     url.UserPassword(config.Username, config.Password),
+		Host:     net.JoinHostPort(config.Host, port),
+		RawQuery: query.Encode(),
+	}
+
+	driver.l.Debug("Opening SQL Server driver",
+		zap.String("dsn", dsn.Redacted()),
+		zap.String("environment", connCtx.EnvironmentName),
+		zap.String("database", connCtx.InstanceName),
+	)
+	db, err := sql.Open("sqlserver", dsn.String())
+	if err != nil {
+		return nil, err
+	}
+	driver.dbType = dbType
+	driver.db = db
+	driver.connectionCtx = connCtx
+	driver.tlsRequired = config.TLSConfig.SslCA != ""
+
+	return driver, nil
+}
+
+// Close closes the driver.
+func (driver *Driver) Close(ctx context.Context) error {
+	return driver.db.Close()
+}
+
+// Ping pings the database.
+func (driver *Driver) Ping(ctx context.Context) error {
+	return driver.db.PingContext(ctx)
+}
+
+// GetDbConnection gets a database connection.
+// The connection isn't bound to the database, the catalog queries of the driver name the database explicitly.
+func (driver *Driver) GetDbConnection(ctx context.Context, database string) (*sql.DB, error) {
+	return driver.db, nil
+}
+
+// GetVersion gets the version.
+func (driver *Driver) GetVersion(ctx context.Context) (string, error) {
+	query := "SELECT @@VERSION"
+	versionRow, err := driver.db.QueryContext(ctx, query)
+	if err != nil {
+		return "", util.FormatErrorWithQuery(err, query)
+	}
+	defer versionRow.Close()
+
+	var version string
+	versionRow.Next()
+	if err := versionRow.Scan(&version); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// SyncSchema synces the schema.
+func (driver *Driver) SyncSchema(ctx context.Context) ([]*db.User, []*db.Schema, error) {
+	userList, err := driver.getUserList(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	databaseList, err := driver.getDatabaseList(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var schemaList []*db.Schema
+	for _, database := range databaseList {
+		tableList, err := driver.getTableSchemaList(ctx, database)
+		if err != nil {
+			return nil, nil, err
+		}
+		viewList, err := driver.getViewList(ctx, database)
+		if err != nil {
+			return nil, nil, err
+		}
+		schema := &db.Schema{
+			Name:     database,
+			ViewList: viewList,
+		}
+		for _, table := range tableList {
+			schema.TableList = append(schema.TableList, table.toTable())
+		}
+		schemaList = append(schemaList, schema)
+	}
+
+	return userList, schemaList, nil
+}
+
+func (driver *Driver) getUserList(ctx context.Context) ([]*db.User, error) {
+	// Skip the certificate-mapped logins of the system, e.g. ##MS_PolicyEventProcessingLogin##.
+	query := `
+		SELECT name, type_desc
+		FROM sys.server_principals
+		WHERE type IN ('S', 'U', 'G') AND name NOT LIKE '##%'
+		ORDER BY name`
+	rows, err := driver.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, util.FormatErrorWithQuery(err, query)
+	}
+	defer rows.Close()
+
+	var userList []*db.User
+	for rows.Next() {
+		var name, typeDesc string
+		if err := rows.Scan(&name, &typeDesc); err != nil {
+			return nil, err
+		}
+		userList = append(userList, &db.User{
+			Name:  name,
+			Grant: typeDesc,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return userList, nil
+}
+
+// getDatabaseList returns the user databases sorted by name.
+func (driver *Driver) getDatabaseList(ctx context.Context) ([]string, error) {
+	query := `
+		SELECT name
+		FROM sys.databases
+		WHERE database_id > @p1
+		ORDER BY name`
+	rows, err := driver.db.QueryContext(ctx, query, systemDatabaseMaxID)
+	if err != nil {
+		return nil, util.FormatErrorWithQuery(err, query)
+	}
+	defer rows.Close()
+
+	var databaseList []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		databaseList = append(databaseList, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return databaseList, nil
+}
+
+func (driver *Driver) getViewList(ctx context.Context, database string) ([]db.View, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			s.name,
+			v.name,
+			DATEDIFF(SECOND, '1970-01-01', v.create_date),
+			DATEDIFF(SECOND, '1970-01-01', v.modify_date),
+			ISNULL(m.definition, '')
+		FROM %[1]s.sys.views v
+		JOIN %[1]s.sys.schemas s ON s.schema_id = v.schema_id
+		LEFT JOIN %[1]s.sys.sql_modules m ON m.object_id = v.object_id
+		WHERE v.is_ms_shipped = 0`, quoteIdentifier(database))
+	rows, err := driver.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, util.FormatErrorWithQuery(err, query)
+	}
+	defer rows.Close()
+
+	var viewList []db.View
+	for rows.Next() {
+		var schemaName, viewName string
+		var view db.View
+		if err := rows.Scan(
+			&schemaName,
+			&viewName,
+			&view.CreatedTs,
+			&view.UpdatedTs,
+			&view.Definition,
+		); err != nil {
+			return nil, err
+		}
+		view.Name = fmt.Sprintf("%s.%s", schemaName, viewName)
+		viewList = append(viewList, view)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return viewList, nil
+}
+
+// Execute executes a SQL statement.
+func (driver *Driver) Execute(ctx context.Context, statement string) error {
+	tx, err := driver.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, statement); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Capabilities returns the optional features supported for SQL Server.
+func (driver *Driver) Capabilities() db.Capabilities {
+	return db.Capabilities{
+		SupportsTLSState:        true,
+		SupportsIndexInspection: true,
+	}
+}
+
+// FindLongRunningTransactionList is not supported for SQL Server.
+func (driver *Driver) FindLongRunningTransactionList(ctx context.Context, database string, threshold time.Duration) ([]*db.Transaction, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("listing transactions is not supported for SQL Server"))
+}
+
+// GetDiskUsage is not supported for SQL Server.
+func (driver *Driver) GetDiskUsage(ctx context.Context) (*db.DiskUsage, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("getting disk usage is not supported for SQL Server"))
+}
+
+// ReplicationStatus is not supported for SQL Server.
+func (driver *Driver) ReplicationStatus(ctx context.Context) (*db.ReplicationStatus, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("getting replication status is not supported for SQL Server"))
+}
+
+// GetConnectionTLSState returns whether the connection is encrypted from sys.dm_exec_connections, which needs the VIEW SERVER STATE permission.
+// The server certificate isn't reported.
+func (driver *Driver) GetConnectionTLSState(ctx context.Context) (*db.ConnectionTLSState, error) {
+	query := "SELECT encrypt_option FROM sys.dm_exec_connections WHERE session_id = @@SPID"
+	var encryptOption string
+	if err := driver.db.QueryRowContext(ctx, query).Scan(&encryptOption); err != nil {
+		return nil, util.FormatErrorWithQuery(err, query)
+	}
+	return &db.ConnectionTLSState{
+		Required:  driver.tlsRequired,
+		Encrypted: strings.EqualFold(encryptOption, "TRUE"),
+	}, nil
+}
+
+// NeedsSetupMigration is not supported for SQL Server.
+func (driver *Driver) NeedsSetupMigration(ctx context.Context) (bool, error) {
+	return false, common.Errorf(common.NotImplemented, fmt.Errorf("migration history is not supported for SQL Server"))
+}
+
+// SetupMigrationIfNeeded is not supported for SQL Server.
+func (driver *Driver) SetupMigrationIfNeeded(ctx context.Context) error {
+	return common.Errorf(common.NotImplemented, fmt.Errorf("migration history is not supported for SQL Server"))
+}
+
+// ExecuteMigration is not supported for SQL Server.
+func (driver *Driver) ExecuteMigration(ctx context.Context, m *db.MigrationInfo, statement string) (int64, string, error) {
+	return -1, "", common.Errorf(common.NotImplemented, fmt.Errorf("executing migration is not supported for SQL Server"))
+}
+
+// FindMigrationHistoryList is not supported for SQL Server.
+func (driver *Driver) FindMigrationHistoryList(ctx context.Context, find *db.MigrationHistoryFind) ([]*db.MigrationHistory, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("migration history is not supported for SQL Server"))
+}
+
+// Dump dumps the schema of the database, or the schemas of all user databases if database is empty.
+// Dumping data isn't supported.
+func (driver *Driver) Dump(ctx context.Context, database string, out io.Writer, schemaOnly bool) error {
+	if !schemaOnly {
+		return common.Errorf(common.NotImplemented, fmt.Errorf("dumping data is not supported for SQL Server"))
+	}
+
+	databaseList, err := driver.getDatabaseList(ctx)
+	if err != nil {
+		return err
+	}
+	if database != "" {
+		exist := false
+		for _, name := range databaseList {
+			if name == database {
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			return common.Errorf(common.NotFound, fmt.Errorf("database %s not found", database))
+		}
+		databaseList = []string{database}
+	}
+
+	for _, name := range databaseList {
+		tableList, err := driver.getTableSchemaList(ctx, name)
+		if err != nil {
+			return err
+		}
+		if database == "" {
+			if _, err := io.WriteString(out, fmt.Sprintf("--\n-- Database structure for %s\n--\nUSE %s;\n\n", quoteIdentifier(name), quoteIdentifier(name))); err != nil {
+				return err
+			}
+		}
+		if err := writeTableSchemaList(out, tableList); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore is not supported for SQL Server.
+func (driver *Driver) Restore(ctx context.Context, sc *bufio.Scanner) error {
+	return common.Errorf(common.NotImplemented, fmt.Errorf("restoring is not supported for SQL Server"))
+}
+
+// tableSchema is the user table read from the catalog views of a database.
+type tableSchema struct {
+	schema         string
+	name           string
+	columnList     []*columnSchema
+	indexList      []*indexSchema
+	checkList      []*constraintSchema
+	foreignKeyList []*foreignKeySchema
+}
+
+// columnSchema is the column of a table.
+type columnSchema struct {
+	name     string
+	position int
+	// typeName is the formatted type, e.g. "nvarchar(100)", empty for a computed column.
+	typeName string
+	nullable bool
+	// identity is the identity specification, e.g. "IDENTITY(1,1)", empty if the column isn't an identity column.
+	identity string
+	// defaultConstraint is the default constraint of the column, nil if the column has no default.
+	defaultConstraint *constraintSchema
+	// computed is the definition of a computed column, empty if the column isn't computed.
+	computed  string
+	persisted bool
+}
+
+// constraintSchema is a default or check constraint.
+type constraintSchema struct {
+	// name is empty if the constraint is named by the system, since the generated names differ between databases.
+	name       string
+	definition string
+}
+
+// indexColumn is the key or included column of an index.
+type indexColumn struct {
+	name       string
+	position   int
+	descending bool
+}
+
+// indexSchema is the index of a table, including the ones backing the primary key and unique constraints.
+type indexSchema struct {
+	name string
+	// typeDesc is the index type, e.g. "CLUSTERED", "NONCLUSTERED".
+	typeDesc         string
+	primaryKey       bool
+	uniqueConstraint bool
+	unique           bool
+	// systemNamed is whether the name of the primary key or unique constraint is generated by the system.
+	systemNamed bool
+	filter      string
+	keyList     []*indexColumn
+	includeList []*indexColumn
+}
+
+// foreignKeySchema is the foreign key of a table.
+type foreignKeySchema struct {
+	// name is empty if the foreign key is named by the system.
+	name             string
+	columnList       []*indexColumn
+	referencedSchema string
+	referencedTable  string
+	// referencedColumnList is in the order of columnList.
+	referencedColumnList []*indexColumn
+	onDelete             string
+	onUpdate             string
+}
+
+// quoteIdentifier quotes the identifier with brackets, e.g. "[order]".
+func quoteIdentifier(name string) string {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+}
+
+// formatColumnType formats the type of a column from sys.columns, where maxLength is in bytes and -1 for max.
+func formatColumnType(typeName string, maxLength, precision, scale int) string {
+	switch strings.ToLower(typeName) {
+	case "char", "varchar", "binary", "varbinary":
+		if maxLength == -1 {
+			return fmt.Sprintf("%s(max)", typeName)
+		}
+		return fmt.Sprintf("%s(%d)", typeName, maxLength)
+	case "nchar", "nvarchar":
+		if maxLength == -1 {
+			return fmt.Sprintf("%s(max)", typeName)
+		}
+		// The length of the national characters is in byte pairs.
+		return fmt.Sprintf("%s(%d)", typeName, maxLength/2)
+	case "decimal", "numeric":
+		return fmt.Sprintf("%s(%d,%d)", typeName, precision, scale)
+	case "datetime2", "datetimeoffset", "time":
+		return fmt.Sprintf("%s(%d)", typeName, scale)
+	case "float":
+		if precision != 53 {
+			return fmt.Sprintf("%s(%d)", typeName, precision)
+		}
+	}
+	return typeName
+}
+
+// getTableSchemaList reads the user tables of the database with their columns, indexes and constraints.
+func (driver *Driver) getTableSchemaList(ctx context.Context, database string) ([]*tableSchema, error) {
+	tableMap := make(map[string]*tableSchema)
+	getTable := func(schemaName, tableName string) *tableSchema {
+		key := fmt.Sprintf("%s.%s", schemaName, tableName)
+		table, ok := tableMap[key]
+		if !ok {
+			table = &tableSchema{
+				schema: schemaName,
+				name:   tableName,
+			}
+			tableMap[key] = table
+		}
+		return table
+	}
+
+	if err := driver.readColumnList(ctx, database, getTable); err != nil {
+		return nil, err
+	}
+	if err := driver.readIndexList(ctx, database, getTable); err != nil {
+		return nil, err
+	}
+	if err := driver.readCheckList(ctx, database, getTable); err != nil {
+		return nil, err
+	}
+	if err := driver.readForeignKeyList(ctx, database, getTable); err != nil {
+		return nil, err
+	}
+
+	var tableList []*tableSchema
+	for _, table := range tableMap {
+		tableList = append(tableList, table)
+	}
+	sortTableSchemaList(tableList)
+	return tableList, nil
+}
+
+func (driver *Driver) readColumnList(ctx context.Context, database string, getTable func(schemaName, tableName string) *tableSchema) error {
+	// The user-defined types are named with their schemas, while the system types are formatted with their lengths.
+	query := fmt.Sprintf(`
+		SELECT
+			s.name,
+			t.name,
+			c.name,
+			c.column_id,
+			tys.name,
+			ty.name,
+			ty.is_user_defined,
+			c.max_length,
+			c.precision,
+			c.scale,
+			c.is_nullable,
+			ISNULL(CONVERT(NVARCHAR(40), ic.seed_value), ''),
+			ISNULL(CONVERT(NVARCHAR(40), ic.increment_value), ''),
+			ISNULL(dc.name, ''),
+			ISNULL(dc.is_system_named, 0),
+			ISNULL(dc.definition, ''),
+			ISNULL(cc.definition, ''),
+			ISNULL(cc.is_persisted, 0)
+		FROM %[1]s.sys.tables t
+		JOIN %[1]s.sys.schemas s ON s.schema_id = t.schema_id
+		JOIN %[1]s.sys.columns c ON c.object_id = t.object_id
+		JOIN %[1]s.sys.types ty ON ty.user_type_id = c.user_type_id
+		JOIN %[1]s.sys.schemas tys ON tys.schema_id = ty.schema_id
+		LEFT JOIN %[1]s.sys.identity_columns ic ON ic.object_id = c.object_id AND ic.column_id = c.column_id
+		LEFT JOIN %[1]s.sys.default_constraints dc ON dc.object_id = c.default_object_id
+		LEFT JOIN %[1]s.sys.computed_columns cc ON cc.object_id = c.object_id AND cc.column_id = c.column_id
+		WHERE t.is_ms_shipped = 0`, quoteIdentifier(database))
+	rows, err := driver.db.QueryContext(ctx, query)
+	if err != nil {
+		return util.FormatErrorWithQuery(err, query)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var schemaName, tableName, typeSchema, typeName string
+		var userDefined, defaultSystemNamed bool
+		var maxLength, precision, scale int
+		var seed, increment, defaultName, defaultDefinition string
+		var column columnSchema
+		if err := rows.Scan(
+			&schemaName,
+			&tableName,
+			&column.name,
+			&column.position,
+			&typeSchema,
+			&typeName,
+			&userDefined,
+			&maxLength,
+			&precision,
+			&scale,
+			&column.nullable,
+			&seed,
+			&increment,
+			&defaultName,
+			&defaultSystemNamed,
+			&defaultDefinition,
+			&column.computed,
+			&column.persisted,
+		); err != nil {
+			return err
+		}
+		if column.computed == "" {
+			if userDefined {
+				column.typeName = fmt.Sprintf("%s.%s", quoteIdentifier(typeSchema), quoteIdentifier(typeName))
+			} else {
+				column.typeName = formatColumnType(typeName, maxLength, precision, scale)
+			}
+		}
+		if seed != "" {
+			column.identity = fmt.Sprintf("IDENTITY(%s,%s)", seed, increment)
+		}
+		if defaultDefinition != "" {
+			column.defaultConstraint = &constraintSchema{definition: defaultDefinition}
+			if !defaultSystemNamed {
+				column.defaultConstraint.name = defaultName
+			}
+		}
+		table := getTable(schemaName, tableName)
+		table.columnList = append(table.columnList, &column)
+	}
+	return rows.Err()
+}
+
+func (driver *Driver) readIndexList(ctx context.Context, database string, getTable func(schemaName, tableName string) *tableSchema) error {
+	// The heaps, whose index type is 0, have no index to dump.
+	query := fmt.Sprintf(`
+		SELECT
+			s.name,
+			t.name,
+			i.name,
+			i.type_desc,
+			i.is_primary_key,
+			i.is_unique_constraint,
+			i.is_unique,
+			ISNULL(kc.is_system_named, 0),
+			ISNULL(i.filter_definition, ''),
+			c.name,
+			ic.key_ordinal,
+			ic.index_column_id,
+			ic.is_descending_key,
+			ic.is_included_column
+		FROM %[1]s.sys.indexes i
+		JOIN %[1]s.sys.tables t ON t.object_id = i.object_id
+		JOIN %[1]s.sys.schemas s ON s.schema_id = t.schema_id
+		JOIN %[1]s.sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+		JOIN %[1]s.sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+		LEFT JOIN %[1]s.sys.key_constraints kc ON kc.parent_object_id = i.object_id AND kc.unique_index_id = i.index_id
+		WHERE t.is_ms_shipped = 0 AND i.type > 0`, quoteIdentifier(database))
+	rows, err := driver.db.QueryContext(ctx, query)
+	if err != nil {
+		return util.FormatErrorWithQuery(err, query)
+	}
+	defer rows.Close()
+
+	indexMap := make(map[string]*indexSchema)
+	for rows.Next() {
+		var schemaName, tableName string
+		var index indexSchema
+		var column indexColumn
+		var keyOrdinal, indexColumnID int
+		var included bool
+		if err := rows.Scan(
+			&schemaName,
+			&tableName,
+			&index.name,
+			&index.typeDesc,
+			&index.primaryKey,
+			&index.uniqueConstraint,
+			&index.unique,
+			&index.systemNamed,
+			&index.filter,
+			&column.name,
+			&keyOrdinal,
+			&indexColumnID,
+			&column.descending,
+			&included,
+		); err != nil {
+			return err
+		}
+		key := fmt.Sprintf("%s.%s.%s", schemaName, tableName, index.name)
+		existing, ok := indexMap[key]
+		if !ok {
+			existing = &index
+			indexMap[key] = existing
+			table := getTable(schemaName, tableName)
+			table.indexList = append(table.indexList, existing)
+		}
+		if included {
+			column.position = indexColumnID
+			existing.includeList = append(existing.includeList, &column)
+		} else {
+			column.position = keyOrdinal
+			existing.keyList = append(existing.keyList, &column)
+		}
+	}
+	return rows.Err()
+}
+
+func (driver *Driver) readCheckList(ctx context.Context, database string, getTable func(schemaName, tableName string) *tableSchema) error {
+	query := fmt.Sprintf(`
+		SELECT
+			s.name,
+			t.name,
+			cc.name,
+			cc.is_system_named,
+			cc.definition
+		FROM %[1]s.sys.check_constraints cc
+		JOIN %[1]s.sys.tables t ON t.object_id = cc.parent_object_id
+		JOIN %[1]s.sys.schemas s ON s.schema_id = t.schema_id
+		WHERE t.is_ms_shipped = 0`, quoteIdentifier(database))
+	rows, err := driver.db.QueryContext(ctx, query)
+	if err != nil {
+		return util.FormatErrorWithQuery(err, query)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var schemaName, tableName, name string
+		var systemNamed bool
+		var check constraintSchema
+		if err := rows.Scan(
+			&schemaName,
+			&tableName,
+			&name,
+			&systemNamed,
+			&check.definition,
+		); err != nil {
+			return err
+		}
+		if !systemNamed {
+			check.name = name
+		}
+		table := getTable(schemaName, tableName)
+		table.checkList = append(table.checkList, &check)
+	}
+	return rows.Err()
+}
+
+func (driver *Driver) readForeignKeyList(ctx context.Context, database string, getTable func(schemaName, tableName string) *tableSchema) error {
+	query := fmt.Sprintf(`
+		SELECT
+			s.name,
+			t.name,
+			fk.name,
+			fk.is_system_named,
+			rs.name,
+			rt.name,
+			pc.name,
+			rc.name,
+			fkc.constraint_column_id,
+			fk.delete_referential_action_desc,
+			fk.update_referential_action_desc
+		FROM %[1]s.sys.foreign_keys fk
+		JOIN %[1]s.sys.tables t ON t.object_id = fk.parent_object_id
+		JOIN %[1]s.sys.schemas s ON s.schema_id = t.schema_id
+		JOIN %[1]s.sys.tables rt ON rt.object_id = fk.referenced_object_id
+		JOIN %[1]s.sys.schemas rs ON rs.schema_id = rt.schema_id
+		JOIN %[1]s.sys.foreign_key_columns fkc ON fkc.constraint_object_id = fk.object_id
+		JOIN %[1]s.sys.columns pc ON pc.object_id = fkc.parent_object_id AND pc.column_id = fkc.parent_column_id
+		JOIN %[1]s.sys.columns rc ON rc.object_id = fkc.referenced_object_id AND rc.column_id = fkc.referenced_column_id
+		WHERE t.is_ms_shipped = 0`, quoteIdentifier(database))
+	rows, err := driver.db.QueryContext(ctx, query)
+	if err != nil {
+		return util.FormatErrorWithQuery(err, query)
+	}
+	defer rows.Close()
+
+	foreignKeyMap := make(map[string]*foreignKeySchema)
+	for rows.Next() {
+		var schemaName, tableName, name, columnName, referencedColumnName string
+		var systemNamed bool
+		var position int
+		var foreignKey foreignKeySchema
+		if err := rows.Scan(
+			&schemaName,
+			&tableName,
+			&name,
+			&systemNamed,
+			&foreignKey.referencedSchema,
+			&foreignKey.referencedTable,
+			&columnName,
+			&referencedColumnName,
+			&position,
+			&foreignKey.onDelete,
+			&foreignKey.onUpdate,
+		); err != nil {
+			return err
+		}
+		key := fmt.Sprintf("%s.%s.%s", schemaName, tableName, name)
+		existing, ok := foreignKeyMap[key]
+		if !ok {
+			if !systemNamed {
+				foreignKey.name = name
+			}
+			existing = &foreignKey
+			foreignKeyMap[key] = existing
+			table := getTable(schemaName, tableName)
+			table.foreignKeyList = append(table.foreignKeyList, existing)
+		}
+		existing.columnList = append(existing.columnList, &indexColumn{name: columnName, position: position})
+		existing.referencedColumnList = append(existing.referencedColumnList, &indexColumn{name: referencedColumnName, position: position})
+	}
+	return rows.Err()
+}
+
+// sortTableSchemaList sorts the tables and their indexes and constraints, so that the dump doesn't depend on
+// the order the catalog views return the rows.
+func sortTableSchemaList(tableList []*tableSchema) {
+	sortColumnList := func(columnList []*indexColumn) {
+		sort.Slice(columnList, func(i, j int) bool {
+			return columnList[i].position < columnList[j].position
+		})
+	}
+	sortConstraintList := func(constraintList []*constraintSchema) {
+		sort.Slice(constraintList, func(i, j int) bool {
+			if constraintList[i].name != constraintList[j].name {
+				return constraintList[i].name < constraintList[j].name
+			}
+			return constraintList[i].definition < constraintList[j].definition
+		})
+	}
+
+	sort.Slice(tableList, func(i, j int) bool {
+		if tableList[i].schema != tableList[j].schema {
+			return tableList[i].schema < tableList[j].schema
+		}
+		return tableList[i].name < tableList[j].name
+	})
+	for _, table := range tableList {
+		sort.Slice(table.columnList, func(i, j int) bool {
+			return table.columnList[i].position < table.columnList[j].position
+		})
+		sort.Slice(table.indexList, func(i, j int) bool {
+			return table.indexList[i].name < table.indexList[j].name
+		})
+		for _, index := range table.indexList {
+			sortColumnList(index.keyList)
+			sortColumnList(index.includeList)
+		}
+		sortConstraintList(table.checkList)
+		for _, foreignKey := range table.foreignKeyList {
+			sortColumnList(foreignKey.columnList)
+			sortColumnList(foreignKey.referencedColumnList)
+		}
+		sort.Slice(table.foreignKeyList, func(i, j int) bool {
+			return table.foreignKeyList[i].key() < table.foreignKeyList[j].key()
+		})
+	}
+}
+
+// key is the sort key of the foreign key, the system-named foreign keys are sorted by their columns.
+func (foreignKey *foreignKeySchema) key() string {
+	return fmt.Sprintf("%s %s %s.%s", foreignKey.name, formatColumnList(foreignKey.columnList), foreignKey.referencedSchema, foreignKey.referencedTable)
+}
+
+// formatColumnList formats the index columns, e.g. "[id], [created_ts] DESC".
+func formatColumnList(columnList []*indexColumn) string {
+	var list []string
+	for _, column := range columnList {
+		if column.descending {
+			list = append(list, fmt.Sprintf("%s DESC", quoteIdentifier(column.name)))
+		} else {
+			list = append(list, quoteIdentifier(column.name))
+		}
+	}
+	return strings.Join(list, ", ")
+}
+
+// formatConstraintName formats the CONSTRAINT clause naming the constraint, empty for the system-named constraint.
+func formatConstraintName(name string) string {
+	if name == "" {
+		return ""
+	}
+	return fmt.Sprintf("CONSTRAINT %s ", quoteIdentifier(name))
+}
+
+// writeTableSchemaList writes the CREATE TABLE statements with their constraints and indexes,
+// followed by the foreign keys, in the order sorted by sortTableSchemaList.
+func writeTableSchemaList(out io.Writer, tableList []*tableSchema) error {
+	for _, table := range tableList {
+		if _, err := io.WriteString(out, formatTableSchema(table)); err != nil {
+			return err
+		}
+	}
+	// The foreign keys are added after all tables are created, so that the referenced tables exist.
+	for _, table := range tableList {
+		tableName := fmt.Sprintf("%s.%s", quoteIdentifier(table.schema), quoteIdentifier(table.name))
+		for _, foreignKey := range table.foreignKeyList {
+			var buf strings.Builder
+			fmt.Fprintf(&buf, "ALTER TABLE %s ADD %sFOREIGN KEY (%s) REFERENCES %s (%s)",
+				tableName,
+				formatConstraintName(foreignKey.name),
+				formatColumnList(foreignKey.columnList),
+				fmt.Sprintf("%s.%s", quoteIdentifier(foreignKey.referencedSchema), quoteIdentifier(foreignKey.referencedTable)),
+				formatColumnList(foreignKey.referencedColumnList))
+			if foreignKey.onDelete != "" && foreignKey.onDelete != "NO_ACTION" {
+				fmt.Fprintf(&buf, " ON DELETE %s", strings.ReplaceAll(foreignKey.onDelete, "_", " "))
+			}
+			if foreignKey.onUpdate != "" && foreignKey.onUpdate != "NO_ACTION" {
+				fmt.Fprintf(&buf, " ON UPDATE %s", strings.ReplaceAll(foreignKey.onUpdate, "_", " "))
+			}
+			buf.WriteString(";\n\n")
+			if _, err := io.WriteString(out, buf.String()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// formatTableSchema formats the CREATE TABLE statement of the table, followed by the CREATE INDEX statements
+// of the indexes not backing a constraint.
+func formatTableSchema(table *tableSchema) string {
+	tableName := fmt.Sprintf("%s.%s", quoteIdentifier(table.schema), quoteIdentifier(table.name))
+	var lineList []string
+	for _, column := range table.columnList {
+		var buf strings.Builder
+		buf.WriteString(quoteIdentifier(column.name))
+		if column.computed != "" {
+			fmt.Fprintf(&buf, " AS %s", column.computed)
+			if column.persisted {
+				buf.WriteString(" PERSISTED")
+			}
+		} else {
+			fmt.Fprintf(&buf, " %s", column.typeName)
+			if column.identity != "" {
+				fmt.Fprintf(&buf, " %s", column.identity)
+			}
+			if column.nullable {
+				buf.WriteString(" NULL")
+			} else {
+				buf.WriteString(" NOT NULL")
+			}
+			if column.defaultConstraint != nil {
+				fmt.Fprintf(&buf, " %sDEFAULT %s", formatConstraintName(column.defaultConstraint.name), column.defaultConstraint.definition)
+			}
+		}
+		lineList = append(lineList, buf.String())
+	}
+	var indexStmtList []string
+	for _, index := range table.indexList {
+		if index.primaryKey || index.uniqueConstraint {
+			name := index.name
+			if index.systemNamed {
+				name = ""
+			}
+			kind := "UNIQUE"
+			if index.primaryKey {
+				kind = "PRIMARY KEY"
+			}
+			lineList = append(lineList, fmt.Sprintf("%s%s %s (%s)", formatConstraintName(name), kind, index.typeDesc, formatColumnList(index.keyList)))
+			continue
+		}
+		var buf strings.Builder
+		buf.WriteString("CREATE ")
+		if index.unique {
+			buf.WriteString("UNIQUE ")
+		}
+		fmt.Fprintf(&buf, "%s INDEX %s ON %s (%s)", index.typeDesc, quoteIdentifier(index.name), tableName, formatColumnList(index.keyList))
+		if len(index.includeList) > 0 {
+			fmt.Fprintf(&buf, " INCLUDE (%s)", formatColumnList(index.includeList))
+		}
+		if index.filter != "" {
+			fmt.Fprintf(&buf, " WHERE %s", index.filter)
+		}
+		buf.WriteString(";\n")
+		indexStmtList = append(indexStmtList, buf.String())
+	}
+	for _, check := range table.checkList {
+		lineList = append(lineList, fmt.Sprintf("%sCHECK %s", formatConstraintName(check.name), check.definition))
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--\n-- Table structure for %s\n--\n", tableName)
+	fmt.Fprintf(&buf, "CREATE TABLE %s (\n    %s\n);\n", tableName, strings.Join(lineList, ",\n    "))
+	for _, stmt := range indexStmtList {
+		buf.WriteString(stmt)
+	}
+	buf.WriteString("\n")
+	return buf.String()
+}
+
+// toTable converts the table to the synced table, which is named with its schema, e.g. "dbo.user".
+func (table *tableSchema) toTable() db.Table {
+	result := db.Table{
+		Name: fmt.Sprintf("%s.%s", table.schema, table.name),
+		Type: "BASE TABLE",
+	}
+	for _, column := range table.columnList {
+		syncColumn := db.Column{
+			Name:     column.name,
+			Position: column.position,
+			Nullable: column.nullable,
+			Type:     column.typeName,
+		}
+		if column.defaultConstraint != nil {
+			definition := column.defaultConstraint.definition
+			syncColumn.Default = &definition
+		}
+		result.ColumnList = append(result.ColumnList, syncColumn)
+	}
+	for _, index := range table.indexList {
+		for _, column := range index.keyList {
+			result.IndexList = append(result.IndexList, db.Index{
+				Name:       index.name,
+				Expression: column.name,
+				Position:   column.position,
+				Type:       index.typeDesc,
+				Unique:     index.unique,
+				Visible:    true,
+			})
+		}
+	}
+	for _, foreignKey := range table.foreignKeyList {
+		syncForeignKey := db.ForeignKey{
+			Name:            foreignKey.name,
+			ReferencedTable: fmt.Sprintf("%s.%s", foreignKey.referencedSchema, foreignKey.referencedTable),
+		}
+		for _, column := range foreignKey.columnList {
+			syncForeignKey.ColumnList = append(syncForeignKey.ColumnList, column.name)
+		}
+		result.ForeignKeyList = append(result.ForeignKeyList, syncForeignKey)
+	}
+	return result
+}
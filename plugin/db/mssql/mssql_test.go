@@ -0,0 +1,138 @@
+package mssql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bytebase/bytebase/plugin/db"
+)
+
+func TestFormatColumnType(t *testing.T) {
+	tests := []struct {
+		typeName  string
+		maxLength int
+		precision int
+		scale     int
+		want      string
+	}{
+		{"int", 4, 10, 0, "int"},
+		{"varchar", 50, 0, 0, "varchar(50)"},
+		{"varbinary", -1, 0, 0, "varbinary(max)"},
+		{"nvarchar", 200, 0, 0, "nvarchar(100)"},
+		{"nvarchar", -1, 0, 0, "nvarchar(max)"},
+		{"decimal", 9, 18, 2, "decimal(18,2)"},
+		{"datetime2", 8, 27, 7, "datetime2(7)"},
+		{"float", 8, 53, 0, "float"},
+		{"float", 4, 24, 0, "float(24)"},
+		{"datetime", 8, 23, 3, "datetime"},
+	}
+	for _, test := range tests {
+		if got := formatColumnType(test.typeName, test.maxLength, test.precision, test.scale); got != test.want {
+			t.Errorf("formatColumnType(%q, %d, %d, %d) = %q, want %q", test.typeName, test.maxLength, test.precision, test.scale, got, test.want)
+		}
+	}
+}
+
+func TestWriteTableSchemaList(t *testing.T) {
+	newTableList := func(reverse bool) []*tableSchema {
+		defaultName := "(N'')"
+		user := &tableSchema{
+			schema: "dbo",
+			name:   "user",
+			columnList: []*columnSchema{
+				{name: "id", position: 1, typeName: "int", identity: "IDENTITY(1,1)"},
+				{name: "name", position: 2, typeName: "nvarchar(100)", nullable: true, defaultConstraint: &constraintSchema{definition: defaultName}},
+				{name: "age", position: 3, typeName: "int", nullable: true},
+			},
+			indexList: []*indexSchema{
+				{name: "PK__user__3213E83F", typeDesc: "CLUSTERED", primaryKey: true, unique: true, systemNamed: true, keyList: []*indexColumn{{name: "id", position: 1}}},
+				{name: "idx_user_name", typeDesc: "NONCLUSTERED", keyList: []*indexColumn{{name: "name", position: 1, descending: true}}, includeList: []*indexColumn{{name: "age", position: 3}}},
+			},
+			checkList: []*constraintSchema{
+				{name: "ck_user_age", definition: "([age]>(0))"},
+			},
+		}
+		book := &tableSchema{
+			schema: "dbo",
+			name:   "book",
+			columnList: []*columnSchema{
+				{name: "id", position: 1, typeName: "int"},
+				{name: "author_id", position: 2, typeName: "int"},
+			},
+			indexList: []*indexSchema{
+				{name: "pk_book", typeDesc: "CLUSTERED", primaryKey: true, unique: true, keyList: []*indexColumn{{name: "id", position: 1}}},
+			},
+			foreignKeyList: []*foreignKeySchema{
+				{
+					name:                 "fk_book_author",
+					columnList:           []*indexColumn{{name: "author_id", position: 1}},
+					referencedSchema:     "dbo",
+					referencedTable:      "user",
+					referencedColumnList: []*indexColumn{{name: "id", position: 1}},
+					onDelete:             "CASCADE",
+					onUpdate:             "NO_ACTION",
+				},
+			},
+		}
+		if reverse {
+			user.columnList[0], user.columnList[2] = user.columnList[2], user.columnList[0]
+			user.indexList[0], user.indexList[1] = user.indexList[1], user.indexList[0]
+			return []*tableSchema{user, book}
+		}
+		return []*tableSchema{book, user}
+	}
+
+	want := strings.Join([]string{
+		"--",
+		"-- Table structure for [dbo].[book]",
+		"--",
+		"CREATE TABLE [dbo].[book] (",
+		"    [id] int NOT NULL,",
+		"    [author_id] int NOT NULL,",
+		"    CONSTRAINT [pk_book] PRIMARY KEY CLUSTERED ([id])",
+		");",
+		"",
+		"--",
+		"-- Table structure for [dbo].[user]",
+		"--",
+		"CREATE TABLE [dbo].[user] (",
+		"    [id] int IDENTITY(1,1) NOT NULL,",
+		"    [name] nvarchar(100) NULL DEFAULT (N''),",
+		"    [age] int NULL,",
+		"    PRIMARY KEY CLUSTERED ([id]),",
+		"    CONSTRAINT [ck_user_age] CHECK ([age]>(0))",
+		");",
+		"CREATE NONCLUSTERED INDEX [idx_user_name] ON [dbo].[user] ([name] DESC) INCLUDE ([age]);",
+		"",
+		"ALTER TABLE [dbo].[book] ADD CONSTRAINT [fk_book_author] FOREIGN KEY ([author_id]) REFERENCES [dbo].[user] ([id]) ON DELETE CASCADE;",
+		"",
+		"",
+	}, "\n")
+	for _, reverse := range []bool{false, true} {
+		tableList := newTableList(reverse)
+		sortTableSchemaList(tableList)
+		var buf strings.Builder
+		if err := writeTableSchemaList(&buf, tableList); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != want {
+			t.Errorf("writeTableSchemaList(reverse=%v) =\n%s\nwant\n%s", reverse, got, want)
+		}
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	if got, want := quoteIdentifier("a]b"), "[a]]b]"; got != want {
+		t.Errorf("quoteIdentifier() = %q, want %q", got, want)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	want := db.Capabilities{
+		SupportsTLSState:        true,
+		SupportsIndexInspection: true,
+	}
+	if got := (&Driver{}).Capabilities(); got != want {
+		t.Errorf("Capabilities() = %+v, want %+v", got, want)
+	}
+}
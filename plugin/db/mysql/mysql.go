@@ -6,7 +6,10 @@ import (
 	"database/sql"
 	"fmt"
 	"io"
+	"net"
+	"strconv"
 	"strings"
+	"time"
 
 	// embed will embeds the migration schema.
 	_ "embed"
@@ -44,6 +47,9 @@ type Driver struct {
 	l             *zap.Logger
 	connectionCtx db.ConnectionContext
 	dbType        db.Type
+	// tlsRequired is whether the connection is configured to use TLS.
+	tlsRequired     bool
+	peerCertificate db.PeerCertificateRecorder
 
 	db *sql.DB
 }
@@ -84,6 +90,8 @@ func (driver *Driver) Open(ctx context.Context, dbType db.Type, config db.Connec
 	}
 	tlsKey := "db.mysql.tls"
 	if tlsConfig != nil {
+		driver.tlsRequired = true
+		driver.peerCertificate.Record(tlsConfig)
 		if err := mysql.RegisterTLSConfig(tlsKey, tlsConfig); err != nil {
 			return nil, fmt.Errorf("sql: failed to register tls config: %v", err)
 		}
@@ -241,6 +249,58 @@ func (driver *Driver) SyncSchema(ctx context.Context) ([]*db.User, []*db.Schema,
 		}
 	}
 
+	// Query foreign key info
+	foreignKeyWhere := fmt.Sprintf("LOWER(TABLE_SCHEMA) NOT IN (%s) AND REFERENCED_TABLE_NAME IS NOT NULL", strings.Join(excludedDatabaseList, ", "))
+	query = `
+			SELECT
+				TABLE_SCHEMA,
+				TABLE_NAME,
+				CONSTRAINT_NAME,
+				COLUMN_NAME,
+				REFERENCED_TABLE_NAME
+			FROM information_schema.KEY_COLUMN_USAGE
+			WHERE ` + foreignKeyWhere + `
+			ORDER BY TABLE_SCHEMA, TABLE_NAME, CONSTRAINT_NAME, ORDINAL_POSITION`
+	foreignKeyRows, err := driver.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, nil, util.FormatErrorWithQuery(err, query)
+	}
+	defer foreignKeyRows.Close()
+
+	// dbName/tableName -> foreignKeyList map
+	foreignKeyMap := make(map[string][]db.ForeignKey)
+	for foreignKeyRows.Next() {
+		var dbName string
+		var tableName string
+		var constraintName string
+		var columnName string
+		var referencedTable string
+		if err := foreignKeyRows.Scan(
+			&dbName,
+			&tableName,
+			&constraintName,
+			&columnName,
+			&referencedTable,
+		); err != nil {
+			return nil, nil, err
+		}
+
+		key := fmt.Sprintf("%s/%s", dbName, tableName)
+		foreignKeyList := foreignKeyMap[key]
+		// Rows are ordered by constraint, so a multi-column key always extends the last entry.
+		if len(foreignKeyList) > 0 && foreignKeyList[len(foreignKeyList)-1].Name == constraintName {
+			last := &foreignKeyList[len(foreignKeyList)-1]
+			last.ColumnList = append(last.ColumnList, columnName)
+		} else {
+			foreignKeyList = append(foreignKeyList, db.ForeignKey{
+				Name:            constraintName,
+				ColumnList:      []string{columnName},
+				ReferencedTable: referencedTable,
+			})
+		}
+		foreignKeyMap[key] = foreignKeyList
+	}
+
 	// Query column info
 	columnWhere := fmt.Sprintf("LOWER(TABLE_SCHEMA) NOT IN (%s)", strings.Join(excludedDatabaseList, ", "))
 	query = `
@@ -365,6 +425,7 @@ func (driver *Driver) SyncSchema(ctx context.Context) ([]*db.User, []*db.Schema,
 			key := fmt.Sprintf("%s/%s", dbName, table.Name)
 			table.ColumnList = columnMap[key]
 			table.IndexList = indexMap[key]
+			table.ForeignKeyList = foreignKeyMap[key]
 
 			tableList, ok := tableMap[dbName]
 			if ok {
@@ -538,6 +599,176 @@ func (driver *Driver) Execute(ctx context.Context, statement string) error {
 	return err
 }
 
+// Capabilities returns the optional features supported for MySQL, TiDB doesn't support listing transactions or reporting replication lag.
+func (driver *Driver) Capabilities() db.Capabilities {
+	return db.Capabilities{
+		SupportsTxList:            driver.dbType != db.TiDB,
+		SupportsReplicationStatus: driver.dbType != db.TiDB,
+		SupportsTLSState:          true,
+		SupportsIndexInspection:   true,
+		SupportsMigrationHistory:  true,
+	}
+}
+
+// FindLongRunningTransactionList finds the transactions on the database running longer than the threshold.
+func (driver *Driver) FindLongRunningTransactionList(ctx context.Context, database string, threshold time.Duration) ([]*db.Transaction, error) {
+	// TiDB doesn't provide information_schema.INNODB_TRX.
+	if driver.dbType == db.TiDB {
+		return nil, common.Errorf(common.NotImplemented, fmt.Errorf("listing transactions is not supported for TiDB"))
+	}
+	query := `
+		SELECT
+			trx.trx_mysql_thread_id,
+			TIMESTAMPDIFF(SECOND, trx.trx_started, NOW()),
+			IFNULL(trx.trx_query, '')
+		FROM information_schema.INNODB_TRX trx
+		JOIN information_schema.PROCESSLIST p ON p.ID = trx.trx_mysql_thread_id
+		WHERE p.DB = ? AND trx.trx_started < NOW() - INTERVAL ? SECOND
+		ORDER BY trx.trx_started`
+	rows, err := driver.db.QueryContext(ctx, query, database, int64(threshold.Seconds()))
+	if err != nil {
+		return nil, util.FormatErrorWithQuery(err, query)
+	}
+	defer rows.Close()
+
+	var list []*db.Transaction
+	for rows.Next() {
+		var transaction db.Transaction
+		var durationSecond int64
+		if err := rows.Scan(
+			&transaction.PID,
+			&durationSecond,
+			&transaction.Query,
+		); err != nil {
+			return nil, err
+		}
+		transaction.Duration = time.Duration(durationSecond) * time.Second
+		list = append(list, &transaction)
+	}
+	return list, nil
+}
+
+// GetDiskUsage is not supported for MySQL since the disk usage isn't exposed through SQL.
+func (driver *Driver) GetDiskUsage(ctx context.Context) (*db.DiskUsage, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("getting disk usage is not supported for MySQL"))
+}
+
+// GetConnectionTLSState gets the TLS state of the connection of the driver, which uses SSL if the session has an SSL cipher.
+// The server certificate is the one recorded in the handshake, or else its expiration is the one reported by the server.
+func (driver *Driver) GetConnectionTLSState(ctx context.Context) (*db.ConnectionTLSState, error) {
+	cipher, err := driver.getStatus(ctx, "SHOW SESSION STATUS LIKE 'Ssl_cipher'")
+	if err != nil {
+		return nil, err
+	}
+	state := &db.ConnectionTLSState{
+		Required:  driver.tlsRequired,
+		Encrypted: cipher != "",
+	}
+
+	if cert := driver.peerCertificate.Certificate(); cert != nil && state.Encrypted {
+		state.SelfSigned = db.IsSelfSignedCertificate(cert)
+		state.CertificateNotAfter = cert.NotAfter
+		return state, nil
+	}
+	// The server reports the expiration of its certificate even if the connection doesn't use SSL.
+	notAfter, err := driver.getStatus(ctx, "SHOW GLOBAL STATUS LIKE 'Ssl_server_not_after'")
+	if err != nil {
+		return nil, err
+	}
+	if notAfter != "" {
+		state.CertificateNotAfter, err = parseSslTime(notAfter)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return state, nil
+}
+
+// getStatus gets the value of the status variable queried by SHOW STATUS, empty if the variable doesn't exist.
+func (driver *Driver) getStatus(ctx context.Context, query string) (string, error) {
+	var name, value string
+	if err := driver.db.QueryRowContext(ctx, query).Scan(&name, &value); err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", util.FormatErrorWithQuery(err, query)
+	}
+	return value, nil
+}
+
+// parseSslTime parses the certificate time in the status variables such as Ssl_server_not_after, e.g. "Apr 10 08:41:34 2032 GMT".
+func parseSslTime(value string) (time.Time, error) {
+	t, err := time.Parse("Jan _2 15:04:05 2006 MST", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse SSL certificate time %q: %w", value, err)
+	}
+	return t, nil
+}
+
+// ReplicationStatus gets the Seconds_Behind_Master and the Master_Host:Master_Port of the replica.
+func (driver *Driver) ReplicationStatus(ctx context.Context) (*db.ReplicationStatus, error) {
+	if driver.dbType == db.TiDB {
+		return nil, common.Errorf(common.NotImplemented, fmt.Errorf("getting replication status is not supported for TiDB"))
+	}
+	query := "SHOW SLAVE STATUS"
+	rows, err := driver.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, util.FormatErrorWithQuery(err, query)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("instance is not a replica")
+	}
+	// The columns of SHOW SLAVE STATUS vary among MySQL versions, so we locate the columns by name.
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+	valueMap := make(map[string]sql.NullString)
+	for i, column := range columns {
+		valueMap[column] = values[i]
+	}
+	return getReplicationStatus(valueMap)
+}
+
+// getReplicationStatus gets the replication status from the columns of SHOW SLAVE STATUS by name.
+func getReplicationStatus(valueMap map[string]sql.NullString) (*db.ReplicationStatus, error) {
+	secondsBehindMaster, ok := valueMap["Seconds_Behind_Master"]
+	if !ok {
+		return nil, fmt.Errorf("column Seconds_Behind_Master not found in SHOW SLAVE STATUS")
+	}
+	// Seconds_Behind_Master is NULL if the replication threads aren't running.
+	if !secondsBehindMaster.Valid {
+		return nil, fmt.Errorf("replication is not running")
+	}
+	seconds, err := strconv.ParseInt(secondsBehindMaster.String, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Seconds_Behind_Master %q: %w", secondsBehindMaster.String, err)
+	}
+	status := &db.ReplicationStatus{
+		Lag: time.Duration(seconds) * time.Second,
+	}
+	if host := valueMap["Master_Host"]; host.Valid && host.String != "" {
+		status.Primary = host.String
+		if port := valueMap["Master_Port"]; port.Valid && port.String != "" {
+			status.Primary = net.JoinHostPort(host.String, port.String)
+		}
+	}
+	return status, nil
+}
+
 // NeedsSetupMigration returns whether it needs to setup migration.
 func (driver *Driver) NeedsSetupMigration(ctx context.Context) (bool, error) {
 	const query = `
@@ -1022,6 +1253,11 @@ func exportTableData(txn *sql.Tx, dbName, tblName string, includeDbPrefix bool,
 	for i := 0; i < len(cols); i++ {
 		ptrs[i] = &values[i]
 	}
+	table := fmt.Sprintf("`%s`", tblName)
+	if includeDbPrefix {
+		table = fmt.Sprintf("`%s`.%s", dbName, table)
+	}
+	w := util.NewInsertStatementWriter(out, table, util.DataDumpBatchSize)
 	for rows.Next() {
 		if err := rows.Scan(ptrs...); err != nil {
 			return err
@@ -1034,24 +1270,52 @@ func exportTableData(txn *sql.Tx, dbName, tblName string, includeDbPrefix bool,
 			case isNumeric(cols[i].ScanType().Name()):
 				tokens[i] = v.String
 			default:
-				tokens[i] = fmt.Sprintf("'%s'", v.String)
+				tokens[i] = quoteString(v.String)
 			}
 		}
-		dbPrefix := ""
-		if includeDbPrefix {
-			dbPrefix = fmt.Sprintf("`%s`.", dbName)
-		}
-		stmt := fmt.Sprintf("INSERT INTO %s`%s` VALUES (%s);\n", dbPrefix, tblName, strings.Join(tokens, ", "))
-		if _, err := io.WriteString(out, stmt); err != nil {
+		if err := w.WriteRow(tokens); err != nil {
 			return err
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
 	if _, err := io.WriteString(out, "\n"); err != nil {
 		return err
 	}
 	return nil
 }
 
+// quoteString quotes the string value with the backslash escapes, which also keeps the line breaks out of the dumped row.
+// The bytes are escaped one by one so that the binary values are kept as is.
+func quoteString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0:
+			sb.WriteString(`\0`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\x1a':
+			sb.WriteString(`\Z`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\'':
+			sb.WriteString(`\'`)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('\'')
+	return sb.String()
+}
+
 // isNumeric determines whether the value needs quotes.
 // Even if the function returns incorrect result, the data dump will still work.
 func isNumeric(t string) bool {
@@ -0,0 +1,192 @@
+package mysql
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bytebase/bytebase/plugin/db"
+	"github.com/bytebase/bytebase/plugin/db/util"
+	"github.com/pingcap/parser"
+	"github.com/pingcap/parser/ast"
+
+	// Register pingcap parser driver.
+	_ "github.com/pingcap/tidb/types/parser_driver"
+)
+
+func TestDumpTableDataRoundTrip(t *testing.T) {
+	valueList := []string{
+		"plain",
+		"it's",
+		`back\slash`,
+		"line\nbreak;",
+		"-- not a comment",
+		"/* not a comment */",
+		"\x00\x1a\r\n",
+		"",
+	}
+
+	// Dump the rows in batches of 3 and restore them the way Restore splits the statements.
+	var out strings.Builder
+	w := util.NewInsertStatementWriter(&out, "`t`", 3)
+	for i, value := range valueList {
+		if err := w.WriteRow([]string{fmt.Sprintf("%d", i), quoteString(value)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var stmtList []string
+	if err := util.ApplyMultiStatements(bufio.NewScanner(strings.NewReader(out.String())), func(stmt string) error {
+		stmtList = append(stmtList, stmt)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(stmtList) != 3 {
+		t.Fatalf("got %d statements, want 3:\n%s", len(stmtList), out.String())
+	}
+
+	var gotList []string
+	for _, stmt := range stmtList {
+		nodeList, _, err := parser.New().Parse(stmt, "", "")
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", stmt, err)
+		}
+		insert, ok := nodeList[0].(*ast.InsertStmt)
+		if !ok {
+			t.Fatalf("%q is not an INSERT statement", stmt)
+		}
+		for _, row := range insert.Lists {
+			gotList = append(gotList, row[1].(ast.ValueExpr).GetValue().(string))
+		}
+	}
+	if len(gotList) != len(valueList) {
+		t.Fatalf("got %d rows, want %d", len(gotList), len(valueList))
+	}
+	for i := range valueList {
+		if gotList[i] != valueList[i] {
+			t.Errorf("row %d = %q, want %q", i, gotList[i], valueList[i])
+		}
+	}
+}
+
+func TestParseSslTime(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"Apr 10 08:41:34 2032 GMT", time.Date(2032, time.April, 10, 8, 41, 34, 0, time.UTC), false},
+		// OpenSSL pads the single-digit day with a space.
+		{"Jan  5 00:00:00 2030 GMT", time.Date(2030, time.January, 5, 0, 0, 0, 0, time.UTC), false},
+		{"2030-01-05", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSslTime(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("parseSslTime(%q) error = %v, wantErr %t", tt.value, err, tt.wantErr)
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseSslTime(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestGetReplicationStatus(t *testing.T) {
+	value := func(s string) sql.NullString {
+		return sql.NullString{String: s, Valid: true}
+	}
+	tests := []struct {
+		name     string
+		valueMap map[string]sql.NullString
+		want     *db.ReplicationStatus
+		wantErr  bool
+	}{
+		{
+			name: "lagging",
+			valueMap: map[string]sql.NullString{
+				"Master_Host":           value("10.0.0.1"),
+				"Master_Port":           value("3306"),
+				"Seconds_Behind_Master": value("120"),
+			},
+			want: &db.ReplicationStatus{Lag: 2 * time.Minute, Primary: "10.0.0.1:3306"},
+		},
+		{
+			name: "caughtUp",
+			valueMap: map[string]sql.NullString{
+				"Master_Host":           value("primary"),
+				"Master_Port":           value("3306"),
+				"Seconds_Behind_Master": value("0"),
+			},
+			want: &db.ReplicationStatus{Primary: "primary:3306"},
+		},
+		{
+			name: "unknownPrimary",
+			valueMap: map[string]sql.NullString{
+				"Seconds_Behind_Master": value("5"),
+			},
+			want: &db.ReplicationStatus{Lag: 5 * time.Second},
+		},
+		{
+			name: "notRunning",
+			valueMap: map[string]sql.NullString{
+				"Master_Host":           value("10.0.0.1"),
+				"Seconds_Behind_Master": {},
+			},
+			wantErr: true,
+		},
+		{
+			name:     "missingColumn",
+			valueMap: map[string]sql.NullString{},
+			wantErr:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := getReplicationStatus(test.valueMap)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("getReplicationStatus() error = %v, wantErr %t", err, test.wantErr)
+			}
+			if err == nil && *got != *test.want {
+				t.Errorf("getReplicationStatus() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		dbType db.Type
+		want   db.Capabilities
+	}{
+		{
+			dbType: db.MySQL,
+			want: db.Capabilities{
+				SupportsTxList:            true,
+				SupportsReplicationStatus: true,
+				SupportsTLSState:          true,
+				SupportsIndexInspection:   true,
+				SupportsMigrationHistory:  true,
+			},
+		},
+		{
+			dbType: db.TiDB,
+			want: db.Capabilities{
+				SupportsTLSState:         true,
+				SupportsIndexInspection:  true,
+				SupportsMigrationHistory: true,
+			},
+		},
+	}
+	for _, test := range tests {
+		driver := &Driver{dbType: test.dbType}
+		if got := driver.Capabilities(); got != test.want {
+			t.Errorf("Capabilities() of %s = %+v, want %+v", test.dbType, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,635 @@
+package oracle
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bytebase/bytebase/common"
+	"github.com/bytebase/bytebase/plugin/db"
+	"github.com/bytebase/bytebase/plugin/db/util"
+
+	// Register the oracle database/sql driver.
+	_ "github.com/sijms/go-ora/v2"
+	"go.uber.org/zap"
+)
+
+var (
+	// systemConstraintNameRegexp matches the CONSTRAINT clause naming a system-named constraint, e.g. CONSTRAINT "SYS_C008123".
+	systemConstraintNameRegexp = regexp.MustCompile(`CONSTRAINT "SYS_C\d+" `)
+	// sequenceStartWithRegexp matches the START WITH clause of a sequence, which is the next value of the sequence in the DDL.
+	sequenceStartWithRegexp = regexp.MustCompile(`\s+START WITH \d+`)
+
+	_ db.Driver = (*Driver)(nil)
+)
+
+func init() {
+	db.Register(db.Oracle, newDriver)
+}
+
+// Driver is the Oracle driver.
+type Driver struct {
+	l             *zap.Logger
+	connectionCtx db.ConnectionContext
+	dbType        db.Type
+
+	db *sql.DB
+}
+
+func newDriver(config db.DriverConfig) db.Driver {
+	return &Driver{
+		l: config.Logger,
+	}
+}
+
+// Open opens an Oracle driver.
+// The database of the config is ignored, the schemas of the database identified by the service name or SID are synced as the databases.
+func (driver *Driver) Open(ctx context.Context, dbType db.Type, config db.ConnectionConfig, connCtx db.ConnectionContext) (db.Driver, error) {
+	if (config.ServiceName == "") == (config.SID == "") {
+		return nil, fmt.Errorf("oracle: exactly one of the service name and SID must be set")
+	}
+	if config.TLSConfig.SslCA != "" || config.TLSConfig.SslCert != "" || config.TLSConfig.SslKey != "" {
+		return nil, fmt.Errorf("oracle: tls config error: SSL is not supported for Oracle")
+	}
+	port := config.Port
+	if port == "" {
+		port = "1521"
+	}
+	dsn := &url.URL{
+		Scheme: "oracle",
+		User:   url.UserPassword(config.Username, config.Password),
+		Host:   net.JoinHostPort(config.Host, port),
+		Path:   "/" + config.ServiceName,
+	}
+	if config.SID != "" {
+		dsn.RawQuery = url.Values{"SID": []string{config.SID}}.Encode()
+	}
+
+	driver.l.Debug("Opening Oracle driver",
+		zap.String("dsn", dsn.Redacted()),
+		zap.String("environment", connCtx.EnvironmentName),
+		zap.String("database", connCtx.InstanceName),
+	)
+	db, err := sql.Open("oracle", dsn.String())
+	if err != nil {
+		return nil, err
+	}
+	driver.dbType = dbType
+	driver.db = db
+	driver.connectionCtx = connCtx
+
+	return driver, nil
+}
+
+// Close closes the driver.
+func (driver *Driver) Close(ctx context.Context) error {
+	return driver.db.Close()
+}
+
+// Ping pings the database.
+func (driver *Driver) Ping(ctx context.Context) error {
+	return driver.db.PingContext(ctx)
+}
+
+// GetDbConnection gets a database connection.
+// The connection isn't bound to the schema, the catalog queries of the driver name the owner explicitly.
+func (driver *Driver) GetDbConnection(ctx context.Context, database string) (*sql.DB, error) {
+	return driver.db, nil
+}
+
+// GetVersion gets the version from v$version, e.g. "Oracle Database 19c Enterprise Edition Release 19.0.0.0.0 - Production".
+func (driver *Driver) GetVersion(ctx context.Context) (string, error) {
+	query := "SELECT BANNER FROM v$version WHERE BANNER LIKE 'Oracle%'"
+	versionRow, err := driver.db.QueryContext(ctx, query)
+	if err != nil {
+		return "", util.FormatErrorWithQuery(err, query)
+	}
+	defer versionRow.Close()
+
+	var version string
+	versionRow.Next()
+	if err := versionRow.Scan(&version); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// SyncSchema synces the schema.
+// Oracle users are schemas, the schemas of the users not maintained by Oracle are synced as the databases.
+func (driver *Driver) SyncSchema(ctx context.Context) ([]*db.User, []*db.Schema, error) {
+	schemaNameList, err := driver.getSchemaList(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var userList []*db.User
+	var schemaList []*db.Schema
+	for _, name := range schemaNameList {
+		userList = append(userList, &db.User{
+			Name: name,
+		})
+
+		tableList, err := driver.getTableList(ctx, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		viewList, err := driver.getViewList(ctx, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		schemaList = append(schemaList, &db.Schema{
+			Name:      name,
+			TableList: tableList,
+			ViewList:  viewList,
+		})
+	}
+
+	return userList, schemaList, nil
+}
+
+// getSchemaList returns the schemas of the users not maintained by Oracle sorted by name.
+// ORACLE_MAINTAINED is available since Oracle 12c.
+func (driver *Driver) getSchemaList(ctx context.Context) ([]string, error) {
+	query := `
+		SELECT USERNAME
+		FROM ALL_USERS
+		WHERE ORACLE_MAINTAINED = 'N'
+		ORDER BY USERNAME`
+	rows, err := driver.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, util.FormatErrorWithQuery(err, query)
+	}
+	defer rows.Close()
+
+	var schemaList []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		schemaList = append(schemaList, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return schemaList, nil
+}
+
+func (driver *Driver) getTableList(ctx context.Context, schema string) ([]db.Table, error) {
+	// The dropped tables in the recycle bin are named BIN$...
+	query := `
+		SELECT
+			t.TABLE_NAME,
+			ROUND((o.CREATED - DATE '1970-01-01') * 86400),
+			ROUND((o.LAST_DDL_TIME - DATE '1970-01-01') * 86400),
+			NVL(t.NUM_ROWS, 0),
+			NVL(c.COMMENTS, '')
+		FROM ALL_TABLES t
+		JOIN ALL_OBJECTS o ON o.OWNER = t.OWNER AND o.OBJECT_NAME = t.TABLE_NAME AND o.OBJECT_TYPE = 'TABLE'
+		LEFT JOIN ALL_TAB_COMMENTS c ON c.OWNER = t.OWNER AND c.TABLE_NAME = t.TABLE_NAME
+		WHERE t.OWNER = :1 AND t.NESTED = 'NO' AND t.SECONDARY = 'N' AND t.TABLE_NAME NOT LIKE 'BIN$%'
+		ORDER BY t.TABLE_NAME`
+	rows, err := driver.db.QueryContext(ctx, query, schema)
+	if err != nil {
+		return nil, util.FormatErrorWithQuery(err, query)
+	}
+	defer rows.Close()
+
+	var tableList []db.Table
+	tableIndex := make(map[string]int)
+	for rows.Next() {
+		table := db.Table{
+			Type: "BASE TABLE",
+		}
+		if err := rows.Scan(
+			&table.Name,
+			&table.CreatedTs,
+			&table.UpdatedTs,
+			&table.RowCount,
+			&table.Comment,
+		); err != nil {
+			return nil, err
+		}
+		tableIndex[table.Name] = len(tableList)
+		tableList = append(tableList, table)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	columnQuery := `
+		SELECT
+			TABLE_NAME,
+			COLUMN_NAME,
+			COLUMN_ID,
+			DATA_TYPE,
+			CHAR_LENGTH,
+			DATA_LENGTH,
+			DATA_PRECISION,
+			DATA_SCALE,
+			NULLABLE
+		FROM ALL_TAB_COLUMNS
+		WHERE OWNER = :1
+		ORDER BY TABLE_NAME, COLUMN_ID`
+	columnRows, err := driver.db.QueryContext(ctx, columnQuery, schema)
+	if err != nil {
+		return nil, util.FormatErrorWithQuery(err, columnQuery)
+	}
+	defer columnRows.Close()
+
+	for columnRows.Next() {
+		var tableName, dataType, nullable string
+		var charLength, dataLength int
+		var precision, scale sql.NullInt64
+		var column db.Column
+		if err := columnRows.Scan(
+			&tableName,
+			&column.Name,
+			&column.Position,
+			&dataType,
+			&charLength,
+			&dataLength,
+			&precision,
+			&scale,
+			&nullable,
+		); err != nil {
+			return nil, err
+		}
+		// ALL_TAB_COLUMNS also lists the columns of the views and clusters.
+		i, ok := tableIndex[tableName]
+		if !ok {
+			continue
+		}
+		column.Type = formatColumnType(dataType, charLength, dataLength, precision, scale)
+		column.Nullable = nullable == "Y"
+		tableList[i].ColumnList = append(tableList[i].ColumnList, column)
+	}
+	if err := columnRows.Err(); err != nil {
+		return nil, err
+	}
+	return tableList, nil
+}
+
+func (driver *Driver) getViewList(ctx context.Context, schema string) ([]db.View, error) {
+	query := `
+		SELECT
+			v.VIEW_NAME,
+			ROUND((o.CREATED - DATE '1970-01-01') * 86400),
+			ROUND((o.LAST_DDL_TIME - DATE '1970-01-01') * 86400),
+			v.TEXT
+		FROM ALL_VIEWS v
+		JOIN ALL_OBJECTS o ON o.OWNER = v.OWNER AND o.OBJECT_NAME = v.VIEW_NAME AND o.OBJECT_TYPE = 'VIEW'
+		WHERE v.OWNER = :1
+		ORDER BY v.VIEW_NAME`
+	rows, err := driver.db.QueryContext(ctx, query, schema)
+	if err != nil {
+		return nil, util.FormatErrorWithQuery(err, query)
+	}
+	defer rows.Close()
+
+	var viewList []db.View
+	for rows.Next() {
+		var view db.View
+		if err := rows.Scan(
+			&view.Name,
+			&view.CreatedTs,
+			&view.UpdatedTs,
+			&view.Definition,
+		); err != nil {
+			return nil, err
+		}
+		viewList = append(viewList, view)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return viewList, nil
+}
+
+// formatColumnType formats the type of a column from ALL_TAB_COLUMNS, where the precision and scale are NULL if not declared.
+func formatColumnType(dataType string, charLength, dataLength int, precision, scale sql.NullInt64) string {
+	switch dataType {
+	case "VARCHAR2", "NVARCHAR2", "CHAR", "NCHAR":
+		return fmt.Sprintf("%s(%d)", dataType, charLength)
+	case "RAW":
+		return fmt.Sprintf("%s(%d)", dataType, dataLength)
+	case "NUMBER":
+		switch {
+		case !precision.Valid && !scale.Valid:
+			return dataType
+		case !precision.Valid:
+			// INTEGER is NUMBER(*,0).
+			return fmt.Sprintf("%s(*,%d)", dataType, scale.Int64)
+		case scale.Int64 == 0:
+			return fmt.Sprintf("%s(%d)", dataType, precision.Int64)
+		default:
+			return fmt.Sprintf("%s(%d,%d)", dataType, precision.Int64, scale.Int64)
+		}
+	case "FLOAT":
+		if precision.Valid {
+			return fmt.Sprintf("%s(%d)", dataType, precision.Int64)
+		}
+		return dataType
+	default:
+		// The precision of TIMESTAMP and INTERVAL types is already included, e.g. TIMESTAMP(6).
+		return dataType
+	}
+}
+
+// Execute executes a SQL statement.
+func (driver *Driver) Execute(ctx context.Context, statement string) error {
+	tx, err := driver.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, statement); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Capabilities returns the optional features supported for Oracle.
+func (driver *Driver) Capabilities() db.Capabilities {
+	return db.Capabilities{}
+}
+
+// FindLongRunningTransactionList is not supported for Oracle.
+func (driver *Driver) FindLongRunningTransactionList(ctx context.Context, database string, threshold time.Duration) ([]*db.Transaction, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("listing transactions is not supported for Oracle"))
+}
+
+// GetDiskUsage is not supported for Oracle.
+func (driver *Driver) GetDiskUsage(ctx context.Context) (*db.DiskUsage, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("getting disk usage is not supported for Oracle"))
+}
+
+// ReplicationStatus is not supported for Oracle.
+func (driver *Driver) ReplicationStatus(ctx context.Context) (*db.ReplicationStatus, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("getting replication status is not supported for Oracle"))
+}
+
+// GetConnectionTLSState is not supported for Oracle.
+func (driver *Driver) GetConnectionTLSState(ctx context.Context) (*db.ConnectionTLSState, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("getting connection TLS state is not supported for Oracle"))
+}
+
+// NeedsSetupMigration is not supported for Oracle.
+func (driver *Driver) NeedsSetupMigration(ctx context.Context) (bool, error) {
+	return false, common.Errorf(common.NotImplemented, fmt.Errorf("migration history is not supported for Oracle"))
+}
+
+// SetupMigrationIfNeeded is not supported for Oracle.
+func (driver *Driver) SetupMigrationIfNeeded(ctx context.Context) error {
+	return common.Errorf(common.NotImplemented, fmt.Errorf("migration history is not supported for Oracle"))
+}
+
+// ExecuteMigration is not supported for Oracle.
+func (driver *Driver) ExecuteMigration(ctx context.Context, m *db.MigrationInfo, statement string) (int64, string, error) {
+	return -1, "", common.Errorf(common.NotImplemented, fmt.Errorf("executing migration is not supported for Oracle"))
+}
+
+// FindMigrationHistoryList is not supported for Oracle.
+func (driver *Driver) FindMigrationHistoryList(ctx context.Context, find *db.MigrationHistoryFind) ([]*db.MigrationHistory, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("migration history is not supported for Oracle"))
+}
+
+// Dump dumps the schema, or all schemas of the users not maintained by Oracle if database is empty.
+// Dumping data isn't supported.
+func (driver *Driver) Dump(ctx context.Context, database string, out io.Writer, schemaOnly bool) error {
+	if !schemaOnly {
+		return common.Errorf(common.NotImplemented, fmt.Errorf("dumping data is not supported for Oracle"))
+	}
+
+	schemaList, err := driver.getSchemaList(ctx)
+	if err != nil {
+		return err
+	}
+	if database != "" {
+		exist := false
+		for _, name := range schemaList {
+			if name == database {
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			return common.Errorf(common.NotFound, fmt.Errorf("schema %s not found", database))
+		}
+		schemaList = []string{database}
+	}
+
+	// The DBMS_METADATA transforms are set for the session, so all the DDLs are read on the same connection.
+	conn, err := driver.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := setSessionTransform(ctx, conn); err != nil {
+		return err
+	}
+	defer func() {
+		// The connection goes back to the pool, so the transforms are reset for the next user of the connection.
+		query := "BEGIN DBMS_METADATA.SET_TRANSFORM_PARAM(DBMS_METADATA.SESSION_TRANSFORM, 'DEFAULT'); END;"
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			driver.l.Warn("Failed to reset the DBMS_METADATA transforms", zap.Error(err))
+		}
+	}()
+
+	for _, name := range schemaList {
+		objectList, err := getObjectList(ctx, conn, name)
+		if err != nil {
+			return err
+		}
+		if database == "" {
+			if _, err := io.WriteString(out, fmt.Sprintf("--\n-- Schema structure for %s\n--\nALTER SESSION SET CURRENT_SCHEMA = %s;\n\n", quoteIdentifier(name), quoteIdentifier(name))); err != nil {
+				return err
+			}
+		}
+		if err := writeObjectList(out, objectList); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore is not supported for Oracle.
+func (driver *Driver) Restore(ctx context.Context, sc *bufio.Scanner) error {
+	return common.Errorf(common.NotImplemented, fmt.Errorf("restoring is not supported for Oracle"))
+}
+
+// setSessionTransform makes DBMS_METADATA leave out the storage clauses, which differ between databases,
+// and the foreign keys, which are dumped after all tables so that the referenced tables exist.
+func setSessionTransform(ctx context.Context, conn *sql.Conn) error {
+	query := `
+		BEGIN
+			DBMS_METADATA.SET_TRANSFORM_PARAM(DBMS_METADATA.SESSION_TRANSFORM, 'SEGMENT_ATTRIBUTES', FALSE);
+			DBMS_METADATA.SET_TRANSFORM_PARAM(DBMS_METADATA.SESSION_TRANSFORM, 'STORAGE', FALSE);
+			DBMS_METADATA.SET_TRANSFORM_PARAM(DBMS_METADATA.SESSION_TRANSFORM, 'REF_CONSTRAINTS', FALSE);
+			DBMS_METADATA.SET_TRANSFORM_PARAM(DBMS_METADATA.SESSION_TRANSFORM, 'SQLTERMINATOR', TRUE);
+			DBMS_METADATA.SET_TRANSFORM_PARAM(DBMS_METADATA.SESSION_TRANSFORM, 'PRETTY', TRUE);
+		END;`
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return util.FormatErrorWithQuery(err, query)
+	}
+	return nil
+}
+
+// objectKind is the kind of the dumped object, the objects are dumped in the order of their kinds.
+type objectKind int
+
+const (
+	sequenceObject objectKind = iota
+	tableObject
+	indexObject
+	foreignKeyObject
+)
+
+// header returns the name of the kind in the comment header of the object, empty if the object is dumped without a header.
+func (kind objectKind) header() string {
+	switch kind {
+	case sequenceObject:
+		return "Sequence"
+	case tableObject:
+		return "Table"
+	}
+	return ""
+}
+
+// schemaObject is the object of a schema with its DDL from DBMS_METADATA.
+type schemaObject struct {
+	kind objectKind
+	name string
+	ddl  string
+}
+
+// getObjectList reads the sequences, tables, indexes and foreign keys of the schema.
+// The system-generated objects, the dropped tables in the recycle bin, the sequences of the identity columns and
+// the indexes backing the primary key and unique constraints are left out, the constraints come with the tables.
+func getObjectList(ctx context.Context, conn *sql.Conn, schema string) ([]*schemaObject, error) {
+	query := `
+		SELECT
+			o.OBJECT_TYPE,
+			o.OBJECT_NAME,
+			DBMS_METADATA.GET_DDL(o.OBJECT_TYPE, o.OBJECT_NAME, o.OWNER)
+		FROM ALL_OBJECTS o
+		WHERE o.OWNER = :1
+			AND o.OBJECT_TYPE IN ('SEQUENCE', 'TABLE', 'INDEX')
+			AND o.GENERATED = 'N'
+			AND o.SECONDARY = 'N'
+			AND o.OBJECT_NAME NOT LIKE 'BIN$%'
+			AND o.OBJECT_NAME NOT LIKE 'ISEQ$$%'
+			AND NOT EXISTS (
+				SELECT 1
+				FROM ALL_CONSTRAINTS c
+				WHERE o.OBJECT_TYPE = 'INDEX' AND c.OWNER = o.OWNER AND c.INDEX_NAME = o.OBJECT_NAME AND c.CONSTRAINT_TYPE IN ('P', 'U')
+			)`
+	rows, err := conn.QueryContext(ctx, query, schema)
+	if err != nil {
+		return nil, util.FormatErrorWithQuery(err, query)
+	}
+	defer rows.Close()
+
+	var objectList []*schemaObject
+	for rows.Next() {
+		var objectType, name, ddl string
+		if err := rows.Scan(&objectType, &name, &ddl); err != nil {
+			return nil, err
+		}
+		kind := tableObject
+		switch objectType {
+		case "SEQUENCE":
+			kind = sequenceObject
+		case "INDEX":
+			kind = indexObject
+		}
+		objectList = append(objectList, &schemaObject{
+			kind: kind,
+			name: name,
+			ddl:  normalizeDDL(schema, ddl),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	foreignKeyQuery := `
+		SELECT
+			CONSTRAINT_NAME,
+			DBMS_METADATA.GET_DDL('REF_CONSTRAINT', CONSTRAINT_NAME, OWNER)
+		FROM ALL_CONSTRAINTS
+		WHERE OWNER = :1 AND CONSTRAINT_TYPE = 'R' AND TABLE_NAME NOT LIKE 'BIN$%'`
+	foreignKeyRows, err := conn.QueryContext(ctx, foreignKeyQuery, schema)
+	if err != nil {
+		return nil, util.FormatErrorWithQuery(err, foreignKeyQuery)
+	}
+	defer foreignKeyRows.Close()
+
+	for foreignKeyRows.Next() {
+		var name, ddl string
+		if err := foreignKeyRows.Scan(&name, &ddl); err != nil {
+			return nil, err
+		}
+		objectList = append(objectList, &schemaObject{
+			kind: foreignKeyObject,
+			name: name,
+			ddl:  normalizeDDL(schema, ddl),
+		})
+	}
+	if err := foreignKeyRows.Err(); err != nil {
+		return nil, err
+	}
+	return objectList, nil
+}
+
+// normalizeDDL strips the parts of the DDL that differ between the schemas with the same objects, i.e. the owner
+// qualifying the names, the names of the system-named constraints and the next value of the sequences.
+func normalizeDDL(schema, ddl string) string {
+	ddl = strings.TrimSpace(ddl)
+	ddl = strings.ReplaceAll(ddl, quoteIdentifier(schema)+".", "")
+	ddl = systemConstraintNameRegexp.ReplaceAllString(ddl, "")
+	ddl = sequenceStartWithRegexp.ReplaceAllString(ddl, "")
+	return ddl
+}
+
+// sortObjectList sorts the objects by kind and then by DDL, so that the dump doesn't depend on the order the catalog
+// views return the rows, nor on the names of the system-named constraints.
+func sortObjectList(objectList []*schemaObject) {
+	sort.Slice(objectList, func(i, j int) bool {
+		if objectList[i].kind != objectList[j].kind {
+			return objectList[i].kind < objectList[j].kind
+		}
+		return objectList[i].ddl < objectList[j].ddl
+	})
+}
+
+// writeObjectList writes the DDLs of the objects sorted by sortObjectList, the sequences and tables come with a comment header.
+func writeObjectList(out io.Writer, objectList []*schemaObject) error {
+	sortObjectList(objectList)
+	for _, object := range objectList {
+		var stmt string
+		if header := object.kind.header(); header != "" {
+			stmt = fmt.Sprintf("--\n-- %s structure for %s\n--\n", header, quoteIdentifier(object.name))
+		}
+		stmt += object.ddl + "\n\n"
+		if _, err := io.WriteString(out, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quoteIdentifier quotes the identifier with double quotes, e.g. "ORDER".
+func quoteIdentifier(name string) string {
+	return fmt.Sprintf(`"%s"`, strings.ReplaceAll(name, `"`, `""`))
+}
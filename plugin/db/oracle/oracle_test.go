@@ -0,0 +1,131 @@
+package oracle
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/bytebase/bytebase/plugin/db"
+)
+
+func TestFormatColumnType(t *testing.T) {
+	null := sql.NullInt64{}
+	value := func(v int64) sql.NullInt64 {
+		return sql.NullInt64{Int64: v, Valid: true}
+	}
+	tests := []struct {
+		dataType   string
+		charLength int
+		dataLength int
+		precision  sql.NullInt64
+		scale      sql.NullInt64
+		want       string
+	}{
+		{"VARCHAR2", 50, 200, null, null, "VARCHAR2(50)"},
+		{"NCHAR", 10, 20, null, null, "NCHAR(10)"},
+		{"RAW", 0, 16, null, null, "RAW(16)"},
+		{"NUMBER", 0, 22, null, null, "NUMBER"},
+		{"NUMBER", 0, 22, null, value(0), "NUMBER(*,0)"},
+		{"NUMBER", 0, 22, value(10), value(0), "NUMBER(10)"},
+		{"NUMBER", 0, 22, value(18), value(2), "NUMBER(18,2)"},
+		{"FLOAT", 0, 22, value(126), null, "FLOAT(126)"},
+		{"TIMESTAMP(6)", 0, 11, null, value(6), "TIMESTAMP(6)"},
+		{"DATE", 0, 7, null, null, "DATE"},
+	}
+	for _, test := range tests {
+		if got := formatColumnType(test.dataType, test.charLength, test.dataLength, test.precision, test.scale); got != test.want {
+			t.Errorf("formatColumnType(%q, %d, %d, %v, %v) = %q, want %q", test.dataType, test.charLength, test.dataLength, test.precision, test.scale, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeDDL(t *testing.T) {
+	tests := []struct {
+		name string
+		ddl  string
+		want string
+	}{
+		{
+			"table",
+			"\n  CREATE TABLE \"APP\".\"BOOK\" \n   (\t\"ID\" NUMBER NOT NULL ENABLE, \n\t CONSTRAINT \"SYS_C008123\" PRIMARY KEY (\"ID\")\n  USING INDEX  ENABLE\n   ) ;\n  ",
+			"CREATE TABLE \"BOOK\" \n   (\t\"ID\" NUMBER NOT NULL ENABLE, \n\t PRIMARY KEY (\"ID\")\n  USING INDEX  ENABLE\n   ) ;",
+		},
+		{
+			"namedConstraint",
+			"ALTER TABLE \"APP\".\"BOOK\" ADD CONSTRAINT \"FK_BOOK_AUTHOR\" FOREIGN KEY (\"AUTHOR_ID\")\n\t  REFERENCES \"APP\".\"AUTHOR\" (\"ID\") ENABLE;",
+			"ALTER TABLE \"BOOK\" ADD CONSTRAINT \"FK_BOOK_AUTHOR\" FOREIGN KEY (\"AUTHOR_ID\")\n\t  REFERENCES \"AUTHOR\" (\"ID\") ENABLE;",
+		},
+		{
+			"sequence",
+			"CREATE SEQUENCE  \"APP\".\"BOOK_SEQ\"  MINVALUE 1 MAXVALUE 9999999999999999999999999999 INCREMENT BY 1 START WITH 1041 CACHE 20 NOORDER  NOCYCLE ;",
+			"CREATE SEQUENCE  \"BOOK_SEQ\"  MINVALUE 1 MAXVALUE 9999999999999999999999999999 INCREMENT BY 1 CACHE 20 NOORDER  NOCYCLE ;",
+		},
+	}
+	for _, test := range tests {
+		if got := normalizeDDL("APP", test.ddl); got != test.want {
+			t.Errorf("%s: normalizeDDL() =\n%q\nwant\n%q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestWriteObjectList(t *testing.T) {
+	newObjectList := func(reverse bool) []*schemaObject {
+		objectList := []*schemaObject{
+			{kind: sequenceObject, name: "BOOK_SEQ", ddl: `CREATE SEQUENCE "BOOK_SEQ" INCREMENT BY 1 CACHE 20;`},
+			{kind: tableObject, name: "AUTHOR", ddl: `CREATE TABLE "AUTHOR" ("ID" NUMBER, PRIMARY KEY ("ID") ENABLE);`},
+			{kind: tableObject, name: "BOOK", ddl: `CREATE TABLE "BOOK" ("ID" NUMBER, "AUTHOR_ID" NUMBER);`},
+			{kind: indexObject, name: "IDX_BOOK_AUTHOR_ID", ddl: `CREATE INDEX "IDX_BOOK_AUTHOR_ID" ON "BOOK" ("AUTHOR_ID");`},
+			{kind: foreignKeyObject, name: "SYS_C008124", ddl: `ALTER TABLE "BOOK" ADD FOREIGN KEY ("AUTHOR_ID") REFERENCES "AUTHOR" ("ID") ENABLE;`},
+		}
+		if reverse {
+			for i, j := 0, len(objectList)-1; i < j; i, j = i+1, j-1 {
+				objectList[i], objectList[j] = objectList[j], objectList[i]
+			}
+		}
+		return objectList
+	}
+
+	want := strings.Join([]string{
+		"--",
+		`-- Sequence structure for "BOOK_SEQ"`,
+		"--",
+		`CREATE SEQUENCE "BOOK_SEQ" INCREMENT BY 1 CACHE 20;`,
+		"",
+		"--",
+		`-- Table structure for "AUTHOR"`,
+		"--",
+		`CREATE TABLE "AUTHOR" ("ID" NUMBER, PRIMARY KEY ("ID") ENABLE);`,
+		"",
+		"--",
+		`-- Table structure for "BOOK"`,
+		"--",
+		`CREATE TABLE "BOOK" ("ID" NUMBER, "AUTHOR_ID" NUMBER);`,
+		"",
+		`CREATE INDEX "IDX_BOOK_AUTHOR_ID" ON "BOOK" ("AUTHOR_ID");`,
+		"",
+		`ALTER TABLE "BOOK" ADD FOREIGN KEY ("AUTHOR_ID") REFERENCES "AUTHOR" ("ID") ENABLE;`,
+		"",
+		"",
+	}, "\n")
+	for _, reverse := range []bool{false, true} {
+		var buf strings.Builder
+		if err := writeObjectList(&buf, newObjectList(reverse)); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != want {
+			t.Errorf("writeObjectList(reverse=%v) =\n%s\nwant\n%s", reverse, got, want)
+		}
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	if got, want := quoteIdentifier(`a"b`), `"a""b"`; got != want {
+		t.Errorf("quoteIdentifier() = %q, want %q", got, want)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	if got, want := (&Driver{}).Capabilities(), (db.Capabilities{}); got != want {
+		t.Errorf("Capabilities() = %+v, want %+v", got, want)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"fmt"
 	"io"
+	"net"
 	"regexp"
 	"strings"
 	"time"
@@ -13,6 +14,7 @@ import (
 	// embed will embeds the migration schema.
 	_ "embed"
 
+	"github.com/bytebase/bytebase/common"
 	"github.com/bytebase/bytebase/plugin/db"
 	"github.com/bytebase/bytebase/plugin/db/util"
 	"go.uber.org/zap"
@@ -197,6 +199,8 @@ type Driver struct {
 
 	db      *sql.DB
 	baseDSN string
+	// tlsRequired is whether the connection is configured to use SSL.
+	tlsRequired bool
 }
 
 func newDriver(config db.DriverConfig) db.Driver {
@@ -234,6 +238,7 @@ func (driver *Driver) Open(ctx context.Context, dbType db.Type, config db.Connec
 	driver.db = db
 	driver.baseDSN = dsn
 	driver.connectionCtx = connCtx
+	driver.tlsRequired = config.TLSConfig.SslCA != ""
 
 	return driver, nil
 }
@@ -392,6 +397,17 @@ func (driver *Driver) SyncSchema(ctx context.Context) ([]*db.User, []*db.Schema,
 			indicesMap[key] = append(indicesMap[key], idx)
 		}
 
+		// Foreign key statements.
+		foreignKeysMap := make(map[string][]*foreignKeySchema)
+		foreignKeys, err := getForeignKeys(txn)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get foreign keys from database %q: %s", dbName, err)
+		}
+		for _, fk := range foreignKeys {
+			key := fmt.Sprintf("%s.%s", fk.schemaName, fk.tableName)
+			foreignKeysMap[key] = append(foreignKeysMap[key], fk)
+		}
+
 		// Table statements.
 		tables, err := getPgTables(txn)
 		if err != nil {
@@ -429,6 +445,13 @@ func (driver *Driver) SyncSchema(ctx context.Context) ([]*db.User, []*db.Schema,
 					dbTable.IndexList = append(dbTable.IndexList, dbIndex)
 				}
 			}
+			for _, fk := range foreignKeysMap[dbTable.Name] {
+				dbTable.ForeignKeyList = append(dbTable.ForeignKeyList, db.ForeignKey{
+					Name:            fk.name,
+					ColumnList:      fk.columnList,
+					ReferencedTable: fk.referencedTable,
+				})
+			}
 
 			schema.TableList = append(schema.TableList, dbTable)
 		}
@@ -519,6 +542,119 @@ func (driver *Driver) Execute(ctx context.Context, statement string) error {
 	return err
 }
 
+// Capabilities returns the optional features supported for Postgres.
+func (driver *Driver) Capabilities() db.Capabilities {
+	return db.Capabilities{
+		SupportsTxList:            true,
+		SupportsReplicationStatus: true,
+		SupportsTLSState:          true,
+		SupportsIndexInspection:   true,
+		SupportsMigrationHistory:  true,
+	}
+}
+
+// FindLongRunningTransactionList finds the transactions on the database running longer than the threshold.
+func (driver *Driver) FindLongRunningTransactionList(ctx context.Context, database string, threshold time.Duration) ([]*db.Transaction, error) {
+	query := `
+		SELECT
+			pid,
+			CAST(EXTRACT(EPOCH FROM (now() - xact_start)) AS BIGINT),
+			COALESCE(query, '')
+		FROM pg_stat_activity
+		WHERE datname = $1 AND xact_start IS NOT NULL AND xact_start < now() - make_interval(secs => $2)
+		ORDER BY xact_start`
+	rows, err := driver.db.QueryContext(ctx, query, database, threshold.Seconds())
+	if err != nil {
+		return nil, util.FormatErrorWithQuery(err, query)
+	}
+	defer rows.Close()
+
+	var list []*db.Transaction
+	for rows.Next() {
+		var transaction db.Transaction
+		var durationSecond int64
+		if err := rows.Scan(
+			&transaction.PID,
+			&durationSecond,
+			&transaction.Query,
+		); err != nil {
+			return nil, err
+		}
+		transaction.Duration = time.Duration(durationSecond) * time.Second
+		list = append(list, &transaction)
+	}
+	return list, nil
+}
+
+// GetDiskUsage is not supported for Postgres since the disk usage isn't exposed through SQL.
+func (driver *Driver) GetDiskUsage(ctx context.Context) (*db.DiskUsage, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("getting disk usage is not supported for Postgres"))
+}
+
+// GetConnectionTLSState gets the TLS state of the connection of the driver.
+// The server certificate is unknown since it isn't exposed by either the driver or SQL.
+func (driver *Driver) GetConnectionTLSState(ctx context.Context) (*db.ConnectionTLSState, error) {
+	var ssl bool
+	query := "SELECT ssl FROM pg_stat_ssl WHERE pid = pg_backend_pid()"
+	if err := driver.db.QueryRowContext(ctx, query).Scan(&ssl); err != nil {
+		return nil, util.FormatErrorWithQuery(err, query)
+	}
+	return &db.ConnectionTLSState{
+		Required:  driver.tlsRequired,
+		Encrypted: ssl,
+	}, nil
+}
+
+// ReplicationStatus gets the replay delay of the standby, and the primary from the connection info of the WAL receiver.
+// The lag is 0 if the standby has replayed all the WAL received from the primary.
+// The connection info is only visible to the superusers and the members of pg_read_all_stats, the primary is empty otherwise.
+func (driver *Driver) ReplicationStatus(ctx context.Context) (*db.ReplicationStatus, error) {
+	query := `
+		SELECT
+			pg_is_in_recovery(),
+			CASE
+				WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+				ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+			END,
+			COALESCE((SELECT conninfo FROM pg_stat_wal_receiver LIMIT 1), '')`
+	var inRecovery bool
+	var lagSeconds float64
+	var connInfo string
+	if err := driver.db.QueryRowContext(ctx, query).Scan(&inRecovery, &lagSeconds, &connInfo); err != nil {
+		return nil, util.FormatErrorWithQuery(err, query)
+	}
+	if !inRecovery {
+		return nil, fmt.Errorf("instance is not a replica")
+	}
+	return &db.ReplicationStatus{
+		Lag:     time.Duration(lagSeconds * float64(time.Second)),
+		Primary: getConnInfoPrimary(connInfo),
+	}, nil
+}
+
+// getConnInfoPrimary returns the "host:port" in the libpq connection string of the WAL receiver, e.g. "host=10.0.0.1 port=5432 user=replicator".
+// The port defaults to 5432, and the primary is empty if the host isn't set.
+func getConnInfoPrimary(connInfo string) string {
+	host, port := "", "5432"
+	for _, field := range strings.Fields(connInfo) {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.Trim(kv[1], "'")
+		switch kv[0] {
+		case "host":
+			host = value
+		case "port":
+			port = value
+		}
+	}
+	if host == "" {
+		return ""
+	}
+	return net.JoinHostPort(host, port)
+}
+
 // NeedsSetupMigration returns whether it needs to setup migration.
 func (driver *Driver) NeedsSetupMigration(ctx context.Context) (bool, error) {
 	exist, err := driver.hasBytebaseDatabase(ctx)
@@ -1005,6 +1141,15 @@ type indexSchema struct {
 	comment           string
 }
 
+// foreignKeySchema describes the schema of a pg foreign key constraint.
+type foreignKeySchema struct {
+	schemaName      string
+	tableName       string
+	name            string
+	columnList      []string
+	referencedTable string
+}
+
 // sequencePgSchema describes the schema of a pg sequence.
 type sequencePgSchema struct {
 	schemaName   string
@@ -1503,6 +1648,53 @@ func getIndices(txn *sql.Tx) ([]*indexSchema, error) {
 	return indices, nil
 }
 
+// getForeignKeys gets all foreign key constraints of a database.
+func getForeignKeys(txn *sql.Tx) ([]*foreignKeySchema, error) {
+	query := "" +
+		"SELECT n.nspname, c.relname, con.conname, a.attname, rn.nspname, rc.relname " +
+		"FROM pg_constraint con " +
+		"JOIN pg_class c ON c.oid = con.conrelid " +
+		"JOIN pg_namespace n ON n.oid = c.relnamespace " +
+		"JOIN pg_class rc ON rc.oid = con.confrelid " +
+		"JOIN pg_namespace rn ON rn.oid = rc.relnamespace " +
+		"CROSS JOIN LATERAL unnest(con.conkey) WITH ORDINALITY AS k(attnum, ord) " +
+		"JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = k.attnum " +
+		"WHERE con.contype = 'f' AND n.nspname NOT IN ('pg_catalog', 'information_schema') " +
+		"ORDER BY n.nspname, c.relname, con.conname, k.ord;"
+
+	var foreignKeys []*foreignKeySchema
+	rows, err := txn.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var schemaName, tableName, name, columnName, referencedSchemaName, referencedTableName string
+		if err := rows.Scan(&schemaName, &tableName, &name, &columnName, &referencedSchemaName, &referencedTableName); err != nil {
+			return nil, err
+		}
+		schemaName, tableName, name = quoteIdentifier(schemaName), quoteIdentifier(tableName), quoteIdentifier(name)
+		// Rows are ordered by constraint, so a multi-column key always extends the last entry.
+		if n := len(foreignKeys); n > 0 {
+			last := foreignKeys[n-1]
+			if last.schemaName == schemaName && last.tableName == tableName && last.name == name {
+				last.columnList = append(last.columnList, quoteIdentifier(columnName))
+				continue
+			}
+		}
+		foreignKeys = append(foreignKeys, &foreignKeySchema{
+			schemaName:      schemaName,
+			tableName:       tableName,
+			name:            name,
+			columnList:      []string{quoteIdentifier(columnName)},
+			referencedTable: fmt.Sprintf("%s.%s", quoteIdentifier(referencedSchemaName), quoteIdentifier(referencedTableName)),
+		})
+	}
+
+	return foreignKeys, nil
+}
+
 func getIndex(txn *sql.Tx, idx *indexSchema) error {
 	commentQuery := fmt.Sprintf("SELECT obj_description('%s.%s'::regclass);", idx.schemaName, idx.name)
 	crows, err := txn.Query(commentQuery)
@@ -1596,6 +1788,7 @@ func exportTableData(txn *sql.Tx, tbl *tableSchema, out io.Writer) error {
 	for i := 0; i < len(cols); i++ {
 		ptrs[i] = &values[i]
 	}
+	w := util.NewInsertStatementWriter(out, fmt.Sprintf("%s.%s", tbl.schemaName, tbl.name), util.DataDumpBatchSize)
 	for rows.Next() {
 		if err := rows.Scan(ptrs...); err != nil {
 			return err
@@ -1608,20 +1801,35 @@ func exportTableData(txn *sql.Tx, tbl *tableSchema, out io.Writer) error {
 			case isNumeric(cols[i].ScanType().Name()):
 				tokens[i] = v.String
 			default:
-				tokens[i] = fmt.Sprintf("'%s'", v.String)
+				tokens[i] = quoteString(v.String)
 			}
 		}
-		stmt := fmt.Sprintf("INSERT INTO %s.%s VALUES (%s);\n", tbl.schemaName, tbl.name, strings.Join(tokens, ", "))
-		if _, err := io.WriteString(out, stmt); err != nil {
+		if err := w.WriteRow(tokens); err != nil {
 			return err
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
 	if _, err := io.WriteString(out, "\n"); err != nil {
 		return err
 	}
 	return nil
 }
 
+// quoteString quotes the string value. The value with backslashes or line breaks is quoted as an escape string,
+// which keeps the line breaks out of the dumped row, otherwise as a standard string.
+func quoteString(s string) string {
+	if !strings.ContainsAny(s, "\\\n\r") {
+		return fmt.Sprintf("'%s'", strings.ReplaceAll(s, "'", "''"))
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, "'", "''")
+	return fmt.Sprintf("E'%s'", replacer.Replace(s))
+}
+
 // isNumeric determines whether the value needs quotes.
 // Even if the function returns incorrect result, the data dump will still work.
 func isNumeric(t string) bool {
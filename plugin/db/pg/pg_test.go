@@ -0,0 +1,57 @@
+package pg
+
+import (
+	"testing"
+
+	"github.com/bytebase/bytebase/plugin/db"
+)
+
+func TestQuoteString(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain", `'plain'`},
+		{"", `''`},
+		{"it's", `'it''s'`},
+		{`back\slash`, `E'back\\slash'`},
+		{"line\nbreak;", `E'line\nbreak;'`},
+		{"it's\r\n", `E'it''s\r\n'`},
+	}
+	for _, test := range tests {
+		if got := quoteString(test.value); got != test.want {
+			t.Errorf("quoteString(%q) = %s, want %s", test.value, got, test.want)
+		}
+	}
+}
+
+func TestGetConnInfoPrimary(t *testing.T) {
+	tests := []struct {
+		connInfo string
+		want     string
+	}{
+		{"user=replicator password=******** dbname=replication host=10.0.0.1 port=5433 fallback_application_name=walreceiver", "10.0.0.1:5433"},
+		{"host=primary.example.com user=replicator", "primary.example.com:5432"},
+		{"host='fe80::1' port=5432", "[fe80::1]:5432"},
+		{"user=replicator", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := getConnInfoPrimary(test.connInfo); got != test.want {
+			t.Errorf("getConnInfoPrimary(%q) = %q, want %q", test.connInfo, got, test.want)
+		}
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	want := db.Capabilities{
+		SupportsTxList:            true,
+		SupportsReplicationStatus: true,
+		SupportsTLSState:          true,
+		SupportsIndexInspection:   true,
+		SupportsMigrationHistory:  true,
+	}
+	if got := (&Driver{}).Capabilities(); got != want {
+		t.Errorf("Capabilities() = %+v, want %+v", got, want)
+	}
+}
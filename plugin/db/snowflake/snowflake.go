@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	// embed will embeds the migration schema.
 	_ "embed"
 
+	"github.com/bytebase/bytebase/common"
 	"github.com/bytebase/bytebase/plugin/db"
 	"github.com/bytebase/bytebase/plugin/db/util"
 	snow "github.com/snowflakedb/gosnowflake"
@@ -512,6 +514,37 @@ func (driver *Driver) Execute(ctx context.Context, statement string) error {
 	return err
 }
 
+// Capabilities returns the optional features supported for Snowflake.
+func (driver *Driver) Capabilities() db.Capabilities {
+	return db.Capabilities{
+		SupportsTLSState:         true,
+		SupportsMigrationHistory: true,
+	}
+}
+
+// FindLongRunningTransactionList is not supported for Snowflake.
+func (driver *Driver) FindLongRunningTransactionList(ctx context.Context, database string, threshold time.Duration) ([]*db.Transaction, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("listing transactions is not supported for Snowflake"))
+}
+
+// GetDiskUsage is not supported for Snowflake since the disk usage isn't exposed through SQL.
+func (driver *Driver) GetDiskUsage(ctx context.Context) (*db.DiskUsage, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("getting disk usage is not supported for Snowflake"))
+}
+
+// GetConnectionTLSState returns the connection is encrypted since Snowflake is only accessible over HTTPS.
+func (driver *Driver) GetConnectionTLSState(ctx context.Context) (*db.ConnectionTLSState, error) {
+	return &db.ConnectionTLSState{
+		Required:  true,
+		Encrypted: true,
+	}, nil
+}
+
+// ReplicationStatus is not supported for Snowflake.
+func (driver *Driver) ReplicationStatus(ctx context.Context) (*db.ReplicationStatus, error) {
+	return nil, common.Errorf(common.NotImplemented, fmt.Errorf("getting replication status is not supported for Snowflake"))
+}
+
 // NeedsSetupMigration returns whether it needs to setup migration.
 func (driver *Driver) NeedsSetupMigration(ctx context.Context) (bool, error) {
 	exist, err := driver.hasBytebaseDatabase(ctx)
@@ -0,0 +1,17 @@
+package snowflake
+
+import (
+	"testing"
+
+	"github.com/bytebase/bytebase/plugin/db"
+)
+
+func TestCapabilities(t *testing.T) {
+	want := db.Capabilities{
+		SupportsTLSState:         true,
+		SupportsMigrationHistory: true,
+	}
+	if got := (&Driver{}).Capabilities(); got != want {
+		t.Errorf("Capabilities() = %+v, want %+v", got, want)
+	}
+}
@@ -1,10 +1,12 @@
 package db
 
 import (
+	"bytes"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"io/ioutil"
+	"sync"
 )
 
 // TLSConfig is the configuration for SSL connection.
@@ -62,3 +64,47 @@ func (tc TLSConfig) GetSslConfig() (*tls.Config, error) {
 	}
 	return cfg, nil
 }
+
+// PeerCertificateRecorder records the leaf certificate presented by the server in the latest TLS handshake.
+type PeerCertificateRecorder struct {
+	mu   sync.Mutex
+	cert *x509.Certificate
+}
+
+// Record makes the handshakes with cfg record the server certificate once it passes the verification of cfg.
+func (r *PeerCertificateRecorder) Record(cfg *tls.Config) {
+	verify := cfg.VerifyPeerCertificate
+	cfg.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if verify != nil {
+			if err := verify(rawCerts, verifiedChains); err != nil {
+				return err
+			}
+		}
+		if len(rawCerts) == 0 {
+			return nil
+		}
+		cert, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return err
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.cert = cert
+		return nil
+	}
+}
+
+// Certificate returns the recorded server certificate, nil if no handshake has been made.
+func (r *PeerCertificateRecorder) Certificate() *x509.Certificate {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cert
+}
+
+// IsSelfSignedCertificate returns whether the certificate is issued by its own subject and signed by its own key.
+func IsSelfSignedCertificate(cert *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		return false
+	}
+	return cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// newTestCertificate creates a certificate of the subject signed by the parent, or a self-signed one if parent is nil.
+func newTestCertificate(t *testing.T, subject string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: subject},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  parent == nil,
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestIsSelfSignedCertificate(t *testing.T) {
+	ca, caKey := newTestCertificate(t, "ca", nil, nil)
+	server, _ := newTestCertificate(t, "server", ca, caKey)
+	// The certificate issued by a CA of the same name isn't self-signed.
+	impostor, _ := newTestCertificate(t, "ca", ca, caKey)
+
+	tests := []struct {
+		name string
+		cert *x509.Certificate
+		want bool
+	}{
+		{"selfSigned", ca, true},
+		{"issuedByCA", server, false},
+		{"sameNameAsIssuer", impostor, false},
+	}
+	for _, tt := range tests {
+		if got := IsSelfSignedCertificate(tt.cert); got != tt.want {
+			t.Errorf("%s: IsSelfSignedCertificate() = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPeerCertificateRecorder(t *testing.T) {
+	cert, _ := newTestCertificate(t, "server", nil, nil)
+	var r PeerCertificateRecorder
+	if r.Certificate() != nil {
+		t.Fatalf("expect no certificate before the handshake")
+	}
+
+	// The certificate failing the verification isn't recorded.
+	cfg := &tls.Config{
+		VerifyPeerCertificate: func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+			return x509.UnknownAuthorityError{}
+		},
+	}
+	r.Record(cfg)
+	if err := cfg.VerifyPeerCertificate([][]byte{cert.Raw}, nil); err == nil {
+		t.Fatalf("expect the verification error to be returned")
+	}
+	if r.Certificate() != nil {
+		t.Fatalf("expect the certificate failing the verification not to be recorded")
+	}
+
+	cfg = &tls.Config{}
+	r.Record(cfg)
+	if err := cfg.VerifyPeerCertificate([][]byte{cert.Raw}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Certificate(); got == nil || !got.Equal(cert) {
+		t.Errorf("Certificate() = %v, want the server certificate", got)
+	}
+}
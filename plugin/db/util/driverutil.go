@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/bytebase/bytebase/common"
 	"github.com/bytebase/bytebase/plugin/db"
@@ -518,25 +519,8 @@ func FindMigrationHistoryList(ctx context.Context, dbType db.Type, driver db.Dri
 	}
 	defer tx.Rollback()
 
-	queryParams := &db.QueryParams{DatabaseType: dbType}
-	if v := find.ID; v != nil {
-		queryParams.AddParam("id", *v)
-	}
-	if v := find.Database; v != nil {
-		queryParams.AddParam("namespace", *v)
-	}
-	if v := find.Version; v != nil {
-		queryParams.AddParam("version", *v)
-	}
-
-	var query = baseQuery +
-		queryParams.QueryString() +
-		`ORDER BY created_ts DESC`
-	if v := find.Limit; v != nil {
-		query += fmt.Sprintf(" LIMIT %d", *v)
-	}
-
-	rows, err := tx.QueryContext(ctx, query, queryParams.Params...)
+	query, args := findMigrationHistoryQuery(dbType, find, baseQuery)
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, FormatErrorWithQuery(err, query)
 	}
@@ -583,6 +567,51 @@ func FindMigrationHistoryList(ctx context.Context, dbType db.Type, driver db.Dri
 	return list, nil
 }
 
+// findMigrationHistoryQuery returns the query and its arguments for the migration history list matching find, most recent first.
+func findMigrationHistoryQuery(dbType db.Type, find *db.MigrationHistoryFind, baseQuery string) (string, []interface{}) {
+	queryParams := &db.QueryParams{DatabaseType: dbType}
+	if v := find.ID; v != nil {
+		queryParams.AddParam("id", *v)
+	}
+	if v := find.Database; v != nil {
+		queryParams.AddParam("namespace", *v)
+	}
+	if v := find.Version; v != nil {
+		queryParams.AddParam("version", *v)
+	}
+	if v := find.VersionPrefix; v != nil {
+		// Compare the leading characters rather than using LIKE, which would need escaping the wildcards in the prefix.
+		substr := "SUBSTR"
+		if dbType == db.ClickHouse {
+			// ClickHouse SUBSTR counts bytes.
+			substr = "substringUTF8"
+		}
+		queryParams.AddParam(fmt.Sprintf("%s(version, 1, %d) = ?", substr, utf8.RuneCountInString(*v)), *v)
+	}
+	if v := find.Status; v != nil {
+		queryParams.AddParam("status", string(*v))
+	}
+
+	var query = baseQuery +
+		queryParams.QueryString() +
+		`ORDER BY created_ts DESC`
+	if v := find.Limit; v != nil {
+		query += fmt.Sprintf(" LIMIT %d", *v)
+	} else if find.Offset != nil {
+		// Only Postgres allows OFFSET without LIMIT.
+		switch dbType {
+		case db.MySQL, db.TiDB, db.ClickHouse:
+			query += " LIMIT 18446744073709551615"
+		case db.Snowflake:
+			query += " LIMIT NULL"
+		}
+	}
+	if v := find.Offset; v != nil {
+		query += fmt.Sprintf(" OFFSET %d", *v)
+	}
+	return query, queryParams.Params
+}
+
 func formatError(err error) error {
 	if err == nil {
 		return nil
@@ -0,0 +1,93 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bytebase/bytebase/plugin/db"
+)
+
+func TestFindMigrationHistoryQuery(t *testing.T) {
+	const baseQuery = "SELECT id FROM migration_history "
+	database, prefix, status := "db", "1.2", db.Failed
+	limit, offset := 10, 20
+	tests := []struct {
+		name      string
+		dbType    db.Type
+		find      *db.MigrationHistoryFind
+		wantQuery string
+		wantArgs  []interface{}
+	}{
+		{
+			name:      "limit",
+			dbType:    db.MySQL,
+			find:      &db.MigrationHistoryFind{Database: &database, Limit: &limit},
+			wantQuery: baseQuery + "WHERE namespace = ? ORDER BY created_ts DESC LIMIT 10",
+			wantArgs:  []interface{}{database},
+		},
+		{
+			name:      "limitOffset",
+			dbType:    db.Postgres,
+			find:      &db.MigrationHistoryFind{Limit: &limit, Offset: &offset},
+			wantQuery: baseQuery + "ORDER BY created_ts DESC LIMIT 10 OFFSET 20",
+		},
+		{
+			name:      "offsetMySQL",
+			dbType:    db.MySQL,
+			find:      &db.MigrationHistoryFind{Offset: &offset},
+			wantQuery: baseQuery + "ORDER BY created_ts DESC LIMIT 18446744073709551615 OFFSET 20",
+		},
+		{
+			name:      "offsetPostgres",
+			dbType:    db.Postgres,
+			find:      &db.MigrationHistoryFind{Offset: &offset},
+			wantQuery: baseQuery + "ORDER BY created_ts DESC OFFSET 20",
+		},
+		{
+			name:      "offsetSnowflake",
+			dbType:    db.Snowflake,
+			find:      &db.MigrationHistoryFind{Offset: &offset},
+			wantQuery: baseQuery + "ORDER BY created_ts DESC LIMIT NULL OFFSET 20",
+		},
+		{
+			name:      "statusMySQL",
+			dbType:    db.MySQL,
+			find:      &db.MigrationHistoryFind{Database: &database, Status: &status},
+			wantQuery: baseQuery + "WHERE namespace = ? AND status = ? ORDER BY created_ts DESC",
+			wantArgs:  []interface{}{database, "FAILED"},
+		},
+		{
+			name:      "statusPostgres",
+			dbType:    db.Postgres,
+			find:      &db.MigrationHistoryFind{Database: &database, Status: &status},
+			wantQuery: baseQuery + "WHERE namespace=$1 AND status=$2 ORDER BY created_ts DESC",
+			wantArgs:  []interface{}{database, "FAILED"},
+		},
+		{
+			name:      "versionPrefix",
+			dbType:    db.Postgres,
+			find:      &db.MigrationHistoryFind{VersionPrefix: &prefix},
+			wantQuery: baseQuery + "WHERE SUBSTR(version, 1, 3) = $1 ORDER BY created_ts DESC",
+			wantArgs:  []interface{}{prefix},
+		},
+		{
+			name:      "versionPrefixClickHouse",
+			dbType:    db.ClickHouse,
+			find:      &db.MigrationHistoryFind{VersionPrefix: &prefix},
+			wantQuery: baseQuery + "WHERE substringUTF8(version, 1, 3) = ? ORDER BY created_ts DESC",
+			wantArgs:  []interface{}{prefix},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, args := findMigrationHistoryQuery(test.dbType, test.find, baseQuery)
+			if query != test.wantQuery {
+				t.Errorf("query = %q, want %q", query, test.wantQuery)
+			}
+			if !reflect.DeepEqual(args, test.wantArgs) {
+				t.Errorf("args = %v, want %v", args, test.wantArgs)
+			}
+		})
+	}
+}
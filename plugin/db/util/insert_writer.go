@@ -0,0 +1,57 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DataDumpBatchSize is the max number of rows in an INSERT statement of the data dump.
+const DataDumpBatchSize = 100
+
+// InsertStatementWriter writes the table rows as multi-row INSERT statements of up to batchSize rows.
+// The rows are written as they come, so the data dump doesn't hold the table in memory.
+// Each row is on its own line and only the last line of a statement ends with ";", which is how
+// ApplyMultiStatements splits the statements on restore, so the values must not contain line breaks.
+type InsertStatementWriter struct {
+	out       io.Writer
+	table     string
+	batchSize int
+	// count is the number of rows written of the current statement.
+	count int
+}
+
+// NewInsertStatementWriter creates a new InsertStatementWriter for the quoted table.
+func NewInsertStatementWriter(out io.Writer, table string, batchSize int) *InsertStatementWriter {
+	return &InsertStatementWriter{
+		out:       out,
+		table:     table,
+		batchSize: batchSize,
+	}
+}
+
+// WriteRow writes a row of the quoted values.
+func (w *InsertStatementWriter) WriteRow(valueList []string) error {
+	prefix := ",\n"
+	if w.count == 0 {
+		prefix = fmt.Sprintf("INSERT INTO %s VALUES\n", w.table)
+	}
+	if _, err := io.WriteString(w.out, fmt.Sprintf("%s  (%s)", prefix, strings.Join(valueList, ", "))); err != nil {
+		return err
+	}
+	w.count++
+	if w.count >= w.batchSize {
+		return w.Close()
+	}
+	return nil
+}
+
+// Close ends the current statement if any.
+func (w *InsertStatementWriter) Close() error {
+	if w.count == 0 {
+		return nil
+	}
+	w.count = 0
+	_, err := io.WriteString(w.out, ";\n")
+	return err
+}
@@ -0,0 +1,54 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestInsertStatementWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		rowCount int
+		want     string
+	}{
+		{
+			name:     "empty",
+			rowCount: 0,
+			want:     "",
+		},
+		{
+			name:     "partialBatch",
+			rowCount: 1,
+			want:     "INSERT INTO t VALUES\n  (0, 'v');\n",
+		},
+		{
+			name:     "fullBatch",
+			rowCount: 2,
+			want:     "INSERT INTO t VALUES\n  (0, 'v'),\n  (1, 'v');\n",
+		},
+		{
+			name:     "multipleBatches",
+			rowCount: 3,
+			want:     "INSERT INTO t VALUES\n  (0, 'v'),\n  (1, 'v');\nINSERT INTO t VALUES\n  (2, 'v');\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out strings.Builder
+			w := NewInsertStatementWriter(&out, "t", 2)
+			for i := 0; i < test.rowCount; i++ {
+				if err := w.WriteRow([]string{fmt.Sprintf("%d", i), "'v'"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"time"
+
+	"github.com/bytebase/bytebase/api"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// anomalyMetricActionCreated is the action label of the anomalies newly opened by the scanner.
+	anomalyMetricActionCreated = "created"
+	// anomalyMetricActionArchived is the action label of the anomalies archived by the scanner.
+	anomalyMetricActionArchived = "archived"
+)
+
+// anomalyScannerMetrics is the Prometheus metrics of the anomaly scanner.
+// The metric names and labels are part of the monitoring interface, so they must stay stable.
+type anomalyScannerMetrics struct {
+	// instanceScanDuration is the duration of the instance scans labeled by the engine.
+	instanceScanDuration *prometheus.HistogramVec
+	// anomalyCount is the number of anomalies created and archived labeled by the type and the action.
+	anomalyCount *prometheus.CounterVec
+	// scanErrorCount is the number of the scans failing to complete, e.g. an instance or a database scan timing out.
+	scanErrorCount prometheus.Counter
+}
+
+func newAnomalyScannerMetrics() *anomalyScannerMetrics {
+	return &anomalyScannerMetrics{
+		instanceScanDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "bytebase",
+			Subsystem: "anomaly_scanner",
+			Name:      "instance_scan_duration_seconds",
+			Help:      "The duration of scanning an instance and its databases.",
+			// From 0.5 seconds to about 4 minutes, an instance scan is bounded by the scan timeout.
+			Buckets: prometheus.ExponentialBuckets(0.5, 2, 10),
+		}, []string{"engine"}),
+		anomalyCount: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "bytebase",
+			Subsystem: "anomaly_scanner",
+			Name:      "anomalies_total",
+			Help:      "The number of anomalies created and archived by the scanner.",
+		}, []string{"type", "action"}),
+		scanErrorCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "bytebase",
+			Subsystem: "anomaly_scanner",
+			Name:      "scan_errors_total",
+			Help:      "The number of the instance and database scans failing to complete.",
+		}),
+	}
+}
+
+func (m *anomalyScannerMetrics) observeInstanceScan(instance *api.Instance, duration time.Duration) {
+	m.instanceScanDuration.WithLabelValues(string(instance.Engine)).Observe(duration.Seconds())
+}
+
+func (m *anomalyScannerMetrics) countAnomaly(anomalyType api.AnomalyType, action string) {
+	m.anomalyCount.WithLabelValues(string(anomalyType), action).Inc()
+}
+
+// RegisterMetrics registers the Prometheus metrics of the scanner on the registerer,
+// including the gauge of the instance scans running at the moment.
+func (s *AnomalyScanner) RegisterMetrics(registerer prometheus.Registerer) error {
+	runningScans := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "bytebase",
+		Subsystem: "anomaly_scanner",
+		Name:      "running_scans",
+		Help:      "The number of instance scans running, including the manual ones.",
+	}, func() float64 {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return float64(len(s.runningTasks))
+	})
+	for _, collector := range []prometheus.Collector{
+		s.metrics.instanceScanDuration,
+		s.metrics.anomalyCount,
+		s.metrics.scanErrorCount,
+		runningScans,
+	} {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bytebase/bytebase/api"
+	"go.uber.org/zap"
+)
+
+// anomalyReport is the JSON report of the active anomalies written after each scan round.
+type anomalyReport struct {
+	StartedTs  int64 `json:"startedTs"`
+	FinishedTs int64 `json:"finishedTs"`
+	// Complete is false if the round didn't finish for every instance, in which case the anomalies of
+	// FailedInstanceList, or of any instance if the round was aborted, may be stale.
+	Complete           bool                     `json:"complete"`
+	FailedInstanceList []string                 `json:"failedInstanceList,omitempty"`
+	InstanceList       []*anomalyReportInstance `json:"instanceList"`
+}
+
+// anomalyReportInstance is the active anomalies of an instance and its databases.
+type anomalyReportInstance struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// AnomalyMap is the instance anomalies by type
+	AnomalyMap   map[api.AnomalyType]*anomalyReportAnomaly `json:"anomalyMap"`
+	DatabaseList []*anomalyReportDatabase                  `json:"databaseList"`
+}
+
+// anomalyReportDatabase is the active anomalies of a database.
+type anomalyReportDatabase struct {
+	ID         int                                       `json:"id"`
+	Name       string                                    `json:"name"`
+	AnomalyMap map[api.AnomalyType]*anomalyReportAnomaly `json:"anomalyMap"`
+}
+
+// anomalyReportAnomaly is an active anomaly in the report.
+type anomalyReportAnomaly struct {
+	Severity   api.AnomalySeverity `json:"severity"`
+	CreatedTs  int64               `json:"createdTs"`
+	LastSeenTs int64               `json:"lastSeenTs"`
+	// Payload is the type specific payload of the anomaly
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// writeAnomalyReportFile writes the report of the last completed round to the report path if it's configured.
+// The report is written to a temporary file first and then renamed, so that the readers never see a partial report.
+func (s *AnomalyScanner) writeAnomalyReportFile() {
+	if s.reportPath == "" {
+		return
+	}
+	if err := func() error {
+		f, err := os.CreateTemp(filepath.Dir(s.reportPath), filepath.Base(s.reportPath)+".*.tmp")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		if err := s.writeAnomalyReport(context.Background(), f, s.Stats()); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		return os.Rename(f.Name(), s.reportPath)
+	}(); err != nil {
+		s.l.Error("Failed to write anomaly report",
+			zap.String("path", s.reportPath),
+			zap.Error(err))
+	}
+}
+
+// writeAnomalyReport writes the JSON report of the currently active anomalies to w, stats is the statistics of the round.
+func (s *AnomalyScanner) writeAnomalyReport(ctx context.Context, w io.Writer, stats AnomalyScanStats) error {
+	report, err := s.composeAnomalyReport(ctx, stats)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func (s *AnomalyScanner) composeAnomalyReport(ctx context.Context, stats AnomalyScanStats) (*anomalyReport, error) {
+	status := api.Normal
+	anomalyList, err := s.server.AnomalyService.FindAnomalyList(ctx, &api.AnomalyFind{
+		RowStatus: &status,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find anomaly list: %w", err)
+	}
+	instanceList, err := s.server.InstanceService.FindInstanceList(ctx, &api.InstanceFind{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find instance list: %w", err)
+	}
+	databaseList, err := s.server.DatabaseService.FindDatabaseList(ctx, &api.DatabaseFind{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find database list: %w", err)
+	}
+	instanceNameMap := make(map[int]string)
+	for _, instance := range instanceList {
+		instanceNameMap[instance.ID] = instance.Name
+	}
+	databaseNameMap := make(map[int]string)
+	for _, database := range databaseList {
+		databaseNameMap[database.ID] = database.Name
+	}
+
+	instanceMap := make(map[int]*anomalyReportInstance)
+	databaseMap := make(map[int]*anomalyReportDatabase)
+	for _, anomaly := range anomalyList {
+		reportInstance, ok := instanceMap[anomaly.InstanceID]
+		if !ok {
+			reportInstance = &anomalyReportInstance{
+				ID:           anomaly.InstanceID,
+				Name:         instanceNameMap[anomaly.InstanceID],
+				AnomalyMap:   make(map[api.AnomalyType]*anomalyReportAnomaly),
+				DatabaseList: []*anomalyReportDatabase{},
+			}
+			instanceMap[anomaly.InstanceID] = reportInstance
+		}
+		reportAnomaly := &anomalyReportAnomaly{
+			Severity:   api.AnomalySeverityFromType(anomaly.Type),
+			CreatedTs:  anomaly.CreatedTs,
+			LastSeenTs: anomaly.LastSeenTs,
+		}
+		if anomaly.Payload != "" {
+			reportAnomaly.Payload = json.RawMessage(anomaly.Payload)
+		}
+		if anomaly.DatabaseID == nil {
+			reportInstance.AnomalyMap[anomaly.Type] = reportAnomaly
+			continue
+		}
+		reportDatabase, ok := databaseMap[*anomaly.DatabaseID]
+		if !ok {
+			reportDatabase = &anomalyReportDatabase{
+				ID:         *anomaly.DatabaseID,
+				Name:       databaseNameMap[*anomaly.DatabaseID],
+				AnomalyMap: make(map[api.AnomalyType]*anomalyReportAnomaly),
+			}
+			databaseMap[*anomaly.DatabaseID] = reportDatabase
+			reportInstance.DatabaseList = append(reportInstance.DatabaseList, reportDatabase)
+		}
+		reportDatabase.AnomalyMap[anomaly.Type] = reportAnomaly
+	}
+
+	report := &anomalyReport{
+		StartedTs:          stats.StartedTs,
+		FinishedTs:         stats.FinishedTs,
+		Complete:           !stats.Incomplete,
+		FailedInstanceList: stats.FailedInstanceList,
+		InstanceList:       []*anomalyReportInstance{},
+	}
+	for _, reportInstance := range instanceMap {
+		sort.Slice(reportInstance.DatabaseList, func(i, j int) bool {
+			return reportInstance.DatabaseList[i].Name < reportInstance.DatabaseList[j].Name
+		})
+		report.InstanceList = append(report.InstanceList, reportInstance)
+	}
+	sort.Slice(report.InstanceList, func(i, j int) bool {
+		return report.InstanceList[i].Name < report.InstanceList[j].Name
+	})
+	return report, nil
+}
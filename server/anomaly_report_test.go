@@ -0,0 +1,81 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bytebase/bytebase/api"
+)
+
+func TestWriteAnomalyReport(t *testing.T) {
+	ctx := context.Background()
+	s, anomalyService := newTestAnomalyScanner()
+	instance, database := newTestInstance()
+	slowDatabase := &api.Database{ID: 2, InstanceID: instance.ID, Name: "slow"}
+	s.server.BackupService = &fakeBackupService{}
+	s.server.InstanceService = &fakeInstanceService{list: []*api.Instance{instance}}
+	s.server.DatabaseService = &fakeDatabaseService{list: []*api.Database{database, slowDatabase}}
+	// The instance scan times out on the slow database while the database scan timeout isn't reached yet.
+	s.timeout = 50 * time.Millisecond
+	testDriver.blockDumpDatabase = slowDatabase.Name
+	// The anomalies of the checks the fake engine skips, so that they stay active through the scan.
+	for _, upsert := range []*api.AnomalyUpsert{
+		{CreatorID: api.SystemBotID, InstanceID: instance.ID, Type: api.AnomalyDatabaseConnectionCountHigh, Payload: `{"currentConnections":90,"maxConnections":100,"percentage":90}`},
+		{CreatorID: api.SystemBotID, InstanceID: instance.ID, DatabaseID: &database.ID, Type: api.AnomalyDatabaseIndexMissing},
+	} {
+		if _, _, err := anomalyService.UpsertActiveAnomaly(ctx, upsert); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.startRoundStats()
+	s.scanInstance(ctx, instance, &api.AnomalyPolicy{Enabled: true}, map[int]*api.BackupPlanPolicy{
+		instance.EnvironmentID: {Schedule: api.BackupPlanPolicyScheduleUnset},
+	}, nil)
+	s.finishRoundStats()
+
+	var buf bytes.Buffer
+	if err := s.writeAnomalyReport(ctx, &buf, s.Stats()); err != nil {
+		t.Fatal(err)
+	}
+	var report anomalyReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("failed to unmarshal report %s: %v", buf.String(), err)
+	}
+
+	if report.Complete {
+		t.Errorf("expect the report to be incomplete")
+	}
+	if want := []string{instance.Name}; !reflect.DeepEqual(report.FailedInstanceList, want) {
+		t.Errorf("failed instance list = %v, want %v", report.FailedInstanceList, want)
+	}
+	if len(report.InstanceList) != 1 || report.InstanceList[0].Name != instance.Name {
+		t.Fatalf("expect the report to have instance %q, got %s", instance.Name, buf.String())
+	}
+	reportInstance := report.InstanceList[0]
+	connectionCountHigh := reportInstance.AnomalyMap[api.AnomalyDatabaseConnectionCountHigh]
+	if connectionCountHigh == nil {
+		t.Fatalf("expect the connection count anomaly in the instance anomalies, got %s", buf.String())
+	}
+	var payload api.AnomalyDatabaseConnectionCountHighPayload
+	if err := json.Unmarshal(connectionCountHigh.Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if want := (api.AnomalyDatabaseConnectionCountHighPayload{CurrentConnections: 90, MaxConnections: 100, Percentage: 90}); payload != want {
+		t.Errorf("connection count payload = %+v, want %+v", payload, want)
+	}
+	databaseAnomalyMap := make(map[string]map[api.AnomalyType]*anomalyReportAnomaly)
+	for _, reportDatabase := range reportInstance.DatabaseList {
+		databaseAnomalyMap[reportDatabase.Name] = reportDatabase.AnomalyMap
+	}
+	if databaseAnomalyMap[database.Name][api.AnomalyDatabaseIndexMissing] == nil {
+		t.Errorf("expect the index missing anomaly in database %q, got %s", database.Name, buf.String())
+	}
+	if databaseAnomalyMap[slowDatabase.Name][api.AnomalyDatabaseConnection] == nil {
+		t.Errorf("expect the connection anomaly of the timed out database %q, got %s", slowDatabase.Name, buf.String())
+	}
+}
@@ -3,45 +3,843 @@ package server
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/bytebase/bytebase/api"
 	"github.com/bytebase/bytebase/common"
 	"github.com/bytebase/bytebase/plugin/db"
+	"github.com/bytebase/bytebase/plugin/db/util"
 	"go.uber.org/zap"
 )
 
 const (
+	// defaultAnomalyScanInterval is used when no scan interval is configured.
 	// The chosen interval is a balance between anomaly staleness tolerance and background load.
-	anomalyScanInterval = time.Duration(10) * time.Minute
+	defaultAnomalyScanInterval = time.Duration(10) * time.Minute
+	// MinAnomalyScanInterval is the minimum scan interval, it prevents a misconfiguration from turning the scanner into a busy loop.
+	MinAnomalyScanInterval = time.Duration(1) * time.Minute
+	// defaultAnomalyScanTimeout is used when no per-instance scan timeout is configured.
+	defaultAnomalyScanTimeout = time.Duration(5) * time.Minute
+	// defaultAnomalyDatabaseScanTimeout is used when no per-database scan timeout is configured.
+	defaultAnomalyDatabaseScanTimeout = time.Duration(2) * time.Minute
+	// defaultSlowInstanceScanThreshold is used when no slow instance scan threshold is configured.
+	defaultSlowInstanceScanThreshold = time.Duration(1) * time.Minute
+	// defaultAnomalyScanConcurrency is used when no database scan concurrency is configured.
+	defaultAnomalyScanConcurrency = 4
+	// schemaDriftFullSchemaMaxSize is the max size of the expected and the actual schema kept in the schema drift payload.
+	schemaDriftFullSchemaMaxSize = 64 * 1024
+	// schemaDriftDiffContextLines is the number of unchanged lines around each change in the schema drift diff.
+	schemaDriftDiffContextLines = 3
+	// defaultConnectRetryCount is used when no connect retry count is configured.
+	defaultConnectRetryCount = 3
+	// defaultConnectRetryBackoff is used when no connect retry backoff is configured.
+	defaultConnectRetryBackoff = time.Duration(1) * time.Second
+	// defaultConnectionCountThreshold is used when no connection count threshold is configured.
+	defaultConnectionCountThreshold = 80
+	// defaultDiskUsageThreshold is used when no disk usage threshold is configured.
+	defaultDiskUsageThreshold = 90
+	// defaultLongRunningTransactionThreshold is used when no long-running transaction threshold is configured.
+	defaultLongRunningTransactionThreshold = time.Duration(10) * time.Minute
+	// defaultReplicationLagThreshold is used when no replication lag threshold is configured.
+	defaultReplicationLagThreshold = time.Duration(60) * time.Second
+	// defaultTableBloatThreshold is used when no table bloat threshold is configured.
+	defaultTableBloatThreshold = 30
+	// defaultRowCountGrowthThreshold is used when no row count growth threshold is configured.
+	defaultRowCountGrowthThreshold = 10
+	// rowCountGrowthMinRowCount is the min row count of a table to raise the row count growth anomaly, so that small tables don't make noise.
+	rowCountGrowthMinRowCount = 10000
+	// defaultBackupMaxAgeGraceMultiplier is used when no backup max age grace multiplier is configured.
+	// It tolerates a backup finishing a bit later than the schedule, so that a slow nightly backup doesn't flap the missing anomaly.
+	defaultBackupMaxAgeGraceMultiplier = 1.2
+	// defaultBackupChecksumVerifyInterval is used when no backup checksum verify interval is configured.
+	defaultBackupChecksumVerifyInterval = time.Duration(24) * time.Hour
+	// defaultBackupSizeChangeThreshold is used when no backup size change threshold is configured.
+	defaultBackupSizeChangeThreshold = 3
+	// backupSizeHistoryCount is the max number of the backups before the latest one whose median size the latest size is compared against.
+	backupSizeHistoryCount = 5
+	// backupSizeMinHistoryCount is the min number of the backups before the latest one to raise the backup size anomaly,
+	// so that a database with a couple of backups doesn't make noise.
+	backupSizeMinHistoryCount = 3
+	// defaultSequentialScanRatioThreshold is used when no sequential scan ratio threshold is configured.
+	defaultSequentialScanRatioThreshold = 10
+	// defaultEngineVersionEOLWarningPeriod is used when no engine version end-of-life warning period is configured.
+	defaultEngineVersionEOLWarningPeriod = time.Duration(90*24) * time.Hour
+	// pgTableBloatMinDeadTuples is the number of dead tuples below which a table isn't considered bloated,
+	// so that small tables with a handful of dead tuples don't raise the anomaly.
+	pgTableBloatMinDeadTuples = 1000
+	// tableScanMinRows is the number of rows below which a table isn't checked for the missing index,
+	// since a sequential scan of a small table is as cheap as an index scan.
+	tableScanMinRows = 10000
+	// tableScanMinSequentialScans is the number of sequential scans below which a table isn't checked for the missing index.
+	tableScanMinSequentialScans = 1000
 )
 
-// NewAnomalyScanner creates a anomaly scanner
-func NewAnomalyScanner(logger *zap.Logger, server *Server) *AnomalyScanner {
+// minimumEngineVersionMap is the minimum recommended version of each engine, the older versions have reached the end of life.
+// The engines not listed skip the version check.
+var minimumEngineVersionMap = map[db.Type]string{
+	db.MySQL:    "5.7",
+	db.Postgres: "10",
+	db.TiDB:     "5.0",
+}
+
+// defaultExcludedDatabasePatternMap is the patterns of the system databases of each engine, which the scanner always skips.
+// The patterns are regular expressions matching the whole database name.
+var defaultExcludedDatabasePatternMap = map[db.Type][]string{
+	db.MySQL:      {"information_schema", "mysql", "performance_schema", "sys"},
+	db.TiDB:       {"information_schema", "mysql", "performance_schema", "sys", "metrics_schema"},
+	db.Postgres:   {"postgres", `template\d+`},
+	db.ClickHouse: {"system", "information_schema", "INFORMATION_SCHEMA"},
+	db.Snowflake:  {"SNOWFLAKE", "SNOWFLAKE_SAMPLE_DATA"},
+	db.MongoDB:    {"admin", "config", "local"},
+	db.MSSQL:      {"master", "model", "msdb", "tempdb"},
+	db.Oracle:     {"SYS", "SYSTEM", "XDB", "OUTLN", "DBSNMP", "APPQOSSYS"},
+}
+
+// defaultExcludedDatabaseRegexpMap is the compiled defaultExcludedDatabasePatternMap.
+var defaultExcludedDatabaseRegexpMap = func() map[db.Type][]*regexp.Regexp {
+	m := make(map[db.Type][]*regexp.Regexp)
+	for engine, patternList := range defaultExcludedDatabasePatternMap {
+		for _, pattern := range patternList {
+			m[engine] = append(m[engine], regexp.MustCompile(wholeNamePattern(pattern)))
+		}
+	}
+	return m
+}()
+
+// wholeNamePattern anchors the pattern, so that it matches the whole name instead of any part of it.
+func wholeNamePattern(pattern string) string {
+	return "^(?:" + pattern + ")$"
+}
+
+// CompileExcludedDatabasePattern compiles the pattern of the database names excluded from the scan.
+func CompileExcludedDatabasePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(wholeNamePattern(pattern))
+}
+
+// AnomalyCount is the number of anomalies of a type processed in a scan round.
+type AnomalyCount struct {
+	// Opened is the number of anomalies newly raised.
+	Opened int `json:"opened"`
+	// Unchanged is the number of anomalies which were already active and are still active.
+	Unchanged int `json:"unchanged"`
+	// Archived is the number of anomalies resolved.
+	Archived int `json:"archived"`
+}
+
+// AnomalyScanStats is the statistics of a scan round, or of a single instance scan triggered by ScanInstance.
+type AnomalyScanStats struct {
+	StartedTs  int64                            `json:"startedTs"`
+	FinishedTs int64                            `json:"finishedTs"`
+	CountMap   map[api.AnomalyType]AnomalyCount `json:"countMap"`
+	// Incomplete is set if the scan didn't finish for every instance, e.g. the round was aborted or an instance scan timed out,
+	// so that the anomalies of the instances not fully scanned may be stale.
+	Incomplete bool `json:"incomplete"`
+	// FailedInstanceList is the names of the instances whose scan didn't finish.
+	FailedInstanceList []string `json:"failedInstanceList,omitempty"`
+	// InstanceDurationMap is the duration of the latest scan by instance name, including the scans that failed.
+	InstanceDurationMap map[string]time.Duration `json:"instanceDurationMap,omitempty"`
+}
+
+// instanceScanStatsKey is the context key to the statistics of the instance scan triggered by ScanInstance.
+type instanceScanStatsKey struct{}
+
+// dryRunRecorderKey is the context key to the recorder of the anomaly writes skipped by DryRunScanInstance.
+type dryRunRecorderKey struct{}
+
+// AnomalyDryRunChange is an anomaly write skipped by the dry run.
+type AnomalyDryRunChange struct {
+	// Action is one of CREATE, UPDATE and ARCHIVE.
+	Action     string          `json:"action"`
+	InstanceID int             `json:"instanceId"`
+	DatabaseID *int            `json:"databaseId,omitempty"`
+	Type       api.AnomalyType `json:"type"`
+	Payload    string          `json:"payload,omitempty"`
+}
+
+// dryRunRecorder records the anomaly writes skipped by DryRunScanInstance, it's guarded by writeMu of the scanner.
+type dryRunRecorder struct {
+	changeList []*AnomalyDryRunChange
+}
+
+// disabledAnomalyTypeSetKey is the context key to the set of anomaly types disabled by the anomaly policy of the scanned instance.
+type disabledAnomalyTypeSetKey struct{}
+
+// AnomalyNotifier is notified when the anomaly scanner creates or resolves an anomaly.
+// The notifier is called synchronously during the scan, so a slow notifier should do its work in the background.
+type AnomalyNotifier interface {
+	// OnAnomalyOpen is called after an anomaly is created, but not when an active anomaly is updated.
+	OnAnomalyOpen(ctx context.Context, anomaly *api.Anomaly) error
+	// OnAnomalyClose is called after an active anomaly is archived.
+	OnAnomalyClose(ctx context.Context, anomaly *api.Anomaly) error
+}
+
+// AnomalyScannerConfig is the configuration of the anomaly scanner, zero values mean using the defaults.
+type AnomalyScannerConfig struct {
+	// Interval is the interval between two scan rounds. Interval shorter than MinAnomalyScanInterval is raised to the minimum.
+	Interval time.Duration
+	// Timeout bounds the scan of a single instance.
+	Timeout time.Duration
+	// DatabaseTimeout bounds the scan of a single database within the instance scan.
+	DatabaseTimeout time.Duration
+	// SlowInstanceScanThreshold is the duration of an instance scan above which a warning is logged,
+	// since it usually indicates a slow connection or slow queries.
+	SlowInstanceScanThreshold time.Duration
+	// Concurrency is the number of databases of an instance scanned concurrently.
+	Concurrency int
+	// ConnectRetryCount is the number of attempts to connect before raising the connection anomaly.
+	ConnectRetryCount int
+	// ConnectRetryBackoff is the backoff before the first connect retry, it doubles for each following retry.
+	ConnectRetryBackoff time.Duration
+	// ConnectionCountThreshold is the percentage of max connections in use above which the connection count anomaly is raised.
+	ConnectionCountThreshold int
+	// DiskUsageThreshold is the percentage of used disk space above which the disk space low anomaly is raised.
+	DiskUsageThreshold int
+	// LongRunningTransactionThreshold is the duration above which a transaction is reported as long-running.
+	LongRunningTransactionThreshold time.Duration
+	// ReplicationLagThreshold is the replication lag above which the replication lag anomaly is raised for the replica instances.
+	ReplicationLagThreshold time.Duration
+	// TableBloatThreshold is the percentage of dead tuples of a table above which the table bloat anomaly is raised.
+	TableBloatThreshold int
+	// RowCountGrowthThreshold is the ratio of a table's row count to the previous round's above which the row count growth anomaly is raised.
+	RowCountGrowthThreshold float64
+	// SequentialScanRatioThreshold is the ratio of a table's sequential scans to its index scans above which the missing index anomaly is raised.
+	SequentialScanRatioThreshold float64
+	// BackupMaxAgeGraceMultiplier is applied to the max age of the last successful backup allowed by the schedule,
+	// before the backup missing anomaly is raised. Multipliers below 1 use the default.
+	BackupMaxAgeGraceMultiplier float64
+	// BackupMissingGracePeriod is the period after the first scheduled backup since the backup setting changed, within which
+	// the backup missing anomaly isn't raised yet. Zero or negative means one interval of the backup schedule.
+	BackupMissingGracePeriod time.Duration
+	// BackupChecksumVerifyInterval is the interval between two verifications of the same backup's checksum.
+	BackupChecksumVerifyInterval time.Duration
+	// BackupSizeChangeThreshold is the ratio of the latest backup's size to the median size of the backups before, above which
+	// or below the reciprocal of which the backup size anomaly is raised.
+	BackupSizeChangeThreshold float64
+	// EngineVersionEOLWarningPeriod is the period before the end of life of the engine release line within which
+	// the engine version end-of-life anomaly is already raised.
+	EngineVersionEOLWarningPeriod time.Duration
+	// ExcludedDatabasePatternList is the regular expressions of the database names to skip, in addition to the system databases
+	// of each engine. A pattern matches the whole database name, the invalid patterns are ignored.
+	ExcludedDatabasePatternList []string
+	// DisabledTypeList is the anomaly types disabled in every environment, in addition to the types disabled by the anomaly policy
+	// of each environment, e.g. the backup anomalies if the backups are managed externally. The unknown types are ignored.
+	DisabledTypeList []api.AnomalyType
+	// WebhookURL is the URL to POST to when an anomaly is created or resolved, empty means no webhook.
+	// Only applicable if Notifier is not specified.
+	WebhookURL string
+	// Notifier is notified when an anomaly is created or resolved, the webhook notifier is used if it's nil.
+	Notifier AnomalyNotifier
+	// ReportPath is the file to write the JSON report of the active anomalies to after each scan round, empty means no report.
+	ReportPath string
+	// DryRun runs the checks and logs the anomalies which would be created, updated or archived without writing them.
+	DryRun bool
+}
+
+// NewAnomalyScanner creates a anomaly scanner.
+func NewAnomalyScanner(logger *zap.Logger, server *Server, config AnomalyScannerConfig) *AnomalyScanner {
+	interval := config.Interval
+	if interval == 0 {
+		interval = defaultAnomalyScanInterval
+	} else if interval < MinAnomalyScanInterval {
+		logger.Warn("Anomaly scan interval is too short, use the minimum interval instead",
+			zap.Duration("interval", interval),
+			zap.Duration("minimum", MinAnomalyScanInterval))
+		interval = MinAnomalyScanInterval
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultAnomalyScanTimeout
+	}
+	databaseTimeout := config.DatabaseTimeout
+	if databaseTimeout <= 0 {
+		databaseTimeout = defaultAnomalyDatabaseScanTimeout
+	}
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultAnomalyScanConcurrency
+	}
+	connectRetryCount := config.ConnectRetryCount
+	if connectRetryCount <= 0 {
+		connectRetryCount = defaultConnectRetryCount
+	}
+	connectRetryBackoff := config.ConnectRetryBackoff
+	if connectRetryBackoff <= 0 {
+		connectRetryBackoff = defaultConnectRetryBackoff
+	}
+	slowInstanceScanThreshold := config.SlowInstanceScanThreshold
+	if slowInstanceScanThreshold <= 0 {
+		slowInstanceScanThreshold = defaultSlowInstanceScanThreshold
+	}
+	connectionCountThreshold := config.ConnectionCountThreshold
+	if connectionCountThreshold <= 0 {
+		connectionCountThreshold = defaultConnectionCountThreshold
+	}
+	diskUsageThreshold := config.DiskUsageThreshold
+	if diskUsageThreshold <= 0 {
+		diskUsageThreshold = defaultDiskUsageThreshold
+	}
+	longRunningTransactionThreshold := config.LongRunningTransactionThreshold
+	if longRunningTransactionThreshold <= 0 {
+		longRunningTransactionThreshold = defaultLongRunningTransactionThreshold
+	}
+	replicationLagThreshold := config.ReplicationLagThreshold
+	if replicationLagThreshold <= 0 {
+		replicationLagThreshold = defaultReplicationLagThreshold
+	}
+	tableBloatThreshold := config.TableBloatThreshold
+	if tableBloatThreshold <= 0 {
+		tableBloatThreshold = defaultTableBloatThreshold
+	}
+	rowCountGrowthThreshold := config.RowCountGrowthThreshold
+	if rowCountGrowthThreshold <= 0 {
+		rowCountGrowthThreshold = defaultRowCountGrowthThreshold
+	}
+	sequentialScanRatioThreshold := config.SequentialScanRatioThreshold
+	if sequentialScanRatioThreshold <= 0 {
+		sequentialScanRatioThreshold = defaultSequentialScanRatioThreshold
+	}
+	backupMaxAgeGraceMultiplier := config.BackupMaxAgeGraceMultiplier
+	if backupMaxAgeGraceMultiplier < 1 {
+		backupMaxAgeGraceMultiplier = defaultBackupMaxAgeGraceMultiplier
+	}
+	backupMissingGracePeriod := config.BackupMissingGracePeriod
+	if backupMissingGracePeriod < 0 {
+		backupMissingGracePeriod = 0
+	}
+	backupChecksumVerifyInterval := config.BackupChecksumVerifyInterval
+	if backupChecksumVerifyInterval <= 0 {
+		backupChecksumVerifyInterval = defaultBackupChecksumVerifyInterval
+	}
+	backupSizeChangeThreshold := config.BackupSizeChangeThreshold
+	if backupSizeChangeThreshold <= 1 {
+		backupSizeChangeThreshold = defaultBackupSizeChangeThreshold
+	}
+	engineVersionEOLWarningPeriod := config.EngineVersionEOLWarningPeriod
+	if engineVersionEOLWarningPeriod <= 0 {
+		engineVersionEOLWarningPeriod = defaultEngineVersionEOLWarningPeriod
+	}
+	var excludedDatabaseRegexpList []*regexp.Regexp
+	for _, pattern := range config.ExcludedDatabasePatternList {
+		re, err := CompileExcludedDatabasePattern(pattern)
+		if err != nil {
+			logger.Warn("Ignore invalid excluded database pattern",
+				zap.String("pattern", pattern),
+				zap.Error(err))
+			continue
+		}
+		excludedDatabaseRegexpList = append(excludedDatabaseRegexpList, re)
+	}
+	disabledTypeSet := make(map[api.AnomalyType]bool)
+	for _, anomalyType := range config.DisabledTypeList {
+		if !api.AnomalyTypes[anomalyType] {
+			logger.Warn("Ignore unknown disabled anomaly type",
+				zap.String("type", string(anomalyType)))
+			continue
+		}
+		disabledTypeSet[anomalyType] = true
+	}
+	notifier := config.Notifier
+	if notifier == nil && config.WebhookURL != "" {
+		notifier = newAnomalyWebhookNotifier(logger, server, config.WebhookURL)
+	}
 	return &AnomalyScanner{
-		l:      logger,
-		server: server,
+		l:                               logger,
+		server:                          server,
+		now:                             time.Now,
+		interval:                        interval,
+		timeout:                         timeout,
+		databaseTimeout:                 databaseTimeout,
+		slowInstanceScanThreshold:       slowInstanceScanThreshold,
+		concurrency:                     concurrency,
+		connectRetryCount:               connectRetryCount,
+		connectRetryBackoff:             connectRetryBackoff,
+		connectionCountThreshold:        connectionCountThreshold,
+		diskUsageThreshold:              diskUsageThreshold,
+		longRunningTransactionThreshold: longRunningTransactionThreshold,
+		replicationLagThreshold:         replicationLagThreshold,
+		tableBloatThreshold:             tableBloatThreshold,
+		rowCountGrowthThreshold:         rowCountGrowthThreshold,
+		sequentialScanRatioThreshold:    sequentialScanRatioThreshold,
+		backupMaxAgeGraceMultiplier:     backupMaxAgeGraceMultiplier,
+		backupMissingGracePeriod:        backupMissingGracePeriod,
+		backupChecksumVerifyInterval:    backupChecksumVerifyInterval,
+		backupSizeChangeThreshold:       backupSizeChangeThreshold,
+		engineVersionEOLWarningPeriod:   engineVersionEOLWarningPeriod,
+		excludedDatabaseRegexpList:      excludedDatabaseRegexpList,
+		disabledTypeSet:                 disabledTypeSet,
+		notifier:                        notifier,
+		reportPath:                      config.ReportPath,
+		dryRun:                          config.DryRun,
+		backupPlanPolicyCache:           make(map[int]*backupPlanPolicyCacheEntry),
+		backupVerificationMap:           make(map[int]*backupVerification),
+		runningTasks:                    make(map[int]bool),
+		stopCh:                          make(chan struct{}),
+		metrics:                         newAnomalyScannerMetrics(),
 	}
 }
 
+// backupPlanPolicyCacheEntry is a cached backup plan policy.
+type backupPlanPolicyCacheEntry struct {
+	policy    *api.BackupPlanPolicy
+	expiresAt time.Time
+}
+
+// backupVerification is the last checksum verification attempt of a database's latest backup.
+type backupVerification struct {
+	backupID   int
+	verifiedAt time.Time
+}
+
 // AnomalyScanner is the anomaly scanner.
 type AnomalyScanner struct {
 	l      *zap.Logger
 	server *Server
+	// now returns the current time for the time-based checks, it's time.Now except in tests freezing the time.
+	// The durations of the scans are still measured with the real clock.
+	now      func() time.Time
+	interval time.Duration
+	// timeout is the deadline for scanning a single instance, so that an unreachable instance can't stall the whole round.
+	timeout time.Duration
+	// databaseTimeout is the deadline for scanning a single database, so that a slow database can't hold up the other databases of the instance.
+	databaseTimeout time.Duration
+	// slowInstanceScanThreshold is the duration of an instance scan above which a warning is logged.
+	slowInstanceScanThreshold time.Duration
+	// concurrency is the number of databases of an instance scanned concurrently.
+	concurrency int
+	// connectRetryCount is the number of attempts to connect, so that a transient network blip doesn't raise the connection anomaly.
+	connectRetryCount int
+	// connectRetryBackoff is the backoff before the first connect retry, it doubles for each following retry.
+	connectRetryBackoff time.Duration
+	// connectionCountThreshold is the percentage of max connections in use above which the connection count anomaly is raised.
+	connectionCountThreshold int
+	// diskUsageThreshold is the percentage of used disk space above which the disk space low anomaly is raised.
+	diskUsageThreshold int
+	// longRunningTransactionThreshold is the duration above which a transaction is reported as long-running.
+	longRunningTransactionThreshold time.Duration
+	// replicationLagThreshold is the replication lag above which the replication lag anomaly is raised for the replica instances.
+	replicationLagThreshold time.Duration
+	// tableBloatThreshold is the percentage of dead tuples of a table above which the table bloat anomaly is raised.
+	tableBloatThreshold int
+	// rowCountGrowthThreshold is the ratio of a table's row count to the previous round's above which the row count growth anomaly is raised.
+	rowCountGrowthThreshold float64
+	// sequentialScanRatioThreshold is the ratio of a table's sequential scans to its index scans above which the missing index anomaly is raised.
+	sequentialScanRatioThreshold float64
+	// backupMaxAgeGraceMultiplier is applied to the max age of the last successful backup allowed by the schedule.
+	backupMaxAgeGraceMultiplier float64
+	// backupMissingGracePeriod is the period after the first scheduled backup since the backup setting changed, within which
+	// the backup missing anomaly isn't raised yet, 0 means one interval of the backup schedule.
+	backupMissingGracePeriod time.Duration
+	// backupChecksumVerifyInterval is the interval between two verifications of the same backup's checksum,
+	// since re-hashing a large backup every round would be expensive.
+	backupChecksumVerifyInterval time.Duration
+	// backupSizeChangeThreshold is the ratio of the latest backup's size to the median size of the backups before,
+	// above which or below the reciprocal of which the backup size anomaly is raised.
+	backupSizeChangeThreshold float64
+	// engineVersionEOLWarningPeriod is the period before the end of life of the engine release line within which the anomaly is raised.
+	engineVersionEOLWarningPeriod time.Duration
+	// excludedDatabaseRegexpList is the configured patterns of the database names to skip, in addition to defaultExcludedDatabaseRegexpMap.
+	excludedDatabaseRegexpList []*regexp.Regexp
+	// disabledTypeSet is the configured anomaly types disabled in every environment, in addition to the types disabled by the anomaly policy.
+	disabledTypeSet map[api.AnomalyType]bool
+	// notifier is notified when an anomaly is created or resolved, nil means no notification.
+	notifier AnomalyNotifier
+	// reportPath is the file to write the JSON report of the active anomalies to after each scan round.
+	reportPath string
+	// dryRun skips the anomaly writes and logs them instead, the round statistics still count them.
+	dryRun bool
+
+	// runningTasks tracks the instances being scanned, it's shared by the periodic round and the manual scan.
+	runningTasks map[int]bool
+
+	// metrics is the Prometheus metrics of the scans, see RegisterMetrics.
+	metrics *anomalyScannerMetrics
+	// stopped is set by Stop, no new scan will start afterwards.
+	stopped bool
+	mu      sync.Mutex
+	// wg tracks the in-flight instance scans.
+	wg sync.WaitGroup
+	// writeMu serializes the anomaly and row count baseline writes from the concurrent database scans, since the SQLite metadata store
+	// would otherwise fail with "database locked".
+	writeMu sync.Mutex
+
+	stopCh chan struct{}
+	// loopDone is closed when the goroutine started by Run exits. It's nil if Run is never called.
+	loopDone chan struct{}
+
+	policyCacheMu sync.Mutex
+	// backupPlanPolicyCache caches the backup plan policy by environment ID for up to the scan interval.
+	backupPlanPolicyCache map[int]*backupPlanPolicyCacheEntry
+
+	backupVerificationMu sync.Mutex
+	// backupVerificationMap is the last backup checksum verification by database ID.
+	backupVerificationMap map[int]*backupVerification
+
+	statsMu sync.Mutex
+	// roundStats accumulates the statistics of the ongoing round, while lastStats is the snapshot of the last completed round.
+	roundStats AnomalyScanStats
+	lastStats  AnomalyScanStats
+}
+
+// Interval returns the interval between two scan rounds.
+func (s *AnomalyScanner) Interval() time.Duration {
+	return s.interval
+}
+
+// getBackupPlanPolicy returns the backup plan policy of the environment from the cache,
+// and fetches it from the policy service if it's not cached or has been cached longer than the scan interval.
+func (s *AnomalyScanner) getBackupPlanPolicy(ctx context.Context, environmentID int) (*api.BackupPlanPolicy, error) {
+	s.policyCacheMu.Lock()
+	entry, ok := s.backupPlanPolicyCache[environmentID]
+	s.policyCacheMu.Unlock()
+	if ok && s.now().Before(entry.expiresAt) {
+		return entry.policy, nil
+	}
+
+	policy, err := s.server.PolicyService.GetBackupPlanPolicy(ctx, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	s.policyCacheMu.Lock()
+	s.backupPlanPolicyCache[environmentID] = &backupPlanPolicyCacheEntry{
+		policy:    policy,
+		expiresAt: s.now().Add(s.interval),
+	}
+	s.policyCacheMu.Unlock()
+	return policy, nil
+}
+
+// InvalidatePolicyCache drops the cached policies of the environment, so that the next scan reads the updated policies.
+// Invalidating the global policy drops the cached policies of every environment since they may fall back to it.
+func (s *AnomalyScanner) InvalidatePolicyCache(environmentID int) {
+	s.policyCacheMu.Lock()
+	defer s.policyCacheMu.Unlock()
+	if environmentID == api.GlobalPolicyEnvironmentID {
+		s.backupPlanPolicyCache = make(map[int]*backupPlanPolicyCacheEntry)
+		return
+	}
+	delete(s.backupPlanPolicyCache, environmentID)
+}
+
+// Stats returns the statistics of the last completed scan round.
+func (s *AnomalyScanner) Stats() AnomalyScanStats {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	stats := s.lastStats
+	stats.CountMap = make(map[api.AnomalyType]AnomalyCount)
+	for anomalyType, count := range s.lastStats.CountMap {
+		stats.CountMap[anomalyType] = count
+	}
+	stats.FailedInstanceList = append([]string(nil), s.lastStats.FailedInstanceList...)
+	if s.lastStats.InstanceDurationMap != nil {
+		stats.InstanceDurationMap = make(map[string]time.Duration)
+		for name, duration := range s.lastStats.InstanceDurationMap {
+			stats.InstanceDurationMap[name] = duration
+		}
+	}
+	return stats
+}
+
+// FindActiveAnomalyGroupList returns the active anomalies of the instances in the environment, grouped by the database and the type.
+func (s *AnomalyScanner) FindActiveAnomalyGroupList(ctx context.Context, environmentID int) ([]*api.AnomalyGroup, error) {
+	status := api.Normal
+	anomalyList, err := s.server.AnomalyService.FindAnomalyList(ctx, &api.AnomalyFind{
+		RowStatus:     &status,
+		EnvironmentID: &environmentID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find anomaly list of environment %d: %w", environmentID, err)
+	}
+	return api.GroupAnomalyList(anomalyList), nil
+}
+
+func (s *AnomalyScanner) startRoundStats() {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	s.roundStats = AnomalyScanStats{
+		StartedTs: s.now().Unix(),
+		CountMap:  make(map[api.AnomalyType]AnomalyCount),
+	}
+}
+
+func (s *AnomalyScanner) finishRoundStats() {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	s.roundStats.FinishedTs = s.now().Unix()
+	s.lastStats = s.roundStats
+}
+
+// markRoundIncomplete marks the ongoing round as incomplete.
+func (s *AnomalyScanner) markRoundIncomplete() {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	s.roundStats.Incomplete = true
+}
+
+// recordFailedInstance records the instance whose scan didn't finish in the round statistics,
+// and also in the instance scan statistics if ctx carries one.
+func (s *AnomalyScanner) recordFailedInstance(ctx context.Context, instance *api.Instance) {
+	s.metrics.scanErrorCount.Inc()
+
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	statsList := []*AnomalyScanStats{&s.roundStats}
+	if stats, ok := ctx.Value(instanceScanStatsKey{}).(*AnomalyScanStats); ok {
+		statsList = append(statsList, stats)
+	}
+	for _, stats := range statsList {
+		stats.Incomplete = true
+		recorded := false
+		for _, name := range stats.FailedInstanceList {
+			// The instance may fail in multiple databases.
+			if name == instance.Name {
+				recorded = true
+				break
+			}
+		}
+		if !recorded {
+			stats.FailedInstanceList = append(stats.FailedInstanceList, instance.Name)
+		}
+	}
+}
+
+// recordInstanceDuration records the duration of the instance scan in the round statistics,
+// and also in the instance scan statistics if ctx carries one. Logs a warning if the scan is slow.
+func (s *AnomalyScanner) recordInstanceDuration(ctx context.Context, instance *api.Instance, duration time.Duration) {
+	s.metrics.observeInstanceScan(instance, duration)
+	if duration > s.slowInstanceScanThreshold {
+		s.l.Warn("Slow instance anomaly scan",
+			zap.String("instance", instance.Name),
+			zap.Duration("duration", duration),
+			zap.Duration("threshold", s.slowInstanceScanThreshold))
+	}
+
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	statsList := []*AnomalyScanStats{&s.roundStats}
+	if stats, ok := ctx.Value(instanceScanStatsKey{}).(*AnomalyScanStats); ok {
+		statsList = append(statsList, stats)
+	}
+	for _, stats := range statsList {
+		if stats.InstanceDurationMap == nil {
+			stats.InstanceDurationMap = make(map[string]time.Duration)
+		}
+		stats.InstanceDurationMap[instance.Name] = duration
+	}
+}
+
+// recordAnomalyCount records the count in the round statistics, and also in the instance scan statistics if ctx carries one.
+func (s *AnomalyScanner) recordAnomalyCount(ctx context.Context, anomalyType api.AnomalyType, update func(count *AnomalyCount)) {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	statsList := []*AnomalyScanStats{&s.roundStats}
+	if stats, ok := ctx.Value(instanceScanStatsKey{}).(*AnomalyScanStats); ok {
+		statsList = append(statsList, stats)
+	}
+	for _, stats := range statsList {
+		if stats.CountMap == nil {
+			stats.CountMap = make(map[api.AnomalyType]AnomalyCount)
+		}
+		count := stats.CountMap[anomalyType]
+		update(&count)
+		stats.CountMap[anomalyType] = count
+	}
+}
+
+// upsertAnomaly upserts the active anomaly and records it in the round statistics.
+func (s *AnomalyScanner) upsertAnomaly(ctx context.Context, upsert *api.AnomalyUpsert) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if s.isDryRun(ctx) {
+		status := api.Normal
+		list, err := s.server.AnomalyService.FindAnomalyList(ctx, &api.AnomalyFind{
+			RowStatus:    &status,
+			InstanceID:   &upsert.InstanceID,
+			DatabaseID:   upsert.DatabaseID,
+			Type:         &upsert.Type,
+			InstanceOnly: upsert.DatabaseID == nil,
+		})
+		if err != nil {
+			return err
+		}
+		created := len(list) == 0
+		action := "UPDATE"
+		if created {
+			action = "CREATE"
+		}
+		s.logDryRun(ctx, &AnomalyDryRunChange{
+			Action:     action,
+			InstanceID: upsert.InstanceID,
+			DatabaseID: upsert.DatabaseID,
+			Type:       upsert.Type,
+			Payload:    upsert.Payload,
+		})
+		s.recordAnomalyCount(ctx, upsert.Type, func(count *AnomalyCount) {
+			if created {
+				count.Opened++
+			} else {
+				count.Unchanged++
+			}
+		})
+		return nil
+	}
+
+	anomaly, created, err := s.server.AnomalyService.UpsertActiveAnomaly(ctx, upsert)
+	if err != nil {
+		return err
+	}
+	s.recordAnomalyCount(ctx, upsert.Type, func(count *AnomalyCount) {
+		if created {
+			count.Opened++
+		} else {
+			count.Unchanged++
+		}
+	})
+	if created {
+		s.metrics.countAnomaly(upsert.Type, anomalyMetricActionCreated)
+		s.notifyAnomaly(ctx, anomaly, true /* open */)
+	}
+	return nil
+}
+
+// archiveAnomaly archives the anomaly and records it in the round statistics.
+// Returns ENOTFOUND if there is no active anomaly to archive.
+func (s *AnomalyScanner) archiveAnomaly(ctx context.Context, archive *api.AnomalyArchive) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	// Find the anomalies to be archived beforehand for the notifier and the dry run, since ArchiveAnomaly doesn't return them.
+	var resolvedList []*api.Anomaly
+	dryRun := s.isDryRun(ctx)
+	if s.notifier != nil || dryRun {
+		status := api.Normal
+		list, err := s.server.AnomalyService.FindAnomalyList(ctx, &api.AnomalyFind{
+			RowStatus:    &status,
+			InstanceID:   archive.InstanceID,
+			DatabaseID:   archive.DatabaseID,
+			Type:         &archive.Type,
+			InstanceOnly: archive.DatabaseID == nil,
+		})
+		if err != nil {
+			return err
+		}
+		resolvedList = list
+	}
+	if dryRun {
+		if len(resolvedList) == 0 {
+			return &common.Error{Code: common.NotFound, Err: fmt.Errorf("anomaly not found type: %s", archive.Type)}
+		}
+		for _, anomaly := range resolvedList {
+			s.logDryRun(ctx, &AnomalyDryRunChange{
+				Action:     "ARCHIVE",
+				InstanceID: anomaly.InstanceID,
+				DatabaseID: anomaly.DatabaseID,
+				Type:       anomaly.Type,
+			})
+		}
+		s.recordAnomalyCount(ctx, archive.Type, func(count *AnomalyCount) {
+			count.Archived++
+		})
+		return nil
+	}
+	if err := s.server.AnomalyService.ArchiveAnomaly(ctx, archive); err != nil {
+		return err
+	}
+	s.recordAnomalyCount(ctx, archive.Type, func(count *AnomalyCount) {
+		count.Archived++
+	})
+	s.metrics.countAnomaly(archive.Type, anomalyMetricActionArchived)
+	for _, anomaly := range resolvedList {
+		anomaly.ArchiveReason = archive.Reason
+		s.notifyAnomaly(ctx, anomaly, false /* open */)
+	}
+	return nil
+}
+
+// notifyAnomaly notifies the notifier of the created anomaly if open is set, or the resolved anomaly otherwise.
+// The notifier failures are only logged so that they never abort the scan.
+func (s *AnomalyScanner) notifyAnomaly(ctx context.Context, anomaly *api.Anomaly, open bool) {
+	if s.notifier == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err, ok := r.(error)
+			if !ok {
+				err = fmt.Errorf("%v", r)
+			}
+			s.l.Error("Anomaly notifier PANIC RECOVER", zap.Error(err))
+		}
+	}()
+
+	var err error
+	if open {
+		err = s.notifier.OnAnomalyOpen(ctx, anomaly)
+	} else {
+		err = s.notifier.OnAnomalyClose(ctx, anomaly)
+	}
+	if err != nil {
+		s.l.Warn("Failed to notify anomaly",
+			zap.Int("anomaly", anomaly.ID),
+			zap.String("type", string(anomaly.Type)),
+			zap.Bool("open", open),
+			zap.Error(err))
+	}
+}
+
+// isDryRun returns whether the anomaly writes are skipped, either by the dry run mode or by DryRunScanInstance.
+func (s *AnomalyScanner) isDryRun(ctx context.Context) bool {
+	if s.dryRun {
+		return true
+	}
+	_, ok := ctx.Value(dryRunRecorderKey{}).(*dryRunRecorder)
+	return ok
+}
+
+// logDryRun logs the anomaly write skipped by the dry run, and also records it if ctx carries the recorder of DryRunScanInstance.
+// It must be called with writeMu held.
+func (s *AnomalyScanner) logDryRun(ctx context.Context, change *AnomalyDryRunChange) {
+	if recorder, ok := ctx.Value(dryRunRecorderKey{}).(*dryRunRecorder); ok {
+		recorder.changeList = append(recorder.changeList, change)
+	}
+	fields := []zap.Field{
+		zap.String("action", change.Action),
+		zap.Int("instanceId", change.InstanceID),
+	}
+	if change.DatabaseID != nil {
+		fields = append(fields, zap.Int("databaseId", *change.DatabaseID))
+	}
+	fields = append(fields, zap.String("type", string(change.Type)))
+	s.l.Info("Anomaly scan dry run", fields...)
 }
 
 // Run will run the anomaly scanner once.
 func (s *AnomalyScanner) Run() error {
+	s.loopDone = make(chan struct{})
 	go func() {
-		s.l.Debug(fmt.Sprintf("Anomaly scanner started and will run every %v", anomalyScanInterval))
-		runningTasks := make(map[int]bool)
-		mu := sync.RWMutex{}
+		defer close(s.loopDone)
+		s.l.Debug(fmt.Sprintf("Anomaly scanner started and will run every %v", s.interval))
 		for {
 			s.l.Debug("New anomaly scanner round started...")
 			func() {
+				s.startRoundStats()
+				roundFinished := false
+				defer func() {
+					if !roundFinished {
+						s.markRoundIncomplete()
+					}
+					s.finishRoundStats()
+					s.writeAnomalyReportFile()
+				}()
 				defer func() {
 					if r := recover(); r != nil {
 						err, ok := r.(error)
@@ -63,7 +861,7 @@ func (s *AnomalyScanner) Run() error {
 
 				backupPlanPolicyMap := make(map[int]*api.BackupPlanPolicy)
 				for _, env := range environmentList {
-					policy, err := s.server.PolicyService.GetBackupPlanPolicy(ctx, env.ID)
+					policy, err := s.getBackupPlanPolicy(ctx, env.ID)
 					if err != nil {
 						s.l.Error("Failed to retrieve backup policy",
 							zap.String("environment", env.Name),
@@ -73,9 +871,37 @@ func (s *AnomalyScanner) Run() error {
 					backupPlanPolicyMap[env.ID] = policy
 				}
 
+				schemaDriftPolicyMap := make(map[int]*api.SchemaDriftPolicy)
+				for _, env := range environmentList {
+					policy, err := s.server.PolicyService.GetSchemaDriftPolicy(ctx, env.ID)
+					if err != nil {
+						s.l.Error("Failed to retrieve schema drift policy",
+							zap.String("environment", env.Name),
+							zap.Error(err))
+						return
+					}
+					schemaDriftPolicyMap[env.ID] = policy
+				}
+
+				anomalyPolicyMap := make(map[int]*api.AnomalyPolicy)
+				for _, env := range environmentList {
+					policy, err := s.server.PolicyService.GetAnomalyPolicy(ctx, env.ID)
+					if err != nil {
+						s.l.Error("Failed to retrieve anomaly policy",
+							zap.String("environment", env.Name),
+							zap.Error(err))
+						return
+					}
+					anomalyPolicyMap[env.ID] = policy
+				}
+
+				s.archiveDisabledInstanceAnomalyList(ctx)
+
 				rowStatus := api.Normal
+				enableAnomalyScan := true
 				instanceFind := &api.InstanceFind{
-					RowStatus: &rowStatus,
+					RowStatus:         &rowStatus,
+					EnableAnomalyScan: &enableAnomalyScan,
 				}
 				instanceList, err := s.server.InstanceService.FindInstanceList(ctx, instanceFind)
 				if err != nil {
@@ -84,6 +910,10 @@ func (s *AnomalyScanner) Run() error {
 				}
 
 				for _, instance := range instanceList {
+					if s.isStopping() {
+						return
+					}
+
 					for _, env := range environmentList {
 						if env.ID == instance.EnvironmentID {
 							if env.RowStatus == api.Normal {
@@ -104,121 +934,634 @@ func (s *AnomalyScanner) Run() error {
 						continue
 					}
 
-					mu.Lock()
-					if _, ok := runningTasks[instance.ID]; ok {
-						mu.Unlock()
+					if !anomalyPolicyMap[instance.EnvironmentID].Enabled {
+						// Archive the anomalies found before the scan was disabled, so that they won't stay stale.
+						s.archiveInstanceAnomalyList(ctx, instance, nil)
 						continue
 					}
-					runningTasks[instance.ID] = true
-					mu.Unlock()
-
-					// Do NOT use go-routine otherwise would cause "database locked" in underlying SQLite
-					func(instance *api.Instance) {
-						s.l.Debug("Scan instance anomaly", zap.String("instance", instance.Name))
-						defer func() {
-							mu.Lock()
-							delete(runningTasks, instance.ID)
-							mu.Unlock()
-						}()
 
-						s.checkInstanceAnomaly(ctx, instance)
+					if err := s.startTask(instance.ID); err != nil {
+						continue
+					}
 
-						databaseFind := &api.DatabaseFind{
-							InstanceID: &instance.ID,
-						}
-						dbList, err := s.server.DatabaseService.FindDatabaseList(ctx, databaseFind)
-						if err != nil {
-							s.l.Error("Failed to retrieve database list",
-								zap.String("instance", instance.Name),
-								zap.Error(err))
-							return
-						}
-						for _, database := range dbList {
-							s.checkDatabaseAnomaly(ctx, instance, database)
-							s.checkBackupAnomaly(ctx, instance, database, backupPlanPolicyMap)
-						}
+					// Do NOT use go-routine otherwise would cause "database locked" in underlying SQLite.
+					// The databases of the instance are scanned concurrently with the anomaly writes serialized, see forEachDatabase.
+					func(instance *api.Instance) {
+						defer s.finishTask(instance.ID)
+						s.scanInstance(ctx, instance, anomalyPolicyMap[instance.EnvironmentID], backupPlanPolicyMap, schemaDriftPolicyMap[instance.EnvironmentID])
 					}(instance)
 
 					// Sleep 1 second after finishing scanning each instance to avoid database lock error in SQLITE
-					time.Sleep(1 * time.Second)
+					select {
+					case <-s.stopCh:
+						return
+					case <-time.After(1 * time.Second):
+					}
 				}
+				roundFinished = true
 			}()
 
-			time.Sleep(anomalyScanInterval)
+			select {
+			case <-s.stopCh:
+				s.l.Debug("Anomaly scanner stopped")
+				return
+			case <-time.After(s.interval):
+			}
 		}
 	}()
 
 	return nil
 }
 
-func (s *AnomalyScanner) checkInstanceAnomaly(ctx context.Context, instance *api.Instance) {
-	driver, err := getDatabaseDriver(ctx, instance, "", s.l)
+// Stop stops the scan loop started by Run and waits for the in-flight instance scans to complete.
+// It's safe to call Stop multiple times.
+func (s *AnomalyScanner) Stop() {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.stopped = true
+	s.mu.Unlock()
+
+	close(s.stopCh)
+	if s.loopDone != nil {
+		<-s.loopDone
+	}
+	s.wg.Wait()
+
+	s.policyCacheMu.Lock()
+	s.backupPlanPolicyCache = make(map[int]*backupPlanPolicyCacheEntry)
+	s.policyCacheMu.Unlock()
+}
+
+func (s *AnomalyScanner) isStopping() bool {
+	select {
+	case <-s.stopCh:
+		return true
+	default:
+		return false
+	}
+}
 
-	// Check connection
+// ScanInstance scans the instance and its databases immediately instead of waiting for the next round,
+// and returns the statistics of the anomalies opened, unchanged and archived by this scan.
+// Returns ECONFLICT if the instance is being scanned.
+func (s *AnomalyScanner) ScanInstance(ctx context.Context, instanceID int) (*AnomalyScanStats, error) {
+	instance, err := s.server.composeInstanceByID(ctx, instanceID)
 	if err != nil {
-		anomalyPayload := api.AnomalyInstanceConnectionPayload{
-			Detail: err.Error(),
-		}
-		payload, err := json.Marshal(anomalyPayload)
-		if err != nil {
-			s.l.Error("Failed to marshal anomaly payload",
-				zap.String("instance", instance.Name),
-				zap.String("type", string(api.AnomalyInstanceConnection)),
-				zap.Error(err))
-		} else {
-			_, err = s.server.AnomalyService.UpsertActiveAnomaly(ctx, &api.AnomalyUpsert{
-				CreatorID:  api.SystemBotID,
-				InstanceID: instance.ID,
-				Type:       api.AnomalyInstanceConnection,
-				Payload:    string(payload),
-			})
-			if err != nil {
-				s.l.Error("Failed to create anomaly",
-					zap.String("instance", instance.Name),
-					zap.String("type", string(api.AnomalyInstanceConnection)),
-					zap.Error(err))
-			}
-		}
-		return
+		return nil, err
+	}
+	if instance.RowStatus != api.Normal {
+		return nil, common.Errorf(common.Invalid, fmt.Errorf("instance %q is archived", instance.Name))
+	}
+	if instance.Environment.RowStatus != api.Normal {
+		return nil, common.Errorf(common.Invalid, fmt.Errorf("environment %q of instance %q is archived", instance.Environment.Name, instance.Name))
+	}
+	if !instance.EnableAnomalyScan {
+		return nil, common.Errorf(common.Invalid, fmt.Errorf("anomaly scan is disabled for instance %q", instance.Name))
 	}
 
-	defer driver.Close(ctx)
-	err = s.server.AnomalyService.ArchiveAnomaly(ctx, &api.AnomalyArchive{
-		InstanceID: &instance.ID,
-		Type:       api.AnomalyInstanceConnection,
-	})
-	if err != nil && common.ErrorCode(err) != common.NotFound {
-		s.l.Error("Failed to close anomaly",
-			zap.String("instance", instance.Name),
-			zap.String("type", string(api.AnomalyInstanceConnection)),
-			zap.Error(err))
+	anomalyPolicy, err := s.server.PolicyService.GetAnomalyPolicy(ctx, instance.EnvironmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve anomaly policy for environment %q: %w", instance.Environment.Name, err)
+	}
+	if !anomalyPolicy.Enabled {
+		return nil, common.Errorf(common.Invalid, fmt.Errorf("anomaly scan is disabled for environment %q", instance.Environment.Name))
 	}
 
-	// Check migration schema
-	{
-		setup, err := driver.NeedsSetupMigration(ctx)
-		if err != nil {
-			s.l.Error("Failed to check migration schema",
-				zap.String("instance", instance.Name),
-				zap.String("type", string(api.AnomalyInstanceMigrationSchema)),
-				zap.Error(err))
-		} else {
-			if setup {
-				_, err = s.server.AnomalyService.UpsertActiveAnomaly(ctx, &api.AnomalyUpsert{
-					CreatorID:  api.SystemBotID,
-					InstanceID: instance.ID,
-					Type:       api.AnomalyInstanceMigrationSchema,
-				})
-				if err != nil {
+	policy, err := s.getBackupPlanPolicy(ctx, instance.EnvironmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve backup policy for environment %q: %w", instance.Environment.Name, err)
+	}
+	backupPlanPolicyMap := map[int]*api.BackupPlanPolicy{
+		instance.EnvironmentID: policy,
+	}
+
+	schemaDriftPolicy, err := s.server.PolicyService.GetSchemaDriftPolicy(ctx, instance.EnvironmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve schema drift policy for environment %q: %w", instance.Environment.Name, err)
+	}
+
+	if err := s.startTask(instance.ID); err != nil {
+		return nil, err
+	}
+	defer s.finishTask(instance.ID)
+
+	stats := &AnomalyScanStats{
+		StartedTs: s.now().Unix(),
+		CountMap:  make(map[api.AnomalyType]AnomalyCount),
+	}
+	s.scanInstance(context.WithValue(ctx, instanceScanStatsKey{}, stats), instance, anomalyPolicy, backupPlanPolicyMap, schemaDriftPolicy)
+
+	stats.FinishedTs = s.now().Unix()
+	return stats, nil
+}
+
+// DryRunScanInstance runs the checks of the instance and its databases like ScanInstance, including the driver queries,
+// but returns the anomaly writes instead of applying them, e.g. to preview a new threshold before enabling it.
+// Returns ECONFLICT if the instance is being scanned.
+func (s *AnomalyScanner) DryRunScanInstance(ctx context.Context, instanceID int) ([]*AnomalyDryRunChange, error) {
+	recorder := &dryRunRecorder{}
+	if _, err := s.ScanInstance(context.WithValue(ctx, dryRunRecorderKey{}, recorder), instanceID); err != nil {
+		return nil, err
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return recorder.changeList, nil
+}
+
+// startTask marks the instance as being scanned.
+// Returns ECONFLICT if the instance is already being scanned or the scanner has been stopped.
+func (s *AnomalyScanner) startTask(instanceID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return common.Errorf(common.Conflict, fmt.Errorf("anomaly scanner has been stopped"))
+	}
+	if _, ok := s.runningTasks[instanceID]; ok {
+		return common.Errorf(common.Conflict, fmt.Errorf("anomaly scan already in progress for instance %d", instanceID))
+	}
+	s.runningTasks[instanceID] = true
+	s.wg.Add(1)
+	return nil
+}
+
+func (s *AnomalyScanner) finishTask(instanceID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.runningTasks, instanceID)
+	s.wg.Done()
+}
+
+// scanInstance runs the instance checks and the checks for each of its databases.
+// The checks of the anomaly types disabled by the scanner config or the anomaly policy are skipped, and their active anomalies are archived.
+func (s *AnomalyScanner) scanInstance(ctx context.Context, instance *api.Instance, anomalyPolicy *api.AnomalyPolicy, backupPlanPolicyMap map[int]*api.BackupPlanPolicy, schemaDriftPolicy *api.SchemaDriftPolicy) {
+	s.l.Debug("Scan instance anomaly", zap.String("instance", instance.Name))
+	start := time.Now()
+	defer func() {
+		s.recordInstanceDuration(ctx, instance, time.Since(start))
+	}()
+
+	if len(s.disabledTypeSet) > 0 || len(anomalyPolicy.DisabledTypeList) > 0 {
+		disabledTypeSet := make(map[api.AnomalyType]bool)
+		for anomalyType := range s.disabledTypeSet {
+			disabledTypeSet[anomalyType] = true
+		}
+		for _, anomalyType := range anomalyPolicy.DisabledTypeList {
+			disabledTypeSet[anomalyType] = true
+		}
+		// Archive the anomalies found before their types were disabled, so that they won't stay stale.
+		s.archiveInstanceAnomalyList(ctx, instance, disabledTypeSet)
+		ctx = context.WithValue(ctx, disabledAnomalyTypeSetKey{}, disabledTypeSet)
+	}
+
+	scanCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	// The databases share the driver of the instance, so that the instance is connected once per scan instead of once per database.
+	var sharedDriver db.Driver
+	var schemaMap map[string]*db.Schema
+	if driver := s.checkInstanceAnomaly(scanCtx, instance, anomalyPolicy); driver != nil {
+		// Close with a fresh context since scanCtx may have expired by the instance scan timeout.
+		defer driver.Close(context.Background())
+		if isInstanceDriverShareable(instance.Engine) {
+			sharedDriver = driver
+		}
+		schemaMap = s.syncInstanceSchema(scanCtx, instance, driver)
+	}
+	if scanCtx.Err() != nil {
+		// Use the parent context since the scan context has already expired.
+		s.upsertConnectionAnomaly(ctx, instance, nil, s.timeoutError(scanCtx))
+		s.recordFailedInstance(ctx, instance)
+		return
+	}
+
+	databaseFind := &api.DatabaseFind{
+		InstanceID: &instance.ID,
+	}
+	dbList, err := s.server.DatabaseService.FindDatabaseList(scanCtx, databaseFind)
+	if err != nil {
+		s.l.Error("Failed to retrieve database list",
+			zap.String("instance", instance.Name),
+			zap.Error(err))
+		s.recordFailedInstance(ctx, instance)
+		return
+	}
+	var scanList []*api.Database
+	for _, database := range dbList {
+		// The anomalies of the database not found by the last sync are archived by the sync already.
+		if database.SyncStatus == api.NotFound {
+			continue
+		}
+		if s.isDatabaseExcluded(instance.Engine, database.Name) {
+			// Archive the anomalies found before the database was excluded, so that they won't stay stale.
+			s.archiveDatabaseAnomalyList(scanCtx, instance, database)
+			continue
+		}
+		scanList = append(scanList, database)
+	}
+	s.forEachDatabase(scanCtx, instance, scanList, func(database *api.Database) {
+		s.scanDatabase(scanCtx, instance, database, sharedDriver, findDatabaseSchema(schemaMap, database.Name), backupPlanPolicyMap, schemaDriftPolicy)
+		if scanCtx.Err() != nil {
+			// Use the parent context since the scan context has already expired.
+			s.upsertConnectionAnomaly(ctx, instance, database, s.timeoutError(scanCtx))
+			s.recordFailedInstance(ctx, instance)
+		}
+	})
+}
+
+// syncInstanceSchema syncs the schemas of all databases of the instance for the database checks, keyed by the database name.
+// SyncSchema queries every database of the instance, so it's called once per instance scan instead of once per database.
+// Returns nil if none of the checks needs the schema, or the sync fails.
+func (s *AnomalyScanner) syncInstanceSchema(ctx context.Context, instance *api.Instance, driver db.Driver) map[string]*db.Schema {
+	needsIndex := !isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseIndexMissing) && driver.Capabilities().SupportsIndexInspection
+	needsRowCount := !isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseTableRowCountGrowth)
+	if !needsIndex && !needsRowCount {
+		return nil
+	}
+	_, schemaList, err := driver.SyncSchema(ctx)
+	if err != nil {
+		s.l.Error("Failed to sync instance schema",
+			zap.String("instance", instance.Name),
+			zap.Error(err))
+		return nil
+	}
+	schemaMap := make(map[string]*db.Schema)
+	for _, schema := range schemaList {
+		schemaMap[schema.Name] = schema
+	}
+	return schemaMap
+}
+
+// findDatabaseSchema returns the synced schema of the database from schemaMap, which is empty if the sync doesn't find the database,
+// or nil if the instance schema isn't synced.
+func findDatabaseSchema(schemaMap map[string]*db.Schema, databaseName string) *db.Schema {
+	if schemaMap == nil {
+		return nil
+	}
+	if schema, ok := schemaMap[databaseName]; ok {
+		return schema
+	}
+	return &db.Schema{Name: databaseName}
+}
+
+// isInstanceDriverShareable returns whether the driver of the instance can be shared by the concurrent scans of its databases.
+// The Postgres driver is bound to a database, and switching it to another database would close the connection in use by the other scans.
+func isInstanceDriverShareable(engine db.Type) bool {
+	return engine != db.Postgres
+}
+
+// scanDatabase runs the checks for the database within the database scan timeout.
+// Raises the scan timeout anomaly if the checks don't finish in time, and archives it once they do.
+// The checks use the shared driver of the instance, or open a driver of the database if sharedDriver is nil.
+// schema is the database schema synced with the instance, the checks relying on it are skipped if it's nil.
+func (s *AnomalyScanner) scanDatabase(ctx context.Context, instance *api.Instance, database *api.Database, sharedDriver db.Driver, schema *db.Schema, backupPlanPolicyMap map[int]*api.BackupPlanPolicy, schemaDriftPolicy *api.SchemaDriftPolicy) {
+	databaseCtx, cancel := context.WithTimeout(ctx, s.databaseTimeout)
+	defer cancel()
+
+	s.checkDatabaseAnomaly(databaseCtx, instance, database, sharedDriver, schema, schemaDriftPolicy)
+	if databaseCtx.Err() == nil {
+		s.checkBackupAnomaly(databaseCtx, instance, database, backupPlanPolicyMap)
+	}
+	if ctx.Err() != nil {
+		// The instance scan has timed out, which is recorded as the connection anomaly instead.
+		return
+	}
+
+	if databaseCtx.Err() != nil {
+		if isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseScanTimeout) {
+			return
+		}
+		payload, err := json.Marshal(api.AnomalyDatabaseScanTimeoutPayload{
+			TimeoutSeconds: int64(s.databaseTimeout.Seconds()),
+		})
+		if err != nil {
+			s.l.Error("Failed to marshal anomaly payload",
+				zap.String("instance", instance.Name),
+				zap.String("database", database.Name),
+				zap.String("type", string(api.AnomalyDatabaseScanTimeout)),
+				zap.Error(err))
+			return
+		}
+		// Use the parent context since the database scan context has already expired.
+		err = s.upsertAnomaly(ctx, &api.AnomalyUpsert{
+			CreatorID:  api.SystemBotID,
+			InstanceID: instance.ID,
+			DatabaseID: &database.ID,
+			Type:       api.AnomalyDatabaseScanTimeout,
+			Payload:    string(payload),
+		})
+		if err != nil {
+			s.l.Error("Failed to create anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("database", database.Name),
+				zap.String("type", string(api.AnomalyDatabaseScanTimeout)),
+				zap.Error(err))
+		}
+		return
+	}
+
+	err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+		DatabaseID: &database.ID,
+		Type:       api.AnomalyDatabaseScanTimeout,
+		Reason:     api.AnomalyArchiveReasonScanCompleted,
+	})
+	if err != nil && common.ErrorCode(err) != common.NotFound {
+		s.l.Error("Failed to close anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseScanTimeout)),
+			zap.Error(err))
+	}
+}
+
+// forEachDatabase calls fn for each database with at most s.concurrency calls in flight, and returns after all calls finish.
+// It stops dispatching the remaining databases once ctx is done.
+func (s *AnomalyScanner) forEachDatabase(ctx context.Context, instance *api.Instance, dbList []*api.Database, fn func(database *api.Database)) {
+	databaseCh := make(chan *api.Database)
+	var wg sync.WaitGroup
+	for i := 0; i < s.concurrency && i < len(dbList); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for database := range databaseCh {
+				func() {
+					defer func() {
+						if r := recover(); r != nil {
+							err, ok := r.(error)
+							if !ok {
+								err = fmt.Errorf("%v", r)
+							}
+							s.l.Error("Anomaly scanner PANIC RECOVER",
+								zap.String("instance", instance.Name),
+								zap.String("database", database.Name),
+								zap.Error(err))
+							s.recordFailedInstance(ctx, instance)
+						}
+					}()
+					fn(database)
+				}()
+			}
+		}()
+	}
+	for _, database := range dbList {
+		if ctx.Err() != nil {
+			break
+		}
+		databaseCh <- database
+	}
+	close(databaseCh)
+	wg.Wait()
+}
+
+// archiveInstanceAnomalyList archives the active anomalies of the instance and its databases whose types are in typeSet,
+// or all of them if typeSet is nil.
+func (s *AnomalyScanner) archiveInstanceAnomalyList(ctx context.Context, instance *api.Instance, typeSet map[api.AnomalyType]bool) {
+	status := api.Normal
+	anomalyList, err := s.server.AnomalyService.FindAnomalyList(ctx, &api.AnomalyFind{
+		RowStatus:  &status,
+		InstanceID: &instance.ID,
+	})
+	if err != nil {
+		s.l.Error("Failed to retrieve anomaly list",
+			zap.String("instance", instance.Name),
+			zap.Error(err))
+		return
+	}
+	for _, anomaly := range anomalyList {
+		if typeSet != nil && !typeSet[anomaly.Type] {
+			continue
+		}
+		archive := &api.AnomalyArchive{
+			DatabaseID: anomaly.DatabaseID,
+			Type:       anomaly.Type,
+			Reason:     api.AnomalyArchiveReasonCheckDisabled,
+		}
+		if anomaly.DatabaseID == nil {
+			archive.InstanceID = &instance.ID
+		}
+		if err := s.archiveAnomaly(ctx, archive); err != nil && common.ErrorCode(err) != common.NotFound {
+			s.l.Error("Failed to close anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("type", string(anomaly.Type)),
+				zap.Error(err))
+		}
+	}
+}
+
+// archiveDisabledInstanceAnomalyList archives the active anomalies of the instances whose anomaly scan is disabled,
+// so that the anomalies found before the scan was disabled won't stay stale.
+func (s *AnomalyScanner) archiveDisabledInstanceAnomalyList(ctx context.Context) {
+	rowStatus := api.Normal
+	enableAnomalyScan := false
+	instanceList, err := s.server.InstanceService.FindInstanceList(ctx, &api.InstanceFind{
+		RowStatus:         &rowStatus,
+		EnableAnomalyScan: &enableAnomalyScan,
+	})
+	if err != nil {
+		s.l.Error("Failed to retrieve instance list", zap.Error(err))
+		return
+	}
+	for _, instance := range instanceList {
+		if s.isStopping() {
+			return
+		}
+		s.archiveInstanceAnomalyList(ctx, instance, nil)
+	}
+}
+
+// isDatabaseExcluded returns whether the database is skipped by the scan, either as a system database of the engine
+// or by the configured patterns.
+func (s *AnomalyScanner) isDatabaseExcluded(engine db.Type, databaseName string) bool {
+	for _, re := range defaultExcludedDatabaseRegexpMap[engine] {
+		if re.MatchString(databaseName) {
+			return true
+		}
+	}
+	for _, re := range s.excludedDatabaseRegexpList {
+		if re.MatchString(databaseName) {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveDatabaseAnomalyList archives all active anomalies of the database.
+func (s *AnomalyScanner) archiveDatabaseAnomalyList(ctx context.Context, instance *api.Instance, database *api.Database) {
+	status := api.Normal
+	anomalyList, err := s.server.AnomalyService.FindAnomalyList(ctx, &api.AnomalyFind{
+		RowStatus:  &status,
+		DatabaseID: &database.ID,
+	})
+	if err != nil {
+		s.l.Error("Failed to retrieve anomaly list",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.Error(err))
+		return
+	}
+	for _, anomaly := range anomalyList {
+		if err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+			DatabaseID: &database.ID,
+			Type:       anomaly.Type,
+			Reason:     api.AnomalyArchiveReasonCheckDisabled,
+		}); err != nil && common.ErrorCode(err) != common.NotFound {
+			s.l.Error("Failed to close anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("database", database.Name),
+				zap.String("type", string(anomaly.Type)),
+				zap.Error(err))
+		}
+	}
+}
+
+// isAnomalyTypeDisabled returns whether the anomaly type is disabled by the anomaly policy of the instance scanned with ctx.
+func isAnomalyTypeDisabled(ctx context.Context, anomalyType api.AnomalyType) bool {
+	disabledTypeSet, _ := ctx.Value(disabledAnomalyTypeSetKey{}).(map[api.AnomalyType]bool)
+	return disabledTypeSet[anomalyType]
+}
+
+// timeoutError returns the error recorded in the connection anomaly when the instance scan exceeds the timeout.
+func (s *AnomalyScanner) timeoutError(ctx context.Context) error {
+	return fmt.Errorf("anomaly scan did not finish within %v: %w", s.timeout, ctx.Err())
+}
+
+// upsertConnectionAnomaly records the instance connection anomaly if database is nil, otherwise records the database connection anomaly.
+func (s *AnomalyScanner) upsertConnectionAnomaly(ctx context.Context, instance *api.Instance, database *api.Database, connErr error) {
+	anomalyType := api.AnomalyInstanceConnection
+	var databaseID *int
+	category := db.ClassifyConnectionError(connErr)
+	var anomalyPayload interface{} = api.AnomalyInstanceConnectionPayload{
+		Detail:   connErr.Error(),
+		Category: category,
+		Host:     instance.Host,
+		Port:     instance.Port,
+	}
+	logFields := []zap.Field{zap.String("instance", instance.Name)}
+	if database != nil {
+		anomalyType = api.AnomalyDatabaseConnection
+		databaseID = &database.ID
+		anomalyPayload = api.AnomalyDatabaseConnectionPayload{
+			Detail:   connErr.Error(),
+			Category: category,
+			Host:     instance.Host,
+			Port:     instance.Port,
+		}
+		logFields = append(logFields, zap.String("database", database.Name))
+	}
+	if isAnomalyTypeDisabled(ctx, anomalyType) {
+		return
+	}
+	logFields = append(logFields, zap.String("type", string(anomalyType)))
+
+	payload, err := json.Marshal(anomalyPayload)
+	if err != nil {
+		s.l.Error("Failed to marshal anomaly payload", append(logFields, zap.Error(err))...)
+		return
+	}
+	err = s.upsertAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		DatabaseID: databaseID,
+		Type:       anomalyType,
+		Payload:    string(payload),
+	})
+	if err != nil {
+		s.l.Error("Failed to create anomaly", append(logFields, zap.Error(err))...)
+	}
+}
+
+// openDriver opens the driver and pings the database, and retries with exponential backoff on failures
+// up to s.connectRetryCount attempts in total. Returns the error of the last attempt.
+func (s *AnomalyScanner) openDriver(ctx context.Context, instance *api.Instance, databaseName string) (db.Driver, error) {
+	backoff := s.connectRetryBackoff
+	var err error
+	for attempt := 1; attempt <= s.connectRetryCount; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return nil, err
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		var driver db.Driver
+		driver, err = getDatabaseDriver(ctx, instance, databaseName, s.l)
+		if err == nil {
+			if err = driver.Ping(ctx); err == nil {
+				return driver, nil
+			}
+			driver.Close(context.Background())
+			err = common.Errorf(common.DbConnectionFailure, fmt.Errorf("failed to ping database at %s:%s with user %q: %w", instance.Host, instance.Port, instance.Username, err))
+		}
+		s.l.Debug("Failed to connect for anomaly scan",
+			zap.String("instance", instance.Name),
+			zap.String("database", databaseName),
+			zap.Int("attempt", attempt),
+			zap.Error(err))
+	}
+	return nil, err
+}
+
+// checkInstanceAnomaly runs the instance checks, and returns the driver of the instance for the caller to reuse and close,
+// or nil if the instance can't be connected.
+func (s *AnomalyScanner) checkInstanceAnomaly(ctx context.Context, instance *api.Instance, anomalyPolicy *api.AnomalyPolicy) db.Driver {
+	driver, err := s.openDriver(ctx, instance, "")
+
+	// Check connection
+	if err != nil {
+		s.upsertConnectionAnomaly(ctx, instance, nil, err)
+		return nil
+	}
+
+	err = s.archiveAnomaly(ctx, &api.AnomalyArchive{
+		InstanceID: &instance.ID,
+		Type:       api.AnomalyInstanceConnection,
+		Reason:     api.AnomalyArchiveReasonConnectionRestored,
+	})
+	if err != nil && common.ErrorCode(err) != common.NotFound {
+		s.l.Error("Failed to close anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("type", string(api.AnomalyInstanceConnection)),
+			zap.Error(err))
+	}
+
+	s.checkConnectionUnencryptedAnomaly(ctx, instance, driver, anomalyPolicy.RequireEncryptedConnection)
+	s.checkInsecureConnectionAnomaly(ctx, instance, driver, anomalyPolicy.CheckInsecureConnection)
+	s.checkConnectionCountAnomaly(ctx, instance, driver)
+	s.checkDiskSpaceAnomaly(ctx, instance, driver)
+	s.checkReplicationLagAnomaly(ctx, instance, driver)
+	s.checkVersionOutdatedAnomaly(ctx, instance, driver)
+	s.checkEngineVersionEOLAnomaly(ctx, instance, driver)
+
+	// Check migration schema
+	if !isAnomalyTypeDisabled(ctx, api.AnomalyInstanceMigrationSchema) && driver.Capabilities().SupportsMigrationHistory {
+		setup, err := driver.NeedsSetupMigration(ctx)
+		if err != nil {
+			s.l.Error("Failed to check migration schema",
+				zap.String("instance", instance.Name),
+				zap.String("type", string(api.AnomalyInstanceMigrationSchema)),
+				zap.Error(err))
+		} else {
+			if setup {
+				err = s.upsertAnomaly(ctx, &api.AnomalyUpsert{
+					CreatorID:  api.SystemBotID,
+					InstanceID: instance.ID,
+					Type:       api.AnomalyInstanceMigrationSchema,
+				})
+				if err != nil {
 					s.l.Error("Failed to create anomaly",
 						zap.String("instance", instance.Name),
 						zap.String("type", string(api.AnomalyInstanceMigrationSchema)),
 						zap.Error(err))
 				}
 			} else {
-				err := s.server.AnomalyService.ArchiveAnomaly(ctx, &api.AnomalyArchive{
+				err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
 					InstanceID: &instance.ID,
 					Type:       api.AnomalyInstanceMigrationSchema,
+					Reason:     api.AnomalyArchiveReasonMigrationSchemaSetUp,
 				})
 				if err != nil && common.ErrorCode(err) != common.NotFound {
 					s.l.Error("Failed to close anomaly",
@@ -229,145 +1572,1508 @@ func (s *AnomalyScanner) checkInstanceAnomaly(ctx context.Context, instance *api
 			}
 		}
 	}
+	return driver
+}
+
+// checkConnectionUnencryptedAnomaly raises the unencrypted connection anomaly if the environment requires encryption
+// but the connection to the instance doesn't use TLS, and archives it once encryption is observed or no longer required.
+// The engines whose drivers can't tell the TLS state skip the check.
+func (s *AnomalyScanner) checkConnectionUnencryptedAnomaly(ctx context.Context, instance *api.Instance, driver db.Driver, requireEncryption bool) {
+	if isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseConnectionUnencrypted) {
+		return
+	}
+	encrypted, reason := true, api.AnomalyArchiveReasonCheckDisabled
+	if requireEncryption {
+		if !driver.Capabilities().SupportsTLSState {
+			return
+		}
+		state, err := driver.GetConnectionTLSState(ctx)
+		if err != nil {
+			s.l.Error("Failed to check anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("type", string(api.AnomalyDatabaseConnectionUnencrypted)),
+				zap.Error(err))
+			return
+		}
+		encrypted, reason = state.Encrypted, api.AnomalyArchiveReasonConnectionSecure
+	}
+
+	if encrypted {
+		err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+			InstanceID: &instance.ID,
+			Type:       api.AnomalyDatabaseConnectionUnencrypted,
+			Reason:     reason,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			s.l.Error("Failed to close anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("type", string(api.AnomalyDatabaseConnectionUnencrypted)),
+				zap.Error(err))
+		}
+		return
+	}
+	err := s.upsertAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		Type:       api.AnomalyDatabaseConnectionUnencrypted,
+	})
+	if err != nil {
+		s.l.Error("Failed to create anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("type", string(api.AnomalyDatabaseConnectionUnencrypted)),
+			zap.Error(err))
+	}
+}
+
+// checkInsecureConnectionAnomaly raises the insecure connection anomaly if the connection to the instance falls back to plaintext
+// though configured to use TLS, or the server certificate is self-signed or expired, and archives it once the connection is secure
+// or the check is turned off. The engines whose drivers can't tell the TLS state skip the check.
+func (s *AnomalyScanner) checkInsecureConnectionAnomaly(ctx context.Context, instance *api.Instance, driver db.Driver, enabled bool) {
+	if isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseInsecureConnection) {
+		return
+	}
+	var state *db.ConnectionTLSState
+	reason := api.AnomalyArchiveReasonCheckDisabled
+	if enabled {
+		if !driver.Capabilities().SupportsTLSState {
+			return
+		}
+		var err error
+		state, err = driver.GetConnectionTLSState(ctx)
+		if err != nil {
+			s.l.Error("Failed to check anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("type", string(api.AnomalyDatabaseInsecureConnection)),
+				zap.Error(err))
+			return
+		}
+		reason = api.AnomalyArchiveReasonConnectionSecure
+	}
+
+	if state == nil || !isConnectionInsecure(state, s.now()) {
+		err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+			InstanceID: &instance.ID,
+			Type:       api.AnomalyDatabaseInsecureConnection,
+			Reason:     reason,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			s.l.Error("Failed to close anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("type", string(api.AnomalyDatabaseInsecureConnection)),
+				zap.Error(err))
+		}
+		return
+	}
+	anomalyPayload := api.AnomalyDatabaseInsecureConnectionPayload{
+		Encrypted:  state.Encrypted,
+		SelfSigned: state.SelfSigned,
+	}
+	if !state.CertificateNotAfter.IsZero() {
+		anomalyPayload.CertificateExpireTs = state.CertificateNotAfter.Unix()
+	}
+	payload, err := json.Marshal(anomalyPayload)
+	if err != nil {
+		s.l.Error("Failed to marshal anomaly payload",
+			zap.String("instance", instance.Name),
+			zap.String("type", string(api.AnomalyDatabaseInsecureConnection)),
+			zap.Error(err))
+		return
+	}
+	err = s.upsertAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		Type:       api.AnomalyDatabaseInsecureConnection,
+		Payload:    string(payload),
+	})
+	if err != nil {
+		s.l.Error("Failed to create anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("type", string(api.AnomalyDatabaseInsecureConnection)),
+			zap.Error(err))
+	}
+}
+
+// isConnectionInsecure returns whether the connection falls back to plaintext though configured to use TLS,
+// or the server certificate is self-signed or expired at now.
+func isConnectionInsecure(state *db.ConnectionTLSState, now time.Time) bool {
+	if state.Required && !state.Encrypted {
+		return true
+	}
+	if state.SelfSigned {
+		return true
+	}
+	return !state.CertificateNotAfter.IsZero() && !now.Before(state.CertificateNotAfter)
+}
+
+// checkConnectionCountAnomaly raises the connection count anomaly if the connections in use exceed the threshold of max connections.
+// Only MySQL is supported for now, other engines skip the check.
+func (s *AnomalyScanner) checkConnectionCountAnomaly(ctx context.Context, instance *api.Instance, driver db.Driver) {
+	if isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseConnectionCountHigh) {
+		return
+	}
+	if instance.Engine != db.MySQL {
+		return
+	}
+	sqldb, err := driver.GetDbConnection(ctx, "")
+	if err != nil {
+		s.l.Error("Failed to check anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("type", string(api.AnomalyDatabaseConnectionCountHigh)),
+			zap.Error(err))
+		return
+	}
+	currentConnections, maxConnections, err := getMySQLConnectionCount(ctx, sqldb)
+	if err != nil {
+		s.l.Error("Failed to check anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("type", string(api.AnomalyDatabaseConnectionCountHigh)),
+			zap.Error(err))
+		return
+	}
+	s.updateConnectionCountAnomaly(ctx, instance, currentConnections, maxConnections)
+}
+
+// updateConnectionCountAnomaly raises or archives the connection count anomaly based on the connection usage.
+func (s *AnomalyScanner) updateConnectionCountAnomaly(ctx context.Context, instance *api.Instance, currentConnections int, maxConnections int) {
+	if maxConnections <= 0 {
+		return
+	}
+	percentage := currentConnections * 100 / maxConnections
+	if percentage <= s.connectionCountThreshold {
+		err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+			InstanceID: &instance.ID,
+			Type:       api.AnomalyDatabaseConnectionCountHigh,
+			Reason:     api.AnomalyArchiveReasonBelowThreshold,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			s.l.Error("Failed to close anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("type", string(api.AnomalyDatabaseConnectionCountHigh)),
+				zap.Error(err))
+		}
+		return
+	}
+
+	payload, err := json.Marshal(api.AnomalyDatabaseConnectionCountHighPayload{
+		CurrentConnections: currentConnections,
+		MaxConnections:     maxConnections,
+		Percentage:         percentage,
+	})
+	if err != nil {
+		s.l.Error("Failed to marshal anomaly payload",
+			zap.String("instance", instance.Name),
+			zap.String("type", string(api.AnomalyDatabaseConnectionCountHigh)),
+			zap.Error(err))
+		return
+	}
+	err = s.upsertAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		Type:       api.AnomalyDatabaseConnectionCountHigh,
+		Payload:    string(payload),
+	})
+	if err != nil {
+		s.l.Error("Failed to create anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("type", string(api.AnomalyDatabaseConnectionCountHigh)),
+			zap.Error(err))
+	}
+}
+
+// checkDiskSpaceAnomaly raises the instance anomaly if the used disk space exceeds the threshold.
+// Drivers that can't report disk usage skip the check.
+func (s *AnomalyScanner) checkDiskSpaceAnomaly(ctx context.Context, instance *api.Instance, driver db.Driver) {
+	if isAnomalyTypeDisabled(ctx, api.AnomalyInstanceDiskSpaceLow) {
+		return
+	}
+	if !driver.Capabilities().SupportsDiskUsage {
+		return
+	}
+	usage, err := driver.GetDiskUsage(ctx)
+	if err != nil {
+		s.l.Error("Failed to check anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("type", string(api.AnomalyInstanceDiskSpaceLow)),
+			zap.Error(err))
+		return
+	}
+	if usage.TotalBytes <= 0 {
+		return
+	}
+
+	percentage := int(usage.UsedBytes * 100 / usage.TotalBytes)
+	if percentage <= s.diskUsageThreshold {
+		err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+			InstanceID: &instance.ID,
+			Type:       api.AnomalyInstanceDiskSpaceLow,
+			Reason:     api.AnomalyArchiveReasonBelowThreshold,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			s.l.Error("Failed to close anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("type", string(api.AnomalyInstanceDiskSpaceLow)),
+				zap.Error(err))
+		}
+		return
+	}
+
+	payload, err := json.Marshal(api.AnomalyInstanceDiskSpaceLowPayload{
+		TotalBytes: usage.TotalBytes,
+		UsedBytes:  usage.UsedBytes,
+		FreeBytes:  usage.FreeBytes,
+		Percentage: percentage,
+		Threshold:  s.diskUsageThreshold,
+	})
+	if err != nil {
+		s.l.Error("Failed to marshal anomaly payload",
+			zap.String("instance", instance.Name),
+			zap.String("type", string(api.AnomalyInstanceDiskSpaceLow)),
+			zap.Error(err))
+		return
+	}
+	err = s.upsertAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		Type:       api.AnomalyInstanceDiskSpaceLow,
+		Payload:    string(payload),
+	})
+	if err != nil {
+		s.l.Error("Failed to create anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("type", string(api.AnomalyInstanceDiskSpaceLow)),
+			zap.Error(err))
+	}
+}
+
+// checkReplicationLagAnomaly raises the replication lag anomaly if the replica falls behind its primary longer than the threshold.
+// The instances not flagged as replicas are skipped.
+func (s *AnomalyScanner) checkReplicationLagAnomaly(ctx context.Context, instance *api.Instance, driver db.Driver) {
+	if isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseReplicationLag) {
+		return
+	}
+	if !instance.Replica {
+		// The anomaly raised before the replica flag is cleared is stale.
+		err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+			InstanceID: &instance.ID,
+			Type:       api.AnomalyDatabaseReplicationLag,
+			Reason:     api.AnomalyArchiveReasonCheckDisabled,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			s.l.Error("Failed to close anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("type", string(api.AnomalyDatabaseReplicationLag)),
+				zap.Error(err))
+		}
+		return
+	}
+	if !driver.Capabilities().SupportsReplicationStatus {
+		return
+	}
+
+	status, err := driver.ReplicationStatus(ctx)
+	if err != nil {
+		s.l.Error("Failed to check anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("type", string(api.AnomalyDatabaseReplicationLag)),
+			zap.Error(err))
+		return
+	}
+
+	if status.Lag <= s.replicationLagThreshold {
+		err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+			InstanceID: &instance.ID,
+			Type:       api.AnomalyDatabaseReplicationLag,
+			Reason:     api.AnomalyArchiveReasonBelowThreshold,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			s.l.Error("Failed to close anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("type", string(api.AnomalyDatabaseReplicationLag)),
+				zap.Error(err))
+		}
+		return
+	}
+
+	payload, err := json.Marshal(api.AnomalyDatabaseReplicationLagPayload{
+		LagSeconds:       int64(status.Lag.Seconds()),
+		ThresholdSeconds: int64(s.replicationLagThreshold.Seconds()),
+		Primary:          status.Primary,
+	})
+	if err != nil {
+		s.l.Error("Failed to marshal anomaly payload",
+			zap.String("instance", instance.Name),
+			zap.String("type", string(api.AnomalyDatabaseReplicationLag)),
+			zap.Error(err))
+		return
+	}
+	err = s.upsertAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		Type:       api.AnomalyDatabaseReplicationLag,
+		Payload:    string(payload),
+	})
+	if err != nil {
+		s.l.Error("Failed to create anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("type", string(api.AnomalyDatabaseReplicationLag)),
+			zap.Error(err))
+	}
+}
+
+// checkVersionOutdatedAnomaly raises the version outdated anomaly if the engine version is older than the minimum recommended version.
+// The engines without a minimum recommended version or whose drivers can't report the version skip the check.
+func (s *AnomalyScanner) checkVersionOutdatedAnomaly(ctx context.Context, instance *api.Instance, driver db.Driver) {
+	if isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseVersionOutdated) {
+		return
+	}
+	minimumVersion, ok := minimumEngineVersionMap[instance.Engine]
+	if !ok {
+		return
+	}
+	version, err := driver.GetVersion(ctx)
+	if err != nil {
+		if common.ErrorCode(err) != common.NotImplemented {
+			s.l.Error("Failed to check anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("type", string(api.AnomalyDatabaseVersionOutdated)),
+				zap.Error(err))
+		}
+		return
+	}
+	outdated, err := isEngineVersionOlder(instance.Engine, version, minimumVersion)
+	if err != nil {
+		s.l.Error("Failed to check anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("type", string(api.AnomalyDatabaseVersionOutdated)),
+			zap.Error(err))
+		return
+	}
+
+	if !outdated {
+		err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+			InstanceID: &instance.ID,
+			Type:       api.AnomalyDatabaseVersionOutdated,
+			Reason:     api.AnomalyArchiveReasonVersionSupported,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			s.l.Error("Failed to close anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("type", string(api.AnomalyDatabaseVersionOutdated)),
+				zap.Error(err))
+		}
+		return
+	}
+
+	payload, err := json.Marshal(api.AnomalyDatabaseVersionOutdatedPayload{
+		Version:        version,
+		MinimumVersion: minimumVersion,
+	})
+	if err != nil {
+		s.l.Error("Failed to marshal anomaly payload",
+			zap.String("instance", instance.Name),
+			zap.String("type", string(api.AnomalyDatabaseVersionOutdated)),
+			zap.Error(err))
+		return
+	}
+	err = s.upsertAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		Type:       api.AnomalyDatabaseVersionOutdated,
+		Payload:    string(payload),
+	})
+	if err != nil {
+		s.l.Error("Failed to create anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("type", string(api.AnomalyDatabaseVersionOutdated)),
+			zap.Error(err))
+	}
+}
+
+// checkEngineVersionEOLAnomaly raises the engine version end-of-life anomaly if the release line of the engine version
+// is past its end of life or within the warning period of it.
+// The engines without known end-of-life dates or whose drivers can't report the version skip the check.
+func (s *AnomalyScanner) checkEngineVersionEOLAnomaly(ctx context.Context, instance *api.Instance, driver db.Driver) {
+	if isAnomalyTypeDisabled(ctx, api.AnomalyInstanceEngineVersionEOL) {
+		return
+	}
+	version, err := driver.GetVersion(ctx)
+	if err != nil {
+		if common.ErrorCode(err) != common.NotImplemented {
+			s.l.Error("Failed to check anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("type", string(api.AnomalyInstanceEngineVersionEOL)),
+				zap.Error(err))
+		}
+		return
+	}
+	s.updateEngineVersionEOLAnomaly(ctx, instance, version, s.now())
+}
+
+// updateEngineVersionEOLAnomaly raises or archives the engine version end-of-life anomaly for the version as of now.
+// An unknown release line archives the anomaly, e.g. after upgrading to a release line newer than the end-of-life table.
+func (s *AnomalyScanner) updateEngineVersionEOLAnomaly(ctx context.Context, instance *api.Instance, version string, now time.Time) {
+	eol := db.GetEngineVersionEOL(instance.Engine, version)
+	if eol == nil || now.Add(s.engineVersionEOLWarningPeriod).Before(eol.Date) {
+		err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+			InstanceID: &instance.ID,
+			Type:       api.AnomalyInstanceEngineVersionEOL,
+			Reason:     api.AnomalyArchiveReasonVersionSupported,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			s.l.Error("Failed to close anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("type", string(api.AnomalyInstanceEngineVersionEOL)),
+				zap.Error(err))
+		}
+		return
+	}
+
+	payload, err := json.Marshal(api.AnomalyInstanceEngineVersionEOLPayload{
+		Version:       version,
+		EOLDate:       eol.Date.Format("2006-01-02"),
+		DaysRemaining: int(math.Floor(eol.Date.Sub(now).Hours() / 24)),
+	})
+	if err != nil {
+		s.l.Error("Failed to marshal anomaly payload",
+			zap.String("instance", instance.Name),
+			zap.String("type", string(api.AnomalyInstanceEngineVersionEOL)),
+			zap.Error(err))
+		return
+	}
+	err = s.upsertAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		Type:       api.AnomalyInstanceEngineVersionEOL,
+		Payload:    string(payload),
+	})
+	if err != nil {
+		s.l.Error("Failed to create anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("type", string(api.AnomalyInstanceEngineVersionEOL)),
+			zap.Error(err))
+	}
+}
+
+// engineVersionRegexp matches the leading dotted numbers of a version string, e.g. "14.2" in "14.2 (Debian 14.2-1.pgdg110+1)".
+var engineVersionRegexp = regexp.MustCompile(`^\d+(\.\d+)*`)
+
+// parseEngineVersion parses the numeric components of the version reported by the engine.
+// TiDB reports a MySQL compatible version like "5.7.25-TiDB-v5.4.0", whose TiDB version is parsed instead.
+func parseEngineVersion(engine db.Type, version string) ([]int, error) {
+	v := strings.TrimSpace(version)
+	if engine == db.TiDB {
+		if i := strings.Index(v, "-TiDB-v"); i >= 0 {
+			v = v[i+len("-TiDB-v"):]
+		}
+	}
+	match := engineVersionRegexp.FindString(v)
+	if match == "" {
+		return nil, fmt.Errorf("invalid %s version %q", engine, version)
+	}
+	var componentList []int
+	for _, component := range strings.Split(match, ".") {
+		n, err := strconv.Atoi(component)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s version %q: %w", engine, version, err)
+		}
+		componentList = append(componentList, n)
+	}
+	return componentList, nil
+}
+
+// isEngineVersionOlder returns whether the version reported by the engine is older than the minimum version.
+// The missing components are treated as 0, e.g. "10" equals "10.0".
+func isEngineVersionOlder(engine db.Type, version string, minimumVersion string) (bool, error) {
+	current, err := parseEngineVersion(engine, version)
+	if err != nil {
+		return false, err
+	}
+	minimum, err := parseEngineVersion(engine, minimumVersion)
+	if err != nil {
+		return false, err
+	}
+	for i := 0; i < len(current) || i < len(minimum); i++ {
+		var c, m int
+		if i < len(current) {
+			c = current[i]
+		}
+		if i < len(minimum) {
+			m = minimum[i]
+		}
+		if c != m {
+			return c < m, nil
+		}
+	}
+	return false, nil
+}
+
+// getMySQLConnectionCount returns the current and max connections of a MySQL instance.
+func getMySQLConnectionCount(ctx context.Context, sqldb *sql.DB) (int, int, error) {
+	var name string
+	var currentConnections int
+	query := "SHOW GLOBAL STATUS LIKE 'Threads_connected'"
+	if err := sqldb.QueryRowContext(ctx, query).Scan(&name, &currentConnections); err != nil {
+		return 0, 0, util.FormatErrorWithQuery(err, query)
+	}
+	var maxConnections int
+	query = "SELECT @@max_connections"
+	if err := sqldb.QueryRowContext(ctx, query).Scan(&maxConnections); err != nil {
+		return 0, 0, util.FormatErrorWithQuery(err, query)
+	}
+	return currentConnections, maxConnections, nil
+}
+
+// checkDatabaseAnomaly runs the database checks with the shared driver of the instance, or with a driver of the database
+// opened for the checks if sharedDriver is nil or fails to ping. Succeeding in connecting also archives the database connection anomaly.
+func (s *AnomalyScanner) checkDatabaseAnomaly(ctx context.Context, instance *api.Instance, database *api.Database, sharedDriver db.Driver, schema *db.Schema, schemaDriftPolicy *api.SchemaDriftPolicy) {
+	driver := sharedDriver
+	if driver != nil {
+		// The shared connection may have been broken since the instance check, e.g. by the server killing it.
+		// Fall back to a connection of the database, so that the broken shared one doesn't fail the checks of every database.
+		if err := driver.Ping(ctx); err != nil {
+			s.l.Debug("Failed to ping the shared instance connection, connect to the database instead",
+				zap.String("instance", instance.Name),
+				zap.String("database", database.Name),
+				zap.Error(err))
+			driver = nil
+		}
+	}
+	if driver == nil {
+		var err error
+		driver, err = s.openDriver(ctx, instance, database.Name)
+
+		// Check connection
+		if err != nil {
+			s.upsertConnectionAnomaly(ctx, instance, database, err)
+			return
+		}
+		// Close with a fresh context since ctx may have expired by the database scan timeout.
+		defer driver.Close(context.Background())
+	}
+	err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+		DatabaseID: &database.ID,
+		Type:       api.AnomalyDatabaseConnection,
+		Reason:     api.AnomalyArchiveReasonConnectionRestored,
+	})
+	if err != nil && common.ErrorCode(err) != common.NotFound {
+		s.l.Error("Failed to close anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseConnection)),
+			zap.Error(err))
+	}
+
+	s.checkSchemaDriftAnomaly(ctx, instance, database, driver, schemaDriftPolicy)
+	s.checkUntrackedAnomaly(ctx, instance, database, driver)
+	s.checkIndexMissingAnomaly(ctx, instance, database, driver, schema)
+	s.checkLongRunningTransactionAnomaly(ctx, instance, database, driver)
+	s.checkTableBloatAnomaly(ctx, instance, database, driver)
+	s.checkMissingIndexAnomaly(ctx, instance, database, driver)
+	s.checkTableRowCountGrowthAnomaly(ctx, instance, database, schema)
+}
+
+// checkUntrackedAnomaly raises an anomaly if the database has no migration history, not even a baseline.
+// For the managed databases, which are expected to be under migration control, it's the no migration history anomaly
+// catching the databases created out-of-band. Otherwise, it's the untracked anomaly so the operators get the databases
+// to put under migration management.
+func (s *AnomalyScanner) checkUntrackedAnomaly(ctx context.Context, instance *api.Instance, database *api.Database, driver db.Driver) {
+	anomalyType, otherType := api.AnomalyDatabaseUntracked, api.AnomalyDatabaseNoMigrationHistory
+	if database.Managed {
+		anomalyType, otherType = otherType, anomalyType
+	}
+	// The other anomaly no longer applies once the database is flagged or unflagged as managed.
+	if !isAnomalyTypeDisabled(ctx, otherType) {
+		err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+			DatabaseID: &database.ID,
+			Type:       otherType,
+			Reason:     api.AnomalyArchiveReasonCheckDisabled,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			s.l.Error("Failed to close anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("database", database.Name),
+				zap.String("type", string(otherType)),
+				zap.Error(err))
+		}
+	}
+	if isAnomalyTypeDisabled(ctx, anomalyType) {
+		return
+	}
+	if !driver.Capabilities().SupportsMigrationHistory {
+		return
+	}
+	setup, err := driver.NeedsSetupMigration(ctx)
+	if err != nil {
+		s.l.Debug("Failed to check anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(anomalyType)),
+			zap.Error(err))
+		return
+	}
+	// Skip if migration schema is not ready (we have instance anomaly to cover that)
+	if setup {
+		return
+	}
+	limit := 1
+	list, err := driver.FindMigrationHistoryList(ctx, &db.MigrationHistoryFind{
+		Database: &database.Name,
+		Limit:    &limit,
+	})
+	if err != nil {
+		s.l.Error("Failed to check anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(anomalyType)),
+			zap.Error(err))
+		return
+	}
+
+	if len(list) > 0 {
+		err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+			DatabaseID: &database.ID,
+			Type:       anomalyType,
+			Reason:     api.AnomalyArchiveReasonDatabaseTracked,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			s.l.Error("Failed to close anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("database", database.Name),
+				zap.String("type", string(anomalyType)),
+				zap.Error(err))
+		}
+		return
+	}
+
+	var payload []byte
+	if database.Managed {
+		payload, err = json.Marshal(api.AnomalyDatabaseNoMigrationHistoryPayload{
+			InstanceName: instance.Name,
+			DatabaseName: database.Name,
+		})
+	} else {
+		payload, err = json.Marshal(api.AnomalyDatabaseUntrackedPayload{
+			InstanceName: instance.Name,
+			DatabaseName: database.Name,
+		})
+	}
+	if err != nil {
+		s.l.Error("Failed to marshal anomaly payload",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(anomalyType)),
+			zap.Error(err))
+		return
+	}
+	if err := s.upsertAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		DatabaseID: &database.ID,
+		Type:       anomalyType,
+		Payload:    string(payload),
+	}); err != nil {
+		s.l.Error("Failed to create anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(anomalyType)),
+			zap.Error(err))
+	}
+}
+
+// pgTableBloat is the tuple statistics of a Postgres table.
+type pgTableBloat struct {
+	table      string
+	liveTuples int64
+	deadTuples int64
+}
+
+// checkTableBloatAnomaly raises the table bloat anomaly if the dead tuple ratio of any table exceeds the threshold.
+// Only Postgres is supported for now, other engines skip the check.
+func (s *AnomalyScanner) checkTableBloatAnomaly(ctx context.Context, instance *api.Instance, database *api.Database, driver db.Driver) {
+	if isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseTableBloat) {
+		return
+	}
+	if instance.Engine != db.Postgres {
+		return
+	}
+	sqldb, err := driver.GetDbConnection(ctx, database.Name)
+	if err != nil {
+		s.l.Error("Failed to check anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseTableBloat)),
+			zap.Error(err))
+		return
+	}
+	tableList, err := getPGTableBloatList(ctx, sqldb)
+	if err != nil {
+		s.l.Error("Failed to check anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseTableBloat)),
+			zap.Error(err))
+		return
+	}
+	s.updateTableBloatAnomaly(ctx, instance, database, tableList)
+}
+
+// getPGTableBloatList returns the tuple statistics of the user tables with at least pgTableBloatMinDeadTuples dead tuples.
+func getPGTableBloatList(ctx context.Context, sqldb *sql.DB) ([]*pgTableBloat, error) {
+	query := `
+		SELECT schemaname, relname, n_live_tup, n_dead_tup
+		FROM pg_stat_user_tables
+		WHERE n_dead_tup >= $1`
+	rows, err := sqldb.QueryContext(ctx, query, pgTableBloatMinDeadTuples)
+	if err != nil {
+		return nil, util.FormatErrorWithQuery(err, query)
+	}
+	defer rows.Close()
+
+	var list []*pgTableBloat
+	for rows.Next() {
+		var schemaName, tableName string
+		table := &pgTableBloat{}
+		if err := rows.Scan(&schemaName, &tableName, &table.liveTuples, &table.deadTuples); err != nil {
+			return nil, err
+		}
+		table.table = fmt.Sprintf("%s.%s", schemaName, tableName)
+		list = append(list, table)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// updateTableBloatAnomaly raises the table bloat anomaly for the table with the highest dead tuple ratio above the threshold,
+// or archives the anomaly if every table is under the threshold.
+func (s *AnomalyScanner) updateTableBloatAnomaly(ctx context.Context, instance *api.Instance, database *api.Database, tableList []*pgTableBloat) {
+	var worst *pgTableBloat
+	worstPercentage := 0
+	for _, table := range tableList {
+		total := table.liveTuples + table.deadTuples
+		if total <= 0 {
+			continue
+		}
+		percentage := int(table.deadTuples * 100 / total)
+		if percentage > s.tableBloatThreshold && percentage > worstPercentage {
+			worst = table
+			worstPercentage = percentage
+		}
+	}
+
+	if worst == nil {
+		err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+			DatabaseID: &database.ID,
+			Type:       api.AnomalyDatabaseTableBloat,
+			Reason:     api.AnomalyArchiveReasonBelowThreshold,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			s.l.Error("Failed to close anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("database", database.Name),
+				zap.String("type", string(api.AnomalyDatabaseTableBloat)),
+				zap.Error(err))
+		}
+		return
+	}
+
+	payload, err := json.Marshal(api.AnomalyDatabaseTableBloatPayload{
+		Table:      worst.table,
+		DeadTuples: worst.deadTuples,
+		LiveTuples: worst.liveTuples,
+		Percentage: worstPercentage,
+		Threshold:  s.tableBloatThreshold,
+	})
+	if err != nil {
+		s.l.Error("Failed to marshal anomaly payload",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseTableBloat)),
+			zap.Error(err))
+		return
+	}
+	err = s.upsertAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		DatabaseID: &database.ID,
+		Type:       api.AnomalyDatabaseTableBloat,
+		Payload:    string(payload),
+	})
+	if err != nil {
+		s.l.Error("Failed to create anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseTableBloat)),
+			zap.Error(err))
+	}
+}
+
+// tableScanStats is the scan statistics of a table.
+type tableScanStats struct {
+	table           string
+	sequentialScans int64
+	indexScans      int64
+}
+
+// checkMissingIndexAnomaly raises the missing index anomaly if any table is scanned sequentially far more often than through an index.
+// The statistics come from pg_stat_user_tables on Postgres and from the performance schema on MySQL, other engines skip the check.
+func (s *AnomalyScanner) checkMissingIndexAnomaly(ctx context.Context, instance *api.Instance, database *api.Database, driver db.Driver) {
+	if isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseMissingIndex) {
+		return
+	}
+	if instance.Engine != db.Postgres && instance.Engine != db.MySQL {
+		return
+	}
+	sqldb, err := driver.GetDbConnection(ctx, database.Name)
+	if err != nil {
+		s.l.Error("Failed to check anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseMissingIndex)),
+			zap.Error(err))
+		return
+	}
+	var tableList []*tableScanStats
+	if instance.Engine == db.Postgres {
+		tableList, err = getPGTableScanStatsList(ctx, sqldb)
+	} else {
+		tableList, err = getMySQLTableScanStatsList(ctx, sqldb, database.Name)
+	}
+	if err != nil {
+		s.l.Error("Failed to check anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseMissingIndex)),
+			zap.Error(err))
+		return
+	}
+	s.updateMissingIndexAnomaly(ctx, instance, database, tableList)
+}
+
+// getPGTableScanStatsList returns the scan statistics of the user tables with at least tableScanMinRows live tuples.
+// The index scans of a table without any index are NULL and counted as 0.
+func getPGTableScanStatsList(ctx context.Context, sqldb *sql.DB) ([]*tableScanStats, error) {
+	query := `
+		SELECT schemaname, relname, seq_scan, COALESCE(idx_scan, 0)
+		FROM pg_stat_user_tables
+		WHERE n_live_tup >= $1`
+	rows, err := sqldb.QueryContext(ctx, query, tableScanMinRows)
+	if err != nil {
+		return nil, util.FormatErrorWithQuery(err, query)
+	}
+	defer rows.Close()
+
+	var list []*tableScanStats
+	for rows.Next() {
+		var schemaName, tableName string
+		table := &tableScanStats{}
+		if err := rows.Scan(&schemaName, &tableName, &table.sequentialScans, &table.indexScans); err != nil {
+			return nil, err
+		}
+		table.table = fmt.Sprintf("%s.%s", schemaName, tableName)
+		list = append(list, table)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// getMySQLTableScanStatsList returns the scan statistics of the tables of the database with at least tableScanMinRows rows.
+// The performance schema only counts the rows read, so the scans are the rows read by full table scans and through indexes.
+// The list is empty if the performance schema is disabled.
+func getMySQLTableScanStatsList(ctx context.Context, sqldb *sql.DB, databaseName string) ([]*tableScanStats, error) {
+	query := `
+		SELECT s.OBJECT_NAME,
+			SUM(IF(s.INDEX_NAME IS NULL, s.COUNT_READ, 0)),
+			SUM(IF(s.INDEX_NAME IS NULL, 0, s.COUNT_READ))
+		FROM performance_schema.table_io_waits_summary_by_index_usage s
+		JOIN information_schema.TABLES t ON t.TABLE_SCHEMA = s.OBJECT_SCHEMA AND t.TABLE_NAME = s.OBJECT_NAME
+		WHERE s.OBJECT_SCHEMA = ? AND t.TABLE_ROWS >= ?
+		GROUP BY s.OBJECT_NAME`
+	rows, err := sqldb.QueryContext(ctx, query, databaseName, tableScanMinRows)
+	if err != nil {
+		return nil, util.FormatErrorWithQuery(err, query)
+	}
+	defer rows.Close()
+
+	var list []*tableScanStats
+	for rows.Next() {
+		table := &tableScanStats{}
+		if err := rows.Scan(&table.table, &table.sequentialScans, &table.indexScans); err != nil {
+			return nil, err
+		}
+		list = append(list, table)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// updateMissingIndexAnomaly raises the missing index anomaly for the table with the highest ratio of sequential to index scans
+// above the threshold, or archives the anomaly if every table is under the threshold.
+// The tables with fewer than tableScanMinSequentialScans sequential scans are skipped, and a table without index scans
+// is treated as having one.
+func (s *AnomalyScanner) updateMissingIndexAnomaly(ctx context.Context, instance *api.Instance, database *api.Database, tableList []*tableScanStats) {
+	var worst *tableScanStats
+	var worstRatio float64
+	for _, table := range tableList {
+		if table.sequentialScans < tableScanMinSequentialScans {
+			continue
+		}
+		indexScans := table.indexScans
+		if indexScans < 1 {
+			indexScans = 1
+		}
+		ratio := float64(table.sequentialScans) / float64(indexScans)
+		if ratio > s.sequentialScanRatioThreshold && ratio > worstRatio {
+			worst = table
+			worstRatio = ratio
+		}
+	}
+
+	if worst == nil {
+		err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+			DatabaseID: &database.ID,
+			Type:       api.AnomalyDatabaseMissingIndex,
+			Reason:     api.AnomalyArchiveReasonBelowThreshold,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			s.l.Error("Failed to close anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("database", database.Name),
+				zap.String("type", string(api.AnomalyDatabaseMissingIndex)),
+				zap.Error(err))
+		}
+		return
+	}
+
+	payload, err := json.Marshal(api.AnomalyDatabaseMissingIndexPayload{
+		Table:           worst.table,
+		SequentialScans: worst.sequentialScans,
+		IndexScans:      worst.indexScans,
+		Ratio:           math.Round(worstRatio*100) / 100,
+		Threshold:       s.sequentialScanRatioThreshold,
+	})
+	if err != nil {
+		s.l.Error("Failed to marshal anomaly payload",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseMissingIndex)),
+			zap.Error(err))
+		return
+	}
+	err = s.upsertAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		DatabaseID: &database.ID,
+		Type:       api.AnomalyDatabaseMissingIndex,
+		Payload:    string(payload),
+	})
+	if err != nil {
+		s.l.Error("Failed to create anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseMissingIndex)),
+			zap.Error(err))
+	}
+}
+
+// checkTableRowCountGrowthAnomaly raises the row count growth anomaly if the row count estimate of any table grows beyond
+// the threshold since the previous round. The row counts observed in this round replace the baselines afterwards.
+// schema is the database schema synced with the instance, the check is skipped if it's nil.
+func (s *AnomalyScanner) checkTableRowCountGrowthAnomaly(ctx context.Context, instance *api.Instance, database *api.Database, schema *db.Schema) {
+	if isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseTableRowCountGrowth) || schema == nil {
+		return
+	}
+	rowCountMap := make(map[string]int64)
+	for _, table := range schema.TableList {
+		rowCountMap[table.Name] = table.RowCount
+	}
+
+	baselineList, err := s.server.RowCountBaselineService.FindRowCountBaselineList(ctx, &api.RowCountBaselineFind{
+		DatabaseID: &database.ID,
+	})
+	if err != nil {
+		s.l.Error("Failed to check anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseTableRowCountGrowth)),
+			zap.Error(err))
+		return
+	}
+	s.updateTableRowCountGrowthAnomaly(ctx, instance, database, baselineList, rowCountMap)
+
+	// Dry run writes nothing to the metadata store, so the next round still compares with the same baselines.
+	if s.isDryRun(ctx) {
+		return
+	}
+	if err := s.upsertRowCountBaselineList(ctx, &api.RowCountBaselineUpsert{
+		DatabaseID:  database.ID,
+		RowCountMap: rowCountMap,
+	}); err != nil {
+		s.l.Error("Failed to save row count baseline",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.Error(err))
+	}
+}
+
+// upsertRowCountBaselineList saves the row count baselines of the database, serialized with the anomaly writes.
+func (s *AnomalyScanner) upsertRowCountBaselineList(ctx context.Context, upsert *api.RowCountBaselineUpsert) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.server.RowCountBaselineService.UpsertRowCountBaselineList(ctx, upsert)
+}
+
+// updateTableRowCountGrowthAnomaly raises the row count growth anomaly for the table with the highest growth ratio above the threshold,
+// or archives the anomaly if every table is under the threshold. Tables without a baseline or with fewer than
+// rowCountGrowthMinRowCount rows are skipped.
+func (s *AnomalyScanner) updateTableRowCountGrowthAnomaly(ctx context.Context, instance *api.Instance, database *api.Database, baselineList []*api.RowCountBaseline, rowCountMap map[string]int64) {
+	var worst *api.RowCountBaseline
+	worstRatio := 0.0
+	for _, baseline := range baselineList {
+		rowCount, ok := rowCountMap[baseline.TableName]
+		if !ok || baseline.RowCount <= 0 || rowCount < rowCountGrowthMinRowCount {
+			continue
+		}
+		ratio := float64(rowCount) / float64(baseline.RowCount)
+		if ratio > s.rowCountGrowthThreshold && ratio > worstRatio {
+			worst = baseline
+			worstRatio = ratio
+		}
+	}
+
+	if worst == nil {
+		err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+			DatabaseID: &database.ID,
+			Type:       api.AnomalyDatabaseTableRowCountGrowth,
+			Reason:     api.AnomalyArchiveReasonBelowThreshold,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			s.l.Error("Failed to close anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("database", database.Name),
+				zap.String("type", string(api.AnomalyDatabaseTableRowCountGrowth)),
+				zap.Error(err))
+		}
+		return
+	}
+
+	payload, err := json.Marshal(api.AnomalyDatabaseTableRowCountGrowthPayload{
+		Table:            worst.TableName,
+		PreviousRowCount: worst.RowCount,
+		PreviousTs:       worst.ObservedTs,
+		CurrentRowCount:  rowCountMap[worst.TableName],
+		GrowthRatio:      math.Round(worstRatio*100) / 100,
+		Threshold:        s.rowCountGrowthThreshold,
+	})
+	if err != nil {
+		s.l.Error("Failed to marshal anomaly payload",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseTableRowCountGrowth)),
+			zap.Error(err))
+		return
+	}
+	err = s.upsertAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		DatabaseID: &database.ID,
+		Type:       api.AnomalyDatabaseTableRowCountGrowth,
+		Payload:    string(payload),
+	})
+	if err != nil {
+		s.l.Error("Failed to create anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseTableRowCountGrowth)),
+			zap.Error(err))
+	}
+}
+
+// checkSchemaDriftAnomaly compares the dumped schema against the schema recorded in the latest migration history.
+// The objects matching the ignore patterns of the schema drift policy are excluded from the comparison, the policy can be nil.
+func (s *AnomalyScanner) checkSchemaDriftAnomaly(ctx context.Context, instance *api.Instance, database *api.Database, driver db.Driver, schemaDriftPolicy *api.SchemaDriftPolicy) {
+	if isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseSchemaDrift) {
+		return
+	}
+	// Without the migration history, there is no expected schema to compare with.
+	if !driver.Capabilities().SupportsMigrationHistory {
+		return
+	}
+	var ignorePatternList []*regexp.Regexp
+	if schemaDriftPolicy != nil {
+		for _, pattern := range schemaDriftPolicy.IgnorePatternList {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				s.l.Error("Invalid schema drift ignore pattern",
+					zap.String("instance", instance.Name),
+					zap.String("database", database.Name),
+					zap.String("pattern", pattern),
+					zap.Error(err))
+				return
+			}
+			ignorePatternList = append(ignorePatternList, re)
+		}
+	}
+
+	setup, err := driver.NeedsSetupMigration(ctx)
+	if err != nil {
+		s.l.Debug("Failed to check anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseSchemaDrift)),
+			zap.Error(err))
+		return
+	}
+	// Skip drift check if migration schema is not ready (we have instance anomaly to cover that)
+	if setup {
+		return
+	}
+	var schemaBuf bytes.Buffer
+	if err := driver.Dump(ctx, database.Name, &schemaBuf, true /*schemaOnly*/); err != nil {
+		if common.ErrorCode(err) == common.NotFound {
+			s.l.Debug("Failed to check anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("database", database.Name),
+				zap.String("type", string(api.AnomalyDatabaseSchemaDrift)),
+				zap.Error(err))
+		} else {
+			s.l.Error("Failed to check anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("database", database.Name),
+				zap.String("type", string(api.AnomalyDatabaseSchemaDrift)),
+				zap.Error(err))
+		}
+		return
+	}
+	limit := 1
+	list, err := driver.FindMigrationHistoryList(ctx, &db.MigrationHistoryFind{
+		Database: &database.Name,
+		Limit:    &limit,
+	})
+	if err != nil {
+		s.l.Error("Failed to check anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseSchemaDrift)),
+			zap.Error(err))
+		return
+	}
+	// Without any migration history, e.g. the history has been reset, there is no expected schema to drift from,
+	// so the anomaly raised against the previous history is archived.
+	var anomalyPayload *api.AnomalyDatabaseSchemaDriftPayload
+	if len(list) > 0 {
+		// The payload keeps the raw schemas for debugging, while the drift decision is based on the normalized ones.
+		expect := normalizeSchema(instance.Engine, removeIgnoredSchemaObject(list[0].Schema, ignorePatternList))
+		actual := normalizeSchema(instance.Engine, removeIgnoredSchemaObject(schemaBuf.String(), ignorePatternList))
+		if expect != actual {
+			anomalyPayload = &api.AnomalyDatabaseSchemaDriftPayload{
+				Version: list[0].Version,
+				Diff:    common.UnifiedDiff(list[0].Version, "actual", list[0].Schema, schemaBuf.String(), schemaDriftDiffContextLines),
+			}
+			// Only the diff is kept for large schemas to not bloat the metadata store.
+			if len(list[0].Schema) <= schemaDriftFullSchemaMaxSize && schemaBuf.Len() <= schemaDriftFullSchemaMaxSize {
+				anomalyPayload.Expect = list[0].Schema
+				anomalyPayload.Actual = schemaBuf.String()
+			}
+		}
+	}
+
+	if anomalyPayload != nil {
+		payload, err := json.Marshal(*anomalyPayload)
+		if err != nil {
+			s.l.Error("Failed to marshal anomaly payload",
+				zap.String("instance", instance.Name),
+				zap.String("database", database.Name),
+				zap.String("type", string(api.AnomalyDatabaseSchemaDrift)),
+				zap.Error(err))
+			return
+		}
+		err = s.upsertAnomaly(ctx, &api.AnomalyUpsert{
+			CreatorID:  api.SystemBotID,
+			InstanceID: instance.ID,
+			DatabaseID: &database.ID,
+			Type:       api.AnomalyDatabaseSchemaDrift,
+			Payload:    string(payload),
+		})
+		if err != nil {
+			s.l.Error("Failed to create anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("database", database.Name),
+				zap.String("type", string(api.AnomalyDatabaseSchemaDrift)),
+				zap.Error(err))
+		}
+		return
+	}
+
+	err = s.archiveAnomaly(ctx, &api.AnomalyArchive{
+		DatabaseID: &database.ID,
+		Type:       api.AnomalyDatabaseSchemaDrift,
+		Reason:     api.AnomalyArchiveReasonSchemaMatched,
+	})
+	if err != nil && common.ErrorCode(err) != common.NotFound {
+		s.l.Error("Failed to close anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseSchemaDrift)),
+			zap.Error(err))
+	}
+}
+
+// schemaDriftBaselineVersion returns the migration version of the baseline adopting the live schema, which is
+// the timestamp suffixed with ".baseline", e.g. "20220301150405.baseline". It sorts after the versions of the
+// earlier UI migrations, which are the timestamp suffixed with the task ID.
+func schemaDriftBaselineVersion(now time.Time) string {
+	return fmt.Sprintf("%s.baseline", now.Format("20060102150405"))
+}
+
+// BaselineSchemaDrift adopts the live schema of the database as the intended one, after an operator acknowledges
+// the schema drift. It records a baseline migration history, whose schema is the current dump of the database,
+// and archives the schema drift anomaly if any, so the following scans compare against the new baseline.
+// Returns the version of the baseline migration history.
+func (s *AnomalyScanner) BaselineSchemaDrift(ctx context.Context, instance *api.Instance, database *api.Database, creator string) (string, error) {
+	driver, err := s.openDriver(ctx, instance, database.Name)
+	if err != nil {
+		return "", err
+	}
+	defer driver.Close(context.Background())
+
+	// An empty baseline statement records the dumped schema of the database as is.
+	mi := &db.MigrationInfo{
+		ReleaseVersion: s.server.version,
+		Version:        schemaDriftBaselineVersion(s.now()),
+		Namespace:      database.Name,
+		Database:       database.Name,
+		Environment:    instance.Environment.Name,
+		Engine:         db.UI,
+		Type:           db.Baseline,
+		Description:    "Adopt the live schema as the baseline after the schema drift",
+		Creator:        creator,
+	}
+	if _, _, err := driver.ExecuteMigration(ctx, mi, ""); err != nil {
+		return "", fmt.Errorf("failed to create the schema drift baseline for database %q: %w", database.Name, err)
+	}
+
+	err = s.archiveAnomaly(ctx, &api.AnomalyArchive{
+		DatabaseID: &database.ID,
+		Type:       api.AnomalyDatabaseSchemaDrift,
+		Reason:     api.AnomalyArchiveReasonSchemaMatched,
+	})
+	if err != nil && common.ErrorCode(err) != common.NotFound {
+		return "", fmt.Errorf("failed to archive the schema drift anomaly of database %q: %w", database.Name, err)
+	}
+	return mi.Version, nil
+}
+
+// checkIndexMissingAnomaly reports foreign keys whose columns are not covered by the leading columns of any index.
+// schema is the database schema synced with the instance, the check is skipped if it's nil.
+func (s *AnomalyScanner) checkIndexMissingAnomaly(ctx context.Context, instance *api.Instance, database *api.Database, driver db.Driver, schema *db.Schema) {
+	if isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseIndexMissing) {
+		return
+	}
+	if !driver.Capabilities().SupportsIndexInspection || schema == nil {
+		return
+	}
+
+	var foreignKeyList []api.IndexMissingForeignKey
+	for _, table := range schema.TableList {
+		for _, fk := range findForeignKeyWithoutIndex(table) {
+			foreignKeyList = append(foreignKeyList, api.IndexMissingForeignKey{
+				Table:             table.Name,
+				ColumnList:        fk.ColumnList,
+				SuggestedIndexDDL: suggestedForeignKeyIndexDDL(instance.Engine, table.Name, fk),
+			})
+		}
+	}
+
+	if len(foreignKeyList) == 0 {
+		err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+			DatabaseID: &database.ID,
+			Type:       api.AnomalyDatabaseIndexMissing,
+			Reason:     api.AnomalyArchiveReasonIndexPresent,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			s.l.Error("Failed to close anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("database", database.Name),
+				zap.String("type", string(api.AnomalyDatabaseIndexMissing)),
+				zap.Error(err))
+		}
+		return
+	}
+
+	payload, err := json.Marshal(api.AnomalyDatabaseIndexMissingPayload{
+		ForeignKeyList: foreignKeyList,
+	})
+	if err != nil {
+		s.l.Error("Failed to marshal anomaly payload",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseIndexMissing)),
+			zap.Error(err))
+		return
+	}
+	err = s.upsertAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		DatabaseID: &database.ID,
+		Type:       api.AnomalyDatabaseIndexMissing,
+		Payload:    string(payload),
+	})
+	if err != nil {
+		s.l.Error("Failed to create anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseIndexMissing)),
+			zap.Error(err))
+	}
+}
+
+// findForeignKeyWithoutIndex returns the foreign keys of the table whose columns don't form the leading columns of any index.
+// The column order within the leading columns doesn't matter since the lookup on the referencing side is an equality match.
+func findForeignKeyWithoutIndex(table db.Table) []db.ForeignKey {
+	// index name -> columns ordered by position
+	indexColumnMap := make(map[string][]string)
+	sortedIndexList := make([]db.Index, len(table.IndexList))
+	copy(sortedIndexList, table.IndexList)
+	sort.SliceStable(sortedIndexList, func(i, j int) bool {
+		return sortedIndexList[i].Position < sortedIndexList[j].Position
+	})
+	for _, index := range sortedIndexList {
+		indexColumnMap[index.Name] = append(indexColumnMap[index.Name], index.Expression)
+	}
+
+	var list []db.ForeignKey
+	for _, fk := range table.ForeignKeyList {
+		covered := false
+		for _, columnList := range indexColumnMap {
+			if isColumnPrefixCovered(columnList, fk.ColumnList) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			list = append(list, fk)
+		}
+	}
+	return list
+}
+
+// isColumnPrefixCovered returns whether the first len(columnList) index columns are exactly the given columns in any order.
+func isColumnPrefixCovered(indexColumnList []string, columnList []string) bool {
+	if len(columnList) == 0 || len(indexColumnList) < len(columnList) {
+		return false
+	}
+	prefix := make(map[string]bool)
+	for _, column := range indexColumnList[:len(columnList)] {
+		prefix[column] = true
+	}
+	for _, column := range columnList {
+		if !prefix[column] {
+			return false
+		}
+	}
+	return true
 }
 
-func (s *AnomalyScanner) checkDatabaseAnomaly(ctx context.Context, instance *api.Instance, database *api.Database) {
-	driver, err := getDatabaseDriver(ctx, instance, database.Name, s.l)
-
-	// Check connection
-	if err != nil {
-		anomalyPayload := api.AnomalyDatabaseConnectionPayload{
-			Detail: err.Error(),
+// suggestedForeignKeyIndexDDL returns the DDL to create an index covering the foreign key columns.
+func suggestedForeignKeyIndexDDL(engine db.Type, tableName string, fk db.ForeignKey) string {
+	switch engine {
+	case db.Postgres:
+		// Postgres table and column names are already quoted when needed, and the index name is generated if omitted.
+		return fmt.Sprintf("CREATE INDEX ON %s (%s);", tableName, strings.Join(fk.ColumnList, ", "))
+	case db.MSSQL:
+		// SQL Server table names are qualified with the schema, e.g. "dbo.book", and the foreign key name is empty if named by the system.
+		name := fk.Name
+		if name == "" {
+			name = strings.Join(fk.ColumnList, "_")
 		}
-		payload, err := json.Marshal(anomalyPayload)
-		if err != nil {
-			s.l.Error("Failed to marshal anomaly payload",
-				zap.String("instance", instance.Name),
-				zap.String("database", database.Name),
-				zap.String("type", string(api.AnomalyDatabaseConnection)),
-				zap.Error(err))
-		} else {
-			_, err = s.server.AnomalyService.UpsertActiveAnomaly(ctx, &api.AnomalyUpsert{
-				CreatorID:  api.SystemBotID,
-				InstanceID: instance.ID,
-				DatabaseID: &database.ID,
-				Type:       api.AnomalyDatabaseConnection,
-				Payload:    string(payload),
-			})
-			if err != nil {
-				s.l.Error("Failed to create anomaly",
-					zap.String("instance", instance.Name),
-					zap.String("database", database.Name),
-					zap.String("type", string(api.AnomalyDatabaseConnection)),
-					zap.Error(err))
-			}
+		var partList []string
+		for _, part := range strings.SplitN(tableName, ".", 2) {
+			partList = append(partList, fmt.Sprintf("[%s]", part))
+		}
+		var columnList []string
+		for _, column := range fk.ColumnList {
+			columnList = append(columnList, fmt.Sprintf("[%s]", column))
 		}
+		return fmt.Sprintf("CREATE INDEX [idx_%s] ON %s (%s);", name, strings.Join(partList, "."), strings.Join(columnList, ", "))
+	default:
+		var columnList []string
+		for _, column := range fk.ColumnList {
+			columnList = append(columnList, fmt.Sprintf("`%s`", column))
+		}
+		return fmt.Sprintf("CREATE INDEX `idx_%s` ON `%s` (%s);", fk.Name, tableName, strings.Join(columnList, ", "))
+	}
+}
+
+// checkLongRunningTransactionAnomaly raises the anomaly if any transaction on the database runs longer than the threshold.
+// Drivers that can't list active transactions skip the check.
+func (s *AnomalyScanner) checkLongRunningTransactionAnomaly(ctx context.Context, instance *api.Instance, database *api.Database, driver db.Driver) {
+	if isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseLongRunningTransaction) {
 		return
 	}
-	defer driver.Close(ctx)
-	err = s.server.AnomalyService.ArchiveAnomaly(ctx, &api.AnomalyArchive{
-		DatabaseID: &database.ID,
-		Type:       api.AnomalyDatabaseConnection,
-	})
-	if err != nil && common.ErrorCode(err) != common.NotFound {
-		s.l.Error("Failed to close anomaly",
+	if !driver.Capabilities().SupportsTxList {
+		return
+	}
+	list, err := driver.FindLongRunningTransactionList(ctx, database.Name, s.longRunningTransactionThreshold)
+	if err != nil {
+		s.l.Error("Failed to check anomaly",
 			zap.String("instance", instance.Name),
 			zap.String("database", database.Name),
-			zap.String("type", string(api.AnomalyDatabaseConnection)),
+			zap.String("type", string(api.AnomalyDatabaseLongRunningTransaction)),
 			zap.Error(err))
+		return
 	}
 
-	// Check schema drift
-	{
-		setup, err := driver.NeedsSetupMigration(ctx)
-		if err != nil {
-			s.l.Debug("Failed to check anomaly",
-				zap.String("instance", instance.Name),
-				zap.String("database", database.Name),
-				zap.String("type", string(api.AnomalyDatabaseSchemaDrift)),
-				zap.Error(err))
-			goto SchemaDriftEnd
-		}
-		// Skip drift check if migration schema is not ready (we have instance anomaly to cover that)
-		if setup {
-			goto SchemaDriftEnd
-		}
-		var schemaBuf bytes.Buffer
-		if err := driver.Dump(ctx, database.Name, &schemaBuf, true /*schemaOnly*/); err != nil {
-			if common.ErrorCode(err) == common.NotFound {
-				s.l.Debug("Failed to check anomaly",
-					zap.String("instance", instance.Name),
-					zap.String("database", database.Name),
-					zap.String("type", string(api.AnomalyDatabaseSchemaDrift)),
-					zap.Error(err))
-			} else {
-				s.l.Error("Failed to check anomaly",
-					zap.String("instance", instance.Name),
-					zap.String("database", database.Name),
-					zap.String("type", string(api.AnomalyDatabaseSchemaDrift)),
-					zap.Error(err))
-			}
-			goto SchemaDriftEnd
-		}
-		limit := 1
-		list, err := driver.FindMigrationHistoryList(ctx, &db.MigrationHistoryFind{
-			Database: &database.Name,
-			Limit:    &limit,
+	if len(list) == 0 {
+		err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+			DatabaseID: &database.ID,
+			Type:       api.AnomalyDatabaseLongRunningTransaction,
+			Reason:     api.AnomalyArchiveReasonBelowThreshold,
 		})
-		if err != nil {
-			s.l.Error("Failed to check anomaly",
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			s.l.Error("Failed to close anomaly",
 				zap.String("instance", instance.Name),
 				zap.String("database", database.Name),
-				zap.String("type", string(api.AnomalyDatabaseSchemaDrift)),
+				zap.String("type", string(api.AnomalyDatabaseLongRunningTransaction)),
 				zap.Error(err))
-			goto SchemaDriftEnd
-		}
-		if len(list) > 0 {
-			if list[0].Schema != schemaBuf.String() {
-				anomalyPayload := api.AnomalyDatabaseSchemaDriftPayload{
-					Version: list[0].Version,
-					Expect:  list[0].Schema,
-					Actual:  schemaBuf.String(),
-				}
-				payload, err := json.Marshal(anomalyPayload)
-				if err != nil {
-					s.l.Error("Failed to marshal anomaly payload",
-						zap.String("instance", instance.Name),
-						zap.String("database", database.Name),
-						zap.String("type", string(api.AnomalyDatabaseSchemaDrift)),
-						zap.Error(err))
-				} else {
-					_, err = s.server.AnomalyService.UpsertActiveAnomaly(ctx, &api.AnomalyUpsert{
-						CreatorID:  api.SystemBotID,
-						InstanceID: instance.ID,
-						DatabaseID: &database.ID,
-						Type:       api.AnomalyDatabaseSchemaDrift,
-						Payload:    string(payload),
-					})
-					if err != nil {
-						s.l.Error("Failed to create anomaly",
-							zap.String("instance", instance.Name),
-							zap.String("database", database.Name),
-							zap.String("type", string(api.AnomalyDatabaseSchemaDrift)),
-							zap.Error(err))
-					}
-				}
-			} else {
-				err := s.server.AnomalyService.ArchiveAnomaly(ctx, &api.AnomalyArchive{
-					DatabaseID: &database.ID,
-					Type:       api.AnomalyDatabaseConnection,
-				})
-				if err != nil && common.ErrorCode(err) != common.NotFound {
-					s.l.Error("Failed to close anomaly",
-						zap.String("instance", instance.Name),
-						zap.String("database", database.Name),
-						zap.String("type", string(api.AnomalyDatabaseSchemaDrift)),
-						zap.Error(err))
-				}
-			}
 		}
+		return
+	}
+
+	// The list is ordered with the most long-running transaction first.
+	payload, err := json.Marshal(api.AnomalyDatabaseLongRunningTransactionPayload{
+		PID:             list[0].PID,
+		DurationSeconds: int64(list[0].Duration.Seconds()),
+		Query:           list[0].Query,
+		Count:           len(list),
+	})
+	if err != nil {
+		s.l.Error("Failed to marshal anomaly payload",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseLongRunningTransaction)),
+			zap.Error(err))
+		return
+	}
+	err = s.upsertAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		DatabaseID: &database.ID,
+		Type:       api.AnomalyDatabaseLongRunningTransaction,
+		Payload:    string(payload),
+	})
+	if err != nil {
+		s.l.Error("Failed to create anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseLongRunningTransaction)),
+			zap.Error(err))
 	}
-SchemaDriftEnd:
 }
 
 func (s *AnomalyScanner) checkBackupAnomaly(ctx context.Context, instance *api.Instance, database *api.Database, policyMap map[int]*api.BackupPlanPolicy) {
@@ -384,34 +3090,31 @@ func (s *AnomalyScanner) checkBackupAnomaly(ctx context.Context, instance *api.I
 				zap.Error(err))
 			return
 		}
-	} else {
-		if backupSetting.Enabled && backupSetting.Hour != -1 {
-			if backupSetting.DayOfWeek == -1 {
-				schedule = api.BackupPlanPolicyScheduleDaily
-			} else {
-				schedule = api.BackupPlanPolicyScheduleWeekly
-			}
+	} else if backupSetting.Enabled {
+		// A malformed setting would be classified as the wrong schedule, so the checks are skipped instead.
+		if err := backupSetting.Validate(); err != nil {
+			s.l.Warn("Skip checking the malformed backup setting",
+				zap.String("instance", instance.Name),
+				zap.String("database", database.Name),
+				zap.Error(err))
+			return
 		}
+		schedule = getBackupSettingSchedule(backupSetting)
 	}
 
 	// Check backup policy violation
-	{
+	if !isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseBackupPolicyViolation) {
+		// The environment may be created after the policy map is built, it's treated as having no backup requirement until the next round.
+		expectedSchedule := api.BackupPlanPolicyScheduleUnset
+		if policy, ok := policyMap[instance.EnvironmentID]; ok && policy != nil {
+			expectedSchedule = policy.Schedule
+		}
 		var backupPolicyAnomalyPayload *api.AnomalyDatabaseBackupPolicyViolationPayload
-		if policyMap[instance.EnvironmentID].Schedule != api.BackupPlanPolicyScheduleUnset {
-			if policyMap[instance.EnvironmentID].Schedule == api.BackupPlanPolicyScheduleDaily &&
-				schedule != api.BackupPlanPolicyScheduleDaily {
-				backupPolicyAnomalyPayload = &api.AnomalyDatabaseBackupPolicyViolationPayload{
-					EnvironmentID:          instance.EnvironmentID,
-					ExpectedBackupSchedule: policyMap[instance.EnvironmentID].Schedule,
-					ActualBackupSchedule:   schedule,
-				}
-			} else if policyMap[instance.EnvironmentID].Schedule == api.BackupPlanPolicyScheduleWeekly &&
-				schedule == api.BackupPlanPolicyScheduleUnset {
-				backupPolicyAnomalyPayload = &api.AnomalyDatabaseBackupPolicyViolationPayload{
-					EnvironmentID:          instance.EnvironmentID,
-					ExpectedBackupSchedule: policyMap[instance.EnvironmentID].Schedule,
-					ActualBackupSchedule:   schedule,
-				}
+		if !backupScheduleSatisfies(schedule, expectedSchedule) {
+			backupPolicyAnomalyPayload = &api.AnomalyDatabaseBackupPolicyViolationPayload{
+				EnvironmentID:          instance.EnvironmentID,
+				ExpectedBackupSchedule: expectedSchedule,
+				ActualBackupSchedule:   schedule,
 			}
 		}
 
@@ -424,7 +3127,7 @@ func (s *AnomalyScanner) checkBackupAnomaly(ctx context.Context, instance *api.I
 					zap.String("type", string(api.AnomalyDatabaseBackupPolicyViolation)),
 					zap.Error(err))
 			} else {
-				_, err = s.server.AnomalyService.UpsertActiveAnomaly(ctx, &api.AnomalyUpsert{
+				err = s.upsertAnomaly(ctx, &api.AnomalyUpsert{
 					CreatorID:  api.SystemBotID,
 					InstanceID: instance.ID,
 					DatabaseID: &database.ID,
@@ -440,9 +3143,10 @@ func (s *AnomalyScanner) checkBackupAnomaly(ctx context.Context, instance *api.I
 				}
 			}
 		} else {
-			err := s.server.AnomalyService.ArchiveAnomaly(ctx, &api.AnomalyArchive{
+			err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
 				DatabaseID: &database.ID,
 				Type:       api.AnomalyDatabaseBackupPolicyViolation,
+				Reason:     api.AnomalyArchiveReasonBackupPolicySatisfied,
 			})
 			if err != nil && common.ErrorCode(err) != common.NotFound {
 				s.l.Error("Failed to close anomaly",
@@ -454,41 +3158,43 @@ func (s *AnomalyScanner) checkBackupAnomaly(ctx context.Context, instance *api.I
 		}
 	}
 
+	// The backup checks below share the done backups of the database, the latest first.
+	if isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseBackupMissing) &&
+		isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseBackupCorrupt) &&
+		isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseBackupSizeAnomalous) {
+		return
+	}
+	status := api.BackupStatusDone
+	backupList, err := s.server.BackupService.FindBackupList(ctx, &api.BackupFind{
+		DatabaseID: &database.ID,
+		Status:     &status,
+	})
+	if err != nil {
+		s.l.Error("Failed to retrieve backup list",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.Error(err))
+		return
+	}
+
 	// Check backup missing
-	{
+	if !isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseBackupMissing) {
 		var backupMissingAnomalyPayload *api.AnomalyDatabaseBackupMissingPayload
 		// The anomaly fires if backup is enabled, however no succesful backup has been taken during the period.
 		if backupSetting != nil && backupSetting.Enabled {
-			expectedSchedule := api.BackupPlanPolicyScheduleWeekly
-			backupMaxAge := time.Duration(7*24) * time.Hour
-			if backupSetting.DayOfWeek == -1 {
-				expectedSchedule = api.BackupPlanPolicyScheduleDaily
-				backupMaxAge = time.Duration(24) * time.Hour
-			}
+			expectedSchedule := getBackupSettingSchedule(backupSetting)
+			backupMaxAge := time.Duration(float64(getBackupMaxAge(expectedSchedule)) * s.backupMaxAgeGraceMultiplier)
+			now := s.now()
 
-			// Ignore if backup setting has been changed after the max age.
-			if backupSetting.UpdatedTs < time.Now().Add(-backupMaxAge).Unix() {
-				status := api.BackupStatusDone
-				backupFind := &api.BackupFind{
-					DatabaseID: &database.ID,
-					Status:     &status,
-				}
-				backupList, err := s.server.BackupService.FindBackupList(ctx, backupFind)
-				if err != nil {
-					s.l.Error("Failed to retrieve backup list",
-						zap.String("instance", instance.Name),
-						zap.String("database", database.Name),
-						zap.Error(err))
-				}
-
-				hasValidBackup := false
-				if len(backupList) > 0 {
-					if backupList[0].UpdatedTs >= time.Now().Add(-backupMaxAge).Unix() {
-						hasValidBackup = true
-					}
-				}
-
-				if !hasValidBackup {
+			// Ignore until the first scheduled backup since the setting changed has had the grace period to complete,
+			// e.g. a newly enabled daily backup isn't missing before its first run.
+			gracePeriod := s.backupMissingGracePeriod
+			if gracePeriod == 0 {
+				gracePeriod = getBackupMaxAge(expectedSchedule)
+			}
+			firstBackupTime := getNextBackupTime(backupSetting, time.Unix(backupSetting.UpdatedTs, 0))
+			if !now.Before(firstBackupTime.Add(gracePeriod)) {
+				if len(backupList) == 0 || isBackupOverdue(backupList[0].UpdatedTs, backupMaxAge, now) {
 					backupMissingAnomalyPayload = &api.AnomalyDatabaseBackupMissingPayload{
 						ExpectedBackupSchedule: expectedSchedule,
 					}
@@ -508,7 +3214,7 @@ func (s *AnomalyScanner) checkBackupAnomaly(ctx context.Context, instance *api.I
 					zap.String("type", string(api.AnomalyDatabaseBackupMissing)),
 					zap.Error(err))
 			} else {
-				_, err = s.server.AnomalyService.UpsertActiveAnomaly(ctx, &api.AnomalyUpsert{
+				err = s.upsertAnomaly(ctx, &api.AnomalyUpsert{
 					CreatorID:  api.SystemBotID,
 					InstanceID: instance.ID,
 					DatabaseID: &database.ID,
@@ -524,9 +3230,10 @@ func (s *AnomalyScanner) checkBackupAnomaly(ctx context.Context, instance *api.I
 				}
 			}
 		} else {
-			err := s.server.AnomalyService.ArchiveAnomaly(ctx, &api.AnomalyArchive{
+			err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
 				DatabaseID: &database.ID,
 				Type:       api.AnomalyDatabaseBackupMissing,
+				Reason:     api.AnomalyArchiveReasonBackupPresent,
 			})
 			if err != nil && common.ErrorCode(err) != common.NotFound {
 				s.l.Error("Failed to close anomaly",
@@ -537,4 +3244,382 @@ func (s *AnomalyScanner) checkBackupAnomaly(ctx context.Context, instance *api.I
 			}
 		}
 	}
+
+	// Check backup corrupt
+	if !isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseBackupCorrupt) {
+		s.checkBackupCorruptAnomaly(ctx, instance, database, backupList)
+	}
+
+	// Check backup size
+	if !isAnomalyTypeDisabled(ctx, api.AnomalyDatabaseBackupSizeAnomalous) {
+		s.checkBackupSizeAnomaly(ctx, instance, database, backupList)
+	}
+}
+
+// checkBackupCorruptAnomaly verifies the checksum of the latest backup of the database by re-hashing the stored file.
+// The anomaly is archived once the latest backup verifies, e.g. after a later valid backup is taken.
+// The same backup is verified at most once per backupChecksumVerifyInterval, and the anomaly is left as is in between.
+// backupList is the done backups of the database, the latest first.
+func (s *AnomalyScanner) checkBackupCorruptAnomaly(ctx context.Context, instance *api.Instance, database *api.Database, backupList []*api.Backup) {
+	// The backups taken before checksum support can't be verified.
+	if len(backupList) == 0 || backupList[0].Checksum == "" {
+		return
+	}
+	backup := backupList[0]
+
+	s.backupVerificationMu.Lock()
+	verification, ok := s.backupVerificationMap[database.ID]
+	s.backupVerificationMu.Unlock()
+	if ok && verification.backupID == backup.ID && s.now().Sub(verification.verifiedAt) < s.backupChecksumVerifyInterval {
+		return
+	}
+
+	storage, err := getBackupStorage(ctx, s.server.dataDir, backup.StorageBackend, backup.Path, nil /* s3Config */)
+	if err != nil {
+		s.l.Error("Failed to check anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseBackupCorrupt)),
+			zap.Error(err))
+		return
+	}
+	// The attempt is recorded up front, so a backup too large to hash within the deadline isn't re-read every round.
+	// DryRunScanInstance doesn't record it to leave the verification of the next round intact.
+	if _, ok := ctx.Value(dryRunRecorderKey{}).(*dryRunRecorder); !ok {
+		s.backupVerificationMu.Lock()
+		s.backupVerificationMap[database.ID] = &backupVerification{
+			backupID:   backup.ID,
+			verifiedAt: s.now(),
+		}
+		s.backupVerificationMu.Unlock()
+	}
+
+	// A failure to read the backup, e.g. the storage being unreachable, isn't reported as corrupt.
+	checksum, err := computeBackupChecksum(ctx, storage, backup.Path)
+	if err != nil {
+		s.l.Error("Failed to check anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseBackupCorrupt)),
+			zap.Error(err))
+		return
+	}
+
+	if checksum == backup.Checksum {
+		err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+			DatabaseID: &database.ID,
+			Type:       api.AnomalyDatabaseBackupCorrupt,
+			Reason:     api.AnomalyArchiveReasonBackupVerified,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			s.l.Error("Failed to close anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("database", database.Name),
+				zap.String("type", string(api.AnomalyDatabaseBackupCorrupt)),
+				zap.Error(err))
+		}
+		return
+	}
+
+	payload, err := json.Marshal(api.AnomalyDatabaseBackupCorruptPayload{
+		BackupID:         backup.ID,
+		BackupName:       backup.Name,
+		ExpectedChecksum: backup.Checksum,
+		ActualChecksum:   checksum,
+	})
+	if err != nil {
+		s.l.Error("Failed to marshal anomaly payload",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseBackupCorrupt)),
+			zap.Error(err))
+		return
+	}
+	if err := s.upsertAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		DatabaseID: &database.ID,
+		Type:       api.AnomalyDatabaseBackupCorrupt,
+		Payload:    string(payload),
+	}); err != nil {
+		s.l.Error("Failed to create anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseBackupCorrupt)),
+			zap.Error(err))
+	}
+}
+
+// checkBackupSizeAnomaly compares the size of the latest backup of the database against the median size of the backups before it,
+// and raises the backup size anomaly if it shrinks or grows beyond backupSizeChangeThreshold, e.g. a truncated backup marked as done.
+// The backups taken before size support are skipped. backupList is the done backups of the database, the latest first.
+func (s *AnomalyScanner) checkBackupSizeAnomaly(ctx context.Context, instance *api.Instance, database *api.Database, backupList []*api.Backup) {
+	var sizedList []*api.Backup
+	for _, backup := range backupList {
+		if backup.SizeBytes > 0 {
+			sizedList = append(sizedList, backup)
+		}
+		if len(sizedList) > backupSizeHistoryCount {
+			break
+		}
+	}
+	if len(sizedList) <= backupSizeMinHistoryCount {
+		return
+	}
+	backup := sizedList[0]
+	var previousSizeList []int64
+	for _, previous := range sizedList[1:] {
+		previousSizeList = append(previousSizeList, previous.SizeBytes)
+	}
+	previousSize := medianBackupSize(previousSizeList)
+
+	ratio := float64(backup.SizeBytes) / float64(previousSize)
+	if ratio <= s.backupSizeChangeThreshold && ratio >= 1/s.backupSizeChangeThreshold {
+		err := s.archiveAnomaly(ctx, &api.AnomalyArchive{
+			DatabaseID: &database.ID,
+			Type:       api.AnomalyDatabaseBackupSizeAnomalous,
+			Reason:     api.AnomalyArchiveReasonBelowThreshold,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			s.l.Error("Failed to close anomaly",
+				zap.String("instance", instance.Name),
+				zap.String("database", database.Name),
+				zap.String("type", string(api.AnomalyDatabaseBackupSizeAnomalous)),
+				zap.Error(err))
+		}
+		return
+	}
+
+	payload, err := json.Marshal(api.AnomalyDatabaseBackupSizeAnomalousPayload{
+		BackupID:          backup.ID,
+		BackupName:        backup.Name,
+		PreviousSizeBytes: previousSize,
+		CurrentSizeBytes:  backup.SizeBytes,
+		Ratio:             math.Round(ratio*100) / 100,
+		Threshold:         s.backupSizeChangeThreshold,
+	})
+	if err != nil {
+		s.l.Error("Failed to marshal anomaly payload",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseBackupSizeAnomalous)),
+			zap.Error(err))
+		return
+	}
+	if err := s.upsertAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		DatabaseID: &database.ID,
+		Type:       api.AnomalyDatabaseBackupSizeAnomalous,
+		Payload:    string(payload),
+	}); err != nil {
+		s.l.Error("Failed to create anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseBackupSizeAnomalous)),
+			zap.Error(err))
+	}
+}
+
+// medianBackupSize returns the median of the non-empty sizeList, the lower one of the two middle sizes for an even count.
+func medianBackupSize(sizeList []int64) int64 {
+	sorted := append([]int64(nil), sizeList...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(len(sorted)-1)/2]
+}
+
+// backupScheduleFrequencyMap ranks the backup schedules by how often the backup runs.
+var backupScheduleFrequencyMap = map[api.BackupPlanPolicySchedule]int{
+	api.BackupPlanPolicyScheduleUnset:   0,
+	api.BackupPlanPolicyScheduleMonthly: 1,
+	api.BackupPlanPolicyScheduleWeekly:  2,
+	api.BackupPlanPolicyScheduleDaily:   3,
+}
+
+// backupScheduleSatisfies returns whether the actual schedule backs up at least as often as the expected schedule.
+func backupScheduleSatisfies(actual api.BackupPlanPolicySchedule, expected api.BackupPlanPolicySchedule) bool {
+	return backupScheduleFrequencyMap[actual] >= backupScheduleFrequencyMap[expected]
+}
+
+// getBackupSettingSchedule returns the schedule an enabled backup setting runs on.
+// It runs monthly if the day of month is set (DayOfMonth != -1, DayOfWeek is -1 then), otherwise daily (DayOfWeek == -1)
+// or weekly. The hour and minute only shift the backup time within the period.
+func getBackupSettingSchedule(backupSetting *api.BackupSetting) api.BackupPlanPolicySchedule {
+	if backupSetting.DayOfMonth != -1 {
+		return api.BackupPlanPolicyScheduleMonthly
+	}
+	if backupSetting.DayOfWeek == -1 {
+		return api.BackupPlanPolicyScheduleDaily
+	}
+	return api.BackupPlanPolicyScheduleWeekly
+}
+
+// getBackupMaxAge returns the max age of the last successful backup allowed by the schedule.
+func getBackupMaxAge(schedule api.BackupPlanPolicySchedule) time.Duration {
+	switch schedule {
+	case api.BackupPlanPolicyScheduleDaily:
+		return time.Duration(24) * time.Hour
+	case api.BackupPlanPolicyScheduleMonthly:
+		// Roughly the longest month.
+		return time.Duration(31*24) * time.Hour
+	default:
+		return time.Duration(7*24) * time.Hour
+	}
+}
+
+// getNextBackupTime returns the first time after t the backup setting is scheduled to run, following the backup runner
+// which matches the schedule in UTC and runs the backup with the unset minute at the start of the hour.
+// It returns the zero time if the setting never runs, which doesn't happen for a valid setting.
+func getNextBackupTime(backupSetting *api.BackupSetting, t time.Time) time.Time {
+	minute := backupSetting.Minute
+	if minute < 0 {
+		minute = 0
+	}
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), t.Day(), backupSetting.Hour, minute, 0, 0, time.UTC)
+	// The monthly backup runs within 31 days, since the day of month stops at 28.
+	for i := 0; i <= 31; i++ {
+		backupTime := start.AddDate(0, 0, i)
+		if !backupTime.After(t) {
+			continue
+		}
+		if backupSetting.DayOfWeek != -1 && int(backupTime.Weekday()) != backupSetting.DayOfWeek {
+			continue
+		}
+		if backupSetting.DayOfMonth != -1 && backupTime.Day() != backupSetting.DayOfMonth {
+			continue
+		}
+		return backupTime
+	}
+	return time.Time{}
+}
+
+// isBackupOverdue returns whether the last successful backup at lastBackupTs is older than maxAge at now.
+func isBackupOverdue(lastBackupTs int64, maxAge time.Duration, now time.Time) bool {
+	return lastBackupTs < now.Add(-maxAge).Unix()
+}
+
+// schemaObjectHeaderRegexp matches the comment header preceding each object in the schema dump, e.g. "-- Table structure for `t`".
+var schemaObjectHeaderRegexp = regexp.MustCompile("^-- .+ structure for (.+)$")
+
+// removeIgnoredSchemaObject removes the objects whose names match any of the patterns from the schema dump.
+// An object spans from its "--" comment header to the next object header, the content before the first object is kept.
+// For Postgres, the object name is qualified with the schema name, e.g. "public.t".
+func removeIgnoredSchemaObject(schema string, patternList []*regexp.Regexp) string {
+	if len(patternList) == 0 {
+		return schema
+	}
+	lines := strings.SplitAfter(schema, "\n")
+	var b strings.Builder
+	ignored := false
+	for i, line := range lines {
+		// An object header is a "--" line followed by the "-- ... structure for <name>" line.
+		if strings.TrimRight(line, "\n") == "--" && i+1 < len(lines) {
+			if matches := schemaObjectHeaderRegexp.FindStringSubmatch(strings.TrimRight(lines[i+1], "\n")); matches != nil {
+				name := strings.Trim(matches[1], "`\"")
+				ignored = false
+				for _, re := range patternList {
+					if re.MatchString(name) {
+						ignored = true
+						break
+					}
+				}
+			}
+		}
+		if !ignored {
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
+
+// mysqlAutoIncrementRegexp matches the AUTO_INCREMENT table option, whose counter changes whenever rows are inserted.
+var mysqlAutoIncrementRegexp = regexp.MustCompile(`\s+AUTO_INCREMENT=\d+`)
+
+// oracleSimpleIdentifierRegexp matches the identifier Oracle stores as is when written unquoted.
+var oracleSimpleIdentifierRegexp = regexp.MustCompile(`^[A-Z][A-Z0-9_$#]*$`)
+
+// normalizeSchema canonicalizes the schema dump so that the changes irrelevant to the schema don't count as drift.
+// It normalizes line endings and whitespace outside of quotes, drops blank lines, formats "--" comments as "-- comment",
+// for MySQL strips the AUTO_INCREMENT=N table option, and for Oracle folds the identifiers with foldOracleIdentifiers.
+func normalizeSchema(dialect db.Type, schema string) string {
+	schema = strings.ReplaceAll(schema, "\r\n", "\n")
+	if dialect == db.MySQL || dialect == db.TiDB {
+		schema = mysqlAutoIncrementRegexp.ReplaceAllString(schema, "")
+	}
+
+	var lineList []string
+	for _, line := range strings.Split(schema, "\n") {
+		line = collapseWhitespace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "--") {
+			comment := strings.TrimSpace(strings.TrimPrefix(line, "--"))
+			line = "--"
+			if comment != "" {
+				line = "-- " + comment
+			}
+		} else if dialect == db.Oracle {
+			line = foldOracleIdentifiers(line)
+		}
+		lineList = append(lineList, line)
+	}
+	return strings.Join(lineList, "\n")
+}
+
+// foldOracleIdentifiers uppercases the text outside of quotes and unquotes the double-quoted identifiers that mean the same
+// unquoted, since Oracle stores the unquoted identifiers in uppercase, e.g. both create table book and CREATE TABLE "BOOK"
+// become CREATE TABLE BOOK. The string literals and the other quoted identifiers, e.g. "Book", are kept as is.
+func foldOracleIdentifiers(line string) string {
+	var b strings.Builder
+	for len(line) > 0 {
+		switch line[0] {
+		case '\'', '"':
+			end := strings.IndexByte(line[1:], line[0])
+			if end < 0 {
+				b.WriteString(line)
+				return b.String()
+			}
+			quoted := line[:end+2]
+			if name := quoted[1 : len(quoted)-1]; line[0] == '"' && oracleSimpleIdentifierRegexp.MatchString(name) {
+				quoted = name
+			}
+			b.WriteString(quoted)
+			line = line[end+2:]
+		default:
+			end := strings.IndexAny(line, `'"`)
+			if end < 0 {
+				end = len(line)
+			}
+			b.WriteString(strings.ToUpper(line[:end]))
+			line = line[end:]
+		}
+	}
+	return b.String()
+}
+
+// collapseWhitespace trims the line and collapses each run of whitespace outside of quotes into a single space.
+func collapseWhitespace(line string) string {
+	var b strings.Builder
+	var quote rune
+	pendingSpace := false
+	for _, r := range strings.TrimSpace(line) {
+		if quote == 0 && (r == ' ' || r == '\t') {
+			pendingSpace = true
+			continue
+		}
+		if pendingSpace {
+			b.WriteRune(' ')
+			pendingSpace = false
+		}
+		switch {
+		case quote == 0 && (r == '\'' || r == '"' || r == '`'):
+			quote = r
+		case quote == r:
+			quote = 0
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
 }
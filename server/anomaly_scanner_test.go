@@ -0,0 +1,3028 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bytebase/bytebase/api"
+	"github.com/bytebase/bytebase/common"
+	"github.com/bytebase/bytebase/plugin/db"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNewAnomalyScannerInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		want     time.Duration
+	}{
+		{"unset", 0, defaultAnomalyScanInterval},
+		{"tooShort", time.Second, MinAnomalyScanInterval},
+		{"minimum", MinAnomalyScanInterval, MinAnomalyScanInterval},
+		{"custom", 30 * time.Minute, 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewAnomalyScanner(zap.NewNop(), nil, AnomalyScannerConfig{Interval: tt.interval})
+			if got := s.Interval(); got != tt.want {
+				t.Errorf("Interval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+const fakeDriverType = db.Type("FAKE")
+
+// fakeDriver is the db.Driver used by anomaly scanner tests, each test sets the fields it needs.
+type fakeDriver struct {
+	openErr error
+	// failOpenCount is the number of the following opens failing with a connection error, it's updated atomically.
+	failOpenCount int32
+	needsSetup    bool
+	schema        string
+	historyList   []*db.MigrationHistory
+	// transactionList is nil if the driver doesn't support listing transactions.
+	transactionList []*db.Transaction
+	// diskUsage is nil if the driver doesn't support reporting disk usage.
+	diskUsage *db.DiskUsage
+	// replicationLag is nil if the driver doesn't support reporting the replication status.
+	replicationLag *time.Duration
+	// replicationPrimary is the primary in the replication status.
+	replicationPrimary string
+	// schemaList is the schemas returned by SyncSchema.
+	schemaList []*db.Schema
+	// version is empty if the driver doesn't support reporting the version.
+	version string
+	// tlsState is nil if the driver can't tell the TLS state of the connection.
+	tlsState *db.ConnectionTLSState
+	// pingErr is returned by Ping if it's set.
+	pingErr error
+	// openDelay is the time each open takes, including the failed ones.
+	openDelay time.Duration
+	// blockDumpDatabase is the database whose Dump blocks until the context is done.
+	blockDumpDatabase string
+	// openCount and closeCount count the driver opens and closes, they are updated atomically.
+	openCount  int32
+	closeCount int32
+	// syncSchemaCount counts the SyncSchema calls, it's updated atomically.
+	syncSchemaCount int32
+}
+
+// testDriver is the fake driver returned by the FAKE db type.
+var testDriver = &fakeDriver{}
+
+func init() {
+	db.Register(fakeDriverType, func(config db.DriverConfig) db.Driver {
+		return testDriver
+	})
+}
+
+func (d *fakeDriver) Open(ctx context.Context, dbType db.Type, config db.ConnectionConfig, connCtx db.ConnectionContext) (db.Driver, error) {
+	time.Sleep(d.openDelay)
+	if d.openErr != nil {
+		return nil, d.openErr
+	}
+	if n := atomic.LoadInt32(&d.failOpenCount); n > 0 && atomic.CompareAndSwapInt32(&d.failOpenCount, n, n-1) {
+		return nil, fmt.Errorf("connection refused")
+	}
+	atomic.AddInt32(&d.openCount, 1)
+	return d, nil
+}
+
+func (d *fakeDriver) Close(ctx context.Context) error {
+	atomic.AddInt32(&d.closeCount, 1)
+	return nil
+}
+
+func (d *fakeDriver) Ping(ctx context.Context) error {
+	return d.pingErr
+}
+
+func (d *fakeDriver) GetDbConnection(ctx context.Context, database string) (*sql.DB, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+func (d *fakeDriver) GetVersion(ctx context.Context) (string, error) {
+	if d.version == "" {
+		return "", common.Errorf(common.NotImplemented, fmt.Errorf("not supported"))
+	}
+	return d.version, nil
+}
+
+func (d *fakeDriver) SyncSchema(ctx context.Context) ([]*db.User, []*db.Schema, error) {
+	atomic.AddInt32(&d.syncSchemaCount, 1)
+	return nil, d.schemaList, nil
+}
+
+func (d *fakeDriver) Execute(ctx context.Context, statement string) error {
+	return nil
+}
+
+// Capabilities reports the optional features whose fields are set by the test.
+func (d *fakeDriver) Capabilities() db.Capabilities {
+	return db.Capabilities{
+		SupportsDiskUsage:         d.diskUsage != nil,
+		SupportsTxList:            d.transactionList != nil,
+		SupportsReplicationStatus: d.replicationLag != nil,
+		SupportsTLSState:          d.tlsState != nil,
+		SupportsIndexInspection:   d.schemaList != nil,
+		SupportsMigrationHistory:  true,
+	}
+}
+
+func (d *fakeDriver) NeedsSetupMigration(ctx context.Context) (bool, error) {
+	return d.needsSetup, nil
+}
+
+func (d *fakeDriver) SetupMigrationIfNeeded(ctx context.Context) error {
+	return nil
+}
+
+// ExecuteMigration only supports the baseline, which records the schema as the latest migration history.
+func (d *fakeDriver) ExecuteMigration(ctx context.Context, m *db.MigrationInfo, statement string) (int64, string, error) {
+	if m.Type != db.Baseline || statement != "" {
+		return 0, "", fmt.Errorf("not supported")
+	}
+	d.historyList = append([]*db.MigrationHistory{{
+		Namespace: m.Namespace,
+		Engine:    m.Engine,
+		Type:      m.Type,
+		Status:    db.Done,
+		Version:   m.Version,
+		Schema:    d.schema,
+	}}, d.historyList...)
+	return int64(len(d.historyList)), d.schema, nil
+}
+
+func (d *fakeDriver) FindLongRunningTransactionList(ctx context.Context, database string, threshold time.Duration) ([]*db.Transaction, error) {
+	if d.transactionList == nil {
+		return nil, common.Errorf(common.NotImplemented, fmt.Errorf("not supported"))
+	}
+	var list []*db.Transaction
+	for _, transaction := range d.transactionList {
+		if transaction.Duration > threshold {
+			list = append(list, transaction)
+		}
+	}
+	return list, nil
+}
+
+func (d *fakeDriver) GetDiskUsage(ctx context.Context) (*db.DiskUsage, error) {
+	if d.diskUsage == nil {
+		return nil, common.Errorf(common.NotImplemented, fmt.Errorf("not supported"))
+	}
+	return d.diskUsage, nil
+}
+
+func (d *fakeDriver) ReplicationStatus(ctx context.Context) (*db.ReplicationStatus, error) {
+	if d.replicationLag == nil {
+		return nil, common.Errorf(common.NotImplemented, fmt.Errorf("not supported"))
+	}
+	return &db.ReplicationStatus{Lag: *d.replicationLag, Primary: d.replicationPrimary}, nil
+}
+
+func (d *fakeDriver) GetConnectionTLSState(ctx context.Context) (*db.ConnectionTLSState, error) {
+	if d.tlsState == nil {
+		return nil, common.Errorf(common.NotImplemented, fmt.Errorf("not supported"))
+	}
+	return d.tlsState, nil
+}
+
+func (d *fakeDriver) FindMigrationHistoryList(ctx context.Context, find *db.MigrationHistoryFind) ([]*db.MigrationHistory, error) {
+	return d.historyList, nil
+}
+
+func (d *fakeDriver) Dump(ctx context.Context, database string, out io.Writer, schemaOnly bool) error {
+	if database == d.blockDumpDatabase {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	_, err := io.WriteString(out, d.schema)
+	return err
+}
+
+func (d *fakeDriver) Restore(ctx context.Context, sc *bufio.Scanner) error {
+	return nil
+}
+
+// fakeRowCountBaselineService is the api.RowCountBaselineService used by anomaly scanner tests.
+type fakeRowCountBaselineService struct {
+	mu   sync.Mutex
+	list []*api.RowCountBaseline
+}
+
+func (s *fakeRowCountBaselineService) FindRowCountBaselineList(ctx context.Context, find *api.RowCountBaselineFind) ([]*api.RowCountBaseline, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := []*api.RowCountBaseline{}
+	for _, baseline := range s.list {
+		if v := find.DatabaseID; v != nil && baseline.DatabaseID != *v {
+			continue
+		}
+		list = append(list, baseline)
+	}
+	return list, nil
+}
+
+func (s *fakeRowCountBaselineService) UpsertRowCountBaselineList(ctx context.Context, upsert *api.RowCountBaselineUpsert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := []*api.RowCountBaseline{}
+	for _, baseline := range s.list {
+		if baseline.DatabaseID != upsert.DatabaseID {
+			list = append(list, baseline)
+		}
+	}
+	for tableName, rowCount := range upsert.RowCountMap {
+		list = append(list, &api.RowCountBaseline{
+			DatabaseID: upsert.DatabaseID,
+			TableName:  tableName,
+			RowCount:   rowCount,
+			ObservedTs: time.Now().Unix(),
+		})
+	}
+	s.list = list
+	return nil
+}
+
+// fakeBackupService is the api.BackupService used by anomaly scanner tests, every database has the same backup setting if set,
+// and the same backup list for any find.
+type fakeBackupService struct {
+	api.BackupService
+	setting *api.BackupSetting
+	list    []*api.Backup
+	// findListCount counts the FindBackupList calls.
+	findListCount int
+}
+
+func (s *fakeBackupService) FindBackupList(ctx context.Context, find *api.BackupFind) ([]*api.Backup, error) {
+	s.findListCount++
+	return s.list, nil
+}
+
+func (s *fakeBackupService) FindBackupSetting(ctx context.Context, find *api.BackupSettingFind) (*api.BackupSetting, error) {
+	if s.setting != nil {
+		return s.setting, nil
+	}
+	return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("backup setting not found")}
+}
+
+// fakeInstanceService is the api.InstanceService used by anomaly scanner tests, it returns the same instance list for any find
+// except for the EnableAnomalyScan filter.
+type fakeInstanceService struct {
+	api.InstanceService
+	list []*api.Instance
+}
+
+func (s *fakeInstanceService) FindInstanceList(ctx context.Context, find *api.InstanceFind) ([]*api.Instance, error) {
+	if find.EnableAnomalyScan == nil {
+		return s.list, nil
+	}
+	var list []*api.Instance
+	for _, instance := range s.list {
+		if instance.EnableAnomalyScan == *find.EnableAnomalyScan {
+			list = append(list, instance)
+		}
+	}
+	return list, nil
+}
+
+func (s *fakeInstanceService) FindInstance(ctx context.Context, find *api.InstanceFind) (*api.Instance, error) {
+	for _, instance := range s.list {
+		if find.ID != nil && instance.ID == *find.ID {
+			return instance, nil
+		}
+	}
+	return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("instance not found")}
+}
+
+// fakeDatabaseService is the api.DatabaseService used by anomaly scanner tests, it returns the same database list for any find.
+type fakeDatabaseService struct {
+	api.DatabaseService
+	list []*api.Database
+}
+
+func (s *fakeDatabaseService) FindDatabaseList(ctx context.Context, find *api.DatabaseFind) ([]*api.Database, error) {
+	return s.list, nil
+}
+
+// fakePolicyService is the api.PolicyService used by anomaly scanner tests, it counts the backup plan policy reads.
+type fakePolicyService struct {
+	api.PolicyService
+	backupPlanPolicyReadCount int32
+}
+
+func (s *fakePolicyService) GetBackupPlanPolicy(ctx context.Context, environmentID int) (*api.BackupPlanPolicy, error) {
+	atomic.AddInt32(&s.backupPlanPolicyReadCount, 1)
+	return &api.BackupPlanPolicy{Schedule: api.BackupPlanPolicyScheduleDaily}, nil
+}
+
+// fakeAnomalyService is an in-memory api.AnomalyService.
+type fakeAnomalyService struct {
+	mu     sync.Mutex
+	nextID int
+	list   []*api.Anomaly
+	status map[int]api.RowStatus
+}
+
+func newFakeAnomalyService() *fakeAnomalyService {
+	return &fakeAnomalyService{
+		nextID: 100,
+		status: make(map[int]api.RowStatus),
+	}
+}
+
+func (s *fakeAnomalyService) UpsertActiveAnomaly(ctx context.Context, upsert *api.AnomalyUpsert) (*api.Anomaly, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, anomaly := range s.list {
+		if s.status[anomaly.ID] == api.Normal && s.match(anomaly, upsert.InstanceID, upsert.DatabaseID, upsert.Type) {
+			anomaly.Payload = upsert.Payload
+			return anomaly, false, nil
+		}
+	}
+	s.nextID++
+	anomaly := &api.Anomaly{
+		ID:         s.nextID,
+		CreatorID:  upsert.CreatorID,
+		UpdaterID:  upsert.CreatorID,
+		InstanceID: upsert.InstanceID,
+		DatabaseID: upsert.DatabaseID,
+		Type:       upsert.Type,
+		Severity:   api.AnomalySeverityFromType(upsert.Type),
+		Payload:    upsert.Payload,
+	}
+	s.list = append(s.list, anomaly)
+	s.status[anomaly.ID] = api.Normal
+	return anomaly, true, nil
+}
+
+func (s *fakeAnomalyService) FindAnomalyList(ctx context.Context, find *api.AnomalyFind) ([]*api.Anomaly, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := []*api.Anomaly{}
+	for _, anomaly := range s.list {
+		if v := find.RowStatus; v != nil && s.status[anomaly.ID] != *v {
+			continue
+		}
+		if v := find.InstanceID; v != nil && anomaly.InstanceID != *v {
+			continue
+		}
+		if v := find.DatabaseID; v != nil && (anomaly.DatabaseID == nil || *anomaly.DatabaseID != *v) {
+			continue
+		}
+		if find.InstanceOnly && anomaly.DatabaseID != nil {
+			continue
+		}
+		if v := find.Type; v != nil && anomaly.Type != *v {
+			continue
+		}
+		if v := find.Severity; v != nil && api.AnomalySeverityFromType(anomaly.Type) != *v {
+			continue
+		}
+		list = append(list, anomaly)
+	}
+	return list, nil
+}
+
+func (s *fakeAnomalyService) CountAnomaly(ctx context.Context, find *api.AnomalyFind) (int, error) {
+	list, err := s.FindAnomalyList(ctx, find)
+	return len(list), err
+}
+
+func (s *fakeAnomalyService) ArchiveAnomaly(ctx context.Context, archive *api.AnomalyArchive) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	archived := false
+	for _, anomaly := range s.list {
+		if s.status[anomaly.ID] != api.Normal || anomaly.Type != archive.Type {
+			continue
+		}
+		if archive.InstanceID != nil && (anomaly.InstanceID != *archive.InstanceID || anomaly.DatabaseID != nil) {
+			continue
+		}
+		if archive.DatabaseID != nil && (anomaly.DatabaseID == nil || *anomaly.DatabaseID != *archive.DatabaseID) {
+			continue
+		}
+		s.status[anomaly.ID] = api.Archived
+		anomaly.ArchiveReason = archive.Reason
+		archived = true
+	}
+	if !archived {
+		return &common.Error{Code: common.NotFound, Err: fmt.Errorf("anomaly not found")}
+	}
+	return nil
+}
+
+func (s *fakeAnomalyService) ArchiveAnomaliesForDatabase(ctx context.Context, databaseID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, anomaly := range s.list {
+		if s.status[anomaly.ID] != api.Normal || anomaly.DatabaseID == nil || *anomaly.DatabaseID != databaseID {
+			continue
+		}
+		s.status[anomaly.ID] = api.Archived
+		anomaly.ArchiveReason = api.AnomalyArchiveReasonDatabaseNotFound
+		count++
+	}
+	return count, nil
+}
+
+func (s *fakeAnomalyService) match(anomaly *api.Anomaly, instanceID int, databaseID *int, anomalyType api.AnomalyType) bool {
+	if anomaly.InstanceID != instanceID || anomaly.Type != anomalyType {
+		return false
+	}
+	if databaseID == nil {
+		return anomaly.DatabaseID == nil
+	}
+	return anomaly.DatabaseID != nil && *anomaly.DatabaseID == *databaseID
+}
+
+// activeTypes returns the types of active anomalies for the database.
+func (s *fakeAnomalyService) activeTypes(databaseID int) map[api.AnomalyType]bool {
+	status := api.Normal
+	list, _ := s.FindAnomalyList(context.Background(), &api.AnomalyFind{
+		RowStatus:  &status,
+		DatabaseID: &databaseID,
+	})
+	types := make(map[api.AnomalyType]bool)
+	for _, anomaly := range list {
+		types[anomaly.Type] = true
+	}
+	return types
+}
+
+// activeInstanceTypes returns the types of active instance anomalies.
+func (s *fakeAnomalyService) activeInstanceTypes(instanceID int) map[api.AnomalyType]bool {
+	status := api.Normal
+	list, _ := s.FindAnomalyList(context.Background(), &api.AnomalyFind{
+		RowStatus:    &status,
+		InstanceID:   &instanceID,
+		InstanceOnly: true,
+	})
+	types := make(map[api.AnomalyType]bool)
+	for _, anomaly := range list {
+		types[anomaly.Type] = true
+	}
+	return types
+}
+
+// newTestAnomalyScanner returns a scanner backed by the fake anomaly service and a fresh fake driver.
+func newTestAnomalyScanner() (*AnomalyScanner, *fakeAnomalyService) {
+	testDriver = &fakeDriver{}
+	anomalyService := newFakeAnomalyService()
+	server := &Server{
+		AnomalyService:          anomalyService,
+		RowCountBaselineService: &fakeRowCountBaselineService{},
+	}
+	s := NewAnomalyScanner(zap.NewNop(), server, AnomalyScannerConfig{ConnectRetryBackoff: time.Millisecond})
+	return s, anomalyService
+}
+
+func newTestInstance() (*api.Instance, *api.Database) {
+	instance := &api.Instance{
+		ID:          1,
+		Name:        "instance",
+		Engine:      fakeDriverType,
+		Environment: &api.Environment{ID: 1, Name: "test"},
+	}
+	database := &api.Database{
+		ID:         1,
+		InstanceID: instance.ID,
+		Name:       "db",
+	}
+	return instance, database
+}
+
+func TestCheckSchemaDriftAnomalyArchive(t *testing.T) {
+	ctx := context.Background()
+	s, anomalyService := newTestAnomalyScanner()
+	instance, database := newTestInstance()
+
+	// An active connection anomaly which must not be touched by the drift check.
+	if _, _, err := anomalyService.UpsertActiveAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		DatabaseID: &database.ID,
+		Type:       api.AnomalyDatabaseConnection,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	testDriver.historyList = []*db.MigrationHistory{{Version: "1", Schema: "CREATE TABLE t (id INT);"}}
+	testDriver.schema = "CREATE TABLE t (id INT, name TEXT);"
+	s.checkSchemaDriftAnomaly(ctx, instance, database, testDriver, nil)
+	if !anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseSchemaDrift] {
+		t.Fatalf("expect schema drift anomaly to be raised")
+	}
+
+	testDriver.schema = "CREATE TABLE t (id INT);"
+	s.checkSchemaDriftAnomaly(ctx, instance, database, testDriver, nil)
+	types := anomalyService.activeTypes(database.ID)
+	if types[api.AnomalyDatabaseSchemaDrift] {
+		t.Errorf("expect schema drift anomaly to be archived")
+	}
+	if !types[api.AnomalyDatabaseConnection] {
+		t.Errorf("expect connection anomaly to be untouched")
+	}
+}
+
+func TestCheckSchemaDriftAnomalyEmptyHistory(t *testing.T) {
+	ctx := context.Background()
+	s, anomalyService := newTestAnomalyScanner()
+	instance, database := newTestInstance()
+
+	testDriver.historyList = []*db.MigrationHistory{{Version: "1", Schema: "CREATE TABLE t (id INT);"}}
+	testDriver.schema = "CREATE TABLE t (id INT, name TEXT);"
+	s.checkSchemaDriftAnomaly(ctx, instance, database, testDriver, nil)
+	if !anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseSchemaDrift] {
+		t.Fatalf("expect schema drift anomaly to be raised")
+	}
+
+	// The migration history is reset.
+	testDriver.historyList = nil
+	s.checkSchemaDriftAnomaly(ctx, instance, database, testDriver, nil)
+	if anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseSchemaDrift] {
+		t.Errorf("expect schema drift anomaly to be archived")
+	}
+}
+
+func TestCheckSchemaDriftAnomalyDiff(t *testing.T) {
+	ctx := context.Background()
+	s, anomalyService := newTestAnomalyScanner()
+	instance, database := newTestInstance()
+
+	// A 500-table schema where one table gains a column, which is too large to keep in full.
+	var expect, actual strings.Builder
+	for i := 0; i < 500; i++ {
+		table := fmt.Sprintf("CREATE TABLE t%d (\n  id INT NOT NULL,\n  name VARCHAR(255) NOT NULL,\n  description TEXT,\n  creator_id INT NOT NULL,\n  created_ts BIGINT NOT NULL\n);\n", i)
+		expect.WriteString(table)
+		if i == 250 {
+			table = strings.Replace(table, "  name VARCHAR(255) NOT NULL,\n", "  name VARCHAR(255) NOT NULL,\n  email VARCHAR(255),\n", 1)
+		}
+		actual.WriteString(table)
+	}
+	if expect.Len() <= schemaDriftFullSchemaMaxSize {
+		t.Fatalf("expect the schema to be larger than %d", schemaDriftFullSchemaMaxSize)
+	}
+	testDriver.historyList = []*db.MigrationHistory{{Version: "1", Schema: expect.String()}}
+	testDriver.schema = actual.String()
+	s.checkSchemaDriftAnomaly(ctx, instance, database, testDriver, nil)
+
+	driftType := api.AnomalyDatabaseSchemaDrift
+	list, err := anomalyService.FindAnomalyList(ctx, &api.AnomalyFind{
+		DatabaseID: &database.ID,
+		Type:       &driftType,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("got %d schema drift anomalies, want 1", len(list))
+	}
+	var payload api.AnomalyDatabaseSchemaDriftPayload
+	if err := json.Unmarshal([]byte(list[0].Payload), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Expect != "" || payload.Actual != "" {
+		t.Errorf("expect the full schemas to be omitted")
+	}
+	want := "--- 1\n+++ actual\n@@ -1751,6 +1751,7 @@\n CREATE TABLE t250 (\n   id INT NOT NULL,\n   name VARCHAR(255) NOT NULL,\n+  email VARCHAR(255),\n   description TEXT,\n   creator_id INT NOT NULL,\n   created_ts BIGINT NOT NULL\n"
+	if payload.Diff != want {
+		t.Errorf("Diff = %q, want %q", payload.Diff, want)
+	}
+
+	// The full schemas are kept when small.
+	testDriver.historyList = []*db.MigrationHistory{{Version: "2", Schema: "CREATE TABLE t (id INT);\n"}}
+	testDriver.schema = "CREATE TABLE t (id INT, name TEXT);\n"
+	s.checkSchemaDriftAnomaly(ctx, instance, database, testDriver, nil)
+	list, err = anomalyService.FindAnomalyList(ctx, &api.AnomalyFind{
+		DatabaseID: &database.ID,
+		Type:       &driftType,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(list[0].Payload), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Expect != testDriver.historyList[0].Schema || payload.Actual != testDriver.schema || payload.Diff == "" {
+		t.Errorf("expect the full schemas and the diff to be kept, got %+v", payload)
+	}
+}
+
+func TestBaselineSchemaDrift(t *testing.T) {
+	ctx := context.Background()
+	s, anomalyService := newTestAnomalyScanner()
+	instance, database := newTestInstance()
+
+	testDriver.historyList = []*db.MigrationHistory{{Version: "1", Schema: "CREATE TABLE t (id INT);"}}
+	testDriver.schema = "CREATE TABLE t (id INT, name TEXT);"
+	s.checkSchemaDriftAnomaly(ctx, instance, database, testDriver, nil)
+	if !anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseSchemaDrift] {
+		t.Fatalf("expect schema drift anomaly to be raised")
+	}
+
+	version, err := s.BaselineSchemaDrift(ctx, instance, database, "operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(version, ".baseline") {
+		t.Errorf("version = %q, want the baseline version", version)
+	}
+	if anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseSchemaDrift] {
+		t.Errorf("expect schema drift anomaly to be archived by the baseline")
+	}
+	latest := testDriver.historyList[0]
+	if latest.Type != db.Baseline || latest.Version != version || latest.Schema != testDriver.schema {
+		t.Errorf("latest migration history = %+v, want the baseline of the live schema", latest)
+	}
+
+	// The drift doesn't come back as the live schema is the baseline now.
+	s.checkSchemaDriftAnomaly(ctx, instance, database, testDriver, nil)
+	if anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseSchemaDrift] {
+		t.Errorf("expect no schema drift anomaly after baselining")
+	}
+}
+
+func TestSchemaDriftBaselineVersion(t *testing.T) {
+	now := time.Date(2022, 3, 1, 15, 4, 5, 0, time.Local)
+	if got, want := schemaDriftBaselineVersion(now), "20220301150405.baseline"; got != want {
+		t.Errorf("schemaDriftBaselineVersion() = %q, want %q", got, want)
+	}
+	// The baseline sorts after the UI migrations of the same second.
+	if uiVersion := "20220301150405.123"; schemaDriftBaselineVersion(now) <= uiVersion {
+		t.Errorf("expect the baseline version to sort after %q", uiVersion)
+	}
+}
+
+func TestAnomalyScannerStopWaitsForRunningScan(t *testing.T) {
+	s := NewAnomalyScanner(zap.NewNop(), nil, AnomalyScannerConfig{})
+	if err := s.startTask(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.startTask(1); common.ErrorCode(err) != common.Conflict {
+		t.Fatalf("expect ECONFLICT for the instance being scanned, got %v", err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatalf("Stop returned before the running scan finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.finishTask(1)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatalf("Stop did not return after the running scan finished")
+	}
+	if len(s.runningTasks) != 0 {
+		t.Errorf("expect running tasks to be drained, got %v", s.runningTasks)
+	}
+	if err := s.startTask(2); common.ErrorCode(err) != common.Conflict {
+		t.Errorf("expect no scan to start after Stop, got %v", err)
+	}
+	// Stop is idempotent.
+	s.Stop()
+}
+
+func TestAnomalyScannerStats(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestAnomalyScanner()
+	instance, database := newTestInstance()
+	testDriver.historyList = []*db.MigrationHistory{{Version: "1", Schema: "CREATE TABLE t (id INT);"}}
+
+	round := func(schema string) AnomalyScanStats {
+		testDriver.schema = schema
+		s.startRoundStats()
+		s.checkDatabaseAnomaly(ctx, instance, database, nil, nil, nil)
+		s.finishRoundStats()
+		return s.Stats()
+	}
+
+	stats := round("CREATE TABLE t (id INT, name TEXT);")
+	if got := stats.CountMap[api.AnomalyDatabaseSchemaDrift]; got != (AnomalyCount{Opened: 1}) {
+		t.Errorf("first round count = %+v, want opened", got)
+	}
+	stats = round("CREATE TABLE t (id INT, name TEXT);")
+	if got := stats.CountMap[api.AnomalyDatabaseSchemaDrift]; got != (AnomalyCount{Unchanged: 1}) {
+		t.Errorf("second round count = %+v, want unchanged", got)
+	}
+	stats = round("CREATE TABLE t (id INT);")
+	if got := stats.CountMap[api.AnomalyDatabaseSchemaDrift]; got != (AnomalyCount{Archived: 1}) {
+		t.Errorf("third round count = %+v, want archived", got)
+	}
+	stats = round("CREATE TABLE t (id INT);")
+	if got := stats.CountMap[api.AnomalyDatabaseSchemaDrift]; got != (AnomalyCount{}) {
+		t.Errorf("fourth round count = %+v, want no change", got)
+	}
+}
+
+func TestCheckUntrackedAnomaly(t *testing.T) {
+	tests := []struct {
+		name          string
+		historyList   []*db.MigrationHistory
+		disabled      bool
+		wantUntracked bool
+	}{
+		{"untracked", nil, false, true},
+		{"migrated", []*db.MigrationHistory{{Version: "1", Type: db.Migrate}}, false, false},
+		{"baseline", []*db.MigrationHistory{{Version: "1", Type: db.Baseline}}, false, false},
+		// The anomaly policy of the environment opts out of the anomaly.
+		{"disabled", nil, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.disabled {
+				ctx = context.WithValue(ctx, disabledAnomalyTypeSetKey{}, map[api.AnomalyType]bool{api.AnomalyDatabaseUntracked: true})
+			}
+			s, anomalyService := newTestAnomalyScanner()
+			instance, database := newTestInstance()
+			testDriver.historyList = tt.historyList
+
+			s.checkDatabaseAnomaly(ctx, instance, database, nil, nil, nil)
+			if got := anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseUntracked]; got != tt.wantUntracked {
+				t.Fatalf("untracked anomaly active = %t, want %t", got, tt.wantUntracked)
+			}
+			if !tt.wantUntracked {
+				return
+			}
+			payload := &api.AnomalyDatabaseUntrackedPayload{}
+			if err := json.Unmarshal([]byte(anomalyService.list[0].Payload), payload); err != nil {
+				t.Fatal(err)
+			}
+			if payload.InstanceName != instance.Name || payload.DatabaseName != database.Name {
+				t.Errorf("payload = %+v, want instance %q and database %q", payload, instance.Name, database.Name)
+			}
+
+			// Onboarding the database with a baseline archives the anomaly.
+			testDriver.historyList = []*db.MigrationHistory{{Version: "1", Type: db.Baseline}}
+			s.checkDatabaseAnomaly(ctx, instance, database, nil, nil, nil)
+			if anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseUntracked] {
+				t.Error("untracked anomaly is not archived after the baseline")
+			}
+		})
+	}
+}
+
+func TestCheckNoMigrationHistoryAnomaly(t *testing.T) {
+	ctx := context.Background()
+	s, anomalyService := newTestAnomalyScanner()
+	instance, database := newTestInstance()
+	database.Managed = true
+
+	s.checkDatabaseAnomaly(ctx, instance, database, nil, nil, nil)
+	activeTypes := anomalyService.activeTypes(database.ID)
+	if !activeTypes[api.AnomalyDatabaseNoMigrationHistory] {
+		t.Fatal("no migration history anomaly is not raised for the managed database without history")
+	}
+	if activeTypes[api.AnomalyDatabaseUntracked] {
+		t.Error("untracked anomaly is raised for the managed database")
+	}
+	payload := &api.AnomalyDatabaseNoMigrationHistoryPayload{}
+	if err := json.Unmarshal([]byte(anomalyService.list[0].Payload), payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.InstanceName != instance.Name || payload.DatabaseName != database.Name {
+		t.Errorf("payload = %+v, want instance %q and database %q", payload, instance.Name, database.Name)
+	}
+
+	// Unflagging the database swaps the anomaly for the untracked one.
+	database.Managed = false
+	s.checkDatabaseAnomaly(ctx, instance, database, nil, nil, nil)
+	activeTypes = anomalyService.activeTypes(database.ID)
+	if activeTypes[api.AnomalyDatabaseNoMigrationHistory] || !activeTypes[api.AnomalyDatabaseUntracked] {
+		t.Errorf("active types = %v after unflagging, want only the untracked anomaly", activeTypes)
+	}
+
+	// The migration history appearing archives the anomaly.
+	database.Managed = true
+	s.checkDatabaseAnomaly(ctx, instance, database, nil, nil, nil)
+	testDriver.historyList = []*db.MigrationHistory{{Version: "1", Type: db.Migrate}}
+	s.checkDatabaseAnomaly(ctx, instance, database, nil, nil, nil)
+	activeTypes = anomalyService.activeTypes(database.ID)
+	if activeTypes[api.AnomalyDatabaseNoMigrationHistory] || activeTypes[api.AnomalyDatabaseUntracked] {
+		t.Errorf("active types = %v after the migration, want neither anomaly", activeTypes)
+	}
+}
+
+func TestAnomalyScannerInstanceScanStats(t *testing.T) {
+	s, _ := newTestAnomalyScanner()
+	instance, database := newTestInstance()
+	testDriver.historyList = []*db.MigrationHistory{{Version: "1", Schema: "CREATE TABLE t (id INT);"}}
+	testDriver.schema = "CREATE TABLE t (id INT, name TEXT);"
+
+	stats := &AnomalyScanStats{}
+	ctx := context.WithValue(context.Background(), instanceScanStatsKey{}, stats)
+	s.startRoundStats()
+	s.checkDatabaseAnomaly(ctx, instance, database, nil, nil, nil)
+	s.finishRoundStats()
+
+	if got := stats.CountMap[api.AnomalyDatabaseSchemaDrift]; got != (AnomalyCount{Opened: 1}) {
+		t.Errorf("instance scan count = %+v, want opened", got)
+	}
+	if got := s.Stats().CountMap[api.AnomalyDatabaseSchemaDrift]; got != (AnomalyCount{Opened: 1}) {
+		t.Errorf("round count = %+v, want opened", got)
+	}
+}
+
+func TestAnomalyScannerBackupPlanPolicyCache(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestAnomalyScanner()
+	policyService := &fakePolicyService{}
+	s.server.PolicyService = policyService
+	get := func(environmentID int, wantReadCount int32) {
+		t.Helper()
+		if _, err := s.getBackupPlanPolicy(ctx, environmentID); err != nil {
+			t.Fatal(err)
+		}
+		if got := atomic.LoadInt32(&policyService.backupPlanPolicyReadCount); got != wantReadCount {
+			t.Fatalf("backup plan policy read count = %d, want %d", got, wantReadCount)
+		}
+	}
+
+	get(1, 1)
+	get(1, 1)
+	get(2, 2)
+
+	// Invalidating an environment only drops its own policy.
+	s.InvalidatePolicyCache(1)
+	get(1, 3)
+	get(2, 3)
+
+	// Invalidating the global policy drops every environment.
+	s.InvalidatePolicyCache(api.GlobalPolicyEnvironmentID)
+	get(1, 4)
+	get(2, 5)
+
+	// The cached policy expires after the scan interval.
+	s.policyCacheMu.Lock()
+	s.backupPlanPolicyCache[1].expiresAt = time.Now().Add(-time.Second)
+	s.policyCacheMu.Unlock()
+	get(1, 6)
+
+	// The concurrent reads are safe.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(environmentID int) {
+			defer wg.Done()
+			if _, err := s.getBackupPlanPolicy(ctx, environmentID); err != nil {
+				t.Error(err)
+			}
+			s.InvalidatePolicyCache(environmentID)
+		}(i % 3)
+	}
+	wg.Wait()
+
+	if _, err := s.getBackupPlanPolicy(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	s.Stop()
+	s.policyCacheMu.Lock()
+	defer s.policyCacheMu.Unlock()
+	if len(s.backupPlanPolicyCache) != 0 {
+		t.Errorf("expect the policy cache to be cleared on Stop, got %d entries", len(s.backupPlanPolicyCache))
+	}
+}
+
+func TestFindForeignKeyWithoutIndex(t *testing.T) {
+	fk := db.ForeignKey{Name: "fk_order_user", ColumnList: []string{"tenant_id", "user_id"}, ReferencedTable: "user"}
+	tests := []struct {
+		name      string
+		indexList []db.Index
+		want      int
+	}{
+		{"noIndex", nil, 1},
+		{
+			"exactIndex",
+			[]db.Index{
+				{Name: "idx_tenant_user", Expression: "tenant_id", Position: 1},
+				{Name: "idx_tenant_user", Expression: "user_id", Position: 2},
+			},
+			0,
+		},
+		{
+			"reorderedPrefix",
+			[]db.Index{
+				{Name: "idx_user_tenant_ts", Expression: "created_ts", Position: 3},
+				{Name: "idx_user_tenant_ts", Expression: "tenant_id", Position: 2},
+				{Name: "idx_user_tenant_ts", Expression: "user_id", Position: 1},
+			},
+			0,
+		},
+		{
+			"notLeading",
+			[]db.Index{
+				{Name: "idx_ts_tenant_user", Expression: "created_ts", Position: 1},
+				{Name: "idx_ts_tenant_user", Expression: "tenant_id", Position: 2},
+				{Name: "idx_ts_tenant_user", Expression: "user_id", Position: 3},
+			},
+			1,
+		},
+		{
+			"partialIndex",
+			[]db.Index{
+				{Name: "idx_tenant", Expression: "tenant_id", Position: 1},
+			},
+			1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := db.Table{
+				Name:           "order",
+				IndexList:      tt.indexList,
+				ForeignKeyList: []db.ForeignKey{fk},
+			}
+			if got := findForeignKeyWithoutIndex(table); len(got) != tt.want {
+				t.Errorf("findForeignKeyWithoutIndex() = %v, want %d foreign keys", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestedForeignKeyIndexDDL(t *testing.T) {
+	fk := db.ForeignKey{Name: "fk_order_user", ColumnList: []string{"tenant_id", "user_id"}}
+	tests := []struct {
+		engine db.Type
+		table  string
+		want   string
+	}{
+		{db.MySQL, "order", "CREATE INDEX `idx_fk_order_user` ON `order` (`tenant_id`, `user_id`);"},
+		{db.Postgres, "public.\"order\"", "CREATE INDEX ON public.\"order\" (tenant_id, user_id);"},
+		{db.MSSQL, "dbo.order", "CREATE INDEX [idx_fk_order_user] ON [dbo].[order] ([tenant_id], [user_id]);"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.engine), func(t *testing.T) {
+			if got := suggestedForeignKeyIndexDDL(tt.engine, tt.table, fk); got != tt.want {
+				t.Errorf("suggestedForeignKeyIndexDDL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateConnectionCountAnomaly(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		current   int
+		max       int
+		want      bool
+	}{
+		{"belowDefault", 0, 80, 100, false},
+		{"aboveDefault", 0, 81, 100, true},
+		{"aboveCustom", 50, 60, 100, true},
+		{"belowCustom", 90, 85, 100, false},
+		{"unknownMax", 0, 10, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			s.connectionCountThreshold = NewAnomalyScanner(zap.NewNop(), nil, AnomalyScannerConfig{ConnectionCountThreshold: tt.threshold}).connectionCountThreshold
+			instance, _ := newTestInstance()
+
+			s.updateConnectionCountAnomaly(ctx, instance, tt.current, tt.max)
+			if got := anomalyService.activeInstanceTypes(instance.ID)[api.AnomalyDatabaseConnectionCountHigh]; got != tt.want {
+				t.Fatalf("connection count anomaly active = %t, want %t", got, tt.want)
+			}
+
+			// The anomaly is archived once the usage drops back.
+			s.updateConnectionCountAnomaly(ctx, instance, 0, 100)
+			if anomalyService.activeInstanceTypes(instance.ID)[api.AnomalyDatabaseConnectionCountHigh] {
+				t.Errorf("expect connection count anomaly to be archived")
+			}
+		})
+	}
+}
+
+func TestUpdateTableBloatAnomaly(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		tableList []*pgTableBloat
+		wantTable string
+	}{
+		{"belowDefault", 0, []*pgTableBloat{{table: "public.t", liveTuples: 7000, deadTuples: 3000}}, ""},
+		{"aboveDefault", 0, []*pgTableBloat{{table: "public.t", liveTuples: 6000, deadTuples: 4000}}, "public.t"},
+		{"aboveCustom", 10, []*pgTableBloat{{table: "public.t", liveTuples: 8000, deadTuples: 2000}}, "public.t"},
+		{"worst", 0, []*pgTableBloat{
+			{table: "public.a", liveTuples: 6000, deadTuples: 4000},
+			{table: "public.b", liveTuples: 1000, deadTuples: 9000},
+			{table: "public.c", liveTuples: 9000, deadTuples: 1000},
+		}, "public.b"},
+		{"emptyTable", 0, []*pgTableBloat{{table: "public.t"}}, ""},
+		{"noTable", 0, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			s.tableBloatThreshold = NewAnomalyScanner(zap.NewNop(), nil, AnomalyScannerConfig{TableBloatThreshold: tt.threshold}).tableBloatThreshold
+			instance, database := newTestInstance()
+
+			s.updateTableBloatAnomaly(ctx, instance, database, tt.tableList)
+			status := api.Normal
+			anomalyType := api.AnomalyDatabaseTableBloat
+			list, _ := anomalyService.FindAnomalyList(ctx, &api.AnomalyFind{
+				RowStatus:  &status,
+				DatabaseID: &database.ID,
+				Type:       &anomalyType,
+			})
+			if tt.wantTable == "" {
+				if len(list) != 0 {
+					t.Fatalf("expect no table bloat anomaly, got %s", list[0].Payload)
+				}
+				return
+			}
+			if len(list) != 1 {
+				t.Fatalf("expect table bloat anomaly to be raised")
+			}
+			var payload api.AnomalyDatabaseTableBloatPayload
+			if err := json.Unmarshal([]byte(list[0].Payload), &payload); err != nil {
+				t.Fatal(err)
+			}
+			if payload.Table != tt.wantTable {
+				t.Errorf("table bloat anomaly table = %q, want %q", payload.Table, tt.wantTable)
+			}
+
+			// The anomaly is archived once every table falls back under the threshold.
+			s.updateTableBloatAnomaly(ctx, instance, database, []*pgTableBloat{{table: tt.wantTable, liveTuples: 10000}})
+			if anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseTableBloat] {
+				t.Errorf("expect table bloat anomaly to be archived")
+			}
+		})
+	}
+}
+
+func TestUpdateMissingIndexAnomaly(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold float64
+		tableList []*tableScanStats
+		wantTable string
+		wantRatio float64
+	}{
+		{"belowDefault", 0, []*tableScanStats{{table: "public.t", sequentialScans: 5000, indexScans: 1000}}, "", 0},
+		{"aboveDefault", 0, []*tableScanStats{{table: "public.t", sequentialScans: 50000, indexScans: 1000}}, "public.t", 50},
+		{"aboveCustom", 2, []*tableScanStats{{table: "public.t", sequentialScans: 5000, indexScans: 1000}}, "public.t", 5},
+		{"noIndexScan", 0, []*tableScanStats{{table: "public.t", sequentialScans: 2000}}, "public.t", 2000},
+		{"fewSequentialScans", 0, []*tableScanStats{{table: "public.t", sequentialScans: 999}}, "", 0},
+		{"worst", 0, []*tableScanStats{
+			{table: "public.a", sequentialScans: 20000, indexScans: 1000},
+			{table: "public.b", sequentialScans: 90000, indexScans: 3000},
+			{table: "public.c", sequentialScans: 1000, indexScans: 1000},
+		}, "public.b", 30},
+		{"noTable", 0, nil, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			s.sequentialScanRatioThreshold = NewAnomalyScanner(zap.NewNop(), nil, AnomalyScannerConfig{SequentialScanRatioThreshold: tt.threshold}).sequentialScanRatioThreshold
+			instance, database := newTestInstance()
+
+			s.updateMissingIndexAnomaly(ctx, instance, database, tt.tableList)
+			status := api.Normal
+			anomalyType := api.AnomalyDatabaseMissingIndex
+			list, _ := anomalyService.FindAnomalyList(ctx, &api.AnomalyFind{
+				RowStatus:  &status,
+				DatabaseID: &database.ID,
+				Type:       &anomalyType,
+			})
+			if tt.wantTable == "" {
+				if len(list) != 0 {
+					t.Fatalf("expect no missing index anomaly, got %s", list[0].Payload)
+				}
+				return
+			}
+			if len(list) != 1 {
+				t.Fatalf("expect missing index anomaly to be raised")
+			}
+			var payload api.AnomalyDatabaseMissingIndexPayload
+			if err := json.Unmarshal([]byte(list[0].Payload), &payload); err != nil {
+				t.Fatal(err)
+			}
+			if payload.Table != tt.wantTable || payload.Ratio != tt.wantRatio {
+				t.Errorf("missing index anomaly table = %q ratio = %v, want %q ratio %v", payload.Table, payload.Ratio, tt.wantTable, tt.wantRatio)
+			}
+
+			// The anomaly is archived once the ratio improves, e.g. after adding the index.
+			s.updateMissingIndexAnomaly(ctx, instance, database, []*tableScanStats{{table: tt.wantTable, sequentialScans: 50000, indexScans: 1000000}})
+			if anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseMissingIndex] {
+				t.Errorf("expect missing index anomaly to be archived")
+			}
+		})
+	}
+}
+
+func TestUpdateTableRowCountGrowthAnomaly(t *testing.T) {
+	baselineList := []*api.RowCountBaseline{
+		{TableName: "small", RowCount: 10},
+		{TableName: "steady", RowCount: 100000},
+		{TableName: "growing", RowCount: 5000},
+		{TableName: "exploding", RowCount: 2000},
+	}
+	tests := []struct {
+		name        string
+		rowCountMap map[string]int64
+		wantTable   string
+	}{
+		{
+			name: "underThreshold",
+			rowCountMap: map[string]int64{
+				"steady":  200000,
+				"growing": 40000,
+			},
+			wantTable: "",
+		},
+		{
+			name: "tooSmall",
+			rowCountMap: map[string]int64{
+				"small": 9000,
+			},
+			wantTable: "",
+		},
+		{
+			name: "newTable",
+			rowCountMap: map[string]int64{
+				"new": 1000000,
+			},
+			wantTable: "",
+		},
+		{
+			name: "highestRatio",
+			rowCountMap: map[string]int64{
+				"steady":    100000,
+				"growing":   60000,
+				"exploding": 200000,
+			},
+			wantTable: "exploding",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			instance, database := newTestInstance()
+
+			s.updateTableRowCountGrowthAnomaly(ctx, instance, database, baselineList, test.rowCountMap)
+			growthType := api.AnomalyDatabaseTableRowCountGrowth
+			list, _ := anomalyService.FindAnomalyList(ctx, &api.AnomalyFind{
+				DatabaseID: &database.ID,
+				Type:       &growthType,
+			})
+			if test.wantTable == "" {
+				if len(list) != 0 {
+					t.Fatalf("expect no row count growth anomaly, got %s", list[0].Payload)
+				}
+				return
+			}
+			if len(list) != 1 {
+				t.Fatalf("got %d row count growth anomalies, want 1", len(list))
+			}
+			var payload api.AnomalyDatabaseTableRowCountGrowthPayload
+			if err := json.Unmarshal([]byte(list[0].Payload), &payload); err != nil {
+				t.Fatal(err)
+			}
+			if payload.Table != test.wantTable {
+				t.Errorf("Table = %q, want %q", payload.Table, test.wantTable)
+			}
+		})
+	}
+}
+
+func TestCheckTableRowCountGrowthAnomaly(t *testing.T) {
+	ctx := context.Background()
+	s, anomalyService := newTestAnomalyScanner()
+	instance, database := newTestInstance()
+	schemaWithRowCount := func(rowCount int64) *db.Schema {
+		return &db.Schema{Name: database.Name, TableList: []db.Table{{Name: "t", RowCount: rowCount}}}
+	}
+
+	// The first round only records the baseline.
+	s.checkTableRowCountGrowthAnomaly(ctx, instance, database, schemaWithRowCount(20000))
+	if anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseTableRowCountGrowth] {
+		t.Fatalf("expect no row count growth anomaly without a baseline")
+	}
+
+	s.checkTableRowCountGrowthAnomaly(ctx, instance, database, schemaWithRowCount(300000))
+	if !anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseTableRowCountGrowth] {
+		t.Fatalf("expect row count growth anomaly to be raised")
+	}
+
+	// The growth is measured against the previous round, not the first one.
+	s.checkTableRowCountGrowthAnomaly(ctx, instance, database, schemaWithRowCount(400000))
+	if anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseTableRowCountGrowth] {
+		t.Errorf("expect row count growth anomaly to be archived")
+	}
+}
+
+func TestCheckLongRunningTransactionAnomaly(t *testing.T) {
+	tests := []struct {
+		name            string
+		threshold       time.Duration
+		transactionList []*db.Transaction
+		want            bool
+	}{
+		{"unsupported", 0, nil, false},
+		{"none", 0, []*db.Transaction{}, false},
+		{"belowDefault", 0, []*db.Transaction{{PID: 1, Duration: 5 * time.Minute}}, false},
+		{"aboveDefault", 0, []*db.Transaction{{PID: 1, Duration: 15 * time.Minute}}, true},
+		{"aboveCustom", time.Minute, []*db.Transaction{{PID: 1, Duration: 5 * time.Minute}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			s.longRunningTransactionThreshold = NewAnomalyScanner(zap.NewNop(), nil, AnomalyScannerConfig{LongRunningTransactionThreshold: tt.threshold}).longRunningTransactionThreshold
+			instance, database := newTestInstance()
+
+			testDriver.transactionList = tt.transactionList
+			s.checkLongRunningTransactionAnomaly(ctx, instance, database, testDriver)
+			if got := anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseLongRunningTransaction]; got != tt.want {
+				t.Fatalf("long-running transaction anomaly active = %t, want %t", got, tt.want)
+			}
+
+			// The anomaly is archived once the transactions finish.
+			testDriver.transactionList = []*db.Transaction{}
+			s.checkLongRunningTransactionAnomaly(ctx, instance, database, testDriver)
+			if anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseLongRunningTransaction] {
+				t.Errorf("expect long-running transaction anomaly to be archived")
+			}
+		})
+	}
+}
+
+func TestBackupScheduleSatisfies(t *testing.T) {
+	tests := []struct {
+		actual   api.BackupPlanPolicySchedule
+		expected api.BackupPlanPolicySchedule
+		want     bool
+	}{
+		{api.BackupPlanPolicyScheduleUnset, api.BackupPlanPolicyScheduleUnset, true},
+		{api.BackupPlanPolicyScheduleUnset, api.BackupPlanPolicyScheduleMonthly, false},
+		{api.BackupPlanPolicyScheduleWeekly, api.BackupPlanPolicyScheduleMonthly, true},
+		{api.BackupPlanPolicyScheduleDaily, api.BackupPlanPolicyScheduleMonthly, true},
+		{api.BackupPlanPolicyScheduleUnset, api.BackupPlanPolicyScheduleWeekly, false},
+		{api.BackupPlanPolicyScheduleWeekly, api.BackupPlanPolicyScheduleWeekly, true},
+		{api.BackupPlanPolicyScheduleDaily, api.BackupPlanPolicyScheduleWeekly, true},
+		{api.BackupPlanPolicyScheduleWeekly, api.BackupPlanPolicyScheduleDaily, false},
+		{api.BackupPlanPolicyScheduleDaily, api.BackupPlanPolicyScheduleDaily, true},
+	}
+	for _, tt := range tests {
+		if got := backupScheduleSatisfies(tt.actual, tt.expected); got != tt.want {
+			t.Errorf("backupScheduleSatisfies(%s, %s) = %t, want %t", tt.actual, tt.expected, got, tt.want)
+		}
+	}
+}
+
+func TestGetBackupMaxAge(t *testing.T) {
+	tests := []struct {
+		schedule api.BackupPlanPolicySchedule
+		want     time.Duration
+	}{
+		{api.BackupPlanPolicyScheduleDaily, 24 * time.Hour},
+		{api.BackupPlanPolicyScheduleWeekly, 7 * 24 * time.Hour},
+		{api.BackupPlanPolicyScheduleMonthly, 31 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := getBackupMaxAge(tt.schedule); got != tt.want {
+			t.Errorf("getBackupMaxAge(%s) = %v, want %v", tt.schedule, got, tt.want)
+		}
+	}
+}
+
+func TestCheckBackupAnomalyMissingPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		policyMap map[int]*api.BackupPlanPolicy
+	}{
+		{"missingEnvironment", map[int]*api.BackupPlanPolicy{}},
+		{"nilPolicy", map[int]*api.BackupPlanPolicy{1: nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			instance, database := newTestInstance()
+			s.server.BackupService = &fakeBackupService{}
+
+			// The missing policy is treated as UNSET, which any backup setting satisfies.
+			s.checkBackupAnomaly(ctx, instance, database, tt.policyMap)
+			if anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseBackupPolicyViolation] {
+				t.Error("backup policy violation anomaly is raised without a backup plan policy")
+			}
+		})
+	}
+}
+
+func TestIsBackupOverdue(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	maxAge := time.Duration(float64(24*time.Hour) * defaultBackupMaxAgeGraceMultiplier)
+	tests := []struct {
+		name string
+		age  time.Duration
+		want bool
+	}{
+		{"exactly24h", 24 * time.Hour, false},
+		{"exactlyGrace", maxAge, false},
+		{"beyondGrace", maxAge + time.Second, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isBackupOverdue(now.Add(-tt.age).Unix(), maxAge, now); got != tt.want {
+				t.Errorf("isBackupOverdue(age=%v, maxAge=%v) = %t, want %t", tt.age, maxAge, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckBackupMissingAnomalyGrace(t *testing.T) {
+	tests := []struct {
+		name            string
+		graceMultiplier float64
+		age             time.Duration
+		wantMissing     bool
+	}{
+		{"onTime", 0, 23 * time.Hour, false},
+		{"slightlyLate", 0, 25 * time.Hour, false},
+		{"beyondDefaultGrace", 0, 29 * time.Hour, true},
+		{"withinConfiguredGrace", 1.5, 29 * time.Hour, false},
+		// A multiplier below 1 would raise the anomaly before the backup is due, so the default is used.
+		{"multiplierBelowOne", 0.5, 23 * time.Hour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			_, anomalyService := newTestAnomalyScanner()
+			s := NewAnomalyScanner(zap.NewNop(), &Server{
+				AnomalyService:          anomalyService,
+				RowCountBaselineService: &fakeRowCountBaselineService{},
+			}, AnomalyScannerConfig{BackupMaxAgeGraceMultiplier: tt.graceMultiplier})
+			instance, database := newTestInstance()
+			s.server.BackupService = &fakeBackupService{
+				setting: &api.BackupSetting{Enabled: true, Hour: 0, Minute: 0, DayOfWeek: -1, DayOfMonth: -1, UpdatedTs: time.Now().Add(-30 * 24 * time.Hour).Unix()},
+				list:    []*api.Backup{{ID: 1, UpdatedTs: time.Now().Add(-tt.age).Unix()}},
+			}
+			backupPlanPolicyMap := map[int]*api.BackupPlanPolicy{
+				instance.EnvironmentID: {Schedule: api.BackupPlanPolicyScheduleDaily},
+			}
+
+			s.checkBackupAnomaly(ctx, instance, database, backupPlanPolicyMap)
+			if got := anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseBackupMissing]; got != tt.wantMissing {
+				t.Errorf("backup missing anomaly active = %t, want %t", got, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestGetNextBackupTime(t *testing.T) {
+	// 2023-11-14 is a Tuesday.
+	after := time.Date(2023, 11, 14, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		setting *api.BackupSetting
+		want    time.Time
+	}{
+		{"dailyLaterToday", &api.BackupSetting{Hour: 12, Minute: 15, DayOfWeek: -1, DayOfMonth: -1}, time.Date(2023, 11, 14, 12, 15, 0, 0, time.UTC)},
+		{"dailyTomorrow", &api.BackupSetting{Hour: 2, Minute: 0, DayOfWeek: -1, DayOfMonth: -1}, time.Date(2023, 11, 15, 2, 0, 0, 0, time.UTC)},
+		{"dailyExactlyNow", &api.BackupSetting{Hour: 10, Minute: 30, DayOfWeek: -1, DayOfMonth: -1}, time.Date(2023, 11, 15, 10, 30, 0, 0, time.UTC)},
+		{"unsetMinute", &api.BackupSetting{Hour: 11, Minute: -1, DayOfWeek: -1, DayOfMonth: -1}, time.Date(2023, 11, 14, 11, 0, 0, 0, time.UTC)},
+		{"weekly", &api.BackupSetting{Hour: 0, Minute: 0, DayOfWeek: 0, DayOfMonth: -1}, time.Date(2023, 11, 19, 0, 0, 0, 0, time.UTC)},
+		{"monthlyNextMonth", &api.BackupSetting{Hour: 0, Minute: 0, DayOfWeek: -1, DayOfMonth: 1}, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)},
+		{"monthlySameDayPassed", &api.BackupSetting{Hour: 3, Minute: 0, DayOfWeek: -1, DayOfMonth: 14}, time.Date(2023, 12, 14, 3, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getNextBackupTime(tt.setting, after); !got.Equal(tt.want) {
+				t.Errorf("getNextBackupTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckBackupMissingAnomalyFirstBackup(t *testing.T) {
+	tests := []struct {
+		name        string
+		gracePeriod time.Duration
+		// enabledAge is how long ago the daily backup was enabled, its first run is 12 hours after it's enabled.
+		enabledAge  time.Duration
+		wantMissing bool
+	}{
+		// The backup max age alone would raise the anomaly after 28.8 hours.
+		{"withinGrace", 0, 30 * time.Hour, false},
+		{"afterGrace", 0, 40 * time.Hour, true},
+		{"afterConfiguredGrace", time.Hour, 30 * time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			_, anomalyService := newTestAnomalyScanner()
+			s := NewAnomalyScanner(zap.NewNop(), &Server{
+				AnomalyService:          anomalyService,
+				RowCountBaselineService: &fakeRowCountBaselineService{},
+			}, AnomalyScannerConfig{BackupMissingGracePeriod: tt.gracePeriod})
+			instance, database := newTestInstance()
+			enabledTime := time.Now().Add(-tt.enabledAge).Truncate(time.Hour)
+			s.server.BackupService = &fakeBackupService{
+				setting: &api.BackupSetting{Enabled: true, Hour: enabledTime.Add(12 * time.Hour).UTC().Hour(), Minute: 0, DayOfWeek: -1, DayOfMonth: -1, UpdatedTs: enabledTime.Unix()},
+			}
+			backupPlanPolicyMap := map[int]*api.BackupPlanPolicy{
+				instance.EnvironmentID: {Schedule: api.BackupPlanPolicyScheduleDaily},
+			}
+
+			s.checkBackupAnomaly(ctx, instance, database, backupPlanPolicyMap)
+			if got := anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseBackupMissing]; got != tt.wantMissing {
+				t.Errorf("backup missing anomaly active = %t, want %t", got, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestCheckBackupMissingAnomalyBoundary(t *testing.T) {
+	// The daily backup is enabled at 10:00 and first runs at 02:00 the next day.
+	enabledTime := time.Date(2023, 11, 14, 10, 0, 0, 0, time.UTC)
+	firstBackupTime := time.Date(2023, 11, 15, 2, 0, 0, 0, time.UTC)
+	maxAge := time.Duration(float64(24*time.Hour) * defaultBackupMaxAgeGraceMultiplier)
+	tests := []struct {
+		name string
+		now  time.Time
+		// lastBackupTime is the time of the last successful backup, zero for no backup.
+		lastBackupTime time.Time
+		wantMissing    bool
+	}{
+		{"justBeforeGraceEnds", firstBackupTime.Add(24*time.Hour - time.Second), time.Time{}, false},
+		{"graceEnds", firstBackupTime.Add(24 * time.Hour), time.Time{}, true},
+		{"exactlyMaxAge", firstBackupTime.Add(maxAge), firstBackupTime, false},
+		{"beyondMaxAge", firstBackupTime.Add(maxAge + time.Second), firstBackupTime, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			s.now = func() time.Time { return tt.now }
+			instance, database := newTestInstance()
+			backupService := &fakeBackupService{
+				setting: &api.BackupSetting{Enabled: true, Hour: 2, Minute: 0, DayOfWeek: -1, DayOfMonth: -1, UpdatedTs: enabledTime.Unix()},
+			}
+			if !tt.lastBackupTime.IsZero() {
+				backupService.list = []*api.Backup{{ID: 1, UpdatedTs: tt.lastBackupTime.Unix()}}
+			}
+			s.server.BackupService = backupService
+			backupPlanPolicyMap := map[int]*api.BackupPlanPolicy{
+				instance.EnvironmentID: {Schedule: api.BackupPlanPolicyScheduleDaily},
+			}
+
+			s.checkBackupAnomaly(ctx, instance, database, backupPlanPolicyMap)
+			if got := anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseBackupMissing]; got != tt.wantMissing {
+				t.Errorf("backup missing anomaly active = %t, want %t", got, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestCheckBackupMissingAnomalyMonthly(t *testing.T) {
+	tests := []struct {
+		name        string
+		age         time.Duration
+		wantMissing bool
+	}{
+		// A weekly backup would be missing after 10 days.
+		{"withinMonth", 10 * 24 * time.Hour, false},
+		{"beyondMonth", 40 * 24 * time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			instance, database := newTestInstance()
+			s.server.BackupService = &fakeBackupService{
+				setting: &api.BackupSetting{Enabled: true, Hour: 0, Minute: 0, DayOfWeek: -1, DayOfMonth: 1, UpdatedTs: time.Now().Add(-90 * 24 * time.Hour).Unix()},
+				list:    []*api.Backup{{ID: 1, UpdatedTs: time.Now().Add(-tt.age).Unix()}},
+			}
+			backupPlanPolicyMap := map[int]*api.BackupPlanPolicy{
+				instance.EnvironmentID: {Schedule: api.BackupPlanPolicyScheduleMonthly},
+			}
+
+			s.checkBackupAnomaly(ctx, instance, database, backupPlanPolicyMap)
+			activeTypes := anomalyService.activeTypes(database.ID)
+			if got := activeTypes[api.AnomalyDatabaseBackupMissing]; got != tt.wantMissing {
+				t.Errorf("backup missing anomaly active = %t, want %t", got, tt.wantMissing)
+			}
+			if activeTypes[api.AnomalyDatabaseBackupPolicyViolation] {
+				t.Errorf("backup policy violation anomaly is active for the monthly backup under the monthly policy")
+			}
+		})
+	}
+}
+
+func TestGetBackupSettingSchedule(t *testing.T) {
+	tests := []struct {
+		name    string
+		setting *api.BackupSetting
+		want    api.BackupPlanPolicySchedule
+	}{
+		{"daily", &api.BackupSetting{Hour: 1, Minute: 0, DayOfWeek: -1, DayOfMonth: -1}, api.BackupPlanPolicyScheduleDaily},
+		{"dailyWithMinute", &api.BackupSetting{Hour: 1, Minute: 45, DayOfWeek: -1, DayOfMonth: -1}, api.BackupPlanPolicyScheduleDaily},
+		{"weekly", &api.BackupSetting{Hour: 23, Minute: 0, DayOfWeek: 0, DayOfMonth: -1}, api.BackupPlanPolicyScheduleWeekly},
+		{"weeklyWithMinute", &api.BackupSetting{Hour: 23, Minute: 59, DayOfWeek: 6, DayOfMonth: -1}, api.BackupPlanPolicyScheduleWeekly},
+		{"monthly", &api.BackupSetting{Hour: 2, Minute: 0, DayOfWeek: -1, DayOfMonth: 1}, api.BackupPlanPolicyScheduleMonthly},
+		{"monthlyLastDay", &api.BackupSetting{Hour: 2, Minute: 30, DayOfWeek: -1, DayOfMonth: 28}, api.BackupPlanPolicyScheduleMonthly},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getBackupSettingSchedule(tt.setting); got != tt.want {
+				t.Errorf("getBackupSettingSchedule() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckBackupAnomalyMalformedSetting(t *testing.T) {
+	tests := []struct {
+		name          string
+		setting       *api.BackupSetting
+		wantViolation bool
+	}{
+		{"daily", &api.BackupSetting{Enabled: true, Hour: 0, Minute: 0, DayOfWeek: -1, DayOfMonth: -1}, false},
+		{"weekly", &api.BackupSetting{Enabled: true, Hour: 23, Minute: 59, DayOfWeek: 6, DayOfMonth: -1}, true},
+		{"monthly", &api.BackupSetting{Enabled: true, Hour: 23, Minute: 59, DayOfWeek: -1, DayOfMonth: 15}, true},
+		// The malformed settings are skipped rather than classified as weekly.
+		{"dayOfWeekTooLarge", &api.BackupSetting{Enabled: true, Hour: 1, Minute: 0, DayOfWeek: 7, DayOfMonth: -1}, false},
+		{"dayOfWeekTooSmall", &api.BackupSetting{Enabled: true, Hour: 1, Minute: 0, DayOfWeek: -2, DayOfMonth: -1}, false},
+		{"hourTooLarge", &api.BackupSetting{Enabled: true, Hour: 24, Minute: 0, DayOfWeek: 3, DayOfMonth: -1}, false},
+		{"hourUnset", &api.BackupSetting{Enabled: true, Hour: -1, Minute: 0, DayOfWeek: 3, DayOfMonth: -1}, false},
+		{"dayOfMonthTooLarge", &api.BackupSetting{Enabled: true, Hour: 1, Minute: 0, DayOfWeek: -1, DayOfMonth: 29}, false},
+		{"dayOfMonthWithDayOfWeek", &api.BackupSetting{Enabled: true, Hour: 1, Minute: 0, DayOfWeek: 3, DayOfMonth: 1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			instance, database := newTestInstance()
+			// A recently updated setting isn't checked for missing backups.
+			tt.setting.UpdatedTs = time.Now().Unix()
+			s.server.BackupService = &fakeBackupService{setting: tt.setting}
+			backupPlanPolicyMap := map[int]*api.BackupPlanPolicy{
+				instance.EnvironmentID: {Schedule: api.BackupPlanPolicyScheduleDaily},
+			}
+
+			s.checkBackupAnomaly(ctx, instance, database, backupPlanPolicyMap)
+			if got := anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseBackupPolicyViolation]; got != tt.wantViolation {
+				t.Errorf("backup policy violation anomaly active = %t, want %t", got, tt.wantViolation)
+			}
+		})
+	}
+}
+
+func TestCheckBackupCorruptAnomaly(t *testing.T) {
+	ctx := context.Background()
+	s, anomalyService := newTestAnomalyScanner()
+	s.server.dataDir = t.TempDir()
+	instance, database := newTestInstance()
+	backupPlanPolicyMap := map[int]*api.BackupPlanPolicy{
+		instance.EnvironmentID: {Schedule: api.BackupPlanPolicyScheduleUnset},
+	}
+	newBackup := func(id int, content string) *api.Backup {
+		name := fmt.Sprintf("backup%d", id)
+		if err := os.WriteFile(filepath.Join(s.server.dataDir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		checksum := sha256.Sum256([]byte("SELECT 1;"))
+		return &api.Backup{
+			ID:             id,
+			Name:           name,
+			StorageBackend: api.BackupStorageBackendLocal,
+			Path:           name,
+			Checksum:       hex.EncodeToString(checksum[:]),
+		}
+	}
+	isCorrupt := func() bool {
+		return anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseBackupCorrupt]
+	}
+
+	corrupt := newBackup(1, "SELECT 2;")
+	s.server.BackupService = &fakeBackupService{list: []*api.Backup{corrupt}}
+	s.checkBackupAnomaly(ctx, instance, database, backupPlanPolicyMap)
+	if !isCorrupt() {
+		t.Fatal("backup corrupt anomaly is not raised for the mismatching checksum")
+	}
+	payload := &api.AnomalyDatabaseBackupCorruptPayload{}
+	if err := json.Unmarshal([]byte(anomalyService.list[0].Payload), payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.BackupID != corrupt.ID || payload.ExpectedChecksum != corrupt.Checksum || payload.ActualChecksum == corrupt.Checksum {
+		t.Errorf("payload = %+v, want backup %d with expected checksum %s and a different actual checksum", payload, corrupt.ID, corrupt.Checksum)
+	}
+
+	// The same backup isn't re-hashed within the verify interval, so fixing the file doesn't archive the anomaly yet.
+	if err := os.WriteFile(filepath.Join(s.server.dataDir, corrupt.Path), []byte("SELECT 1;"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s.checkBackupAnomaly(ctx, instance, database, backupPlanPolicyMap)
+	if !isCorrupt() {
+		t.Error("backup corrupt anomaly is archived within the verify interval")
+	}
+
+	// A later valid backup is verified right away and archives the anomaly.
+	s.server.BackupService = &fakeBackupService{list: []*api.Backup{newBackup(2, "SELECT 1;"), corrupt}}
+	s.checkBackupAnomaly(ctx, instance, database, backupPlanPolicyMap)
+	if isCorrupt() {
+		t.Error("backup corrupt anomaly is not archived after a later valid backup")
+	}
+
+	// A backup whose file can't be read isn't reported as corrupt.
+	missing := newBackup(3, "")
+	missing.Path = "missing"
+	s.server.BackupService = &fakeBackupService{list: []*api.Backup{missing}}
+	s.checkBackupAnomaly(ctx, instance, database, backupPlanPolicyMap)
+	if isCorrupt() {
+		t.Error("backup corrupt anomaly is raised for the unreadable backup")
+	}
+}
+
+func TestCheckBackupAnomalyFindBackupListOnce(t *testing.T) {
+	ctx := context.Background()
+	s, anomalyService := newTestAnomalyScanner()
+	instance, database := newTestInstance()
+	backupService := &fakeBackupService{
+		setting: &api.BackupSetting{Enabled: true, Hour: 0, Minute: 0, DayOfWeek: -1, DayOfMonth: -1, UpdatedTs: time.Now().Add(-30 * 24 * time.Hour).Unix()},
+		// The backup missing, corrupt and size checks all find the backups, and the stale one raises the backup missing anomaly.
+		list: []*api.Backup{{ID: 1, UpdatedTs: time.Now().Add(-72 * time.Hour).Unix(), SizeBytes: 1000}},
+	}
+	s.server.BackupService = backupService
+	backupPlanPolicyMap := map[int]*api.BackupPlanPolicy{
+		instance.EnvironmentID: {Schedule: api.BackupPlanPolicyScheduleDaily},
+	}
+
+	s.checkBackupAnomaly(ctx, instance, database, backupPlanPolicyMap)
+	if backupService.findListCount != 1 {
+		t.Errorf("found the backup list %d times, want once per database", backupService.findListCount)
+	}
+	if !anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseBackupMissing] {
+		t.Errorf("expect the backup missing anomaly from the shared backup list")
+	}
+}
+
+func TestCheckBackupSizeAnomaly(t *testing.T) {
+	previousSizeList := []int64{1000, 1100, 900, 1050}
+	tests := []struct {
+		name        string
+		sizeBytes   int64
+		wantActive  bool
+		wantRatio   float64
+		historySize int
+	}{
+		{"normal", 1200, false, 0, len(previousSizeList)},
+		// A truncated backup marked as done.
+		{"shrink", 100, true, 0.1, len(previousSizeList)},
+		{"growth", 5000, true, 5, len(previousSizeList)},
+		// Too few backups before to tell.
+		{"fewHistory", 100, false, 0, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			instance, database := newTestInstance()
+			list := []*api.Backup{{ID: 100, Name: "latest", SizeBytes: tt.sizeBytes}}
+			for i, size := range previousSizeList[:tt.historySize] {
+				list = append(list, &api.Backup{ID: i + 1, SizeBytes: size})
+			}
+			// The backups taken before size support are skipped.
+			list = append(list, &api.Backup{ID: 99})
+
+			s.checkBackupSizeAnomaly(ctx, instance, database, list)
+			if got := anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseBackupSizeAnomalous]; got != tt.wantActive {
+				t.Fatalf("backup size anomaly active = %t, want %t", got, tt.wantActive)
+			}
+			if !tt.wantActive {
+				return
+			}
+			payload := &api.AnomalyDatabaseBackupSizeAnomalousPayload{}
+			if err := json.Unmarshal([]byte(anomalyService.list[0].Payload), payload); err != nil {
+				t.Fatal(err)
+			}
+			want := api.AnomalyDatabaseBackupSizeAnomalousPayload{
+				BackupID:          100,
+				BackupName:        "latest",
+				PreviousSizeBytes: 1000,
+				CurrentSizeBytes:  tt.sizeBytes,
+				Ratio:             tt.wantRatio,
+				Threshold:         defaultBackupSizeChangeThreshold,
+			}
+			if *payload != want {
+				t.Errorf("payload = %+v, want %+v", *payload, want)
+			}
+
+			// A normal backup afterwards archives the anomaly.
+			list[0] = &api.Backup{ID: 101, SizeBytes: 1000}
+			s.checkBackupSizeAnomaly(ctx, instance, database, list)
+			if anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseBackupSizeAnomalous] {
+				t.Error("backup size anomaly is not archived after a normal backup")
+			}
+		})
+	}
+}
+
+func TestArchiveInstanceAnomalyList(t *testing.T) {
+	ctx := context.Background()
+	s, anomalyService := newTestAnomalyScanner()
+	instance, database := newTestInstance()
+
+	for _, upsert := range []*api.AnomalyUpsert{
+		{CreatorID: api.SystemBotID, InstanceID: instance.ID, Type: api.AnomalyInstanceMigrationSchema},
+		{CreatorID: api.SystemBotID, InstanceID: instance.ID, DatabaseID: &database.ID, Type: api.AnomalyDatabaseSchemaDrift},
+		{CreatorID: api.SystemBotID, InstanceID: instance.ID, DatabaseID: &database.ID, Type: api.AnomalyDatabaseBackupMissing},
+	} {
+		if _, _, err := anomalyService.UpsertActiveAnomaly(ctx, upsert); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.archiveInstanceAnomalyList(ctx, instance, nil)
+	if types := anomalyService.activeInstanceTypes(instance.ID); len(types) != 0 {
+		t.Errorf("expect instance anomalies to be archived, got %v", types)
+	}
+	if types := anomalyService.activeTypes(database.ID); len(types) != 0 {
+		t.Errorf("expect database anomalies to be archived, got %v", types)
+	}
+}
+
+func TestScanInstanceDisabledAnomalyType(t *testing.T) {
+	tests := []struct {
+		name             string
+		disabledTypeList []api.AnomalyType
+		wantMigration    bool
+		wantViolation    bool
+	}{
+		{"noneDisabled", nil, true, true},
+		{"migrationSchemaDisabled", []api.AnomalyType{api.AnomalyInstanceMigrationSchema}, false, true},
+		{"backupPolicyViolationDisabled", []api.AnomalyType{api.AnomalyDatabaseBackupPolicyViolation}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			instance, database := newTestInstance()
+			s.server.BackupService = &fakeBackupService{}
+			s.server.DatabaseService = &fakeDatabaseService{list: []*api.Database{database}}
+			testDriver.needsSetup = true
+			backupPlanPolicyMap := map[int]*api.BackupPlanPolicy{
+				instance.EnvironmentID: {Schedule: api.BackupPlanPolicyScheduleDaily},
+			}
+			// The anomaly raised before the type is disabled.
+			if _, _, err := anomalyService.UpsertActiveAnomaly(ctx, &api.AnomalyUpsert{
+				CreatorID:  api.SystemBotID,
+				InstanceID: instance.ID,
+				DatabaseID: &database.ID,
+				Type:       api.AnomalyDatabaseBackupPolicyViolation,
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			s.scanInstance(ctx, instance, &api.AnomalyPolicy{Enabled: true, DisabledTypeList: tt.disabledTypeList}, backupPlanPolicyMap, nil)
+			if got := anomalyService.activeInstanceTypes(instance.ID)[api.AnomalyInstanceMigrationSchema]; got != tt.wantMigration {
+				t.Errorf("migration schema anomaly active = %t, want %t", got, tt.wantMigration)
+			}
+			if got := anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseBackupPolicyViolation]; got != tt.wantViolation {
+				t.Errorf("backup policy violation anomaly active = %t, want %t", got, tt.wantViolation)
+			}
+		})
+	}
+}
+
+func TestIsDatabaseExcluded(t *testing.T) {
+	s := NewAnomalyScanner(zap.NewNop(), nil, AnomalyScannerConfig{
+		ExcludedDatabasePatternList: []string{"tmp_.*", "(invalid"},
+	})
+	tests := []struct {
+		engine       db.Type
+		databaseName string
+		want         bool
+	}{
+		{db.MySQL, "mysql", true},
+		{db.MySQL, "performance_schema", true},
+		{db.MySQL, "employee", false},
+		{db.Postgres, "template1", true},
+		{db.Postgres, "template_app", false},
+		{db.Postgres, "postgres", true},
+		// The default patterns only apply to their engines.
+		{db.MySQL, "postgres", false},
+		// The configured patterns apply to every engine and match the whole name.
+		{db.MySQL, "tmp_import", true},
+		{db.Postgres, "tmp_import", true},
+		{db.Postgres, "app_tmp_import", false},
+	}
+	for _, tt := range tests {
+		if got := s.isDatabaseExcluded(tt.engine, tt.databaseName); got != tt.want {
+			t.Errorf("isDatabaseExcluded(%s, %q) = %t, want %t", tt.engine, tt.databaseName, got, tt.want)
+		}
+	}
+}
+
+func TestScanInstanceExcludedDatabase(t *testing.T) {
+	ctx := context.Background()
+	s, anomalyService := newTestAnomalyScanner()
+	s.excludedDatabaseRegexpList = NewAnomalyScanner(zap.NewNop(), nil, AnomalyScannerConfig{
+		ExcludedDatabasePatternList: []string{"excluded_.*"},
+	}).excludedDatabaseRegexpList
+	instance, database := newTestInstance()
+	excluded := &api.Database{ID: 2, InstanceID: instance.ID, Name: "excluded_db"}
+	s.server.BackupService = &fakeBackupService{}
+	s.server.DatabaseService = &fakeDatabaseService{list: []*api.Database{database, excluded}}
+	backupPlanPolicyMap := map[int]*api.BackupPlanPolicy{
+		instance.EnvironmentID: {Schedule: api.BackupPlanPolicyScheduleDaily},
+	}
+	// The anomaly raised before the database is excluded.
+	if _, _, err := anomalyService.UpsertActiveAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		DatabaseID: &excluded.ID,
+		Type:       api.AnomalyDatabaseSchemaDrift,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	s.scanInstance(ctx, instance, &api.AnomalyPolicy{Enabled: true}, backupPlanPolicyMap, nil)
+	if !anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseBackupPolicyViolation] {
+		t.Errorf("expect the database not excluded to be scanned")
+	}
+	if types := anomalyService.activeTypes(excluded.ID); len(types) != 0 {
+		t.Errorf("expect the excluded database to be skipped and its anomalies archived, got %v", types)
+	}
+}
+
+func TestScanInstanceDatabaseNotFound(t *testing.T) {
+	ctx := context.Background()
+	s, anomalyService := newTestAnomalyScanner()
+	instance, database := newTestInstance()
+	dropped := &api.Database{ID: 2, InstanceID: instance.ID, Name: "dropped_db", SyncStatus: api.NotFound}
+	s.server.BackupService = &fakeBackupService{}
+	s.server.DatabaseService = &fakeDatabaseService{list: []*api.Database{database, dropped}}
+	backupPlanPolicyMap := map[int]*api.BackupPlanPolicy{
+		instance.EnvironmentID: {Schedule: api.BackupPlanPolicyScheduleDaily},
+	}
+
+	s.scanInstance(ctx, instance, &api.AnomalyPolicy{Enabled: true}, backupPlanPolicyMap, nil)
+	if !anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseBackupPolicyViolation] {
+		t.Errorf("expect the database found to be scanned")
+	}
+	if types := anomalyService.activeTypes(dropped.ID); len(types) != 0 {
+		t.Errorf("expect the database not found to be skipped, got %v", types)
+	}
+}
+
+func TestScanInstanceSharedDriver(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestAnomalyScanner()
+	instance, database := newTestInstance()
+	s.server.BackupService = &fakeBackupService{}
+	s.server.DatabaseService = &fakeDatabaseService{list: []*api.Database{
+		database,
+		{ID: 2, InstanceID: instance.ID, Name: "db2"},
+		{ID: 3, InstanceID: instance.ID, Name: "db3"},
+	}}
+	backupPlanPolicyMap := map[int]*api.BackupPlanPolicy{
+		instance.EnvironmentID: {Schedule: api.BackupPlanPolicyScheduleUnset},
+	}
+
+	s.scanInstance(ctx, instance, &api.AnomalyPolicy{Enabled: true}, backupPlanPolicyMap, nil)
+	if open := atomic.LoadInt32(&testDriver.openCount); open != 1 {
+		t.Errorf("opened the driver %d times, want once per instance", open)
+	}
+	if closed := atomic.LoadInt32(&testDriver.closeCount); closed != 1 {
+		t.Errorf("closed the driver %d times, want once per instance", closed)
+	}
+	if isInstanceDriverShareable(db.Postgres) {
+		t.Errorf("expect the Postgres driver bound to a database not to be shared")
+	}
+}
+
+func TestScanInstanceSyncSchemaOnce(t *testing.T) {
+	ctx := context.Background()
+	s, anomalyService := newTestAnomalyScanner()
+	instance, database := newTestInstance()
+	unindexed := &api.Database{ID: 2, InstanceID: instance.ID, Name: "db2"}
+	s.server.BackupService = &fakeBackupService{}
+	s.server.DatabaseService = &fakeDatabaseService{list: []*api.Database{
+		database,
+		unindexed,
+		{ID: 3, InstanceID: instance.ID, Name: "db3"},
+	}}
+	testDriver.schemaList = []*db.Schema{
+		{Name: database.Name, TableList: []db.Table{{Name: "user"}}},
+		{Name: unindexed.Name, TableList: []db.Table{{
+			Name:           "order",
+			ForeignKeyList: []db.ForeignKey{{Name: "fk_order_user", ColumnList: []string{"user_id"}, ReferencedTable: "user"}},
+		}}},
+	}
+	backupPlanPolicyMap := map[int]*api.BackupPlanPolicy{
+		instance.EnvironmentID: {Schedule: api.BackupPlanPolicyScheduleUnset},
+	}
+
+	s.scanInstance(ctx, instance, &api.AnomalyPolicy{Enabled: true}, backupPlanPolicyMap, nil)
+	if n := atomic.LoadInt32(&testDriver.syncSchemaCount); n != 1 {
+		t.Errorf("synced the schema %d times, want once per instance", n)
+	}
+	if !anomalyService.activeTypes(unindexed.ID)[api.AnomalyDatabaseIndexMissing] {
+		t.Errorf("expect the index missing anomaly for the database with the foreign key not indexed")
+	}
+	for _, id := range []int{database.ID, 3} {
+		if anomalyService.activeTypes(id)[api.AnomalyDatabaseIndexMissing] {
+			t.Errorf("expect no index missing anomaly for database %d", id)
+		}
+	}
+}
+
+func TestScanInstanceDuration(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		openErr   error
+		wantSlow  bool
+	}{
+		{"fast", time.Hour, nil, false},
+		{"slow", 10 * time.Millisecond, nil, true},
+		// The duration is recorded even if the instance can't be connected.
+		{"slowFailure", 10 * time.Millisecond, fmt.Errorf("connection refused"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			_, anomalyService := newTestAnomalyScanner()
+			core, logs := observer.New(zap.WarnLevel)
+			s := NewAnomalyScanner(zap.New(core), &Server{
+				AnomalyService:          anomalyService,
+				RowCountBaselineService: &fakeRowCountBaselineService{},
+				BackupService:           &fakeBackupService{},
+				DatabaseService:         &fakeDatabaseService{},
+			}, AnomalyScannerConfig{ConnectRetryCount: 1, SlowInstanceScanThreshold: tt.threshold})
+			testDriver.openDelay = 20 * time.Millisecond
+			testDriver.openErr = tt.openErr
+			instance, _ := newTestInstance()
+			backupPlanPolicyMap := map[int]*api.BackupPlanPolicy{
+				instance.EnvironmentID: {Schedule: api.BackupPlanPolicyScheduleUnset},
+			}
+
+			s.startRoundStats()
+			s.scanInstance(ctx, instance, &api.AnomalyPolicy{Enabled: true}, backupPlanPolicyMap, nil)
+			s.finishRoundStats()
+			duration, ok := s.Stats().InstanceDurationMap[instance.Name]
+			if !ok {
+				t.Fatalf("scan duration of instance %q isn't recorded", instance.Name)
+			}
+			if duration < testDriver.openDelay {
+				t.Errorf("scan duration = %v, want at least %v", duration, testDriver.openDelay)
+			}
+			if got := logs.FilterMessage("Slow instance anomaly scan").Len() == 1; got != tt.wantSlow {
+				t.Errorf("slow scan warning logged = %t, want %t", got, tt.wantSlow)
+			}
+		})
+	}
+}
+
+func TestCheckDatabaseAnomalyBrokenSharedDriver(t *testing.T) {
+	tests := []struct {
+		name           string
+		openErr        error
+		wantOpen       int32
+		wantConnection bool
+	}{
+		// The database is checked through its own connection instead.
+		{"fallback", nil, 1, false},
+		{"databaseUnreachable", fmt.Errorf("connection refused"), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			instance, database := newTestInstance()
+			healthyDatabase := &api.Database{ID: 2, InstanceID: instance.ID, Name: "healthy"}
+			broken := &fakeDriver{pingErr: fmt.Errorf("invalid connection")}
+			testDriver.openErr = tt.openErr
+
+			s.checkDatabaseAnomaly(ctx, instance, database, broken, nil, nil)
+			s.checkDatabaseAnomaly(ctx, instance, healthyDatabase, &fakeDriver{}, nil, nil)
+			if got := anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseConnection]; got != tt.wantConnection {
+				t.Errorf("database connection anomaly active = %t, want %t", got, tt.wantConnection)
+			}
+			if anomalyService.activeTypes(healthyDatabase.ID)[api.AnomalyDatabaseConnection] {
+				t.Errorf("expect the database checked with the healthy shared driver not to raise the connection anomaly")
+			}
+			if open := atomic.LoadInt32(&testDriver.openCount); open != tt.wantOpen {
+				t.Errorf("opened the fallback driver %d times, want %d", open, tt.wantOpen)
+			}
+			if closed := atomic.LoadInt32(&testDriver.closeCount); closed != tt.wantOpen {
+				t.Errorf("closed the fallback driver %d times, want %d", closed, tt.wantOpen)
+			}
+			if closed := atomic.LoadInt32(&broken.closeCount); closed != 0 {
+				t.Errorf("closed the shared driver %d times, want it left to the instance scan", closed)
+			}
+		})
+	}
+}
+
+func TestScanInstanceGloballyDisabledType(t *testing.T) {
+	ctx := context.Background()
+	_, anomalyService := newTestAnomalyScanner()
+	s := NewAnomalyScanner(zap.NewNop(), &Server{
+		AnomalyService:          anomalyService,
+		RowCountBaselineService: &fakeRowCountBaselineService{},
+	}, AnomalyScannerConfig{DisabledTypeList: []api.AnomalyType{api.AnomalyDatabaseBackupMissing, "bb.anomaly.unknown"}})
+	instance, database := newTestInstance()
+	s.server.DatabaseService = &fakeDatabaseService{list: []*api.Database{database}}
+	// The backup is overdue, and the daily policy is violated by the weekly backup.
+	s.server.BackupService = &fakeBackupService{
+		setting: &api.BackupSetting{Enabled: true, Hour: 0, Minute: 0, DayOfWeek: 0, DayOfMonth: -1, UpdatedTs: time.Now().Add(-30 * 24 * time.Hour).Unix()},
+	}
+	backupPlanPolicyMap := map[int]*api.BackupPlanPolicy{
+		instance.EnvironmentID: {Schedule: api.BackupPlanPolicyScheduleDaily},
+	}
+	// The anomaly found before the type was disabled.
+	if _, _, err := anomalyService.UpsertActiveAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		DatabaseID: &database.ID,
+		Type:       api.AnomalyDatabaseBackupMissing,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	s.scanInstance(ctx, instance, &api.AnomalyPolicy{Enabled: true}, backupPlanPolicyMap, nil)
+	activeTypes := anomalyService.activeTypes(database.ID)
+	if activeTypes[api.AnomalyDatabaseBackupMissing] {
+		t.Errorf("expect the globally disabled backup missing anomaly to be archived")
+	}
+	if !activeTypes[api.AnomalyDatabaseBackupPolicyViolation] {
+		t.Errorf("expect the backup policy violation anomaly not disabled to be raised")
+	}
+	status := api.Archived
+	anomalyType := api.AnomalyDatabaseBackupMissing
+	list, err := anomalyService.FindAnomalyList(ctx, &api.AnomalyFind{
+		RowStatus:  &status,
+		DatabaseID: &database.ID,
+		Type:       &anomalyType,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ArchiveReason != api.AnomalyArchiveReasonCheckDisabled {
+		t.Errorf("archived backup missing anomalies = %+v, want one archived by %q", list, api.AnomalyArchiveReasonCheckDisabled)
+	}
+}
+
+func TestArchiveDisabledInstanceAnomalyList(t *testing.T) {
+	ctx := context.Background()
+	s, anomalyService := newTestAnomalyScanner()
+	disabled, database := newTestInstance()
+	enabled := &api.Instance{ID: 2, Name: "enabled", Engine: fakeDriverType, EnableAnomalyScan: true}
+	s.server.InstanceService = &fakeInstanceService{list: []*api.Instance{disabled, enabled}}
+	for _, upsert := range []*api.AnomalyUpsert{
+		{InstanceID: disabled.ID, Type: api.AnomalyInstanceConnection},
+		{InstanceID: disabled.ID, DatabaseID: &database.ID, Type: api.AnomalyDatabaseSchemaDrift},
+		{InstanceID: enabled.ID, Type: api.AnomalyInstanceConnection},
+	} {
+		upsert.CreatorID = api.SystemBotID
+		if _, _, err := anomalyService.UpsertActiveAnomaly(ctx, upsert); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.archiveDisabledInstanceAnomalyList(ctx)
+	if types := anomalyService.activeInstanceTypes(disabled.ID); len(types) != 0 {
+		t.Errorf("expect the anomalies of the disabled instance to be archived, got %v", types)
+	}
+	if types := anomalyService.activeTypes(database.ID); len(types) != 0 {
+		t.Errorf("expect the anomalies of the databases of the disabled instance to be archived, got %v", types)
+	}
+	if !anomalyService.activeInstanceTypes(enabled.ID)[api.AnomalyInstanceConnection] {
+		t.Errorf("expect the anomalies of the enabled instance to stay active")
+	}
+}
+
+func TestCheckConnectionUnencryptedAnomaly(t *testing.T) {
+	encrypted, unencrypted := &db.ConnectionTLSState{Encrypted: true}, &db.ConnectionTLSState{}
+	tests := []struct {
+		name              string
+		requireEncryption bool
+		tlsState          *db.ConnectionTLSState
+		want              bool
+	}{
+		{"unencrypted", true, unencrypted, true},
+		{"encrypted", true, encrypted, false},
+		{"notRequired", false, unencrypted, false},
+		{"unsupported", true, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			instance, _ := newTestInstance()
+
+			testDriver.tlsState = tt.tlsState
+			s.checkConnectionUnencryptedAnomaly(ctx, instance, testDriver, tt.requireEncryption)
+			if got := anomalyService.activeInstanceTypes(instance.ID)[api.AnomalyDatabaseConnectionUnencrypted]; got != tt.want {
+				t.Fatalf("unencrypted connection anomaly active = %t, want %t", got, tt.want)
+			}
+
+			// The anomaly is archived once encryption is observed.
+			testDriver.tlsState = encrypted
+			s.checkConnectionUnencryptedAnomaly(ctx, instance, testDriver, tt.requireEncryption)
+			if anomalyService.activeInstanceTypes(instance.ID)[api.AnomalyDatabaseConnectionUnencrypted] {
+				t.Errorf("expect unencrypted connection anomaly to be archived")
+			}
+		})
+	}
+}
+
+func TestCheckInsecureConnectionAnomaly(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		enabled  bool
+		tlsState *db.ConnectionTLSState
+		want     bool
+	}{
+		{"secure", true, &db.ConnectionTLSState{Required: true, Encrypted: true, CertificateNotAfter: now.AddDate(1, 0, 0)}, false},
+		{"plaintextFallback", true, &db.ConnectionTLSState{Required: true}, true},
+		{"plaintextNotRequired", true, &db.ConnectionTLSState{}, false},
+		{"selfSigned", true, &db.ConnectionTLSState{Encrypted: true, SelfSigned: true}, true},
+		{"expired", true, &db.ConnectionTLSState{Encrypted: true, CertificateNotAfter: now.AddDate(0, 0, -1)}, true},
+		{"expiredPlaintext", true, &db.ConnectionTLSState{CertificateNotAfter: now.AddDate(0, 0, -1)}, true},
+		{"certificateUnknown", true, &db.ConnectionTLSState{Encrypted: true}, false},
+		{"disabled", false, &db.ConnectionTLSState{Required: true}, false},
+		{"unsupported", true, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			instance, _ := newTestInstance()
+
+			testDriver.tlsState = tt.tlsState
+			s.checkInsecureConnectionAnomaly(ctx, instance, testDriver, tt.enabled)
+			if got := anomalyService.activeInstanceTypes(instance.ID)[api.AnomalyDatabaseInsecureConnection]; got != tt.want {
+				t.Fatalf("insecure connection anomaly active = %t, want %t", got, tt.want)
+			}
+			if !tt.want {
+				return
+			}
+
+			status := api.Normal
+			anomalyType := api.AnomalyDatabaseInsecureConnection
+			list, _ := anomalyService.FindAnomalyList(ctx, &api.AnomalyFind{
+				RowStatus:  &status,
+				InstanceID: &instance.ID,
+				Type:       &anomalyType,
+			})
+			if len(list) != 1 {
+				t.Fatalf("found %d insecure connection anomalies, want 1", len(list))
+			}
+			var payload api.AnomalyDatabaseInsecureConnectionPayload
+			if err := json.Unmarshal([]byte(list[0].Payload), &payload); err != nil {
+				t.Fatal(err)
+			}
+			want := api.AnomalyDatabaseInsecureConnectionPayload{
+				Encrypted:  tt.tlsState.Encrypted,
+				SelfSigned: tt.tlsState.SelfSigned,
+			}
+			if !tt.tlsState.CertificateNotAfter.IsZero() {
+				want.CertificateExpireTs = tt.tlsState.CertificateNotAfter.Unix()
+			}
+			if payload != want {
+				t.Errorf("payload = %+v, want %+v", payload, want)
+			}
+
+			// The anomaly is archived once the connection is secure.
+			testDriver.tlsState = &db.ConnectionTLSState{Required: true, Encrypted: true}
+			s.checkInsecureConnectionAnomaly(ctx, instance, testDriver, tt.enabled)
+			if anomalyService.activeInstanceTypes(instance.ID)[api.AnomalyDatabaseInsecureConnection] {
+				t.Errorf("expect insecure connection anomaly to be archived")
+			}
+		})
+	}
+}
+
+func TestCheckDiskSpaceAnomaly(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		usage     *db.DiskUsage
+		want      bool
+	}{
+		{"unsupported", 0, nil, false},
+		{"belowDefault", 0, &db.DiskUsage{TotalBytes: 100, UsedBytes: 90, FreeBytes: 10}, false},
+		{"aboveDefault", 0, &db.DiskUsage{TotalBytes: 100, UsedBytes: 95, FreeBytes: 5}, true},
+		{"aboveCustom", 50, &db.DiskUsage{TotalBytes: 100, UsedBytes: 60, FreeBytes: 40}, true},
+		{"unknownTotal", 0, &db.DiskUsage{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			s.diskUsageThreshold = NewAnomalyScanner(zap.NewNop(), nil, AnomalyScannerConfig{DiskUsageThreshold: tt.threshold}).diskUsageThreshold
+			instance, _ := newTestInstance()
+
+			testDriver.diskUsage = tt.usage
+			s.checkDiskSpaceAnomaly(ctx, instance, testDriver)
+			if got := anomalyService.activeInstanceTypes(instance.ID)[api.AnomalyInstanceDiskSpaceLow]; got != tt.want {
+				t.Fatalf("disk space low anomaly active = %t, want %t", got, tt.want)
+			}
+
+			// The anomaly is archived once the disk space is freed.
+			testDriver.diskUsage = &db.DiskUsage{TotalBytes: 100, UsedBytes: 10, FreeBytes: 90}
+			s.checkDiskSpaceAnomaly(ctx, instance, testDriver)
+			if anomalyService.activeInstanceTypes(instance.ID)[api.AnomalyInstanceDiskSpaceLow] {
+				t.Errorf("expect disk space low anomaly to be archived")
+			}
+		})
+	}
+}
+
+// fakeAnomalyNotifier is the AnomalyNotifier used by anomaly scanner tests, it records the notified anomaly IDs.
+type fakeAnomalyNotifier struct {
+	err       error
+	panic     bool
+	openList  []int
+	closeList []int
+}
+
+func (n *fakeAnomalyNotifier) OnAnomalyOpen(ctx context.Context, anomaly *api.Anomaly) error {
+	n.openList = append(n.openList, anomaly.ID)
+	if n.panic {
+		panic("notifier panic")
+	}
+	return n.err
+}
+
+func (n *fakeAnomalyNotifier) OnAnomalyClose(ctx context.Context, anomaly *api.Anomaly) error {
+	n.closeList = append(n.closeList, anomaly.ID)
+	if n.panic {
+		panic("notifier panic")
+	}
+	return n.err
+}
+
+func TestAnomalyScannerNotifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		notifier *fakeAnomalyNotifier
+	}{
+		{"succeed", &fakeAnomalyNotifier{}},
+		{"fail", &fakeAnomalyNotifier{err: fmt.Errorf("notifier failure")}},
+		{"panic", &fakeAnomalyNotifier{panic: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			s.notifier = tt.notifier
+			instance, database := newTestInstance()
+			upsert := &api.AnomalyUpsert{
+				CreatorID:  api.SystemBotID,
+				InstanceID: instance.ID,
+				DatabaseID: &database.ID,
+				Type:       api.AnomalyDatabaseBackupMissing,
+			}
+
+			// Only the newly created anomaly is notified, and the notifier failures don't fail the writes.
+			for i := 0; i < 2; i++ {
+				if err := s.upsertAnomaly(ctx, upsert); err != nil {
+					t.Fatalf("upsertAnomaly() error = %v", err)
+				}
+			}
+			if err := s.archiveAnomaly(ctx, &api.AnomalyArchive{DatabaseID: &database.ID, Type: upsert.Type}); err != nil {
+				t.Fatalf("archiveAnomaly() error = %v", err)
+			}
+			if err := s.archiveAnomaly(ctx, &api.AnomalyArchive{DatabaseID: &database.ID, Type: upsert.Type}); common.ErrorCode(err) != common.NotFound {
+				t.Fatalf("archiveAnomaly() again error = %v, want NotFound", err)
+			}
+
+			anomalyID := anomalyService.list[0].ID
+			if len(tt.notifier.openList) != 1 || tt.notifier.openList[0] != anomalyID {
+				t.Errorf("opened %v, want [%d]", tt.notifier.openList, anomalyID)
+			}
+			if len(tt.notifier.closeList) != 1 || tt.notifier.closeList[0] != anomalyID {
+				t.Errorf("closed %v, want [%d]", tt.notifier.closeList, anomalyID)
+			}
+		})
+	}
+}
+
+func TestAnomalyScannerDryRun(t *testing.T) {
+	ctx := context.Background()
+	s, anomalyService := newTestAnomalyScanner()
+	s.dryRun = true
+	instance, _ := newTestInstance()
+	// The active anomaly which the dry run would archive.
+	if _, _, err := anomalyService.UpsertActiveAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		Type:       api.AnomalyDatabaseReplicationLag,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	s.startRoundStats()
+	testDriver.diskUsage = &db.DiskUsage{TotalBytes: 100, UsedBytes: 95, FreeBytes: 5}
+	s.checkDiskSpaceAnomaly(ctx, instance, testDriver)
+	s.checkReplicationLagAnomaly(ctx, instance, testDriver)
+	s.finishRoundStats()
+
+	types := anomalyService.activeInstanceTypes(instance.ID)
+	if types[api.AnomalyInstanceDiskSpaceLow] {
+		t.Errorf("expect disk space low anomaly not to be created by the dry run")
+	}
+	if !types[api.AnomalyDatabaseReplicationLag] {
+		t.Errorf("expect replication lag anomaly not to be archived by the dry run")
+	}
+	stats := s.Stats()
+	if got := stats.CountMap[api.AnomalyInstanceDiskSpaceLow]; got != (AnomalyCount{Opened: 1}) {
+		t.Errorf("disk space low count = %+v, want %+v", got, AnomalyCount{Opened: 1})
+	}
+	if got := stats.CountMap[api.AnomalyDatabaseReplicationLag]; got != (AnomalyCount{Archived: 1}) {
+		t.Errorf("replication lag count = %+v, want %+v", got, AnomalyCount{Archived: 1})
+	}
+}
+
+func TestAnomalyScannerDryRunRecorder(t *testing.T) {
+	s, anomalyService := newTestAnomalyScanner()
+	instance, _ := newTestInstance()
+	recorder := &dryRunRecorder{}
+	ctx := context.WithValue(context.Background(), dryRunRecorderKey{}, recorder)
+	// The active anomaly which the preview would archive.
+	if _, _, err := anomalyService.UpsertActiveAnomaly(context.Background(), &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		Type:       api.AnomalyDatabaseReplicationLag,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	testDriver.diskUsage = &db.DiskUsage{TotalBytes: 100, UsedBytes: 95, FreeBytes: 5}
+	s.checkDiskSpaceAnomaly(ctx, instance, testDriver)
+	s.checkReplicationLagAnomaly(ctx, instance, testDriver)
+
+	types := anomalyService.activeInstanceTypes(instance.ID)
+	if types[api.AnomalyInstanceDiskSpaceLow] {
+		t.Errorf("expect disk space low anomaly not to be created by the preview")
+	}
+	if !types[api.AnomalyDatabaseReplicationLag] {
+		t.Errorf("expect replication lag anomaly not to be archived by the preview")
+	}
+	if len(recorder.changeList) != 2 {
+		t.Fatalf("got %d changes, want 2", len(recorder.changeList))
+	}
+	if got := recorder.changeList[0]; got.Action != "CREATE" || got.Type != api.AnomalyInstanceDiskSpaceLow || got.Payload == "" {
+		t.Errorf("change[0] = %+v, want CREATE of %s with payload", got, api.AnomalyInstanceDiskSpaceLow)
+	}
+	if got := recorder.changeList[1]; got.Action != "ARCHIVE" || got.Type != api.AnomalyDatabaseReplicationLag || got.InstanceID != instance.ID {
+		t.Errorf("change[1] = %+v, want ARCHIVE of %s", got, api.AnomalyDatabaseReplicationLag)
+	}
+}
+
+func TestCheckReplicationLagAnomaly(t *testing.T) {
+	duration := func(d time.Duration) *time.Duration {
+		return &d
+	}
+	tests := []struct {
+		name      string
+		replica   bool
+		threshold time.Duration
+		lag       *time.Duration
+		want      bool
+	}{
+		{"notReplica", false, 0, duration(time.Hour), false},
+		{"unsupported", true, 0, nil, false},
+		{"belowDefault", true, 0, duration(time.Minute), false},
+		{"aboveDefault", true, 0, duration(2 * time.Minute), true},
+		{"aboveCustom", true, 10 * time.Second, duration(30 * time.Second), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			s.replicationLagThreshold = NewAnomalyScanner(zap.NewNop(), nil, AnomalyScannerConfig{ReplicationLagThreshold: tt.threshold}).replicationLagThreshold
+			instance, _ := newTestInstance()
+			instance.Replica = tt.replica
+
+			testDriver.replicationLag = tt.lag
+			testDriver.replicationPrimary = "10.0.0.1:3306"
+			s.checkReplicationLagAnomaly(ctx, instance, testDriver)
+			if got := anomalyService.activeInstanceTypes(instance.ID)[api.AnomalyDatabaseReplicationLag]; got != tt.want {
+				t.Fatalf("replication lag anomaly active = %t, want %t", got, tt.want)
+			}
+			if tt.want {
+				lagType := api.AnomalyDatabaseReplicationLag
+				list, err := anomalyService.FindAnomalyList(ctx, &api.AnomalyFind{
+					InstanceID: &instance.ID,
+					Type:       &lagType,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				var payload api.AnomalyDatabaseReplicationLagPayload
+				if err := json.Unmarshal([]byte(list[0].Payload), &payload); err != nil {
+					t.Fatal(err)
+				}
+				if payload.LagSeconds != int64(tt.lag.Seconds()) || payload.Primary != testDriver.replicationPrimary {
+					t.Errorf("payload = %+v, want lag %v from primary %q", payload, *tt.lag, testDriver.replicationPrimary)
+				}
+			}
+
+			// The anomaly is archived once the replica catches up.
+			testDriver.replicationLag = duration(0)
+			s.checkReplicationLagAnomaly(ctx, instance, testDriver)
+			if anomalyService.activeInstanceTypes(instance.ID)[api.AnomalyDatabaseReplicationLag] {
+				t.Errorf("expect replication lag anomaly to be archived")
+			}
+		})
+	}
+}
+
+func TestCheckVersionOutdatedAnomaly(t *testing.T) {
+	tests := []struct {
+		name    string
+		engine  db.Type
+		version string
+		want    bool
+	}{
+		{"unsupported", db.MySQL, "", false},
+		{"noMinimum", db.Snowflake, "1.0.0", false},
+		{"mysqlOutdated", db.MySQL, "5.6.51-log", true},
+		{"mysqlMinimum", db.MySQL, "5.7.0", false},
+		{"mysqlCurrent", db.MySQL, "8.0.28-0ubuntu0.20.04.3", false},
+		{"postgresOutdated", db.Postgres, "9.6.24", true},
+		{"postgresCurrent", db.Postgres, "14.2 (Debian 14.2-1.pgdg110+1)", false},
+		{"tidbOutdated", db.TiDB, "5.7.25-TiDB-v4.0.16", true},
+		{"tidbCurrent", db.TiDB, "5.7.25-TiDB-v5.4.0", false},
+		{"unparsable", db.MySQL, "unknown", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			instance, _ := newTestInstance()
+			instance.Engine = tt.engine
+
+			testDriver.version = tt.version
+			s.checkVersionOutdatedAnomaly(ctx, instance, testDriver)
+			if got := anomalyService.activeInstanceTypes(instance.ID)[api.AnomalyDatabaseVersionOutdated]; got != tt.want {
+				t.Fatalf("version outdated anomaly active = %t, want %t", got, tt.want)
+			}
+
+			// The anomaly is archived once the engine is upgraded.
+			testDriver.version = "99.0"
+			s.checkVersionOutdatedAnomaly(ctx, instance, testDriver)
+			if anomalyService.activeInstanceTypes(instance.ID)[api.AnomalyDatabaseVersionOutdated] {
+				t.Errorf("expect version outdated anomaly to be archived")
+			}
+		})
+	}
+}
+
+func TestUpdateEngineVersionEOLAnomaly(t *testing.T) {
+	// Postgres 14 reaches its end of life on 2026-11-12.
+	now := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name              string
+		version           string
+		warningPeriod     time.Duration
+		want              bool
+		wantDaysRemaining int
+	}{
+		{"pastEOL", "9.6.24", 0, true, -1785},
+		{"nearEOL", "14.2", 0, true, 42},
+		{"nearEOLShorterWarningPeriod", "14.2", time.Duration(30*24) * time.Hour, false, 0},
+		{"current", "17.1", 0, false, 0},
+		{"unknownReleaseLine", "99.0", 0, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			if tt.warningPeriod > 0 {
+				s.engineVersionEOLWarningPeriod = tt.warningPeriod
+			}
+			instance, _ := newTestInstance()
+			instance.Engine = db.Postgres
+
+			s.updateEngineVersionEOLAnomaly(ctx, instance, tt.version, now)
+			if got := anomalyService.activeInstanceTypes(instance.ID)[api.AnomalyInstanceEngineVersionEOL]; got != tt.want {
+				t.Fatalf("engine version EOL anomaly active = %t, want %t", got, tt.want)
+			}
+			if tt.want {
+				var payload api.AnomalyInstanceEngineVersionEOLPayload
+				for _, anomaly := range anomalyService.list {
+					if anomaly.Type == api.AnomalyInstanceEngineVersionEOL && anomalyService.status[anomaly.ID] == api.Normal {
+						if err := json.Unmarshal([]byte(anomaly.Payload), &payload); err != nil {
+							t.Fatal(err)
+						}
+					}
+				}
+				if payload.Version != tt.version || payload.DaysRemaining != tt.wantDaysRemaining {
+					t.Errorf("payload = %+v, want version %s and %d days remaining", payload, tt.version, tt.wantDaysRemaining)
+				}
+			}
+
+			// The anomaly is archived once the engine is upgraded.
+			s.updateEngineVersionEOLAnomaly(ctx, instance, "17.1", now)
+			if anomalyService.activeInstanceTypes(instance.ID)[api.AnomalyInstanceEngineVersionEOL] {
+				t.Errorf("expect engine version EOL anomaly to be archived")
+			}
+		})
+	}
+}
+
+func TestForEachDatabase(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		count       int
+	}{
+		{"default", 0, 10},
+		{"serial", 1, 5},
+		{"moreWorkersThanDatabases", 8, 3},
+		{"noDatabase", 4, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewAnomalyScanner(zap.NewNop(), nil, AnomalyScannerConfig{Concurrency: tt.concurrency})
+			instance, _ := newTestInstance()
+			var dbList []*api.Database
+			for i := 0; i < tt.count; i++ {
+				dbList = append(dbList, &api.Database{ID: i, Name: fmt.Sprintf("db%d", i)})
+			}
+
+			var mu sync.Mutex
+			running, maxRunning := 0, 0
+			scanned := make(map[int]bool)
+			s.forEachDatabase(context.Background(), instance, dbList, func(database *api.Database) {
+				mu.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				scanned[database.ID] = true
+				mu.Unlock()
+
+				time.Sleep(10 * time.Millisecond)
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+				// A panicking database must not stop the others from being scanned.
+				if database.ID == 0 {
+					panic("scan failed")
+				}
+			})
+
+			if len(scanned) != tt.count {
+				t.Errorf("scanned %d databases, want %d", len(scanned), tt.count)
+			}
+			if maxRunning > s.concurrency {
+				t.Errorf("max concurrent scans = %d, want at most %d", maxRunning, s.concurrency)
+			}
+		})
+	}
+}
+
+func TestOpenDriverRetry(t *testing.T) {
+	tests := []struct {
+		name          string
+		failOpenCount int32
+		wantAnomaly   bool
+	}{
+		{
+			name:          "succeedOnFirstAttempt",
+			failOpenCount: 0,
+			wantAnomaly:   false,
+		},
+		{
+			name:          "succeedOnLastAttempt",
+			failOpenCount: 2,
+			wantAnomaly:   false,
+		},
+		{
+			name:          "exhaustAttempts",
+			failOpenCount: 3,
+			wantAnomaly:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, anomalyService := newTestAnomalyScanner()
+			instance, database := newTestInstance()
+			testDriver.failOpenCount = test.failOpenCount
+
+			s.checkDatabaseAnomaly(ctx, instance, database, nil, nil, nil)
+			if got := anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseConnection]; got != test.wantAnomaly {
+				t.Errorf("connection anomaly raised %v, want %v", got, test.wantAnomaly)
+			}
+			wantOpenCount := int32(1)
+			if test.wantAnomaly {
+				wantOpenCount = 0
+			}
+			if open := atomic.LoadInt32(&testDriver.openCount); open != wantOpenCount {
+				t.Errorf("opened %d drivers, want %d", open, wantOpenCount)
+			}
+			if remaining := atomic.LoadInt32(&testDriver.failOpenCount); remaining != 0 {
+				t.Errorf("%d failing opens remaining, want 0", remaining)
+			}
+		})
+	}
+}
+
+func TestOpenDriverRetryBackoff(t *testing.T) {
+	if got := NewAnomalyScanner(zap.NewNop(), nil, AnomalyScannerConfig{}).connectRetryBackoff; got != defaultConnectRetryBackoff {
+		t.Errorf("default connect retry backoff = %v, want %v", got, defaultConnectRetryBackoff)
+	}
+
+	ctx := context.Background()
+	s, _ := newTestAnomalyScanner()
+	s.connectRetryBackoff = 20 * time.Millisecond
+	instance, _ := newTestInstance()
+	// The flaky driver fails twice and then succeeds, after backing off 20ms and then 40ms.
+	testDriver.failOpenCount = 2
+
+	start := time.Now()
+	driver, err := s.openDriver(ctx, instance, "")
+	if err != nil {
+		t.Fatalf("expect the third attempt to connect, got %v", err)
+	}
+	driver.Close(ctx)
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("connected in %v, want backing off at least 60ms", elapsed)
+	}
+}
+
+func TestConnectionRestoredArchiveReason(t *testing.T) {
+	ctx := context.Background()
+	s, anomalyService := newTestAnomalyScanner()
+	instance, _ := newTestInstance()
+	anomalyPolicy := &api.AnomalyPolicy{Enabled: true}
+
+	testDriver.failOpenCount = defaultConnectRetryCount
+	s.checkInstanceAnomaly(ctx, instance, anomalyPolicy)
+	if !anomalyService.activeInstanceTypes(instance.ID)[api.AnomalyInstanceConnection] {
+		t.Fatalf("expect instance connection anomaly to be raised")
+	}
+
+	s.checkInstanceAnomaly(ctx, instance, anomalyPolicy)
+	status := api.Archived
+	anomalyType := api.AnomalyInstanceConnection
+	list, _ := anomalyService.FindAnomalyList(ctx, &api.AnomalyFind{
+		RowStatus:  &status,
+		InstanceID: &instance.ID,
+		Type:       &anomalyType,
+	})
+	if len(list) != 1 {
+		t.Fatalf("found %d archived instance connection anomalies, want 1", len(list))
+	}
+	if got := list[0].ArchiveReason; got != api.AnomalyArchiveReasonConnectionRestored {
+		t.Errorf("ArchiveReason = %q, want %q", got, api.AnomalyArchiveReasonConnectionRestored)
+	}
+}
+
+func TestUpsertConnectionAnomalyPayload(t *testing.T) {
+	ctx := context.Background()
+	s, anomalyService := newTestAnomalyScanner()
+	instance, database := newTestInstance()
+	instance.Host = "10.0.0.1"
+	instance.Port = "3306"
+	connErr := fmt.Errorf("Error 1045: Access denied for user 'root'@'10.0.0.2' (using password: YES)")
+
+	s.upsertConnectionAnomaly(ctx, instance, nil, connErr)
+	s.upsertConnectionAnomaly(ctx, instance, database, fmt.Errorf("dial tcp 10.0.0.1:3306: i/o timeout"))
+	tests := []struct {
+		anomalyType api.AnomalyType
+		databaseID  *int
+		want        api.AnomalyInstanceConnectionPayload
+	}{
+		{api.AnomalyInstanceConnection, nil, api.AnomalyInstanceConnectionPayload{Detail: connErr.Error(), Category: db.ConnectionErrorCategoryAuth, Host: "10.0.0.1", Port: "3306"}},
+		{api.AnomalyDatabaseConnection, &database.ID, api.AnomalyInstanceConnectionPayload{Detail: "dial tcp 10.0.0.1:3306: i/o timeout", Category: db.ConnectionErrorCategoryTimeout, Host: "10.0.0.1", Port: "3306"}},
+	}
+	for _, tt := range tests {
+		anomalyType := tt.anomalyType
+		list, err := anomalyService.FindAnomalyList(ctx, &api.AnomalyFind{
+			InstanceID: &instance.ID,
+			DatabaseID: tt.databaseID,
+			Type:       &anomalyType,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 {
+			t.Fatalf("found %d %s anomalies, want 1", len(list), tt.anomalyType)
+		}
+		// The instance and the database connection payloads share the same fields.
+		var payload api.AnomalyInstanceConnectionPayload
+		if err := json.Unmarshal([]byte(list[0].Payload), &payload); err != nil {
+			t.Fatal(err)
+		}
+		if payload != tt.want {
+			t.Errorf("%s payload = %+v, want %+v", tt.anomalyType, payload, tt.want)
+		}
+	}
+}
+
+func TestScanDatabaseTimeout(t *testing.T) {
+	ctx := context.Background()
+	s, anomalyService := newTestAnomalyScanner()
+	s.server.BackupService = &fakeBackupService{}
+	s.databaseTimeout = 50 * time.Millisecond
+	// Scan one database at a time, so the other database is only scanned if the slow one doesn't wedge the loop.
+	s.concurrency = 1
+	instance, database := newTestInstance()
+	slowDatabase := &api.Database{ID: 2, InstanceID: instance.ID, Name: "slow"}
+	backupPlanPolicyMap := map[int]*api.BackupPlanPolicy{
+		instance.EnvironmentID: {Schedule: api.BackupPlanPolicyScheduleUnset},
+	}
+	scan := func() {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			s.forEachDatabase(ctx, instance, []*api.Database{slowDatabase, database}, func(database *api.Database) {
+				s.scanDatabase(ctx, instance, database, nil, nil, backupPlanPolicyMap, nil)
+			})
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("database scan is wedged by the slow database")
+		}
+	}
+
+	testDriver.blockDumpDatabase = slowDatabase.Name
+	scan()
+	if !anomalyService.activeTypes(slowDatabase.ID)[api.AnomalyDatabaseScanTimeout] {
+		t.Errorf("expect scan timeout anomaly to be raised for the slow database")
+	}
+	if anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseScanTimeout] {
+		t.Errorf("expect no scan timeout anomaly for the other database")
+	}
+	if open, closed := atomic.LoadInt32(&testDriver.openCount), atomic.LoadInt32(&testDriver.closeCount); open != 2 || closed != open {
+		t.Errorf("opened %d drivers and closed %d, want 2 both", open, closed)
+	}
+
+	testDriver.blockDumpDatabase = ""
+	scan()
+	if anomalyService.activeTypes(slowDatabase.ID)[api.AnomalyDatabaseScanTimeout] {
+		t.Errorf("expect scan timeout anomaly to be archived")
+	}
+}
+
+func TestRemoveIgnoredSchemaObject(t *testing.T) {
+	schema := "" +
+		"SET character_set_client  = utf8mb4;\n" +
+		"--\n" +
+		"-- Table structure for `user`\n" +
+		"--\n" +
+		"CREATE TABLE `user` (\n  `id` int\n);\n" +
+		"--\n" +
+		"-- Table structure for `tmp_session_1`\n" +
+		"--\n" +
+		"CREATE TABLE `tmp_session_1` (\n  `id` int\n);\n" +
+		"--\n" +
+		"-- View structure for `v`\n" +
+		"--\n" +
+		"CREATE VIEW `v` AS SELECT 1;\n"
+	tests := []struct {
+		name        string
+		patternList []string
+		want        string
+	}{
+		{"noPattern", nil, schema},
+		{
+			"ignoreTable",
+			[]string{"^tmp_"},
+			"" +
+				"SET character_set_client  = utf8mb4;\n" +
+				"--\n" +
+				"-- Table structure for `user`\n" +
+				"--\n" +
+				"CREATE TABLE `user` (\n  `id` int\n);\n" +
+				"--\n" +
+				"-- View structure for `v`\n" +
+				"--\n" +
+				"CREATE VIEW `v` AS SELECT 1;\n",
+		},
+		{
+			"ignoreLastObject",
+			[]string{"^v$"},
+			"" +
+				"SET character_set_client  = utf8mb4;\n" +
+				"--\n" +
+				"-- Table structure for `user`\n" +
+				"--\n" +
+				"CREATE TABLE `user` (\n  `id` int\n);\n" +
+				"--\n" +
+				"-- Table structure for `tmp_session_1`\n" +
+				"--\n" +
+				"CREATE TABLE `tmp_session_1` (\n  `id` int\n);\n",
+		},
+		{"noMatch", []string{"^nothing$"}, schema},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patternList []*regexp.Regexp
+			for _, pattern := range tt.patternList {
+				patternList = append(patternList, regexp.MustCompile(pattern))
+			}
+			if got := removeIgnoredSchemaObject(schema, patternList); got != tt.want {
+				t.Errorf("removeIgnoredSchemaObject() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckSchemaDriftAnomalyIgnorePattern(t *testing.T) {
+	ctx := context.Background()
+	s, anomalyService := newTestAnomalyScanner()
+	instance, database := newTestInstance()
+	policy := &api.SchemaDriftPolicy{IgnorePatternList: []string{"^tmp_"}}
+
+	// Only the ignored temporary table differs between the recorded and the actual schema.
+	testDriver.historyList = []*db.MigrationHistory{{
+		Version: "1",
+		Schema:  "--\n-- Table structure for `t`\n--\nCREATE TABLE t (id INT);\n",
+	}}
+	testDriver.schema = "--\n-- Table structure for `t`\n--\nCREATE TABLE t (id INT);\n" +
+		"--\n-- Table structure for `tmp_1`\n--\nCREATE TABLE tmp_1 (id INT);\n"
+	s.checkSchemaDriftAnomaly(ctx, instance, database, testDriver, policy)
+	if anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseSchemaDrift] {
+		t.Errorf("expect no schema drift anomaly for the ignored objects")
+	}
+
+	s.checkSchemaDriftAnomaly(ctx, instance, database, testDriver, nil)
+	if !anomalyService.activeTypes(database.ID)[api.AnomalyDatabaseSchemaDrift] {
+		t.Errorf("expect schema drift anomaly without the ignore pattern")
+	}
+}
+
+func TestNormalizeSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect db.Type
+		a       string
+		b       string
+		equal   bool
+	}{
+		{
+			"mysqlAutoIncrement",
+			db.MySQL,
+			"CREATE TABLE `t` (\n  `id` int NOT NULL AUTO_INCREMENT\n) ENGINE=InnoDB AUTO_INCREMENT=12 DEFAULT CHARSET=utf8mb4;\n",
+			"CREATE TABLE `t` (\n  `id` int NOT NULL AUTO_INCREMENT\n) ENGINE=InnoDB AUTO_INCREMENT=108 DEFAULT CHARSET=utf8mb4;\n",
+			true,
+		},
+		{
+			"mysqlWhitespaceAndComment",
+			db.MySQL,
+			"--\n--  Table structure for `t`\n--\nCREATE TABLE `t` (\n  `id` int\n);\n",
+			"--\r\n-- Table structure for `t`\r\n--\r\n\r\nCREATE TABLE `t` (\r\n\t`id`   int\r\n);",
+			true,
+		},
+		{
+			"mysqlColumnChange",
+			db.MySQL,
+			"CREATE TABLE `t` (\n  `id` int\n);\n",
+			"CREATE TABLE `t` (\n  `id` bigint\n);\n",
+			false,
+		},
+		{
+			"mysqlQuotedWhitespace",
+			db.MySQL,
+			"CREATE TABLE `t` (\n  `name` varchar(10) DEFAULT 'a  b'\n);\n",
+			"CREATE TABLE `t` (\n  `name` varchar(10) DEFAULT 'a b'\n);\n",
+			false,
+		},
+		{
+			"postgresWhitespaceAndComment",
+			db.Postgres,
+			"--\n--Table structure for public.t\n--\nCREATE TABLE public.t (\n    id integer\n);\n\n",
+			"--\n-- Table structure for public.t\n--\nCREATE TABLE public.t (\n  id  integer\n);\n",
+			true,
+		},
+		{
+			"postgresKeepsAutoIncrementText",
+			db.Postgres,
+			"COMMENT ON TABLE public.t IS 'x AUTO_INCREMENT=1';\n",
+			"COMMENT ON TABLE public.t IS 'x AUTO_INCREMENT=2';\n",
+			false,
+		},
+		{
+			"oracleIdentifierCase",
+			db.Oracle,
+			"CREATE TABLE \"BOOK\" (\n  \"ID\" NUMBER, \"AUTHOR_ID\" NUMBER\n);\n",
+			"create table book (\n  id number, Author_Id NUMBER\n);\n",
+			true,
+		},
+		{
+			"oracleQuotedMixedCase",
+			db.Oracle,
+			"CREATE TABLE \"Book\" (\n  \"ID\" NUMBER\n);\n",
+			"CREATE TABLE BOOK (\n  ID NUMBER\n);\n",
+			false,
+		},
+		{
+			"oracleStringLiteral",
+			db.Oracle,
+			"CREATE TABLE BOOK (\n  NAME VARCHAR2(10) DEFAULT 'it''s'\n);\n",
+			"CREATE TABLE BOOK (\n  NAME VARCHAR2(10) DEFAULT 'IT''S'\n);\n",
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := normalizeSchema(tt.dialect, tt.a), normalizeSchema(tt.dialect, tt.b)
+			if (a == b) != tt.equal {
+				t.Errorf("normalizeSchema() equal = %t, want %t\na: %q\nb: %q", a == b, tt.equal, a, b)
+			}
+		})
+	}
+}
+
+func TestAnomalyScannerMetrics(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestAnomalyScanner()
+	instance, database := newTestInstance()
+	registry := prometheus.NewRegistry()
+	if err := s.RegisterMetrics(registry); err != nil {
+		t.Fatal(err)
+	}
+
+	upsert := &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		DatabaseID: &database.ID,
+		Type:       api.AnomalyDatabaseSchemaDrift,
+	}
+	// Re-observing the active anomaly doesn't count as created.
+	for i := 0; i < 2; i++ {
+		if err := s.upsertAnomaly(ctx, upsert); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.archiveAnomaly(ctx, &api.AnomalyArchive{DatabaseID: &database.ID, Type: api.AnomalyDatabaseSchemaDrift}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		action string
+		want   float64
+	}{
+		{anomalyMetricActionCreated, 1},
+		{anomalyMetricActionArchived, 1},
+	} {
+		if got := testutil.ToFloat64(s.metrics.anomalyCount.WithLabelValues(string(api.AnomalyDatabaseSchemaDrift), tt.action)); got != tt.want {
+			t.Errorf("anomalies_total{action=%q} = %v, want %v", tt.action, got, tt.want)
+		}
+	}
+
+	if err := s.startTask(instance.ID); err != nil {
+		t.Fatal(err)
+	}
+	s.recordFailedInstance(ctx, instance)
+	want := `
+# HELP bytebase_anomaly_scanner_running_scans The number of instance scans running, including the manual ones.
+# TYPE bytebase_anomaly_scanner_running_scans gauge
+bytebase_anomaly_scanner_running_scans 1
+# HELP bytebase_anomaly_scanner_scan_errors_total The number of the instance and database scans failing to complete.
+# TYPE bytebase_anomaly_scanner_scan_errors_total counter
+bytebase_anomaly_scanner_scan_errors_total 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(want), "bytebase_anomaly_scanner_running_scans", "bytebase_anomaly_scanner_scan_errors_total"); err != nil {
+		t.Error(err)
+	}
+	s.finishTask(instance.ID)
+}
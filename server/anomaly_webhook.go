@@ -0,0 +1,158 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/bytebase/bytebase/api"
+	"go.uber.org/zap"
+)
+
+// anomalyWebhookEvent is the event of the anomaly webhook.
+type anomalyWebhookEvent string
+
+const (
+	// anomalyWebhookEventCreated is the event for a newly created anomaly.
+	anomalyWebhookEventCreated anomalyWebhookEvent = "CREATED"
+	// anomalyWebhookEventResolved is the event for an archived anomaly.
+	anomalyWebhookEventResolved anomalyWebhookEvent = "RESOLVED"
+
+	// anomalyWebhookMaxAttempt is the max number of attempts to POST the anomaly webhook.
+	anomalyWebhookMaxAttempt = 4
+	// anomalyWebhookBackoff is the backoff before the first retry, it doubles for each following retry.
+	anomalyWebhookBackoff = time.Duration(1) * time.Second
+	anomalyWebhookTimeout = time.Duration(3) * time.Second
+)
+
+// anomalyWebhookPayload is the JSON payload POSTed to the anomaly webhook.
+type anomalyWebhookPayload struct {
+	Event    anomalyWebhookEvent `json:"event"`
+	Instance string              `json:"instance"`
+	// Database is empty for instance anomaly
+	Database string              `json:"database,omitempty"`
+	Type     api.AnomalyType     `json:"type"`
+	Severity api.AnomalySeverity `json:"severity"`
+	// Detail is the type specific payload of the anomaly
+	Detail json.RawMessage `json:"detail,omitempty"`
+}
+
+// anomalyWebhookNotifier is the AnomalyNotifier POSTing the anomaly events to the webhook.
+type anomalyWebhookNotifier struct {
+	l      *zap.Logger
+	server *Server
+	url    string
+}
+
+func newAnomalyWebhookNotifier(logger *zap.Logger, server *Server, url string) *anomalyWebhookNotifier {
+	return &anomalyWebhookNotifier{
+		l:      logger,
+		server: server,
+		url:    url,
+	}
+}
+
+// OnAnomalyOpen POSTs the created event in the background.
+func (n *anomalyWebhookNotifier) OnAnomalyOpen(ctx context.Context, anomaly *api.Anomaly) error {
+	n.notify(anomalyWebhookEventCreated, anomaly)
+	return nil
+}
+
+// OnAnomalyClose POSTs the resolved event in the background.
+func (n *anomalyWebhookNotifier) OnAnomalyClose(ctx context.Context, anomaly *api.Anomaly) error {
+	n.notify(anomalyWebhookEventResolved, anomaly)
+	return nil
+}
+
+// notify POSTs the anomaly event to the webhook in the background, so that the slow webhook doesn't hold up the scan.
+func (n *anomalyWebhookNotifier) notify(event anomalyWebhookEvent, anomaly *api.Anomaly) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err, ok := r.(error)
+				if !ok {
+					err = fmt.Errorf("%v", r)
+				}
+				n.l.Error("Anomaly webhook PANIC RECOVER", zap.Error(err))
+			}
+		}()
+
+		ctx := context.Background()
+		payload, err := n.composeAnomalyWebhookPayload(ctx, event, anomaly)
+		if err != nil {
+			n.l.Error("Failed to compose anomaly webhook payload",
+				zap.Int("anomaly", anomaly.ID),
+				zap.String("type", string(anomaly.Type)),
+				zap.Error(err))
+			return
+		}
+		client := &http.Client{
+			Timeout: anomalyWebhookTimeout,
+		}
+		if err := postAnomalyWebhook(client, n.url, payload, anomalyWebhookBackoff); err != nil {
+			n.l.Warn("Failed to POST anomaly webhook",
+				zap.Int("anomaly", anomaly.ID),
+				zap.String("type", string(anomaly.Type)),
+				zap.Error(err))
+		}
+	}()
+}
+
+func (n *anomalyWebhookNotifier) composeAnomalyWebhookPayload(ctx context.Context, event anomalyWebhookEvent, anomaly *api.Anomaly) ([]byte, error) {
+	instance, err := n.server.InstanceService.FindInstance(ctx, &api.InstanceFind{
+		ID: &anomaly.InstanceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find instance %d: %w", anomaly.InstanceID, err)
+	}
+	webhookPayload := anomalyWebhookPayload{
+		Event:    event,
+		Instance: instance.Name,
+		Type:     anomaly.Type,
+		Severity: api.AnomalySeverityFromType(anomaly.Type),
+	}
+	if anomaly.DatabaseID != nil {
+		database, err := n.server.DatabaseService.FindDatabase(ctx, &api.DatabaseFind{
+			ID: anomaly.DatabaseID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to find database %d: %w", *anomaly.DatabaseID, err)
+		}
+		webhookPayload.Database = database.Name
+	}
+	if anomaly.Payload != "" {
+		webhookPayload.Detail = json.RawMessage(anomaly.Payload)
+	}
+	return json.Marshal(webhookPayload)
+}
+
+// postAnomalyWebhook POSTs the payload to the webhookURL, and retries with exponential backoff on failures and non-2xx responses.
+func postAnomalyWebhook(client *http.Client, webhookURL string, payload []byte, backoff time.Duration) error {
+	var err error
+	for attempt := 1; attempt <= anomalyWebhookMaxAttempt; attempt++ {
+		if attempt > 1 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		var resp *http.Response
+		resp, err = client.Post(webhookURL, "application/json", bytes.NewReader(payload))
+		if err != nil {
+			// The webhook URL carries the secret for Slack and PagerDuty, so it's left out of the error.
+			if urlErr, ok := err.(*url.Error); ok {
+				err = urlErr.Err
+			}
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		err = fmt.Errorf("received HTTP status %d", resp.StatusCode)
+	}
+	return fmt.Errorf("failed after %d attempts: %w", anomalyWebhookMaxAttempt, err)
+}
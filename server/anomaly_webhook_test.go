@@ -0,0 +1,67 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPostAnomalyWebhook(t *testing.T) {
+	tests := []struct {
+		name         string
+		statusList   []int
+		wantErr      bool
+		wantAttempts int
+	}{
+		{"success", []int{http.StatusOK}, false, 1},
+		{"retrySucceeds", []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusNoContent}, false, 3},
+		{"retryExhausted", []int{http.StatusInternalServerError}, true, anomalyWebhookMaxAttempt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			attempts := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if body, _ := io.ReadAll(r.Body); string(body) != `{"event":"CREATED"}` {
+					t.Errorf("unexpected body %q", body)
+				}
+				status := tt.statusList[len(tt.statusList)-1]
+				if attempts < len(tt.statusList) {
+					status = tt.statusList[attempts]
+				}
+				attempts++
+				w.WriteHeader(status)
+			}))
+			defer ts.Close()
+
+			err := postAnomalyWebhook(ts.Client(), ts.URL, []byte(`{"event":"CREATED"}`), time.Millisecond)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("postAnomalyWebhook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestPostAnomalyWebhookErrorHidesURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	webhookURL := ts.URL + "/services/T000/B000/secret"
+	// The closed server refuses the connection.
+	ts.Close()
+
+	err := postAnomalyWebhook(ts.Client(), webhookURL, []byte(`{"event":"CREATED"}`), time.Millisecond)
+	if err == nil {
+		t.Fatal("expect the POST to the closed server to fail")
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("postAnomalyWebhook() error = %q, want no webhook URL", err)
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/bytebase/bytebase/api"
+	"github.com/bytebase/bytebase/common"
+	"github.com/klauspost/compress/zstd"
+)
+
+// defaultBackupCompression is the compression of the new backups if not specified.
+const defaultBackupCompression = api.BackupCompressionGzip
+
+// validateBackupCompression returns an Invalid error if the compression is not a supported codec.
+func validateBackupCompression(compression api.BackupCompression) error {
+	switch compression {
+	case api.BackupCompressionNone, api.BackupCompressionGzip, api.BackupCompressionZstd:
+		return nil
+	}
+	return common.Errorf(common.Invalid, fmt.Errorf("invalid backup compression %q", compression))
+}
+
+// newBackupCompressWriter returns a writer compressing the backup with the codec into w.
+// The writer must be closed to flush the compressed data, closing it doesn't close w.
+func newBackupCompressWriter(w io.Writer, compression api.BackupCompression) (io.WriteCloser, error) {
+	switch compression {
+	case api.BackupCompressionNone:
+		return nopWriteCloser{w}, nil
+	case api.BackupCompressionGzip:
+		return gzip.NewWriter(w), nil
+	case api.BackupCompressionZstd:
+		return zstd.NewWriter(w)
+	}
+	return nil, validateBackupCompression(compression)
+}
+
+// newBackupDecompressReader returns a reader decompressing the backup compressed with the codec from r.
+// The backups taken before compression support are NONE, and are read as is.
+func newBackupDecompressReader(r io.Reader, compression api.BackupCompression) (io.ReadCloser, error) {
+	switch compression {
+	case api.BackupCompressionNone:
+		return io.NopCloser(r), nil
+	case api.BackupCompressionGzip:
+		return gzip.NewReader(r)
+	case api.BackupCompressionZstd:
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	}
+	return nil, validateBackupCompression(compression)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bytebase/bytebase/api"
+	"github.com/bytebase/bytebase/common"
+)
+
+func TestBackupCompressionRoundTrip(t *testing.T) {
+	dump := strings.Repeat("INSERT INTO t VALUES\n  (1, 'a'),\n  (2, 'b');\n", 100)
+	for _, compression := range []api.BackupCompression{api.BackupCompressionNone, api.BackupCompressionGzip, api.BackupCompressionZstd} {
+		t.Run(compression.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := newBackupCompressWriter(&buf, compression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := io.WriteString(w, dump); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if compression != api.BackupCompressionNone && buf.Len() >= len(dump) {
+				t.Errorf("compressed size = %d, want less than %d", buf.Len(), len(dump))
+			}
+
+			r, err := newBackupDecompressReader(&buf, compression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != dump {
+				t.Errorf("decompressed dump = %q, want %q", got, dump)
+			}
+		})
+	}
+}
+
+func TestBackupCompressionInvalid(t *testing.T) {
+	if err := validateBackupCompression("LZ4"); common.ErrorCode(err) != common.Invalid {
+		t.Errorf("validateBackupCompression() = %v, want Invalid error", err)
+	}
+	if _, err := newBackupCompressWriter(&bytes.Buffer{}, "LZ4"); common.ErrorCode(err) != common.Invalid {
+		t.Errorf("newBackupCompressWriter() = %v, want Invalid error", err)
+	}
+	if _, err := newBackupDecompressReader(&bytes.Buffer{}, "LZ4"); common.ErrorCode(err) != common.Invalid {
+		t.Errorf("newBackupDecompressReader() = %v, want Invalid error", err)
+	}
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bytebase/bytebase/api"
+	"github.com/bytebase/bytebase/common"
+	"go.uber.org/zap"
+)
+
+const (
+	backupPruneInterval = time.Duration(1) * time.Hour
+)
+
+// NewBackupPruner creates a new backup pruner.
+func NewBackupPruner(logger *zap.Logger, server *Server) *BackupPruner {
+	return &BackupPruner{
+		l:      logger,
+		server: server,
+	}
+}
+
+// BackupPruner is the backup pruner deleting the backups past the retention of the backup plan policy.
+type BackupPruner struct {
+	l      *zap.Logger
+	server *Server
+}
+
+// Run is the runner for backup pruner.
+func (s *BackupPruner) Run() error {
+	go func() {
+		s.l.Debug(fmt.Sprintf("Backup pruner started and will run every %v", backupPruneInterval))
+		for {
+			s.l.Debug("New backup pruner round started...")
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						err, ok := r.(error)
+						if !ok {
+							err = fmt.Errorf("%v", r)
+						}
+						s.l.Error("Backup pruner PANIC RECOVER", zap.Error(err))
+					}
+				}()
+
+				s.prune(context.Background())
+			}()
+
+			time.Sleep(backupPruneInterval)
+		}
+	}()
+
+	return nil
+}
+
+// prune deletes the backups past the retention for the databases in the environments whose backup plan policy sets one.
+func (s *BackupPruner) prune(ctx context.Context) {
+	environmentList, err := s.server.EnvironmentService.FindEnvironmentList(ctx, &api.EnvironmentFind{})
+	if err != nil {
+		s.l.Error("Failed to retrieve environment list", zap.Error(err))
+		return
+	}
+
+	retentionDaysMap := make(map[int]int)
+	// anomalyDisabledMap is the set of environments whose anomaly policy disables the prune failure anomaly.
+	anomalyDisabledMap := make(map[int]bool)
+	for _, env := range environmentList {
+		policy, err := s.server.PolicyService.GetBackupPlanPolicy(ctx, env.ID)
+		if err != nil {
+			s.l.Error("Failed to retrieve backup policy",
+				zap.String("environment", env.Name),
+				zap.Error(err))
+			return
+		}
+		if policy.RetentionDays > 0 {
+			retentionDaysMap[env.ID] = policy.RetentionDays
+		}
+
+		anomalyPolicy, err := s.server.PolicyService.GetAnomalyPolicy(ctx, env.ID)
+		if err != nil {
+			s.l.Error("Failed to retrieve anomaly policy",
+				zap.String("environment", env.Name),
+				zap.Error(err))
+			return
+		}
+		for _, anomalyType := range anomalyPolicy.DisabledTypeList {
+			if anomalyType == api.AnomalyDatabaseBackupPruneFailed {
+				anomalyDisabledMap[env.ID] = true
+			}
+		}
+	}
+	if len(retentionDaysMap) == 0 {
+		return
+	}
+
+	rowStatus := api.Normal
+	instanceList, err := s.server.InstanceService.FindInstanceList(ctx, &api.InstanceFind{
+		RowStatus: &rowStatus,
+	})
+	if err != nil {
+		s.l.Error("Failed to retrieve instance list", zap.Error(err))
+		return
+	}
+
+	for _, instance := range instanceList {
+		retentionDays, ok := retentionDaysMap[instance.EnvironmentID]
+		if !ok {
+			continue
+		}
+
+		databaseFind := &api.DatabaseFind{
+			InstanceID: &instance.ID,
+		}
+		dbList, err := s.server.DatabaseService.FindDatabaseList(ctx, databaseFind)
+		if err != nil {
+			s.l.Error("Failed to retrieve database list",
+				zap.String("instance", instance.Name),
+				zap.Error(err))
+			continue
+		}
+		for _, database := range dbList {
+			s.pruneDatabase(ctx, instance, database, retentionDays, !anomalyDisabledMap[instance.EnvironmentID])
+		}
+	}
+}
+
+// pruneDatabase deletes the backups of the database past the retention.
+// Raises the prune failure anomaly if any backup fails to be deleted and raiseAnomaly is set, and archives it once pruning succeeds.
+func (s *BackupPruner) pruneDatabase(ctx context.Context, instance *api.Instance, database *api.Database, retentionDays int, raiseAnomaly bool) {
+	backupList, err := s.server.BackupService.FindBackupList(ctx, &api.BackupFind{
+		DatabaseID: &database.ID,
+	})
+	if err != nil {
+		s.l.Error("Failed to retrieve backup list",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.Error(err))
+		return
+	}
+
+	for _, backup := range getPrunableBackupList(backupList, retentionDays, time.Now()) {
+		if err := s.deleteBackup(ctx, backup); err != nil {
+			s.l.Error("Failed to prune backup",
+				zap.String("instance", instance.Name),
+				zap.String("database", database.Name),
+				zap.String("backup", backup.Name),
+				zap.Error(err))
+			if raiseAnomaly {
+				s.upsertPruneFailedAnomaly(ctx, instance, database, backup, err)
+			}
+			return
+		}
+		s.l.Debug("Pruned backup",
+			zap.String("database", database.Name),
+			zap.String("backup", backup.Name))
+	}
+
+	err = s.server.AnomalyService.ArchiveAnomaly(ctx, &api.AnomalyArchive{
+		DatabaseID: &database.ID,
+		Type:       api.AnomalyDatabaseBackupPruneFailed,
+		Reason:     api.AnomalyArchiveReasonBackupPruned,
+	})
+	if err != nil && common.ErrorCode(err) != common.NotFound {
+		s.l.Error("Failed to close anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseBackupPruneFailed)),
+			zap.Error(err))
+	}
+}
+
+// deleteBackup deletes the backup file and then the backup record.
+func (s *BackupPruner) deleteBackup(ctx context.Context, backup *api.Backup) error {
+	if backup.Path != "" {
+		storage, err := getBackupStorage(ctx, s.server.dataDir, backup.StorageBackend, backup.Path, nil)
+		if err != nil {
+			return err
+		}
+		if err := storage.Delete(ctx, backup.Path); err != nil {
+			return fmt.Errorf("failed to delete backup file %q: %w", backup.Path, err)
+		}
+	}
+	return s.server.BackupService.DeleteBackup(ctx, &api.BackupDelete{
+		ID:        backup.ID,
+		DeleterID: api.SystemBotID,
+	})
+}
+
+func (s *BackupPruner) upsertPruneFailedAnomaly(ctx context.Context, instance *api.Instance, database *api.Database, backup *api.Backup, pruneErr error) {
+	anomalyPayload := api.AnomalyDatabaseBackupPruneFailedPayload{
+		BackupID:   backup.ID,
+		BackupName: backup.Name,
+		Detail:     pruneErr.Error(),
+	}
+	payload, err := json.Marshal(anomalyPayload)
+	if err != nil {
+		s.l.Error("Failed to marshal anomaly payload",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseBackupPruneFailed)),
+			zap.Error(err))
+		return
+	}
+	_, _, err = s.server.AnomalyService.UpsertActiveAnomaly(ctx, &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: instance.ID,
+		DatabaseID: &database.ID,
+		Type:       api.AnomalyDatabaseBackupPruneFailed,
+		Payload:    string(payload),
+	})
+	if err != nil {
+		s.l.Error("Failed to create anomaly",
+			zap.String("instance", instance.Name),
+			zap.String("database", database.Name),
+			zap.String("type", string(api.AnomalyDatabaseBackupPruneFailed)),
+			zap.Error(err))
+	}
+}
+
+// getPrunableBackupList returns the backups created before the retention window.
+// The most recent successful backup is always kept even if it's past the retention window,
+// and the backups still being taken are never pruned.
+func getPrunableBackupList(backupList []*api.Backup, retentionDays int, now time.Time) []*api.Backup {
+	if retentionDays <= 0 {
+		return nil
+	}
+
+	var latestDone *api.Backup
+	for _, backup := range backupList {
+		if backup.Status == api.BackupStatusDone && (latestDone == nil || backup.CreatedTs > latestDone.CreatedTs) {
+			latestDone = backup
+		}
+	}
+
+	cutoffTs := now.Add(-time.Duration(retentionDays*24) * time.Hour).Unix()
+	var list []*api.Backup
+	for _, backup := range backupList {
+		if backup == latestDone || backup.Status == api.BackupStatusPendingCreate {
+			continue
+		}
+		if backup.CreatedTs < cutoffTs {
+			list = append(list, backup)
+		}
+	}
+	return list
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bytebase/bytebase/api"
+)
+
+func TestGetPrunableBackupList(t *testing.T) {
+	now := time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) int64 {
+		return now.Add(-time.Duration(days*24) * time.Hour).Unix()
+	}
+	oldDone := &api.Backup{ID: 1, Status: api.BackupStatusDone, CreatedTs: daysAgo(40)}
+	oldFailed := &api.Backup{ID: 2, Status: api.BackupStatusFailed, CreatedTs: daysAgo(35)}
+	oldPending := &api.Backup{ID: 3, Status: api.BackupStatusPendingCreate, CreatedTs: daysAgo(33)}
+	latestOldDone := &api.Backup{ID: 4, Status: api.BackupStatusDone, CreatedTs: daysAgo(31)}
+	recentFailed := &api.Backup{ID: 5, Status: api.BackupStatusFailed, CreatedTs: daysAgo(1)}
+	recentDone := &api.Backup{ID: 6, Status: api.BackupStatusDone, CreatedTs: daysAgo(2)}
+
+	tests := []struct {
+		name          string
+		backupList    []*api.Backup
+		retentionDays int
+		want          []int
+	}{
+		{"keepForever", []*api.Backup{recentDone, oldDone}, 0, nil},
+		{"pastRetention", []*api.Backup{recentFailed, recentDone, latestOldDone, oldFailed, oldDone}, 30, []int{4, 2, 1}},
+		{"keepLatestDone", []*api.Backup{recentFailed, latestOldDone, oldFailed, oldDone}, 30, []int{2, 1}},
+		{"keepPending", []*api.Backup{oldPending, oldDone}, 30, nil},
+		{"withinRetention", []*api.Backup{recentDone, latestOldDone, oldDone}, 60, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, backup := range getPrunableBackupList(tt.backupList, tt.retentionDays, now) {
+				got = append(got, backup.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getPrunableBackupList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -49,11 +49,14 @@ func (s *BackupRunner) Run() error {
 					}
 				}()
 
-				// Find all databases that need a backup in this hour.
-				t := time.Now().UTC().Truncate(time.Hour)
+				// Find all databases that need a backup in this hour and whose backup minute has been reached.
+				now := time.Now().UTC()
+				t := now.Truncate(time.Hour)
 				match := &api.BackupSettingsMatch{
-					Hour:      t.Hour(),
-					DayOfWeek: int(t.Weekday()),
+					Hour:       t.Hour(),
+					Minute:     now.Minute(),
+					DayOfWeek:  int(t.Weekday()),
+					DayOfMonth: t.Day(),
 				}
 				list, err := s.server.BackupService.FindBackupSettingsMatch(ctx, match)
 				if err != nil {
@@ -83,7 +86,9 @@ func (s *BackupRunner) Run() error {
 					}
 					backupSetting.Database = database
 
-					backupName := fmt.Sprintf("%s-%s-%s-autobackup", api.ProjectShortSlug(database.Project), api.EnvSlug(database.Instance.Environment), t.Format("20060102T030405"))
+					// Name the backup after its scheduled time, so that the later rounds in the same hour won't take it again.
+					scheduledTime := t.Add(time.Duration(backupSetting.Minute) * time.Minute)
+					backupName := fmt.Sprintf("%s-%s-%s-autobackup", api.ProjectShortSlug(database.Project), api.EnvSlug(database.Instance.Environment), scheduledTime.Format("20060102T030405"))
 					go func(database *api.Database, backupSettingID int, backupName string, hookURL string) {
 						s.l.Debug("Schedule auto backup",
 							zap.String("database", database.Name),
@@ -124,7 +129,11 @@ func (s *BackupRunner) Run() error {
 }
 
 func (s *BackupRunner) scheduleBackupTask(ctx context.Context, database *api.Database, backupName string) error {
-	path, err := getAndCreateBackupPath(s.server.dataDir, database, backupName)
+	backupPlanPolicy, err := s.server.PolicyService.GetBackupPlanPolicy(ctx, database.Instance.EnvironmentID)
+	if err != nil {
+		return fmt.Errorf("failed to get backup plan policy for database %q: %w", database.Name, err)
+	}
+	path, err := getBackupPathForStorage(s.server.dataDir, database, backupName, defaultBackupCompression, backupPlanPolicy)
 	if err != nil {
 		return err
 	}
@@ -142,7 +151,8 @@ func (s *BackupRunner) scheduleBackupTask(ctx context.Context, database *api.Dat
 		Status:                  api.BackupStatusPendingCreate,
 		Type:                    api.BackupTypeAutomatic,
 		MigrationHistoryVersion: migrationHistoryVersion,
-		StorageBackend:          api.BackupStorageBackendLocal,
+		StorageBackend:          getBackupPlanStorageBackend(backupPlanPolicy),
+		Compression:             defaultBackupCompression,
 		Path:                    path,
 	}
 	backup, err := s.server.BackupService.CreateBackup(ctx, backupCreate)
@@ -0,0 +1,275 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/bytebase/bytebase/api"
+	"github.com/bytebase/bytebase/common"
+)
+
+// backupStorage is the storage backend persisting the backup files.
+type backupStorage interface {
+	// Write persists the backup file at the path with the content written by write.
+	// The file is not persisted if write returns an error.
+	Write(ctx context.Context, path string, write func(w io.Writer) error) error
+	// Open opens the backup file at the path for reading.
+	Open(ctx context.Context, path string) (io.ReadCloser, error)
+	// Delete deletes the backup file at the path, deleting a missing file is not an error.
+	Delete(ctx context.Context, path string) error
+}
+
+// getBackupStorage returns the storage of the backup at the path with the storage backend.
+// The S3 bucket region is taken from s3Config if it's the bucket of the path, otherwise it's looked up,
+// s3Config can be nil.
+func getBackupStorage(ctx context.Context, dataDir string, backend api.BackupStorageBackend, backupPath string, s3Config *api.BackupS3Config) (backupStorage, error) {
+	switch backend {
+	case api.BackupStorageBackendLocal:
+		return &localBackupStorage{dataDir: dataDir}, nil
+	case api.BackupStorageBackendS3:
+		bucket, _, err := parseS3BackupPath(backupPath)
+		if err != nil {
+			return nil, err
+		}
+		if s3Config != nil && s3Config.Bucket == bucket && s3Config.Region != "" {
+			return newS3BackupStorage(ctx, bucket, s3Config.Region)
+		}
+		return newS3BackupStorageForBucket(ctx, bucket)
+	}
+	return nil, common.Errorf(common.Invalid, fmt.Errorf("invalid backup storage backend %q", backend))
+}
+
+// getBackupPlanStorageBackend returns the storage backend of the new backups under the backup plan policy.
+func getBackupPlanStorageBackend(policy *api.BackupPlanPolicy) api.BackupStorageBackend {
+	if policy.StorageBackend == "" {
+		return api.BackupStorageBackendLocal
+	}
+	return policy.StorageBackend
+}
+
+// getBackupPathForStorage returns the path of a new backup with the storage backend of the backup plan policy.
+func getBackupPathForStorage(dataDir string, database *api.Database, name string, compression api.BackupCompression, policy *api.BackupPlanPolicy) (string, error) {
+	if getBackupPlanStorageBackend(policy) == api.BackupStorageBackendS3 {
+		return getS3BackupPath(policy.S3, database, name, compression), nil
+	}
+	return getAndCreateBackupPath(dataDir, database, name, compression)
+}
+
+// localBackupStorage stores the backup files under the data directory, the paths are relative to it.
+type localBackupStorage struct {
+	dataDir string
+}
+
+func (s *localBackupStorage) abs(backupPath string) string {
+	if filepath.IsAbs(backupPath) {
+		return backupPath
+	}
+	return filepath.Join(s.dataDir, backupPath)
+}
+
+func (s *localBackupStorage) Write(ctx context.Context, backupPath string, write func(w io.Writer) error) error {
+	absPath := s.abs(backupPath)
+	f, err := os.Create(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to open backup path: %s", backupPath)
+	}
+	defer f.Close()
+
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(absPath)
+		return err
+	}
+	return f.Close()
+}
+
+func (s *localBackupStorage) Open(ctx context.Context, backupPath string) (io.ReadCloser, error) {
+	f, err := os.OpenFile(s.abs(backupPath), os.O_RDONLY, os.ModePerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup file at %s: %w", s.abs(backupPath), err)
+	}
+	return f, nil
+}
+
+func (s *localBackupStorage) Delete(ctx context.Context, backupPath string) error {
+	if err := os.Remove(s.abs(backupPath)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// s3BackupStorage stores the backup files in an AWS S3 bucket, the paths are the object URLs s3://<bucket>/<key>.
+type s3BackupStorage struct {
+	bucket string
+	client *s3.Client
+}
+
+// newS3BackupStorage creates the S3 storage of the bucket in the region, the region of the AWS configuration
+// is used if empty. The credentials are loaded by the default credential chain of the AWS SDK.
+func newS3BackupStorage(ctx context.Context, bucket string, region string) (*s3BackupStorage, error) {
+	var optFns []func(*config.LoadOptions) error
+	if region != "" {
+		optFns = append(optFns, config.WithRegion(region))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, optFns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	return &s3BackupStorage{
+		bucket: bucket,
+		client: s3.NewFromConfig(cfg),
+	}, nil
+}
+
+// newS3BackupStorageForBucket creates the S3 storage of the existing bucket, whose region is looked up,
+// so the backups stay readable after the backup plan policy changes.
+func newS3BackupStorageForBucket(ctx context.Context, bucket string) (*s3BackupStorage, error) {
+	storage, err := newS3BackupStorage(ctx, bucket, "")
+	if err != nil {
+		return nil, err
+	}
+	region, err := manager.GetBucketRegion(ctx, storage.client, bucket, func(o *s3.Options) {
+		// Any region can look up the bucket region.
+		if o.Region == "" {
+			o.Region = "us-east-1"
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the region of S3 bucket %q: %w", bucket, err)
+	}
+	return newS3BackupStorage(ctx, bucket, region)
+}
+
+func (s *s3BackupStorage) key(backupPath string) (string, error) {
+	bucket, key, err := parseS3BackupPath(backupPath)
+	if err != nil {
+		return "", err
+	}
+	if bucket != s.bucket {
+		return "", fmt.Errorf("backup path %q is not in S3 bucket %q", backupPath, s.bucket)
+	}
+	return key, nil
+}
+
+func (s *s3BackupStorage) Write(ctx context.Context, backupPath string, write func(w io.Writer) error) error {
+	key, err := s.key(backupPath)
+	if err != nil {
+		return err
+	}
+
+	// The backup is streamed to the multipart upload instead of being buffered, a write error aborts the upload.
+	pr, pw := io.Pipe()
+	uploadErr := make(chan error, 1)
+	go func() {
+		_, err := manager.NewUploader(s.client).Upload(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+			Body:   pr,
+		})
+		// Unblock the writer if the upload fails before consuming the whole backup.
+		pr.CloseWithError(err)
+		uploadErr <- err
+	}()
+
+	if err := write(pw); err != nil {
+		pw.CloseWithError(err)
+		<-uploadErr
+		return err
+	}
+	pw.Close()
+	if err := <-uploadErr; err != nil {
+		return fmt.Errorf("failed to upload backup to %s: %w", backupPath, err)
+	}
+	return nil
+}
+
+func (s *s3BackupStorage) Open(ctx context.Context, backupPath string) (io.ReadCloser, error) {
+	key, err := s.key(backupPath)
+	if err != nil {
+		return nil, err
+	}
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download backup from %s: %w", backupPath, err)
+	}
+	return output.Body, nil
+}
+
+func (s *s3BackupStorage) Delete(ctx context.Context, backupPath string) error {
+	key, err := s.key(backupPath)
+	if err != nil {
+		return err
+	}
+	// S3 doesn't report deleting a missing object as an error.
+	if _, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}); err != nil {
+		return fmt.Errorf("failed to delete backup %s: %w", backupPath, err)
+	}
+	return nil
+}
+
+// computeBackupChecksum returns the hex SHA-256 of the stored backup file at the path.
+// The read is aborted once ctx is done, so hashing a large backup is bounded by the caller's deadline.
+func computeBackupChecksum(ctx context.Context, storage backupStorage, backupPath string) (string, error) {
+	r, err := storage.Open(ctx, backupPath)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, &contextReader{ctx: ctx, r: r}); err != nil {
+		return "", fmt.Errorf("failed to read backup %s: %w", backupPath, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// contextReader is the reader which fails once ctx is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// getS3BackupPath returns the object URL of a backup, whose key is the local backup path under the prefix.
+func getS3BackupPath(s3Config *api.BackupS3Config, database *api.Database, name string, compression api.BackupCompression) string {
+	key := path.Join("backup", "db", fmt.Sprintf("%d", database.ID), fmt.Sprintf("%s.sql%s", name, compression.FileExtension()))
+	u := url.URL{
+		Scheme: "s3",
+		Host:   s3Config.Bucket,
+		Path:   "/" + s3Config.Prefix + key,
+	}
+	return u.String()
+}
+
+// parseS3BackupPath returns the bucket and the object key of the S3 backup path s3://<bucket>/<key>.
+func parseS3BackupPath(backupPath string) (string, string, error) {
+	u, err := url.Parse(backupPath)
+	if err != nil || u.Scheme != "s3" || u.Host == "" || strings.TrimPrefix(u.Path, "/") == "" {
+		return "", "", common.Errorf(common.Invalid, fmt.Errorf("invalid S3 backup path %q, must be s3://<bucket>/<key>", backupPath))
+	}
+	return u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
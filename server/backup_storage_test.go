@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bytebase/bytebase/api"
+	"github.com/bytebase/bytebase/common"
+)
+
+func TestLocalBackupStorage(t *testing.T) {
+	ctx := context.Background()
+	dataDir := t.TempDir()
+	storage, err := getBackupStorage(ctx, dataDir, api.BackupStorageBackendLocal, "backup.sql", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "CREATE TABLE t (id INT);\n"
+	if err := storage.Write(ctx, "backup.sql", func(w io.Writer) error {
+		_, err := io.WriteString(w, want)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := storage.Open(ctx, "backup.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("backup = %q, want %q", got, want)
+	}
+
+	// The partial backup of a failed write is not kept.
+	if err := storage.Write(ctx, "failed.sql", func(w io.Writer) error {
+		io.WriteString(w, "CREATE")
+		return fmt.Errorf("dump failed")
+	}); err == nil {
+		t.Errorf("expect the write error")
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "failed.sql")); !os.IsNotExist(err) {
+		t.Errorf("expect the failed backup to be removed, got %v", err)
+	}
+
+	if err := storage.Delete(ctx, "backup.sql"); err != nil {
+		t.Fatal(err)
+	}
+	// Deleting a missing backup is not an error.
+	if err := storage.Delete(ctx, "backup.sql"); err != nil {
+		t.Errorf("Delete() of a missing backup = %v, want nil", err)
+	}
+}
+
+func TestGetBackupPathForStorage(t *testing.T) {
+	dataDir := t.TempDir()
+	database := &api.Database{ID: 7001}
+
+	// The backups are local by default.
+	path, err := getBackupPathForStorage(dataDir, database, "db-20220301", api.BackupCompressionGzip, &api.BackupPlanPolicy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join("backup", "db", "7001", "db-20220301.sql.gz"); path != want {
+		t.Errorf("local path = %q, want %q", path, want)
+	}
+
+	policy := &api.BackupPlanPolicy{
+		StorageBackend: api.BackupStorageBackendS3,
+		S3:             &api.BackupS3Config{Bucket: "backup-bucket", Prefix: "bytebase/"},
+	}
+	path, err = getBackupPathForStorage(dataDir, database, "db-20220301", api.BackupCompressionZstd, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "s3://backup-bucket/bytebase/backup/db/7001/db-20220301.sql.zst"; path != want {
+		t.Errorf("S3 path = %q, want %q", path, want)
+	}
+	bucket, key, err := parseS3BackupPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bucket != "backup-bucket" || key != "bytebase/backup/db/7001/db-20220301.sql.zst" {
+		t.Errorf("parseS3BackupPath() = %q, %q", bucket, key)
+	}
+}
+
+func TestParseS3BackupPathInvalid(t *testing.T) {
+	for _, path := range []string{
+		"backup/db/7001/db.sql",
+		"s3://bucket",
+		"s3:///key",
+		"https://bucket.s3.amazonaws.com/key",
+	} {
+		_, _, err := parseS3BackupPath(path)
+		if common.ErrorCode(err) != common.Invalid || !strings.Contains(err.Error(), path) {
+			t.Errorf("parseS3BackupPath(%q) = %v, want Invalid error", path, err)
+		}
+	}
+}
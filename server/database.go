@@ -62,6 +62,10 @@ func (s *Server) registerDatabaseRoutes(g *echo.Group) {
 		if name := c.QueryParam("name"); name != "" {
 			databaseFind.Name = &name
 		}
+		if anomalyType := c.QueryParam("anomalyType"); anomalyType != "" {
+			hasAnomalyType := api.AnomalyType(anomalyType)
+			databaseFind.HasAnomalyType = &hasAnomalyType
+		}
 		projectIDStr := c.QueryParams().Get("project")
 		if projectIDStr != "" {
 			projectID, err := strconv.Atoi(projectIDStr)
@@ -390,6 +394,41 @@ func (s *Server) registerDatabaseRoutes(g *echo.Group) {
 		return nil
 	})
 
+	// Adopt the live schema as the intended one after the schema drift is acknowledged.
+	g.POST("/database/:id/anomaly/schema-drift/baseline", func(c echo.Context) error {
+		ctx := context.Background()
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("id"))).SetInternal(err)
+		}
+
+		if s.AnomalyScanner == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Anomaly scanner is not running")
+		}
+		database, err := s.composeDatabaseByFind(ctx, &api.DatabaseFind{ID: &id})
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Database ID not found: %d", id))
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Failed to fetch database ID: %v", id)).SetInternal(err)
+		}
+		creator, err := s.composePrincipalByID(ctx, c.Get(getPrincipalIDContextKey()).(int))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch the principal").SetInternal(err)
+		}
+
+		version, err := s.AnomalyScanner.BaselineSchemaDrift(ctx, database.Instance, database, creator.Name)
+		if err != nil {
+			switch common.ErrorCode(err) {
+			case common.MigrationAlreadyApplied, common.MigrationOutOfOrder:
+				return echo.NewHTTPError(http.StatusConflict, err.Error())
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Failed to baseline the schema drift of database %q", database.Name)).SetInternal(err)
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{"version": version})
+	})
+
 	g.POST("/database/:id/backup", func(c echo.Context) error {
 		ctx := context.Background()
 		id, err := strconv.Atoi(c.Param("id"))
@@ -402,6 +441,12 @@ func (s *Server) registerDatabaseRoutes(g *echo.Group) {
 			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted create backup request").SetInternal(err)
 		}
 		backupCreate.CreatorID = c.Get(getPrincipalIDContextKey()).(int)
+		if backupCreate.Compression == "" {
+			backupCreate.Compression = defaultBackupCompression
+		}
+		if err := validateBackupCompression(backupCreate.Compression); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+		}
 
 		databaseFind := &api.DatabaseFind{
 			ID: &id,
@@ -414,7 +459,12 @@ func (s *Server) registerDatabaseRoutes(g *echo.Group) {
 			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Failed to fetch database ID: %v", id)).SetInternal(err)
 		}
 
-		backupCreate.Path, err = getAndCreateBackupPath(s.dataDir, database, backupCreate.Name)
+		backupPlanPolicy, err := s.PolicyService.GetBackupPlanPolicy(ctx, database.Instance.EnvironmentID)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Failed to get backup plan policy for database ID: %v", id)).SetInternal(err)
+		}
+		backupCreate.StorageBackend = getBackupPlanStorageBackend(backupPlanPolicy)
+		backupCreate.Path, err = getBackupPathForStorage(s.dataDir, database, backupCreate.Name, backupCreate.Compression, backupPlanPolicy)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Failed to create backup directory for database ID: %v", id)).SetInternal(err)
 		}
@@ -530,7 +580,8 @@ func (s *Server) registerDatabaseRoutes(g *echo.Group) {
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("id"))).SetInternal(err)
 		}
 
-		backupSettingUpsert := &api.BackupSettingUpsert{}
+		// The clients unaware of the monthly backup don't send the day of month.
+		backupSettingUpsert := &api.BackupSettingUpsert{DayOfMonth: -1}
 		if err := jsonapi.UnmarshalPayload(c.Request().Body, backupSettingUpsert); err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, "Malformatted set backup setting request").SetInternal(err)
 		}
@@ -550,6 +601,9 @@ func (s *Server) registerDatabaseRoutes(g *echo.Group) {
 
 		backupSetting, err := s.BackupService.UpsertBackupSetting(ctx, backupSettingUpsert)
 		if err != nil {
+			if common.ErrorCode(err) == common.Invalid {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+			}
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to set backup setting").SetInternal(err)
 		}
 
@@ -752,11 +806,13 @@ func getDatabaseDriver(ctx context.Context, instance *api.Instance, databaseName
 		instance.Engine,
 		db.DriverConfig{Logger: logger},
 		db.ConnectionConfig{
-			Username: instance.Username,
-			Password: instance.Password,
-			Host:     instance.Host,
-			Port:     instance.Port,
-			Database: databaseName,
+			Username:    instance.Username,
+			Password:    instance.Password,
+			Host:        instance.Host,
+			Port:        instance.Port,
+			Database:    databaseName,
+			ServiceName: instance.ServiceName,
+			SID:         instance.SID,
 		},
 		db.ConnectionContext{
 			EnvironmentName: instance.Environment.Name,
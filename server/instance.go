@@ -117,7 +117,7 @@ func (s *Server) registerInstanceRoutes(g *echo.Group) {
 		}
 
 		var instance *api.Instance
-		if instancePatch.RowStatus != nil || instancePatch.Name != nil || instancePatch.ExternalLink != nil || instancePatch.Host != nil || instancePatch.Port != nil {
+		if instancePatch.RowStatus != nil || instancePatch.Name != nil || instancePatch.ExternalLink != nil || instancePatch.Host != nil || instancePatch.Port != nil || instancePatch.Replica != nil || instancePatch.EnableAnomalyScan != nil {
 			instance, err = s.InstanceService.PatchInstance(ctx, instancePatch)
 			if err != nil {
 				if common.ErrorCode(err) == common.NotFound {
@@ -187,6 +187,39 @@ func (s *Server) registerInstanceRoutes(g *echo.Group) {
 		return nil
 	})
 
+	// Scan the anomalies of the instance immediately instead of waiting for the next anomaly scan round.
+	// With dryRun=true, the anomaly changes the scan would make are returned instead of being applied.
+	g.POST("/instance/:instanceID/anomaly/scan", func(c echo.Context) error {
+		ctx := context.Background()
+		id, err := strconv.Atoi(c.Param("instanceID"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", c.Param("instanceID"))).SetInternal(err)
+		}
+
+		if s.AnomalyScanner == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Anomaly scanner is not running")
+		}
+		var result interface{}
+		if c.QueryParam("dryRun") == "true" {
+			result, err = s.AnomalyScanner.DryRunScanInstance(ctx, id)
+		} else {
+			result, err = s.AnomalyScanner.ScanInstance(ctx, id)
+		}
+		if err != nil {
+			switch common.ErrorCode(err) {
+			case common.NotFound:
+				return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Instance ID not found: %d", id))
+			case common.Invalid:
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			case common.Conflict:
+				return echo.NewHTTPError(http.StatusConflict, err.Error())
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Failed to scan anomaly for instance ID: %v", id)).SetInternal(err)
+		}
+
+		return c.JSON(http.StatusOK, result)
+	})
+
 	g.GET("/instance/:instanceID/user", func(c echo.Context) error {
 		ctx := context.Background()
 		id, err := strconv.Atoi(c.Param("instanceID"))
@@ -230,10 +263,12 @@ func (s *Server) registerInstanceRoutes(g *echo.Group) {
 			instance.Engine,
 			db.DriverConfig{Logger: s.l},
 			db.ConnectionConfig{
-				Username: instance.Username,
-				Password: instance.Password,
-				Host:     instance.Host,
-				Port:     instance.Port,
+				Username:    instance.Username,
+				Password:    instance.Password,
+				Host:        instance.Host,
+				Port:        instance.Port,
+				ServiceName: instance.ServiceName,
+				SID:         instance.SID,
 			},
 			db.ConnectionContext{
 				EnvironmentName: instance.Environment.Name,
@@ -381,6 +416,16 @@ func (s *Server) registerInstanceRoutes(g *echo.Group) {
 		if versionStr != "" {
 			find.Version = &versionStr
 		}
+		if versionPrefix := c.QueryParam("versionPrefix"); versionPrefix != "" {
+			find.VersionPrefix = &versionPrefix
+		}
+		if statusStr := c.QueryParam("status"); statusStr != "" {
+			status := db.MigrationStatus(statusStr)
+			if status != db.Pending && status != db.Done && status != db.Failed {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid migration status: %s", statusStr))
+			}
+			find.Status = &status
+		}
 		if limitStr := c.QueryParam("limit"); limitStr != "" {
 			limit, err := strconv.Atoi(limitStr)
 			if err != nil {
@@ -388,6 +433,13 @@ func (s *Server) registerInstanceRoutes(g *echo.Group) {
 			}
 			find.Limit = &limit
 		}
+		if offsetStr := c.QueryParam("offset"); offsetStr != "" {
+			offset, err := strconv.Atoi(offsetStr)
+			if err != nil || offset < 0 {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("offset query parameter is not a non-negative number: %s", offsetStr))
+			}
+			find.Offset = &offset
+		}
 
 		historyList := []*api.MigrationHistory{}
 		driver, err := getDatabaseDriver(ctx, instance, "", s.l)
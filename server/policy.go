@@ -7,6 +7,7 @@ import (
 	"strconv"
 
 	"github.com/bytebase/bytebase/api"
+	"github.com/bytebase/bytebase/common"
 	"github.com/google/jsonapi"
 	"github.com/labstack/echo/v4"
 )
@@ -27,15 +28,30 @@ func (s *Server) registerPolicyRoutes(g *echo.Group) {
 		if err := api.ValidatePolicy(pType, ""); err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid policy type: %q", pType)).SetInternal(err)
 		}
+		if err := s.checkPolicyEnvironment(ctx, environmentID); err != nil {
+			return err
+		}
+		instanceID, err := s.getPolicyInstanceID(ctx, c, environmentID)
+		if err != nil {
+			return err
+		}
 		policyUpsert.EnvironmentID = environmentID
+		policyUpsert.InstanceID = instanceID
 		policyUpsert.Type = pType
 		policyUpsert.UpdaterID = c.Get(getPrincipalIDContextKey()).(int)
 
 		policy, err := s.PolicyService.UpsertPolicy(ctx, policyUpsert)
 		if err != nil {
+			if common.ErrorCode(err) == common.Invalid {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid policy payload for type %q", pType)).SetInternal(err)
+			}
 			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Failed to set policy for type %q", pType)).SetInternal(err)
 		}
 
+		if s.AnomalyScanner != nil {
+			s.AnomalyScanner.InvalidatePolicyCache(environmentID)
+		}
+
 		if err := s.composePolicyRelationship(ctx, policy); err != nil {
 			return err
 		}
@@ -58,8 +74,13 @@ func (s *Server) registerPolicyRoutes(g *echo.Group) {
 		if err := api.ValidatePolicy(pType, ""); err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid policy type: %q", pType)).SetInternal(err)
 		}
+		instanceID, err := s.getPolicyInstanceID(ctx, c, environmentID)
+		if err != nil {
+			return err
+		}
 		policyFind.Type = &pType
 		policyFind.EnvironmentID = &environmentID
+		policyFind.InstanceID = instanceID
 
 		policy, err := s.PolicyService.FindPolicy(ctx, policyFind)
 		if err != nil {
@@ -76,6 +97,82 @@ func (s *Server) registerPolicyRoutes(g *echo.Group) {
 		}
 		return nil
 	})
+
+	g.DELETE("/policy/environment/:environmentID", func(c echo.Context) error {
+		ctx := context.Background()
+		environmentID, err := strconv.Atoi(c.Param("environmentID"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("environmentID is not a number: %s", c.Param("environmentID"))).SetInternal(err)
+		}
+		pType := api.PolicyType(c.QueryParam("type"))
+		if err := api.ValidatePolicy(pType, ""); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid policy type: %q", pType)).SetInternal(err)
+		}
+		if err := s.checkPolicyEnvironment(ctx, environmentID); err != nil {
+			return err
+		}
+		instanceID, err := s.getPolicyInstanceID(ctx, c, environmentID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.PolicyService.DeletePolicy(ctx, &api.PolicyFind{
+			EnvironmentID: &environmentID,
+			InstanceID:    instanceID,
+			Type:          &pType,
+		}); err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Policy not found for type %q", pType))
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Failed to delete policy for type %q", pType)).SetInternal(err)
+		}
+
+		if s.AnomalyScanner != nil {
+			s.AnomalyScanner.InvalidatePolicyCache(environmentID)
+		}
+
+		c.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+		c.Response().WriteHeader(http.StatusOK)
+		return nil
+	})
+}
+
+// checkPolicyEnvironment returns an error if the environment of the policy doesn't exist, except for the global policy.
+// The policy table has no foreign key on the environment since the global policy was added, so it's checked here instead.
+func (s *Server) checkPolicyEnvironment(ctx context.Context, environmentID int) error {
+	if environmentID == api.GlobalPolicyEnvironmentID {
+		return nil
+	}
+	if _, err := s.EnvironmentService.FindEnvironment(ctx, &api.EnvironmentFind{ID: &environmentID}); err != nil {
+		if common.ErrorCode(err) == common.NotFound {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Environment not found: %d", environmentID))
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Failed to find environment: %d", environmentID)).SetInternal(err)
+	}
+	return nil
+}
+
+// getPolicyInstanceID returns the instance of the "instance" query parameter, whose policy overrides the environment policy,
+// or nil for the environment policy if the parameter is absent. The instance must belong to the environment.
+func (s *Server) getPolicyInstanceID(ctx context.Context, c echo.Context, environmentID int) (*int, error) {
+	if c.QueryParam("instance") == "" {
+		return nil, nil
+	}
+	instanceID, err := strconv.Atoi(c.QueryParam("instance"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("instance is not a number: %s", c.QueryParam("instance"))).SetInternal(err)
+	}
+	instance, err := s.InstanceService.FindInstance(ctx, &api.InstanceFind{ID: &instanceID})
+	if err != nil {
+		if common.ErrorCode(err) == common.NotFound {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Instance not found: %d", instanceID))
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Failed to find instance: %d", instanceID)).SetInternal(err)
+	}
+	if instance.EnvironmentID != environmentID {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Instance %d doesn't belong to environment %d", instanceID, environmentID))
+	}
+	return &instanceID, nil
 }
 
 func (s *Server) composePolicyRelationship(ctx context.Context, policy *api.Policy) error {
@@ -91,9 +188,12 @@ func (s *Server) composePolicyRelationship(ctx context.Context, policy *api.Poli
 		return err
 	}
 
-	policy.Environment, err = s.composeEnvironmentByID(ctx, policy.EnvironmentID)
-	if err != nil {
-		return err
+	// The global policy doesn't belong to any environment.
+	if policy.EnvironmentID != api.GlobalPolicyEnvironmentID {
+		policy.Environment, err = s.composeEnvironmentByID(ctx, policy.EnvironmentID)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
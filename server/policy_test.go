@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/bytebase/bytebase/api"
+	"github.com/bytebase/bytebase/common"
+	"github.com/labstack/echo/v4"
+)
+
+// fakeEnvironmentService is the api.EnvironmentService finding the environments in list by ID.
+type fakeEnvironmentService struct {
+	api.EnvironmentService
+	list []*api.Environment
+}
+
+func (s *fakeEnvironmentService) FindEnvironment(ctx context.Context, find *api.EnvironmentFind) (*api.Environment, error) {
+	for _, environment := range s.list {
+		if find.ID != nil && environment.ID == *find.ID {
+			return environment, nil
+		}
+	}
+	return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("environment not found")}
+}
+
+func TestCheckPolicyEnvironment(t *testing.T) {
+	s := &Server{EnvironmentService: &fakeEnvironmentService{list: []*api.Environment{{ID: 101, Name: "test"}}}}
+	tests := []struct {
+		name          string
+		environmentID int
+		wantCode      int
+	}{
+		{"existing", 101, 0},
+		// The global policy doesn't belong to any environment.
+		{"global", api.GlobalPolicyEnvironmentID, 0},
+		{"unknown", 999, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.checkPolicyEnvironment(context.Background(), tt.environmentID)
+			code := 0
+			if err != nil {
+				httpErr, ok := err.(*echo.HTTPError)
+				if !ok {
+					t.Fatalf("checkPolicyEnvironment() = %v, want an HTTP error", err)
+				}
+				code = httpErr.Code
+			}
+			if code != tt.wantCode {
+				t.Errorf("checkPolicyEnvironment(%d) returns code %d, want %d", tt.environmentID, code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -15,6 +15,8 @@ import (
 	"github.com/casbin/casbin/v2/model"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	scas "github.com/qiangmzsx/string-adapter/v2"
 	"go.uber.org/zap"
 )
@@ -25,6 +27,7 @@ type Server struct {
 	TaskCheckScheduler *TaskCheckScheduler
 	SchemaSyncer       *SchemaSyncer
 	BackupRunner       *BackupRunner
+	BackupPruner       *BackupPruner
 	AnomalyScanner     *AnomalyScanner
 
 	ActivityManager *ActivityManager
@@ -60,10 +63,13 @@ type Server struct {
 	VCSService              api.VCSService
 	RepositoryService       api.RepositoryService
 	AnomalyService          api.AnomalyService
+	RowCountBaselineService api.RowCountBaselineService
 	LabelService            api.LabelService
 	DeploymentConfigService api.DeploymentConfigService
 
 	e *echo.Echo
+	// metricsRegistry is the registry of the Prometheus metrics exposed at /metrics.
+	metricsRegistry *prometheus.Registry
 
 	l            *zap.Logger
 	version      string
@@ -93,7 +99,7 @@ var casbinDBAPolicy string
 var casbinDeveloperPolicy string
 
 // NewServer creates a server.
-func NewServer(logger *zap.Logger, version string, host string, port int, frontendHost string, frontendPort int, mode string, dataDir string, backupRunnerInterval time.Duration, secret string, readonly bool, demo bool, debug bool) *Server {
+func NewServer(logger *zap.Logger, version string, host string, port int, frontendHost string, frontendPort int, mode string, dataDir string, backupRunnerInterval time.Duration, anomalyScannerConfig AnomalyScannerConfig, secret string, readonly bool, demo bool, debug bool) *Server {
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
@@ -106,21 +112,22 @@ func NewServer(logger *zap.Logger, version string, host string, port int, fronte
 	embedFrontend(logger, e)
 
 	s := &Server{
-		l:            logger,
-		CacheService: NewCacheService(logger),
-		e:            e,
-		version:      version,
-		mode:         mode,
-		host:         host,
-		port:         port,
-		frontendHost: frontendHost,
-		frontendPort: frontendPort,
-		startedTs:    time.Now().Unix(),
-		secret:       secret,
-		readonly:     readonly,
-		demo:         demo,
-		plan:         api.TEAM,
-		dataDir:      dataDir,
+		l:               logger,
+		CacheService:    NewCacheService(logger),
+		e:               e,
+		metricsRegistry: prometheus.NewRegistry(),
+		version:         version,
+		mode:            mode,
+		host:            host,
+		port:            port,
+		frontendHost:    frontendHost,
+		frontendPort:    frontendPort,
+		startedTs:       time.Now().Unix(),
+		secret:          secret,
+		readonly:        readonly,
+		demo:            demo,
+		plan:            api.TEAM,
+		dataDir:         dataDir,
 	}
 
 	if !readonly {
@@ -166,8 +173,14 @@ func NewServer(logger *zap.Logger, version string, host string, port int, fronte
 		// Backup runner
 		s.BackupRunner = NewBackupRunner(logger, s, backupRunnerInterval)
 
+		// Backup pruner
+		s.BackupPruner = NewBackupPruner(logger, s)
+
 		// Anomaly scanner
-		s.AnomalyScanner = NewAnomalyScanner(logger, s)
+		s.AnomalyScanner = NewAnomalyScanner(logger, s, anomalyScannerConfig)
+		if err := s.AnomalyScanner.RegisterMetrics(s.metricsRegistry); err != nil {
+			e.Logger.Fatal(err)
+		}
 	}
 
 	// Middleware
@@ -188,6 +201,9 @@ func NewServer(logger *zap.Logger, version string, host string, port int, fronte
 	webhookGroup := e.Group("/hook")
 	s.registerWebhookRoutes(webhookGroup)
 
+	// The metrics are served outside the API group, so that Prometheus can scrape them without logging in.
+	e.GET("/metrics", echo.WrapHandler(promhttp.HandlerFor(s.metricsRegistry, promhttp.HandlerOpts{})))
+
 	apiGroup := e.Group("/api")
 
 	apiGroup.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -258,6 +274,10 @@ func (server *Server) Run() error {
 			return err
 		}
 
+		if err := server.BackupPruner.Run(); err != nil {
+			return err
+		}
+
 		if err := server.AnomalyScanner.Run(); err != nil {
 			return err
 		}
@@ -271,6 +291,10 @@ func (server *Server) Run() error {
 
 // Shutdown will shut down the server.
 func (server *Server) Shutdown(ctx context.Context) {
+	if server.AnomalyScanner != nil {
+		server.AnomalyScanner.Stop()
+	}
+
 	if err := server.e.Shutdown(ctx); err != nil {
 		server.e.Logger.Fatal(err)
 	}
@@ -11,6 +11,7 @@ import (
 	"github.com/bytebase/bytebase/plugin/db"
 	"github.com/google/jsonapi"
 	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
 )
 
 func (s *Server) registerSQLRoutes(g *echo.Group) {
@@ -39,10 +40,12 @@ func (s *Server) registerSQLRoutes(g *echo.Group) {
 			connectionInfo.Engine,
 			db.DriverConfig{Logger: s.l},
 			db.ConnectionConfig{
-				Username: connectionInfo.Username,
-				Password: password,
-				Host:     connectionInfo.Host,
-				Port:     connectionInfo.Port,
+				Username:    connectionInfo.Username,
+				Password:    password,
+				Host:        connectionInfo.Host,
+				Port:        connectionInfo.Port,
+				ServiceName: connectionInfo.ServiceName,
+				SID:         connectionInfo.SID,
 			},
 			db.ConnectionContext{},
 		)
@@ -102,10 +105,13 @@ func (s *Server) syncEngineVersionAndSchema(ctx context.Context, instance *api.I
 		}
 		defer driver.Close(ctx)
 
-		// Sync engine version
+		// Sync engine version, the schema is still synced if the driver can't determine the version.
 		version, err := driver.GetVersion(ctx)
 		if err != nil {
-			return err
+			if common.ErrorCode(err) != common.NotImplemented {
+				return err
+			}
+			version = instance.EngineVersion
 		}
 		// Underlying version may change due to upgrade, however it's a rare event, so we only update if it actually differs
 		// to avoid changing the updated_ts
@@ -455,6 +461,17 @@ func (s *Server) syncEngineVersionAndSchema(ctx context.Context, instance *api.I
 						}
 						return fmt.Errorf("failed to sync database for instance: %s. Failed to update database: %s. Error: %w", instance.Name, database.Name, err)
 					}
+					// The anomalies of the database no longer apply, and the scanner skips the database until it's found again.
+					count, err := s.AnomalyService.ArchiveAnomaliesForDatabase(ctx, db.ID)
+					if err != nil {
+						return fmt.Errorf("failed to sync database for instance: %s. Failed to archive anomalies for database: %s. Error: %w", instance.Name, db.Name, err)
+					}
+					if count > 0 {
+						s.l.Info("Archived anomalies for the database not found",
+							zap.String("instance", instance.Name),
+							zap.String("database", db.Name),
+							zap.Int("count", count))
+					}
 				}
 			}
 		}
@@ -165,6 +165,16 @@ func (s *Server) registerTaskRoutes(g *echo.Group) {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update task status").SetInternal(err)
 		}
 
+		if task.Status == api.TaskPendingApproval && taskStatusPatch.Status == api.TaskPending {
+			ok, err := s.canPrincipalApproveTask(ctx, task, taskStatusPatch.UpdaterID)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Failed to check whether the user can approve task \"%v\"", task.Name)).SetInternal(err)
+			}
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, fmt.Sprintf("The user's role is not allowed to approve task \"%v\" by the pipeline approval policy", task.Name))
+			}
+		}
+
 		updatedTask, err := s.changeTaskStatusWithPatch(ctx, task, taskStatusPatch)
 		if err != nil {
 			if common.ErrorCode(err) == common.Invalid {
@@ -295,6 +305,46 @@ func (s *Server) composeTaskRelationship(ctx context.Context, task *api.Task) er
 	return nil
 }
 
+// getPipelineApprovalValue returns whether the tasks of the instance need approval under the pipeline approval policy
+// taking effect for the instance.
+func (s *Server) getPipelineApprovalValue(ctx context.Context, instance *api.Instance) (api.PipelineApprovalValue, error) {
+	resolved, err := s.PolicyService.ResolvePolicy(ctx, instance.EnvironmentID, instance.ID, api.PolicyTypePipelineApproval)
+	if err != nil {
+		return "", err
+	}
+	policy, err := api.UnmarshalPipelineApprovalPolicy(resolved.Payload)
+	if err != nil {
+		return "", err
+	}
+	return policy.Value, nil
+}
+
+// canPrincipalApproveTask returns whether the principal's role is an approver role of the pipeline approval policy
+// taking effect for the task's instance.
+func (s *Server) canPrincipalApproveTask(ctx context.Context, task *api.Task, principalID int) (bool, error) {
+	instance, err := s.InstanceService.FindInstance(ctx, &api.InstanceFind{
+		ID: &task.InstanceID,
+	})
+	if err != nil {
+		return false, err
+	}
+	resolved, err := s.PolicyService.ResolvePolicy(ctx, instance.EnvironmentID, instance.ID, api.PolicyTypePipelineApproval)
+	if err != nil {
+		return false, err
+	}
+	policy, err := api.UnmarshalPipelineApprovalPolicy(resolved.Payload)
+	if err != nil {
+		return false, err
+	}
+	member, err := s.MemberService.FindMember(ctx, &api.MemberFind{
+		PrincipalID: &principalID,
+	})
+	if err != nil {
+		return false, err
+	}
+	return policy.CanApprove(member.Role), nil
+}
+
 func (s *Server) changeTaskStatus(ctx context.Context, task *api.Task, newStatus api.TaskStatus, updaterID int) (*api.Task, error) {
 	taskStatusPatch := &api.TaskStatusPatch{
 		ID:        task.ID,
@@ -2,8 +2,11 @@ package server
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -57,20 +60,22 @@ func (exec *DatabaseBackupTaskExecutor) RunOnce(ctx context.Context, server *Ser
 		zap.String("backup", backup.Name),
 	)
 
-	backupErr := exec.backupDatabase(ctx, task.Instance, task.Database.Name, backup, server.dataDir)
+	checksum, size, backupErr := exec.backupDatabase(ctx, server, task.Instance, task.Database.Name, backup)
 	// Update the status of the backup.
-	newBackupStatus := string(api.BackupStatusDone)
-	comment := ""
-	if backupErr != nil {
-		newBackupStatus = string(api.BackupStatusFailed)
-		comment = backupErr.Error()
-	}
-	if _, err = server.BackupService.PatchBackup(ctx, &api.BackupPatch{
+	backupPatch := &api.BackupPatch{
 		ID:        backup.ID,
-		Status:    newBackupStatus,
+		Status:    string(api.BackupStatusDone),
 		UpdaterID: api.SystemBotID,
-		Comment:   comment,
-	}); err != nil {
+		Checksum:  &checksum,
+		SizeBytes: &size,
+	}
+	if backupErr != nil {
+		backupPatch.Status = string(api.BackupStatusFailed)
+		backupPatch.Comment = backupErr.Error()
+		backupPatch.Checksum = nil
+		backupPatch.SizeBytes = nil
+	}
+	if _, err = server.BackupService.PatchBackup(ctx, backupPatch); err != nil {
 		return true, nil, fmt.Errorf("failed to patch backup: %w", err)
 	}
 
@@ -83,25 +88,58 @@ func (exec *DatabaseBackupTaskExecutor) RunOnce(ctx context.Context, server *Ser
 	}, nil
 }
 
-// backupDatabase will take a backup of a database.
-func (exec *DatabaseBackupTaskExecutor) backupDatabase(ctx context.Context, instance *api.Instance, databaseName string, backup *api.Backup, dataDir string) error {
-	driver, err := getDatabaseDriver(ctx, instance, databaseName, exec.l)
+// backupDatabase will take a backup of a database, and returns the hex SHA-256 and the size of the stored backup file.
+func (exec *DatabaseBackupTaskExecutor) backupDatabase(ctx context.Context, server *Server, instance *api.Instance, databaseName string, backup *api.Backup) (string, int64, error) {
+	// The S3 bucket region of the backup plan policy saves looking it up.
+	var s3Config *api.BackupS3Config
+	if backup.StorageBackend == api.BackupStorageBackendS3 {
+		policy, err := server.PolicyService.GetBackupPlanPolicy(ctx, instance.EnvironmentID)
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to get backup plan policy: %w", err)
+		}
+		s3Config = policy.S3
+	}
+	storage, err := getBackupStorage(ctx, server.dataDir, backup.StorageBackend, backup.Path, s3Config)
 	if err != nil {
-		return err
+		return "", 0, err
 	}
-	defer driver.Close(ctx)
 
-	f, err := os.Create(filepath.Join(dataDir, backup.Path))
+	driver, err := getDatabaseDriver(ctx, instance, databaseName, exec.l)
 	if err != nil {
-		return fmt.Errorf("failed to open backup path: %s", backup.Path)
+		return "", 0, err
 	}
-	defer f.Close()
+	defer driver.Close(ctx)
 
-	if err := driver.Dump(ctx, databaseName, f, false /* schemaOnly */); err != nil {
-		return err
+	// The checksum and the size are of the stored bytes, so verifying them doesn't need to decompress the backup.
+	hash := sha256.New()
+	counter := &byteCountWriter{}
+	if err := storage.Write(ctx, backup.Path, func(out io.Writer) error {
+		w, err := newBackupCompressWriter(io.MultiWriter(out, hash, counter), backup.Compression)
+		if err != nil {
+			return err
+		}
+		if err := driver.Dump(ctx, databaseName, w, false /* schemaOnly */); err != nil {
+			w.Close()
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return fmt.Errorf("failed to compress backup: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return "", 0, err
 	}
+	return hex.EncodeToString(hash.Sum(nil)), counter.n, nil
+}
+
+// byteCountWriter counts the bytes written to it.
+type byteCountWriter struct {
+	n int64
+}
 
-	return nil
+func (w *byteCountWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
 }
 
 // getAndCreateBackupDirectory returns the path of a database backup.
@@ -115,13 +153,13 @@ func getAndCreateBackupDirectory(dataDir string, database *api.Database) (string
 	return dir, nil
 }
 
-// getAndCreateBackupPath returns the path of a database backup.
-func getAndCreateBackupPath(dataDir string, database *api.Database, name string) (string, error) {
+// getAndCreateBackupPath returns the path of a database backup, which has the extension of the compression.
+func getAndCreateBackupPath(dataDir string, database *api.Database, name string, compression api.BackupCompression) (string, error) {
 	dir, err := getAndCreateBackupDirectory(dataDir, database)
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(dir, fmt.Sprintf("%s.sql", name)), nil
+	return filepath.Join(dir, fmt.Sprintf("%s.sql%s", name, compression.FileExtension())), nil
 }
 
 func getMigrationVersion(ctx context.Context, database *api.Database, logger *zap.Logger) (string, error) {
@@ -5,8 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strconv"
 
 	"github.com/bytebase/bytebase/api"
@@ -126,17 +124,21 @@ func (exec *DatabaseRestoreTaskExecutor) restoreDatabase(ctx context.Context, in
 	}
 	defer driver.Close(ctx)
 
-	backupPath := backup.Path
-	if !filepath.IsAbs(backupPath) {
-		backupPath = filepath.Join(dataDir, backupPath)
+	storage, err := getBackupStorage(ctx, dataDir, backup.StorageBackend, backup.Path, nil)
+	if err != nil {
+		return err
 	}
-
-	f, err := os.OpenFile(backupPath, os.O_RDONLY, os.ModePerm)
+	f, err := storage.Open(ctx, backup.Path)
 	if err != nil {
-		return fmt.Errorf("failed to open backup file at %s: %w", backupPath, err)
+		return err
 	}
 	defer f.Close()
-	sc := bufio.NewScanner(f)
+	r, err := newBackupDecompressReader(f, backup.Compression)
+	if err != nil {
+		return fmt.Errorf("failed to decompress backup file at %s: %w", backup.Path, err)
+	}
+	defer r.Close()
+	sc := bufio.NewScanner(r)
 
 	if err := driver.Restore(ctx, sc); err != nil {
 		return fmt.Errorf("failed to restore backup: %w", err)
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bytebase/bytebase/api"
+)
+
+// fakeApprovalPolicyService is the api.PolicyService resolving the pipeline approval policy of instancePayloadMap for the instance,
+// or the payload of the environment otherwise.
+type fakeApprovalPolicyService struct {
+	api.PolicyService
+	payload            string
+	instancePayloadMap map[int]string
+}
+
+func (s *fakeApprovalPolicyService) ResolvePolicy(ctx context.Context, environmentID int, instanceID int, pType api.PolicyType) (*api.Policy, error) {
+	if payload, ok := s.instancePayloadMap[instanceID]; ok {
+		return &api.Policy{EnvironmentID: environmentID, InstanceID: instanceID, Type: pType, Payload: payload}, nil
+	}
+	return &api.Policy{EnvironmentID: environmentID, Type: pType, Payload: s.payload}, nil
+}
+
+// fakeMemberService is the api.MemberService returning the member with the same role for any principal.
+type fakeMemberService struct {
+	api.MemberService
+	role api.Role
+}
+
+func (s *fakeMemberService) FindMember(ctx context.Context, find *api.MemberFind) (*api.Member, error) {
+	return &api.Member{PrincipalID: *find.PrincipalID, Role: s.role}, nil
+}
+
+func TestCanPrincipalApproveTask(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		role    api.Role
+		want    bool
+	}{
+		{"anyRole", `{"value":"MANUAL_APPROVAL_ALWAYS"}`, api.Developer, true},
+		{"approverRole", `{"value":"MANUAL_APPROVAL_ALWAYS","approverRoleList":["DBA"]}`, api.DBA, true},
+		{"notApproverRole", `{"value":"MANUAL_APPROVAL_ALWAYS","approverRoleList":["DBA"]}`, api.Developer, false},
+		// The approval chain without the approver roles only allows the roles of its steps.
+		{"chainRole", `{"value":"MANUAL_APPROVAL_ALWAYS","stepList":[{"role":"DBA","count":1}]}`, api.DBA, true},
+		{"notChainRole", `{"value":"MANUAL_APPROVAL_ALWAYS","stepList":[{"role":"DBA","count":1}]}`, api.Developer, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance, _ := newTestInstance()
+			s := &Server{
+				InstanceService: &fakeInstanceService{list: []*api.Instance{instance}},
+				PolicyService:   &fakeApprovalPolicyService{payload: tt.payload},
+				MemberService:   &fakeMemberService{role: tt.role},
+			}
+			got, err := s.canPrincipalApproveTask(context.Background(), &api.Task{InstanceID: instance.ID}, 101)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("canPrincipalApproveTask() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetPipelineApprovalValue(t *testing.T) {
+	overridden, _ := newTestInstance()
+	other := &api.Instance{ID: 2, Name: "other", EnvironmentID: overridden.EnvironmentID}
+	s := &Server{
+		PolicyService: &fakeApprovalPolicyService{
+			payload: `{"value":"MANUAL_APPROVAL_NEVER"}`,
+			instancePayloadMap: map[int]string{
+				overridden.ID: `{"value":"MANUAL_APPROVAL_ALWAYS"}`,
+			},
+		},
+	}
+	for _, tt := range []struct {
+		instance *api.Instance
+		want     api.PipelineApprovalValue
+	}{
+		// The instance policy requires approval in the environment needing none.
+		{overridden, api.PipelineApprovalValueManualAlways},
+		{other, api.PipelineApprovalValueManualNever},
+	} {
+		got, err := s.getPipelineApprovalValue(context.Background(), tt.instance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("getPipelineApprovalValue(%s) = %s, want %s", tt.instance.Name, got, tt.want)
+		}
+	}
+}
@@ -213,7 +213,7 @@ func (s *Server) registerWebhookRoutes(g *echo.Group) {
 					filteredDatabaseList = databaseList
 				}
 
-				var pipelineApprovalByEnv = map[int]api.PipelineApprovalValue{}
+				var pipelineApprovalByInstance = map[int]api.PipelineApprovalValue{}
 				{
 					// It could happen that for a particular environment a project contain 2 database with the same name.
 					// We will emit warning in this case.
@@ -227,14 +227,14 @@ func (s *Server) registerWebhookRoutes(g *echo.Group) {
 							databaseListByEnv[database.Instance.EnvironmentID] = append(list, database)
 						}
 
-						// Load pipeline approval policy per environment.
-						if _, ok := pipelineApprovalByEnv[database.Instance.EnvironmentID]; !ok {
-							p, err := s.PolicyService.GetPipelineApprovalPolicy(ctx, database.Instance.EnvironmentID)
+						// Load pipeline approval policy per instance, which may override the environment policy.
+						if _, ok := pipelineApprovalByInstance[database.InstanceID]; !ok {
+							value, err := s.getPipelineApprovalValue(ctx, database.Instance)
 							if err != nil {
-								createIgnoredFileActivity(fmt.Errorf("failed to find pipeline approval policy for environment %v", database.Instance.EnvironmentID))
+								createIgnoredFileActivity(fmt.Errorf("failed to find pipeline approval policy for instance %v", database.InstanceID))
 								continue
 							}
-							pipelineApprovalByEnv[database.Instance.EnvironmentID] = p.Value
+							pipelineApprovalByInstance[database.InstanceID] = value
 						}
 					}
 
@@ -260,7 +260,7 @@ func (s *Server) registerWebhookRoutes(g *echo.Group) {
 				for _, database := range filteredDatabaseList {
 					databaseID := database.ID
 					taskStatus := api.TaskPendingApproval
-					if pipelineApprovalByEnv[database.Instance.Environment.ID] == api.PipelineApprovalValueManualNever {
+					if pipelineApprovalByInstance[database.InstanceID] == api.PipelineApprovalValueManualNever {
 						taskStatus = api.TaskPending
 					}
 					task := &api.TaskCreate{
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/bytebase/bytebase/api"
@@ -27,12 +28,13 @@ func NewAnomalyService(logger *zap.Logger, db *DB) *AnomalyService {
 }
 
 // UpsertActiveAnomaly would update the existing active anomaly if both database id and type match, otherwise create a new one.
+// Returns true if a new anomaly is created.
 // Do not use ON CONFLICT (upsert syntax) as it will consume autoincrement id. Functional wise, this is fine, but
 // from the UX perspective, it's not great, since user will see large id gaps.
-func (s *AnomalyService) UpsertActiveAnomaly(ctx context.Context, upsert *api.AnomalyUpsert) (*api.Anomaly, error) {
+func (s *AnomalyService) UpsertActiveAnomaly(ctx context.Context, upsert *api.AnomalyUpsert) (*api.Anomaly, bool, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, FormatError(err)
+		return nil, false, FormatError(err)
 	}
 	defer tx.Rollback()
 
@@ -42,17 +44,20 @@ func (s *AnomalyService) UpsertActiveAnomaly(ctx context.Context, upsert *api.An
 		InstanceID: &upsert.InstanceID,
 		DatabaseID: upsert.DatabaseID,
 		Type:       &upsert.Type,
+		// Otherwise an instance anomaly would match the database anomalies of the same type and never be created.
+		InstanceOnly: upsert.DatabaseID == nil,
 	}
 	list, err := findAnomalyList(ctx, tx, find)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	var anomaly *api.Anomaly
-	if len(list) == 0 {
+	created := len(list) == 0
+	if created {
 		anomaly, err = createAnomaly(ctx, tx, upsert)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 	} else if len(list) == 1 {
 		// Even if field value does not change, we still patch to update the updated_ts
@@ -62,17 +67,17 @@ func (s *AnomalyService) UpsertActiveAnomaly(ctx context.Context, upsert *api.An
 			Payload:   upsert.Payload,
 		})
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 	} else {
-		return nil, &common.Error{Code: common.Conflict, Err: fmt.Errorf("found %d active anomalies with filter %+v, expect 1", len(list), find)}
+		return nil, false, &common.Error{Code: common.Conflict, Err: fmt.Errorf("found %d active anomalies with filter %+v, expect 1", len(list), find)}
 	}
 
 	if err := tx.Commit(); err != nil {
-		return nil, FormatError(err)
+		return nil, false, FormatError(err)
 	}
 
-	return anomaly, nil
+	return anomaly, created, nil
 }
 
 // FindAnomalyList retrieves a list of anomalys based on find.
@@ -91,6 +96,28 @@ func (s *AnomalyService) FindAnomalyList(ctx context.Context, find *api.AnomalyF
 	return list, nil
 }
 
+// CountAnomaly returns the number of anomalies matching find regardless of its Limit and Offset.
+func (s *AnomalyService) CountAnomaly(ctx context.Context, find *api.AnomalyFind) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	where, args := findAnomalyWhere(find)
+	var count int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM anomaly
+		WHERE `+strings.Join(where, " AND "),
+		args...,
+	).Scan(&count); err != nil {
+		return 0, FormatError(err)
+	}
+
+	return count, nil
+}
+
 // ArchiveAnomaly archives an existing anomaly by ID.
 // Returns ENOTFOUND if anomaly does not exist.
 func (s *AnomalyService) ArchiveAnomaly(ctx context.Context, archive *api.AnomalyArchive) error {
@@ -112,6 +139,26 @@ func (s *AnomalyService) ArchiveAnomaly(ctx context.Context, archive *api.Anomal
 	return nil
 }
 
+// ArchiveAnomaliesForDatabase archives all active anomalies of the database.
+func (s *AnomalyService) ArchiveAnomaliesForDatabase(ctx context.Context, databaseID int) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	count, err := archiveAnomaliesForDatabase(ctx, tx, databaseID)
+	if err != nil {
+		return 0, FormatError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, FormatError(err)
+	}
+
+	return count, nil
+}
+
 // createAnomaly creates a new anomaly.
 func createAnomaly(ctx context.Context, tx *Tx, upsert *api.AnomalyUpsert) (*api.Anomaly, error) {
 	// Inserts row into database.
@@ -122,10 +169,11 @@ func createAnomaly(ctx context.Context, tx *Tx, upsert *api.AnomalyUpsert) (*api
 			instance_id,
 			database_id,
 			`+"`type`,"+`
-			payload
+			payload,
+			last_seen_ts
 		)
-		VALUES (?, ?, ?, ?, ?, ?)
-		RETURNING id, creator_id, created_ts, updater_id, updated_ts, instance_id, database_id, `+"`type`"+`, payload
+		VALUES (?, ?, ?, ?, ?, ?, strftime('%s', 'now'))
+		RETURNING id, creator_id, created_ts, updater_id, updated_ts, instance_id, database_id, `+"`type`"+`, payload, last_seen_ts, archive_reason
 	`,
 		upsert.CreatorID,
 		upsert.CreatorID,
@@ -153,6 +201,8 @@ func createAnomaly(ctx context.Context, tx *Tx, upsert *api.AnomalyUpsert) (*api
 		&databaseID,
 		&anomaly.Type,
 		&anomaly.Payload,
+		&anomaly.LastSeenTs,
+		&anomaly.ArchiveReason,
 	); err != nil {
 		return nil, FormatError(err)
 	}
@@ -162,12 +212,15 @@ func createAnomaly(ctx context.Context, tx *Tx, upsert *api.AnomalyUpsert) (*api
 	}
 	anomaly.Severity = api.AnomalySeverityFromType(anomaly.Type)
 
-	return nil, err
+	return &anomaly, nil
 }
 
-func findAnomalyList(ctx context.Context, tx *Tx, find *api.AnomalyFind) (_ []*api.Anomaly, err error) {
-	// Build WHERE clause.
+// findAnomalyWhere builds the WHERE clause of find.
+func findAnomalyWhere(find *api.AnomalyFind) ([]string, []interface{}) {
 	where, args := []string{"1 = 1"}, []interface{}{}
+	if v := find.EnvironmentID; v != nil {
+		where, args = append(where, "instance_id IN (SELECT id FROM instance WHERE environment_id = ?)"), append(args, *v)
+	}
 	if v := find.InstanceID; v != nil {
 		where, args = append(where, "instance_id = ?"), append(args, *v)
 		if find.InstanceOnly {
@@ -185,8 +238,67 @@ func findAnomalyList(ctx context.Context, tx *Tx, find *api.AnomalyFind) (_ []*a
 	if v := find.Type; v != nil {
 		where, args = append(where, "`type` = ?"), append(args, *v)
 	}
+	if v := find.Severity; v != nil {
+		// The severity is derived from the type, so match the types of the severity.
+		var typeList []string
+		for anomalyType := range api.AnomalyTypes {
+			if api.AnomalySeverityFromType(anomalyType) == *v {
+				typeList = append(typeList, string(anomalyType))
+			}
+		}
+		if len(typeList) == 0 {
+			where = append(where, "1 = 0")
+		} else {
+			sort.Strings(typeList)
+			where = append(where, "`type` IN ("+strings.Repeat("?, ", len(typeList)-1)+"?)")
+			for _, anomalyType := range typeList {
+				args = append(args, anomalyType)
+			}
+		}
+	}
+	return where, args
+}
 
-	rows, err := tx.QueryContext(ctx, `
+// findAnomalyOrderBy builds the ORDER BY clause of find, the ID breaks the ties so that the pages are stable.
+func findAnomalyOrderBy(find *api.AnomalyFind) (string, []interface{}) {
+	switch find.SortBy {
+	case api.AnomalySortByCreatedTs:
+		return "created_ts DESC, id DESC", nil
+	case api.AnomalySortBySeverity:
+		// The severity is derived from the type, so rank the types by their severity.
+		var typeList []string
+		for anomalyType := range api.AnomalyTypes {
+			typeList = append(typeList, string(anomalyType))
+		}
+		sort.Strings(typeList)
+		rank, args := "CASE `type`", []interface{}{}
+		for _, anomalyType := range typeList {
+			rank, args = rank+" WHEN ? THEN ?", append(args, anomalyType, anomalySeverityRank(api.AnomalySeverityFromType(api.AnomalyType(anomalyType))))
+		}
+		rank += fmt.Sprintf(" ELSE %d END", anomalySeverityRank(api.AnomalySeverityCritical))
+		return rank + " DESC, created_ts DESC, id DESC", args
+	}
+	return "id ASC", nil
+}
+
+// anomalySeverityRank ranks the more severe higher.
+func anomalySeverityRank(severity api.AnomalySeverity) int {
+	switch severity {
+	case api.AnomalySeverityMedium:
+		return 1
+	case api.AnomalySeverityHigh:
+		return 2
+	}
+	return 3
+}
+
+func findAnomalyList(ctx context.Context, tx *Tx, find *api.AnomalyFind) (_ []*api.Anomaly, err error) {
+	// Build WHERE clause.
+	where, args := findAnomalyWhere(find)
+	// Build ORDER BY clause.
+	orderBy, orderByArgs := findAnomalyOrderBy(find)
+	args = append(args, orderByArgs...)
+	query := `
 		SELECT
 			id,
 			creator_id,
@@ -195,13 +307,24 @@ func findAnomalyList(ctx context.Context, tx *Tx, find *api.AnomalyFind) (_ []*a
 			updated_ts,
 			instance_id,
 			database_id,
-			`+"`type`,"+`
-			payload
+			` + "`type`," + `
+			payload,
+			last_seen_ts,
+			archive_reason
 		FROM anomaly
-		WHERE `+strings.Join(where, " AND ")+`
-		`,
-		args...,
-	)
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY ` + orderBy
+	if v := find.Limit; v != nil {
+		query += fmt.Sprintf(" LIMIT %d", *v)
+	} else if find.Offset != nil {
+		// SQLite requires LIMIT for OFFSET, a negative LIMIT means no limit.
+		query += " LIMIT -1"
+	}
+	if v := find.Offset; v != nil {
+		query += fmt.Sprintf(" OFFSET %d", *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, FormatError(err)
 	}
@@ -222,6 +345,8 @@ func findAnomalyList(ctx context.Context, tx *Tx, find *api.AnomalyFind) (_ []*a
 			&databaseID,
 			&anomaly.Type,
 			&anomaly.Payload,
+			&anomaly.LastSeenTs,
+			&anomaly.ArchiveReason,
 		); err != nil {
 			return nil, FormatError(err)
 		}
@@ -255,6 +380,8 @@ func patchAnomaly(ctx context.Context, tx *Tx, patch *anomalyPatch) (*api.Anomal
 	// Build UPDATE clause.
 	set, args := []string{"updater_id = ?"}, []interface{}{patch.UpdaterID}
 	set, args = append(set, "payload = ?"), append(args, patch.Payload)
+	// Only bump the last seen time, created_ts stays as the first time the anomaly is found.
+	set = append(set, "last_seen_ts = strftime('%s', 'now')")
 	args = append(args, patch.ID)
 
 	// Execute update query with RETURNING.
@@ -262,7 +389,7 @@ func patchAnomaly(ctx context.Context, tx *Tx, patch *anomalyPatch) (*api.Anomal
 		UPDATE anomaly
 		SET `+strings.Join(set, ", ")+`
 		WHERE id = ?
-		RETURNING id, creator_id, created_ts, updater_id, updated_ts, instance_id, database_id, `+"`type`"+`, payload
+		RETURNING id, creator_id, created_ts, updater_id, updated_ts, instance_id, database_id, `+"`type`"+`, payload, last_seen_ts, archive_reason
 	`,
 		args...,
 	)
@@ -289,6 +416,8 @@ func patchAnomaly(ctx context.Context, tx *Tx, patch *anomalyPatch) (*api.Anomal
 		&anomaly.DatabaseID,
 		&anomaly.Type,
 		&anomaly.Payload,
+		&anomaly.LastSeenTs,
+		&anomaly.ArchiveReason,
 	); err != nil {
 		return nil, FormatError(err)
 	}
@@ -312,10 +441,12 @@ func archiveAnomaly(ctx context.Context, tx *Tx, archive *api.AnomalyArchive) er
 	// Remove row from database.
 	if archive.InstanceID != nil {
 		result, err := tx.ExecContext(ctx,
-			`UPDATE anomaly SET row_status = ? WHERE instance_id = ? AND database_id IS NULL AND type = ?`,
+			`UPDATE anomaly SET row_status = ?, archive_reason = ? WHERE instance_id = ? AND database_id IS NULL AND type = ? AND row_status = ?`,
 			api.Archived,
+			archive.Reason,
 			*archive.InstanceID,
 			archive.Type,
+			api.Normal,
 		)
 		if err != nil {
 			return FormatError(err)
@@ -327,10 +458,12 @@ func archiveAnomaly(ctx context.Context, tx *Tx, archive *api.AnomalyArchive) er
 		}
 	} else if archive.DatabaseID != nil {
 		result, err := tx.ExecContext(ctx,
-			`UPDATE anomaly SET row_status = ? WHERE database_id = ? AND type = ?`,
+			`UPDATE anomaly SET row_status = ?, archive_reason = ? WHERE database_id = ? AND type = ? AND row_status = ?`,
 			api.Archived,
+			archive.Reason,
 			*archive.DatabaseID,
 			archive.Type,
+			api.Normal,
 		)
 		if err != nil {
 			return FormatError(err)
@@ -344,3 +477,24 @@ func archiveAnomaly(ctx context.Context, tx *Tx, archive *api.AnomalyArchive) er
 
 	return nil
 }
+
+// archiveAnomaliesForDatabase archives all active anomalies of the database and returns the number of archived ones.
+func archiveAnomaliesForDatabase(ctx context.Context, tx *Tx, databaseID int) (int, error) {
+	result, err := tx.ExecContext(ctx,
+		`UPDATE anomaly SET row_status = ?, archive_reason = ? WHERE database_id = ? AND row_status = ?`,
+		api.Archived,
+		api.AnomalyArchiveReasonDatabaseNotFound,
+		databaseID,
+		api.Normal,
+	)
+	if err != nil {
+		return 0, FormatError(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, FormatError(err)
+	}
+
+	return int(rows), nil
+}
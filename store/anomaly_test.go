@@ -0,0 +1,395 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/bytebase/bytebase/api"
+	"github.com/bytebase/bytebase/common"
+	"go.uber.org/zap"
+
+	// Register the sqlite3 driver, which is otherwise registered by the server binary.
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestUpsertActiveAnomalyTimestamp(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(zap.NewNop(), filepath.Join(t.TempDir(), "bytebase_test.db"), "seed/test", true /* forceResetSeed */, false /* readonly */, "test")
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Db.Close()
+	s := NewAnomalyService(zap.NewNop(), db)
+
+	// Instance 6004 and database 7014 are from the test seed.
+	databaseID := 7014
+	upsert := &api.AnomalyUpsert{
+		CreatorID:  api.SystemBotID,
+		InstanceID: 6004,
+		DatabaseID: &databaseID,
+		Type:       api.AnomalyDatabaseTableBloat,
+		Payload:    `{"table":"t","percentage":40}`,
+	}
+	anomaly, created, err := s.UpsertActiveAnomaly(ctx, upsert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Fatalf("expect the anomaly to be created")
+	}
+	if anomaly.LastSeenTs != anomaly.CreatedTs {
+		t.Errorf("LastSeenTs = %d, want CreatedTs %d", anomaly.LastSeenTs, anomaly.CreatedTs)
+	}
+
+	// Pretend the anomaly was found an hour ago, since the timestamps are in seconds.
+	const seenTs = 1000
+	if _, err := db.Db.ExecContext(ctx, `UPDATE anomaly SET created_ts = ?, last_seen_ts = ? WHERE id = ?`, seenTs, seenTs, anomaly.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	upsert.Payload = `{"table":"t","percentage":50}`
+	anomaly, created, err = s.UpsertActiveAnomaly(ctx, upsert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created {
+		t.Fatalf("expect the active anomaly to be updated")
+	}
+	if anomaly.CreatedTs != seenTs {
+		t.Errorf("CreatedTs = %d, want %d", anomaly.CreatedTs, seenTs)
+	}
+	if anomaly.LastSeenTs <= seenTs {
+		t.Errorf("LastSeenTs = %d, want later than %d", anomaly.LastSeenTs, seenTs)
+	}
+	if anomaly.Payload != upsert.Payload {
+		t.Errorf("Payload = %s, want %s", anomaly.Payload, upsert.Payload)
+	}
+
+	// Found again after being archived, the anomaly is reopened as a new one.
+	if err := s.ArchiveAnomaly(ctx, &api.AnomalyArchive{DatabaseID: &databaseID, Type: upsert.Type}); err != nil {
+		t.Fatal(err)
+	}
+	reopened, created, err := s.UpsertActiveAnomaly(ctx, upsert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Fatalf("expect the archived anomaly to be reopened as a new one")
+	}
+	if reopened.ID == anomaly.ID {
+		t.Errorf("ID = %d, want a new anomaly", reopened.ID)
+	}
+	if reopened.CreatedTs <= seenTs || reopened.LastSeenTs != reopened.CreatedTs {
+		t.Errorf("CreatedTs = %d, LastSeenTs = %d, want both the reopening time", reopened.CreatedTs, reopened.LastSeenTs)
+	}
+}
+
+func TestFindAnomalyListPagination(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(zap.NewNop(), filepath.Join(t.TempDir(), "bytebase_test.db"), "seed/test", true /* forceResetSeed */, false /* readonly */, "test")
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Db.Close()
+	s := NewAnomalyService(zap.NewNop(), db)
+
+	// Instance 6004 and database 7014 are from the test seed, which has anomalies of its own.
+	instanceID, databaseID := 6004, 7014
+	for _, anomalyType := range []api.AnomalyType{
+		api.AnomalyDatabaseTableBloat,
+		api.AnomalyDatabaseConnection,
+		api.AnomalyDatabaseReplicationLag,
+		api.AnomalyDatabaseScanTimeout,
+	} {
+		if _, _, err := s.UpsertActiveAnomaly(ctx, &api.AnomalyUpsert{
+			CreatorID:  api.SystemBotID,
+			InstanceID: instanceID,
+			DatabaseID: &databaseID,
+			Type:       anomalyType,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, sortBy := range []api.AnomalySortBy{"", api.AnomalySortByCreatedTs, api.AnomalySortBySeverity} {
+		find := &api.AnomalyFind{
+			InstanceID: &instanceID,
+			SortBy:     sortBy,
+		}
+		all, err := s.FindAnomalyList(ctx, find)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count, err := s.CountAnomaly(ctx, find)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != len(all) || count < 4 {
+			t.Fatalf("sort %q: count = %d, want %d and at least 4", sortBy, count, len(all))
+		}
+		for i := 1; i < len(all); i++ {
+			prev, cur := all[i-1], all[i]
+			var ordered bool
+			switch sortBy {
+			case api.AnomalySortByCreatedTs:
+				ordered = prev.CreatedTs > cur.CreatedTs || (prev.CreatedTs == cur.CreatedTs && prev.ID > cur.ID)
+			case api.AnomalySortBySeverity:
+				prevRank, curRank := anomalySeverityRank(prev.Severity), anomalySeverityRank(cur.Severity)
+				ordered = prevRank > curRank || (prevRank == curRank && (prev.CreatedTs > cur.CreatedTs || (prev.CreatedTs == cur.CreatedTs && prev.ID > cur.ID)))
+			default:
+				ordered = prev.ID < cur.ID
+			}
+			if !ordered {
+				t.Errorf("sort %q: anomaly %d is listed before anomaly %d", sortBy, prev.ID, cur.ID)
+			}
+		}
+
+		// The pages must add up to the full list.
+		limit := 3
+		var paged []*api.Anomaly
+		for offset := 0; offset < count; offset += limit {
+			offset := offset
+			page, err := s.FindAnomalyList(ctx, &api.AnomalyFind{
+				InstanceID: &instanceID,
+				SortBy:     sortBy,
+				Limit:      &limit,
+				Offset:     &offset,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			paged = append(paged, page...)
+		}
+		if len(paged) != len(all) {
+			t.Fatalf("sort %q: got %d anomalies from the pages, want %d", sortBy, len(paged), len(all))
+		}
+		for i := range all {
+			if paged[i].ID != all[i].ID {
+				t.Errorf("sort %q: anomaly %d of the pages is %d, want %d", sortBy, i, paged[i].ID, all[i].ID)
+			}
+		}
+	}
+}
+
+func TestArchiveInstanceAnomaly(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(zap.NewNop(), filepath.Join(t.TempDir(), "bytebase_test.db"), "seed/test", true /* forceResetSeed */, false /* readonly */, "test")
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Db.Close()
+	s := NewAnomalyService(zap.NewNop(), db)
+
+	// Instance 6004 and database 7014 are from the test seed.
+	instanceID, databaseID := 6004, 7014
+	anomalyType := api.AnomalyInstanceDiskSpaceLow
+	for _, upsert := range []*api.AnomalyUpsert{
+		{CreatorID: api.SystemBotID, InstanceID: instanceID, Type: anomalyType},
+		{CreatorID: api.SystemBotID, InstanceID: instanceID, DatabaseID: &databaseID, Type: anomalyType},
+	} {
+		if _, created, err := s.UpsertActiveAnomaly(ctx, upsert); err != nil {
+			t.Fatal(err)
+		} else if !created {
+			t.Fatalf("expect the instance and the database anomaly to be created separately")
+		}
+	}
+
+	archive := &api.AnomalyArchive{InstanceID: &instanceID, Type: anomalyType, Reason: api.AnomalyArchiveReasonBelowThreshold}
+	if err := s.ArchiveAnomaly(ctx, archive); err != nil {
+		t.Fatal(err)
+	}
+	// Only active anomalies are archived.
+	if err := s.ArchiveAnomaly(ctx, archive); common.ErrorCode(err) != common.NotFound {
+		t.Errorf("archive again returns %v, want NotFound", err)
+	}
+
+	status := api.Normal
+	list, err := s.FindAnomalyList(ctx, &api.AnomalyFind{
+		RowStatus:  &status,
+		InstanceID: &instanceID,
+		Type:       &anomalyType,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].DatabaseID == nil || *list[0].DatabaseID != databaseID {
+		t.Errorf("expect only the database anomaly to stay active, got %d anomalies", len(list))
+	}
+	if len(list) == 1 && list[0].ArchiveReason != "" {
+		t.Errorf("ArchiveReason = %q for the active anomaly, want empty", list[0].ArchiveReason)
+	}
+
+	// The archive reason is recorded on the archived anomaly.
+	status = api.Archived
+	list, err = s.FindAnomalyList(ctx, &api.AnomalyFind{
+		RowStatus:    &status,
+		InstanceID:   &instanceID,
+		Type:         &anomalyType,
+		InstanceOnly: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ArchiveReason != api.AnomalyArchiveReasonBelowThreshold {
+		t.Errorf("expect the archived anomaly with reason %q, got %v", api.AnomalyArchiveReasonBelowThreshold, list)
+	}
+}
+
+func TestFindAnomalyListSeverity(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(zap.NewNop(), filepath.Join(t.TempDir(), "bytebase_test.db"), "seed/test", true /* forceResetSeed */, false /* readonly */, "test")
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Db.Close()
+	s := NewAnomalyService(zap.NewNop(), db)
+
+	// Instance 6004 and database 7014 are from the test seed.
+	instanceID, databaseID := 6004, 7014
+	for _, anomalyType := range []api.AnomalyType{
+		api.AnomalyDatabaseTableBloat,
+		api.AnomalyDatabaseReplicationLag,
+		api.AnomalyDatabaseConnection,
+	} {
+		if _, _, err := s.UpsertActiveAnomaly(ctx, &api.AnomalyUpsert{
+			CreatorID:  api.SystemBotID,
+			InstanceID: instanceID,
+			DatabaseID: &databaseID,
+			Type:       anomalyType,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, severity := range []api.AnomalySeverity{api.AnomalySeverityMedium, api.AnomalySeverityHigh, api.AnomalySeverityCritical} {
+		severity := severity
+		list, err := s.FindAnomalyList(ctx, &api.AnomalyFind{
+			InstanceID: &instanceID,
+			Severity:   &severity,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) == 0 {
+			t.Errorf("expect anomalies of severity %s", severity)
+		}
+		for _, anomaly := range list {
+			if anomaly.Severity != severity {
+				t.Errorf("severity %s: got anomaly %d of type %s with severity %s", severity, anomaly.ID, anomaly.Type, anomaly.Severity)
+			}
+		}
+	}
+}
+
+func TestFindAnomalyListEnvironment(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(zap.NewNop(), filepath.Join(t.TempDir(), "bytebase_test.db"), "seed/test", true /* forceResetSeed */, false /* readonly */, "test")
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Db.Close()
+	s := NewAnomalyService(zap.NewNop(), db)
+
+	// Instance 6001 with database 7001 is in environment 5001, and instance 6004 with database 7014 is in environment 5004.
+	instanceMap := map[int]int{5001: 6001, 5004: 6004}
+	devDatabaseID, prodDatabaseID := 7001, 7014
+	for _, upsert := range []*api.AnomalyUpsert{
+		{CreatorID: api.SystemBotID, InstanceID: 6001, DatabaseID: &devDatabaseID, Type: api.AnomalyDatabaseSchemaDrift},
+		{CreatorID: api.SystemBotID, InstanceID: 6004, DatabaseID: &prodDatabaseID, Type: api.AnomalyDatabaseSchemaDrift},
+	} {
+		if _, _, err := s.UpsertActiveAnomaly(ctx, upsert); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for environmentID, instanceID := range instanceMap {
+		environmentID := environmentID
+		list, err := s.FindAnomalyList(ctx, &api.AnomalyFind{EnvironmentID: &environmentID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) == 0 {
+			t.Errorf("expect anomalies of environment %d", environmentID)
+		}
+		for _, anomaly := range list {
+			if anomaly.InstanceID != instanceID {
+				t.Errorf("anomaly of instance %d is found in environment %d, want instance %d", anomaly.InstanceID, environmentID, instanceID)
+			}
+		}
+	}
+}
+
+func TestArchiveAnomaliesForDatabase(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(zap.NewNop(), filepath.Join(t.TempDir(), "bytebase_test.db"), "seed/test", true /* forceResetSeed */, false /* readonly */, "test")
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Db.Close()
+	s := NewAnomalyService(zap.NewNop(), db)
+
+	// Instance 6004 and database 7014 are from the test seed.
+	instanceID, databaseID := 6004, 7014
+	for _, upsert := range []*api.AnomalyUpsert{
+		{CreatorID: api.SystemBotID, InstanceID: instanceID, Type: api.AnomalyInstanceDiskSpaceLow},
+		{CreatorID: api.SystemBotID, InstanceID: instanceID, DatabaseID: &databaseID, Type: api.AnomalyDatabaseSchemaDrift},
+		{CreatorID: api.SystemBotID, InstanceID: instanceID, DatabaseID: &databaseID, Type: api.AnomalyDatabaseBackupMissing},
+		{CreatorID: api.SystemBotID, InstanceID: instanceID, DatabaseID: &databaseID, Type: api.AnomalyDatabaseTableBloat},
+	} {
+		if _, _, err := s.UpsertActiveAnomaly(ctx, upsert); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The anomaly archived already is not counted again.
+	if err := s.ArchiveAnomaly(ctx, &api.AnomalyArchive{DatabaseID: &databaseID, Type: api.AnomalyDatabaseTableBloat}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Including the anomalies of the database from the test seed.
+	status := api.Normal
+	activeCount, err := s.CountAnomaly(ctx, &api.AnomalyFind{RowStatus: &status, DatabaseID: &databaseID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if activeCount < 2 {
+		t.Fatalf("found %d active anomalies for the database, want at least 2", activeCount)
+	}
+
+	count, err := s.ArchiveAnomaliesForDatabase(ctx, databaseID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != activeCount {
+		t.Errorf("archived %d anomalies, want %d", count, activeCount)
+	}
+	if count, err := s.ArchiveAnomaliesForDatabase(ctx, databaseID); err != nil || count != 0 {
+		t.Errorf("archive again returns (%d, %v), want (0, nil)", count, err)
+	}
+
+	list, err := s.FindAnomalyList(ctx, &api.AnomalyFind{RowStatus: &status, InstanceID: &instanceID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	instanceOnly := 0
+	for _, anomaly := range list {
+		if anomaly.DatabaseID == nil {
+			instanceOnly++
+		} else if *anomaly.DatabaseID == databaseID {
+			t.Errorf("expect the anomaly %s of the database to be archived", anomaly.Type)
+		}
+	}
+	if instanceOnly == 0 {
+		t.Errorf("expect the instance anomaly to stay active")
+	}
+
+	status = api.Archived
+	schemaDrift := api.AnomalyDatabaseSchemaDrift
+	list, err = s.FindAnomalyList(ctx, &api.AnomalyFind{RowStatus: &status, DatabaseID: &databaseID, Type: &schemaDrift})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ArchiveReason != api.AnomalyArchiveReasonDatabaseNotFound {
+		t.Errorf("expect the archived anomaly with reason %q, got %v", api.AnomalyArchiveReasonDatabaseNotFound, list)
+	}
+}
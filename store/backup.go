@@ -106,6 +106,26 @@ func (s *BackupService) PatchBackup(ctx context.Context, patch *api.BackupPatch)
 	return backup, nil
 }
 
+// DeleteBackup deletes an existing backup by ID.
+// Returns ENOTFOUND if backup does not exist.
+func (s *BackupService) DeleteBackup(ctx context.Context, delete *api.BackupDelete) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return FormatError(err)
+	}
+	defer tx.Rollback()
+
+	if err := s.deleteBackup(ctx, tx, delete); err != nil {
+		return FormatError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
 // createBackup creates a new backup.
 func (s *BackupService) createBackup(ctx context.Context, tx *Tx, create *api.BackupCreate) (*api.Backup, error) {
 	// Insert row into backup.
@@ -118,11 +138,12 @@ func (s *BackupService) createBackup(ctx context.Context, tx *Tx, create *api.Ba
 			`+"`status`,"+`
 			`+"`type`,"+`
 			storage_backend,
+			compression,
 			migration_history_version,
 			path
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-		RETURNING id, creator_id, created_ts, updater_id, updated_ts, database_id, name, `+"`status`,"+` `+"`type`, storage_backend, migration_history_version, path, comment"+`
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id, creator_id, created_ts, updater_id, updated_ts, database_id, name, `+"`status`,"+` `+"`type`, storage_backend, compression, migration_history_version, path, comment, checksum, size_bytes"+`
 	`,
 		create.CreatorID,
 		create.CreatorID,
@@ -131,6 +152,7 @@ func (s *BackupService) createBackup(ctx context.Context, tx *Tx, create *api.Ba
 		create.Status,
 		create.Type,
 		create.StorageBackend,
+		create.Compression,
 		create.MigrationHistoryVersion,
 		create.Path,
 	)
@@ -153,9 +175,12 @@ func (s *BackupService) createBackup(ctx context.Context, tx *Tx, create *api.Ba
 		&backup.Status,
 		&backup.Type,
 		&backup.StorageBackend,
+		&backup.Compression,
 		&backup.MigrationHistoryVersion,
 		&backup.Path,
 		&backup.Comment,
+		&backup.Checksum,
+		&backup.SizeBytes,
 	); err != nil {
 		return nil, FormatError(err)
 	}
@@ -191,9 +216,12 @@ func (s *BackupService) findBackupList(ctx context.Context, tx *Tx, find *api.Ba
 			`+"`status`,"+`
 			`+"`type`,"+`
 			storage_backend,
+			compression,
 			migration_history_version,
 			path,
-			comment
+			comment,
+			checksum,
+			size_bytes
 		FROM backup
 		WHERE `+strings.Join(where, " AND ")+` ORDER BY updated_ts DESC`,
 		args...,
@@ -218,9 +246,12 @@ func (s *BackupService) findBackupList(ctx context.Context, tx *Tx, find *api.Ba
 			&backup.Status,
 			&backup.Type,
 			&backup.StorageBackend,
+			&backup.Compression,
 			&backup.MigrationHistoryVersion,
 			&backup.Path,
 			&backup.Comment,
+			&backup.Checksum,
+			&backup.SizeBytes,
 		); err != nil {
 			return nil, FormatError(err)
 		}
@@ -240,6 +271,12 @@ func (s *BackupService) patchBackup(ctx context.Context, tx *Tx, patch *api.Back
 	set, args := []string{"updater_id = ?"}, []interface{}{patch.UpdaterID}
 	set, args = append(set, "status = ?"), append(args, patch.Status)
 	set, args = append(set, "comment = ?"), append(args, patch.Comment)
+	if v := patch.Checksum; v != nil {
+		set, args = append(set, "checksum = ?"), append(args, *v)
+	}
+	if v := patch.SizeBytes; v != nil {
+		set, args = append(set, "size_bytes = ?"), append(args, *v)
+	}
 
 	args = append(args, patch.ID)
 
@@ -248,7 +285,7 @@ func (s *BackupService) patchBackup(ctx context.Context, tx *Tx, patch *api.Back
 		UPDATE backup
 		SET `+strings.Join(set, ", ")+`
 		WHERE id = ?
-		RETURNING id, creator_id, created_ts, updater_id, updated_ts, database_id, name, `+"`status`,"+` `+"`type`, storage_backend, migration_history_version, path, comment"+`
+		RETURNING id, creator_id, created_ts, updater_id, updated_ts, database_id, name, `+"`status`,"+` `+"`type`, storage_backend, compression, migration_history_version, path, comment, checksum, size_bytes"+`
 	`,
 		args...,
 	)
@@ -270,9 +307,12 @@ func (s *BackupService) patchBackup(ctx context.Context, tx *Tx, patch *api.Back
 			&backup.Status,
 			&backup.Type,
 			&backup.StorageBackend,
+			&backup.Compression,
 			&backup.MigrationHistoryVersion,
 			&backup.Path,
 			&backup.Comment,
+			&backup.Checksum,
+			&backup.SizeBytes,
 		); err != nil {
 			return nil, FormatError(err)
 		}
@@ -282,6 +322,22 @@ func (s *BackupService) patchBackup(ctx context.Context, tx *Tx, patch *api.Back
 	return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("backup ID not found: %d", patch.ID)}
 }
 
+// deleteBackup permanently deletes a backup by ID.
+func (s *BackupService) deleteBackup(ctx context.Context, tx *Tx, delete *api.BackupDelete) error {
+	// Remove row from database.
+	result, err := tx.ExecContext(ctx, `DELETE FROM backup WHERE id = ?`, delete.ID)
+	if err != nil {
+		return FormatError(err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return &common.Error{Code: common.NotFound, Err: fmt.Errorf("backup ID not found: %d", delete.ID)}
+	}
+
+	return nil
+}
+
 // FindBackupSetting finds the backup setting for a database.
 // Returns ENOTFOUND if no matching record.
 // Returns ECONFLICT if finding more than 1 matching records.
@@ -324,7 +380,9 @@ func (s *BackupService) findBackupSetting(ctx context.Context, tx *Tx, find *api
 			database_id,
 			enabled,
 			hour,
+			minute,
 			day_of_week,
+			day_of_month,
 			hook_url
 		FROM backup_setting
 		WHERE `+strings.Join(where, " AND "),
@@ -348,7 +406,9 @@ func (s *BackupService) findBackupSetting(ctx context.Context, tx *Tx, find *api
 			&backupSetting.DatabaseID,
 			&backupSetting.Enabled,
 			&backupSetting.Hour,
+			&backupSetting.Minute,
 			&backupSetting.DayOfWeek,
+			&backupSetting.DayOfMonth,
 			&backupSetting.HookURL,
 		); err != nil {
 			return nil, FormatError(err)
@@ -365,6 +425,12 @@ func (s *BackupService) findBackupSetting(ctx context.Context, tx *Tx, find *api
 
 // UpsertBackupSetting sets the backup settings for a database.
 func (s *BackupService) UpsertBackupSetting(ctx context.Context, upsert *api.BackupSettingUpsert) (*api.BackupSetting, error) {
+	if err := upsert.Validate(); err != nil {
+		return nil, err
+	}
+	if upsert.Enabled && upsert.Minute == -1 {
+		upsert.Minute = 0
+	}
 	backupPlanPolicy, err := s.policyService.GetBackupPlanPolicy(ctx, upsert.EnvironmentID)
 	if err != nil {
 		return nil, err
@@ -379,6 +445,9 @@ func (s *BackupService) UpsertBackupSetting(ctx context.Context, upsert *api.Bac
 			if upsert.DayOfWeek != -1 {
 				return nil, &common.Error{Code: common.Invalid, Err: fmt.Errorf("backup setting DayOfWeek should be unset for backup plan policy schedule %q", backupPlanPolicy.Schedule)}
 			}
+			if upsert.DayOfMonth != -1 {
+				return nil, &common.Error{Code: common.Invalid, Err: fmt.Errorf("backup setting DayOfMonth should be unset for backup plan policy schedule %q", backupPlanPolicy.Schedule)}
+			}
 		case api.BackupPlanPolicyScheduleWeekly:
 			if upsert.DayOfWeek == -1 {
 				return nil, &common.Error{Code: common.Invalid, Err: fmt.Errorf("backup setting DayOfWeek should be set for backup plan policy schedule %q", backupPlanPolicy.Schedule)}
@@ -414,23 +483,29 @@ func (s *BackupService) UpsertBackupSettingTx(ctx context.Context, tx *sql.Tx, u
 			database_id,
 			`+"`enabled`,"+`
 			hour,
+			minute,
 			day_of_week,
+			day_of_month,
 			hook_url
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(database_id) DO UPDATE SET
 				enabled = excluded.enabled,
 				hour = excluded.hour,
+				minute = excluded.minute,
 				day_of_week = excluded.day_of_week,
+				day_of_month = excluded.day_of_month,
 				hook_url = excluded.hook_url
-		RETURNING id, creator_id, created_ts, updater_id, updated_ts, database_id, `+"`enabled`,"+` `+"hour, day_of_week"+`, hook_url
+		RETURNING id, creator_id, created_ts, updater_id, updated_ts, database_id, `+"`enabled`,"+` `+"hour, minute, day_of_week, day_of_month"+`, hook_url
 		`,
 		upsert.UpdaterID,
 		upsert.UpdaterID,
 		upsert.DatabaseID,
 		upsert.Enabled,
 		upsert.Hour,
+		upsert.Minute,
 		upsert.DayOfWeek,
+		upsert.DayOfMonth,
 		upsert.HookURL,
 	)
 
@@ -450,7 +525,9 @@ func (s *BackupService) UpsertBackupSettingTx(ctx context.Context, tx *sql.Tx, u
 		&backupSetting.DatabaseID,
 		&backupSetting.Enabled,
 		&backupSetting.Hour,
+		&backupSetting.Minute,
 		&backupSetting.DayOfWeek,
+		&backupSetting.DayOfMonth,
 		&backupSetting.HookURL,
 	); err != nil {
 		return nil, FormatError(err)
@@ -477,11 +554,15 @@ func (s *BackupService) FindBackupSettingsMatch(ctx context.Context, match *api.
 			database_id,
 			enabled,
 			hour,
+			minute,
 			day_of_week,
+			day_of_month,
 			hook_url
 		FROM backup_setting
 		WHERE
 			enabled = 1
+			AND minute <= ?
+			AND (day_of_month = -1 OR day_of_month = ?)
 			AND (
 				(hour = ? AND day_of_week = ?)
 				OR
@@ -490,7 +571,7 @@ func (s *BackupService) FindBackupSettingsMatch(ctx context.Context, match *api.
 				(hour = -1 AND day_of_week = ?)
 			)
 		`,
-		match.Hour, match.DayOfWeek, match.Hour, match.DayOfWeek,
+		match.Minute, match.DayOfMonth, match.Hour, match.DayOfWeek, match.Hour, match.DayOfWeek,
 	)
 	if err != nil {
 		return nil, FormatError(err)
@@ -510,7 +591,9 @@ func (s *BackupService) FindBackupSettingsMatch(ctx context.Context, match *api.
 			&backupSetting.DatabaseID,
 			&backupSetting.Enabled,
 			&backupSetting.Hour,
+			&backupSetting.Minute,
 			&backupSetting.DayOfWeek,
+			&backupSetting.DayOfMonth,
 			&backupSetting.HookURL,
 		); err != nil {
 			return nil, FormatError(err)
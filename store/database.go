@@ -76,13 +76,19 @@ func (s *DatabaseService) CreateDatabaseTx(ctx context.Context, tx *sql.Tx, crea
 			DatabaseID: database.ID,
 			Enabled:    true,
 			Hour:       rand.Intn(24),
+			Minute:     rand.Intn(60),
 			HookURL:    "",
 		}
 		switch backupPlanPolicy.Schedule {
 		case api.BackupPlanPolicyScheduleDaily:
 			backupSettingUpsert.DayOfWeek = -1
+			backupSettingUpsert.DayOfMonth = -1
 		case api.BackupPlanPolicyScheduleWeekly:
 			backupSettingUpsert.DayOfWeek = rand.Intn(7)
+			backupSettingUpsert.DayOfMonth = -1
+		case api.BackupPlanPolicyScheduleMonthly:
+			backupSettingUpsert.DayOfWeek = -1
+			backupSettingUpsert.DayOfMonth = rand.Intn(28) + 1
 		}
 		if _, err := s.backupService.UpsertBackupSettingTx(ctx, tx, backupSettingUpsert); err != nil {
 			return nil, err
@@ -198,7 +204,7 @@ func (s *DatabaseService) createDatabase(ctx context.Context, tx *sql.Tx, create
 			last_successful_sync_ts
 		)
 		VALUES (?, ?, ?, ?, ?, ?, ?, 'OK', (strftime('%s', 'now')))
-		RETURNING id, creator_id, created_ts, updater_id, updated_ts, instance_id, project_id, name, character_set, collation, sync_status, last_successful_sync_ts
+		RETURNING id, creator_id, created_ts, updater_id, updated_ts, instance_id, project_id, name, character_set, collation, sync_status, last_successful_sync_ts, managed
 	`,
 		create.CreatorID,
 		create.CreatorID,
@@ -229,6 +235,7 @@ func (s *DatabaseService) createDatabase(ctx context.Context, tx *sql.Tx, create
 		&database.Collation,
 		&database.SyncStatus,
 		&database.LastSuccessfulSyncTs,
+		&database.Managed,
 	); err != nil {
 		return nil, FormatError(err)
 	}
@@ -254,6 +261,9 @@ func (s *DatabaseService) findDatabaseList(ctx context.Context, tx *Tx, find *ap
 	if !find.IncludeAllDatabase {
 		where = append(where, "name != '"+api.AllDatabaseName+"'")
 	}
+	if v := find.HasAnomalyType; v != nil {
+		where, args = append(where, "EXISTS (SELECT 1 FROM anomaly WHERE anomaly.database_id = db.id AND anomaly.row_status = ? AND anomaly.type = ?)"), append(args, api.Normal, *v)
+	}
 
 	rows, err := tx.QueryContext(ctx, `
 		SELECT
@@ -269,7 +279,8 @@ func (s *DatabaseService) findDatabaseList(ctx context.Context, tx *Tx, find *ap
 			character_set,
 			collation,
 			sync_status,
-			last_successful_sync_ts
+			last_successful_sync_ts,
+			managed
 		FROM db
 		WHERE `+strings.Join(where, " AND "),
 		args...,
@@ -298,6 +309,7 @@ func (s *DatabaseService) findDatabaseList(ctx context.Context, tx *Tx, find *ap
 			&database.Collation,
 			&database.SyncStatus,
 			&database.LastSuccessfulSyncTs,
+			&database.Managed,
 		); err != nil {
 			return nil, FormatError(err)
 		}
@@ -330,6 +342,9 @@ func (s *DatabaseService) patchDatabase(ctx context.Context, tx *Tx, patch *api.
 	if v := patch.LastSuccessfulSyncTs; v != nil {
 		set, args = append(set, "last_successful_sync_ts = ?"), append(args, *v)
 	}
+	if v := patch.Managed; v != nil {
+		set, args = append(set, "managed = ?"), append(args, *v)
+	}
 
 	args = append(args, patch.ID)
 
@@ -338,7 +353,7 @@ func (s *DatabaseService) patchDatabase(ctx context.Context, tx *Tx, patch *api.
 		UPDATE db
 		SET `+strings.Join(set, ", ")+`
 		WHERE id = ?
-		RETURNING id, creator_id, created_ts, updater_id, updated_ts, instance_id, project_id, source_backup_id, name, character_set, collation, sync_status, last_successful_sync_ts
+		RETURNING id, creator_id, created_ts, updater_id, updated_ts, instance_id, project_id, source_backup_id, name, character_set, collation, sync_status, last_successful_sync_ts, managed
 	`,
 		args...,
 	)
@@ -364,6 +379,7 @@ func (s *DatabaseService) patchDatabase(ctx context.Context, tx *Tx, patch *api.
 			&database.Collation,
 			&database.SyncStatus,
 			&database.LastSuccessfulSyncTs,
+			&database.Managed,
 		); err != nil {
 			return nil, FormatError(err)
 		}
@@ -172,6 +172,10 @@ func (s *InstanceService) PatchInstance(ctx context.Context, patch *api.Instance
 
 // createInstance creates a new instance.
 func createInstance(ctx context.Context, tx *Tx, create *api.InstanceCreate) (*api.Instance, error) {
+	enableAnomalyScan := true
+	if create.EnableAnomalyScan != nil {
+		enableAnomalyScan = *create.EnableAnomalyScan
+	}
 	// Insert row into database.
 	row, err := tx.QueryContext(ctx, `
 		INSERT INTO instance (
@@ -182,10 +186,14 @@ func createInstance(ctx context.Context, tx *Tx, create *api.InstanceCreate) (*a
 			engine,
 			external_link,
 			host,
-			port
+			port,
+			replica,
+			enable_anomaly_scan,
+			service_name,
+			sid
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-		RETURNING id, row_status, creator_id, created_ts, updater_id, updated_ts, environment_id, name, engine, engine_version, external_link, host, port
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id, row_status, creator_id, created_ts, updater_id, updated_ts, environment_id, name, engine, engine_version, external_link, host, port, replica, enable_anomaly_scan, service_name, sid
 	`,
 		create.CreatorID,
 		create.CreatorID,
@@ -195,6 +203,10 @@ func createInstance(ctx context.Context, tx *Tx, create *api.InstanceCreate) (*a
 		create.ExternalLink,
 		create.Host,
 		create.Port,
+		create.Replica,
+		enableAnomalyScan,
+		create.ServiceName,
+		create.SID,
 	)
 
 	if err != nil {
@@ -218,6 +230,10 @@ func createInstance(ctx context.Context, tx *Tx, create *api.InstanceCreate) (*a
 		&instance.ExternalLink,
 		&instance.Host,
 		&instance.Port,
+		&instance.Replica,
+		&instance.EnableAnomalyScan,
+		&instance.ServiceName,
+		&instance.SID,
 	); err != nil {
 		return nil, FormatError(err)
 	}
@@ -234,6 +250,9 @@ func findInstanceList(ctx context.Context, tx *Tx, find *api.InstanceFind) (_ []
 	if v := find.RowStatus; v != nil {
 		where, args = append(where, "row_status = ?"), append(args, *v)
 	}
+	if v := find.EnableAnomalyScan; v != nil {
+		where, args = append(where, "enable_anomaly_scan = ?"), append(args, *v)
+	}
 
 	rows, err := tx.QueryContext(ctx, `
 		SELECT
@@ -249,7 +268,11 @@ func findInstanceList(ctx context.Context, tx *Tx, find *api.InstanceFind) (_ []
 			engine_version,
 			external_link,
 			host,
-			port
+			port,
+			replica,
+			enable_anomaly_scan,
+			service_name,
+			sid
 		FROM instance
 		WHERE `+strings.Join(where, " AND "),
 		args...,
@@ -277,6 +300,10 @@ func findInstanceList(ctx context.Context, tx *Tx, find *api.InstanceFind) (_ []
 			&instance.ExternalLink,
 			&instance.Host,
 			&instance.Port,
+			&instance.Replica,
+			&instance.EnableAnomalyScan,
+			&instance.ServiceName,
+			&instance.SID,
 		); err != nil {
 			return nil, FormatError(err)
 		}
@@ -312,6 +339,18 @@ func patchInstance(ctx context.Context, tx *Tx, patch *api.InstancePatch) (*api.
 	if v := patch.Port; v != nil {
 		set, args = append(set, "port = ?"), append(args, *v)
 	}
+	if v := patch.Replica; v != nil {
+		set, args = append(set, "replica = ?"), append(args, *v)
+	}
+	if v := patch.EnableAnomalyScan; v != nil {
+		set, args = append(set, "enable_anomaly_scan = ?"), append(args, *v)
+	}
+	if v := patch.ServiceName; v != nil {
+		set, args = append(set, "service_name = ?"), append(args, *v)
+	}
+	if v := patch.SID; v != nil {
+		set, args = append(set, "sid = ?"), append(args, *v)
+	}
 
 	args = append(args, patch.ID)
 
@@ -320,7 +359,7 @@ func patchInstance(ctx context.Context, tx *Tx, patch *api.InstancePatch) (*api.
 		UPDATE instance
 		SET `+strings.Join(set, ", ")+`
 		WHERE id = ?
-		RETURNING id, row_status, creator_id, created_ts, updater_id, updated_ts, environment_id, name, engine, engine_version, external_link, host, port
+		RETURNING id, row_status, creator_id, created_ts, updater_id, updated_ts, environment_id, name, engine, engine_version, external_link, host, port, replica, enable_anomaly_scan, service_name, sid
 	`,
 		args...,
 	)
@@ -345,6 +384,10 @@ func patchInstance(ctx context.Context, tx *Tx, patch *api.InstancePatch) (*api.
 			&instance.ExternalLink,
 			&instance.Host,
 			&instance.Port,
+			&instance.Replica,
+			&instance.EnableAnomalyScan,
+			&instance.ServiceName,
+			&instance.SID,
 		); err != nil {
 			return nil, FormatError(err)
 		}
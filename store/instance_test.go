@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/bytebase/bytebase/api"
+	"go.uber.org/zap"
+)
+
+func TestFindInstanceListEnableAnomalyScan(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(zap.NewNop(), filepath.Join(t.TempDir(), "bytebase_test.db"), "seed/test", true /* forceResetSeed */, false /* readonly */, "test")
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Db.Close()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	// The instances from the test seed are scanned by default.
+	enabled, disabled := true, false
+	list, err := findInstanceList(ctx, tx, &api.InstanceFind{EnableAnomalyScan: &disabled})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("found %d instances with the anomaly scan disabled, want 0", len(list))
+	}
+
+	// Instance 6001 is from the test seed.
+	instance, err := patchInstance(ctx, tx, &api.InstancePatch{
+		ID:                6001,
+		UpdaterID:         api.SystemBotID,
+		EnableAnomalyScan: &disabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.EnableAnomalyScan {
+		t.Errorf("EnableAnomalyScan = true after patch, want false")
+	}
+	list, err = findInstanceList(ctx, tx, &api.InstanceFind{EnableAnomalyScan: &disabled})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != 6001 {
+		t.Errorf("found %v with the anomaly scan disabled, want instance 6001", list)
+	}
+	list, err = findInstanceList(ctx, tx, &api.InstanceFind{EnableAnomalyScan: &enabled})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, instance := range list {
+		if instance.ID == 6001 {
+			t.Errorf("found instance 6001 with the anomaly scan enabled")
+		}
+	}
+}
+
+func TestCreateInstanceEngine(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(zap.NewNop(), filepath.Join(t.TempDir(), "bytebase_test.db"), "seed/test", true /* forceResetSeed */, false /* readonly */, "test")
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Db.Close()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	// The engines added after the initial schema are allowed by the engine CHECK constraint.
+	// Environment 5001 is from the test seed.
+	for _, create := range []*api.InstanceCreate{
+		{CreatorID: api.SystemBotID, EnvironmentID: 5001, Name: "MongoDB", Engine: "MONGODB", Host: "127.0.0.1", Port: "27017"},
+		{CreatorID: api.SystemBotID, EnvironmentID: 5001, Name: "SQL Server", Engine: "MSSQL", Host: "127.0.0.1", Port: "1433"},
+	} {
+		instance, err := createInstance(ctx, tx, create)
+		if err != nil {
+			t.Fatalf("failed to create %s instance: %v", create.Engine, err)
+		}
+		if instance.Engine != create.Engine {
+			t.Errorf("Engine = %s, want %s", instance.Engine, create.Engine)
+		}
+	}
+}
+
+func TestCreateInstanceOracle(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(zap.NewNop(), filepath.Join(t.TempDir(), "bytebase_test.db"), "seed/test", true /* forceResetSeed */, false /* readonly */, "test")
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Db.Close()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	// Environment 5001 is from the test seed.
+	instance, err := createInstance(ctx, tx, &api.InstanceCreate{
+		CreatorID:     api.SystemBotID,
+		EnvironmentID: 5001,
+		Name:          "Oracle",
+		Engine:        "ORACLE",
+		Host:          "127.0.0.1",
+		Port:          "1521",
+		ServiceName:   "ORCLPDB1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.ServiceName != "ORCLPDB1" || instance.SID != "" {
+		t.Errorf("ServiceName, SID = %q, %q, want %q, %q", instance.ServiceName, instance.SID, "ORCLPDB1", "")
+	}
+	// The instances are numbered after the instances from the test seed.
+	if instance.ID <= 6001 {
+		t.Errorf("ID = %d, want greater than 6001", instance.ID)
+	}
+
+	serviceName, sid := "", "ORCL"
+	instance, err = patchInstance(ctx, tx, &api.InstancePatch{
+		ID:          instance.ID,
+		UpdaterID:   api.SystemBotID,
+		ServiceName: &serviceName,
+		SID:         &sid,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.ServiceName != "" || instance.SID != "ORCL" {
+		t.Errorf("ServiceName, SID = %q, %q after patch, want %q, %q", instance.ServiceName, instance.SID, "", "ORCL")
+	}
+}
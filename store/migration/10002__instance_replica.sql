@@ -0,0 +1,4 @@
+PRAGMA user_version = 10002;
+
+-- replica is whether the instance is a read replica, the replication lag is only checked for replicas.
+ALTER TABLE instance ADD COLUMN replica INTEGER NOT NULL CHECK (replica IN (0, 1)) DEFAULT 0;
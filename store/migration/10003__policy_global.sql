@@ -0,0 +1,74 @@
+PRAGMA user_version = 10003;
+
+-- Recreate the policy table without the foreign key on environment_id, so that environment_id 0 can hold
+-- the global policy which applies to the environments without their own policy.
+CREATE TABLE policy_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    row_status TEXT NOT NULL CHECK (
+        row_status IN ('NORMAL', 'ARCHIVED')
+    ) DEFAULT 'NORMAL',
+    creator_id INTEGER NOT NULL REFERENCES principal (id),
+    created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    updater_id INTEGER NOT NULL REFERENCES principal (id),
+    updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    -- environment_id is 0 for the global policy.
+    environment_id INTEGER NOT NULL,
+    `type` TEXT NOT NULL CHECK (`type` LIKE 'bb.policy.%'),
+    payload TEXT NOT NULL
+);
+
+INSERT INTO
+    sqlite_sequence (name, seq)
+SELECT
+    'policy_new',
+    seq
+FROM
+    sqlite_sequence
+WHERE
+    name = 'policy';
+
+INSERT INTO
+    policy_new (
+        id,
+        row_status,
+        creator_id,
+        created_ts,
+        updater_id,
+        updated_ts,
+        environment_id,
+        `type`,
+        payload
+    )
+SELECT
+    id,
+    row_status,
+    creator_id,
+    created_ts,
+    updater_id,
+    updated_ts,
+    environment_id,
+    `type`,
+    payload
+FROM
+    policy;
+
+DROP TABLE policy;
+
+ALTER TABLE policy_new RENAME TO policy;
+
+CREATE INDEX idx_policy_environment_id ON policy(environment_id);
+
+CREATE UNIQUE INDEX idx_policy_environment_id_type ON policy(environment_id, type);
+
+CREATE TRIGGER IF NOT EXISTS `trigger_update_policy_modification_time`
+AFTER
+UPDATE
+    ON `policy` FOR EACH ROW BEGIN
+UPDATE
+    `policy`
+SET
+    updated_ts = (strftime('%s', 'now'))
+WHERE
+    rowid = old.rowid;
+
+END;
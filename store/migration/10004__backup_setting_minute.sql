@@ -0,0 +1,7 @@
+PRAGMA user_version = 10004;
+
+-- minute is the minute of the hour to take the automatic backup, it can be -1 which is unset like hour.
+ALTER TABLE backup_setting ADD COLUMN minute INTEGER NOT NULL CHECK (
+    -1 <= minute
+    AND minute < 60
+) DEFAULT 0;
@@ -0,0 +1,25 @@
+PRAGMA user_version = 10005;
+
+-- last_seen_ts is the last time the scanner found the anomaly, while created_ts is the first time.
+ALTER TABLE anomaly ADD COLUMN last_seen_ts BIGINT NOT NULL DEFAULT 0;
+
+-- Backfill from updated_ts which is bumped by every upsert, the trigger is dropped meanwhile to keep updated_ts intact.
+DROP TRIGGER IF EXISTS `trigger_update_anomaly_modification_time`;
+
+UPDATE
+    anomaly
+SET
+    last_seen_ts = updated_ts;
+
+CREATE TRIGGER IF NOT EXISTS `trigger_update_anomaly_modification_time`
+AFTER
+UPDATE
+    ON `anomaly` FOR EACH ROW BEGIN
+UPDATE
+    `anomaly`
+SET
+    updated_ts = (strftime('%s', 'now'))
+WHERE
+    rowid = old.rowid;
+
+END;
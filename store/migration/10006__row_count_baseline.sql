@@ -0,0 +1,12 @@
+PRAGMA user_version = 10006;
+
+-- row_count_baseline stores the table row counts observed by the anomaly scanner in the previous round,
+-- which the row count growth anomaly is computed against.
+-- There is no id column since the rows of a database are replaced every round.
+CREATE TABLE row_count_baseline (
+    database_id INTEGER NOT NULL REFERENCES db (id) ON DELETE CASCADE,
+    table_name TEXT NOT NULL,
+    row_count BIGINT NOT NULL,
+    observed_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    PRIMARY KEY (database_id, table_name)
+);
@@ -0,0 +1,4 @@
+PRAGMA user_version = 10008;
+
+-- compression is the codec the backup file is compressed with, the backups taken before are uncompressed.
+ALTER TABLE backup ADD COLUMN compression TEXT NOT NULL CHECK (compression IN ('NONE', 'GZIP', 'ZSTD')) DEFAULT 'NONE';
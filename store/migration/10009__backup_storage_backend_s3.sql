@@ -0,0 +1,147 @@
+PRAGMA user_version = 10009;
+
+-- Recreate the backup table to allow the S3 storage backend in the CHECK constraint of storage_backend.
+CREATE TABLE backup_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    row_status TEXT NOT NULL CHECK (
+        row_status IN ('NORMAL', 'ARCHIVED')
+    ) DEFAULT 'NORMAL',
+    creator_id INTEGER NOT NULL REFERENCES principal (id),
+    created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    updater_id INTEGER NOT NULL REFERENCES principal (id),
+    updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    database_id INTEGER NOT NULL REFERENCES db (id),
+    name TEXT NOT NULL,
+    `status` TEXT NOT NULL CHECK (`status` IN ('PENDING_CREATE', 'DONE', 'FAILED')),
+    `type` TEXT NOT NULL CHECK (`type` IN ('MANUAL', 'AUTOMATIC')),
+    storage_backend TEXT NOT NULL CHECK (storage_backend IN ('LOCAL', 'S3')),
+    migration_history_version TEXT NOT NULL,
+    -- path is relative to the data directory for LOCAL, and is the object URL s3://<bucket>/<key> for S3.
+    path TEXT NOT NULL,
+    `comment` TEXT NOT NULL DEFAULT '',
+    compression TEXT NOT NULL CHECK (compression IN ('NONE', 'GZIP', 'ZSTD')) DEFAULT 'NONE',
+    UNIQUE(database_id, name)
+);
+
+INSERT INTO
+    sqlite_sequence (name, seq)
+SELECT
+    'backup_new',
+    seq
+FROM
+    sqlite_sequence
+WHERE
+    name = 'backup';
+
+INSERT INTO
+    backup_new (
+        id,
+        row_status,
+        creator_id,
+        created_ts,
+        updater_id,
+        updated_ts,
+        database_id,
+        name,
+        `status`,
+        `type`,
+        storage_backend,
+        migration_history_version,
+        path,
+        `comment`,
+        compression
+    )
+SELECT
+    id,
+    row_status,
+    creator_id,
+    created_ts,
+    updater_id,
+    updated_ts,
+    database_id,
+    name,
+    `status`,
+    `type`,
+    storage_backend,
+    migration_history_version,
+    path,
+    `comment`,
+    compression
+FROM
+    backup;
+
+-- Dropping the backup table sets db.source_backup_id to NULL by its ON DELETE SET NULL foreign key,
+-- so the source backups of the databases are kept aside and put back afterwards.
+CREATE TEMP TABLE db_source_backup AS
+SELECT
+    id,
+    source_backup_id,
+    updated_ts
+FROM
+    db
+WHERE
+    source_backup_id IS NOT NULL;
+
+DROP TABLE backup;
+
+ALTER TABLE backup_new RENAME TO backup;
+
+CREATE INDEX idx_backup_database_id ON backup(database_id);
+
+CREATE TRIGGER IF NOT EXISTS `trigger_update_backup_modification_time`
+AFTER
+UPDATE
+    ON `backup` FOR EACH ROW BEGIN
+UPDATE
+    `backup`
+SET
+    updated_ts = (strftime('%s', 'now'))
+WHERE
+    rowid = old.rowid;
+
+END;
+
+-- Put back the source backups without touching the modification time of the databases.
+DROP TRIGGER `trigger_update_db_modification_time`;
+
+UPDATE
+    db
+SET
+    source_backup_id = (
+        SELECT
+            source_backup_id
+        FROM
+            db_source_backup
+        WHERE
+            db_source_backup.id = db.id
+    ),
+    updated_ts = (
+        SELECT
+            updated_ts
+        FROM
+            db_source_backup
+        WHERE
+            db_source_backup.id = db.id
+    )
+WHERE
+    id IN (
+        SELECT
+            id
+        FROM
+            db_source_backup
+    );
+
+CREATE TRIGGER IF NOT EXISTS `trigger_update_db_modification_time`
+AFTER
+UPDATE
+    ON `db` FOR EACH ROW BEGIN
+UPDATE
+    `db`
+SET
+    updated_ts = (strftime('%s', 'now'))
+WHERE
+    rowid = old.rowid;
+
+END;
+
+DROP TABLE db_source_backup;
@@ -0,0 +1,4 @@
+PRAGMA user_version = 10010;
+
+-- checksum is the hex SHA-256 of the stored backup file, it's empty for the backups taken before.
+ALTER TABLE backup ADD COLUMN checksum TEXT NOT NULL DEFAULT '';
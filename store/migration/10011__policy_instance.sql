@@ -0,0 +1,9 @@
+PRAGMA user_version = 10011;
+
+-- instance_id is the instance the policy overrides the environment policy for, it's 0 for the environment policy.
+-- It's 0 rather than NULL so that the unique index still applies to the environment policies.
+ALTER TABLE policy ADD COLUMN instance_id INTEGER NOT NULL DEFAULT 0;
+
+DROP INDEX idx_policy_environment_id_type;
+
+CREATE UNIQUE INDEX idx_policy_environment_id_instance_id_type ON policy(environment_id, instance_id, type);
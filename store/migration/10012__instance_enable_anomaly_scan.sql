@@ -0,0 +1,4 @@
+PRAGMA user_version = 10012;
+
+-- enable_anomaly_scan is whether the anomaly scanner scans the instance, e.g. it's disabled for the instances intentionally offline.
+ALTER TABLE instance ADD COLUMN enable_anomaly_scan INTEGER NOT NULL CHECK (enable_anomaly_scan IN (0, 1)) DEFAULT 1;
@@ -0,0 +1,4 @@
+PRAGMA user_version = 10013;
+
+-- archive_reason is why the anomaly is archived, e.g. the schema matching the baseline again, empty for the active anomaly.
+ALTER TABLE anomaly ADD COLUMN archive_reason TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+PRAGMA user_version = 10015;
+
+-- day_of_month can be -1 which is unset, otherwise it's the day of the month to take the monthly backup, day_of_week is -1 then.
+ALTER TABLE backup_setting ADD COLUMN day_of_month INTEGER NOT NULL CHECK (
+    day_of_month = -1
+    OR (
+        1 <= day_of_month
+        AND day_of_month <= 28
+    )
+) DEFAULT -1;
@@ -0,0 +1,4 @@
+PRAGMA user_version = 10016;
+
+-- managed is whether the database is expected to be under migration control, i.e. to have migration history.
+ALTER TABLE db ADD COLUMN managed INTEGER NOT NULL CHECK (managed IN (0, 1)) DEFAULT 0;
@@ -0,0 +1,4 @@
+PRAGMA user_version = 10017;
+
+-- size_bytes is the size of the stored backup file, it's 0 for the backups taken before.
+ALTER TABLE backup ADD COLUMN size_bytes INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,98 @@
+PRAGMA user_version = 10018;
+
+-- SQLite can't alter the CHECK constraint of a column, so the instance table is recreated to allow the ORACLE engine.
+-- The rows are copied back after the table is recreated, so that the deferred foreign keys referencing the instance are satisfied again on commit.
+PRAGMA defer_foreign_keys = ON;
+
+CREATE TEMP TABLE instance_old AS SELECT * FROM instance;
+
+CREATE TEMP TABLE instance_old_sequence AS SELECT seq FROM sqlite_sequence WHERE name = 'instance';
+
+DROP TABLE instance;
+
+CREATE TABLE instance (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    row_status TEXT NOT NULL CHECK (
+        row_status IN ('NORMAL', 'ARCHIVED')
+    ) DEFAULT 'NORMAL',
+    creator_id INTEGER NOT NULL REFERENCES principal (id),
+    created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    updater_id INTEGER NOT NULL REFERENCES principal (id),
+    updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    environment_id INTEGER NOT NULL REFERENCES environment (id),
+    name TEXT NOT NULL,
+    `engine` TEXT NOT NULL CHECK (`engine` IN ('MYSQL', 'POSTGRES', 'TIDB', 'CLICKHOUSE', 'SNOWFLAKE', 'SQLITE', 'MONGODB', 'MSSQL', 'ORACLE')),
+    engine_version TEXT NOT NULL DEFAULT '',
+    host TEXT NOT NULL,
+    port TEXT NOT NULL,
+    external_link TEXT NOT NULL DEFAULT '',
+    replica INTEGER NOT NULL CHECK (replica IN (0, 1)) DEFAULT 0,
+    enable_anomaly_scan INTEGER NOT NULL CHECK (enable_anomaly_scan IN (0, 1)) DEFAULT 1,
+    -- service_name and sid identify the Oracle database to connect to, only one of them is set for the ORACLE engine.
+    service_name TEXT NOT NULL DEFAULT '',
+    sid TEXT NOT NULL DEFAULT ''
+);
+
+INSERT INTO
+    instance (
+        id,
+        row_status,
+        creator_id,
+        created_ts,
+        updater_id,
+        updated_ts,
+        environment_id,
+        name,
+        `engine`,
+        engine_version,
+        host,
+        port,
+        external_link,
+        replica,
+        enable_anomaly_scan
+    )
+SELECT
+    id,
+    row_status,
+    creator_id,
+    created_ts,
+    updater_id,
+    updated_ts,
+    environment_id,
+    name,
+    `engine`,
+    engine_version,
+    host,
+    port,
+    external_link,
+    replica,
+    enable_anomaly_scan
+FROM
+    instance_old;
+
+DELETE FROM sqlite_sequence WHERE name = 'instance';
+
+INSERT INTO
+    sqlite_sequence (name, seq)
+SELECT
+    'instance',
+    seq
+FROM
+    instance_old_sequence;
+
+DROP TABLE instance_old;
+
+DROP TABLE instance_old_sequence;
+
+CREATE TRIGGER IF NOT EXISTS `trigger_update_instance_modification_time`
+AFTER
+UPDATE
+    ON `instance` FOR EACH ROW BEGIN
+UPDATE
+    `instance`
+SET
+    updated_ts = (strftime('%s', 'now'))
+WHERE
+    rowid = old.rowid;
+
+END;
@@ -34,7 +34,7 @@ func (s *PolicyService) FindPolicy(ctx context.Context, find *api.PolicyFind) (*
 	// Validate policy type existence.
 	if find.Type != nil && *find.Type != "" {
 		if err := api.ValidatePolicy(*find.Type, ""); err != nil {
-			return nil, &common.Error{Code: common.Invalid, Err: err}
+			return nil, err
 		}
 	}
 	tx, err := s.db.BeginTx(ctx, nil)
@@ -54,6 +54,9 @@ func (s *PolicyService) FindPolicy(ctx context.Context, find *api.PolicyFind) (*
 			EnvironmentID: *find.EnvironmentID,
 			Type:          *find.Type,
 		}
+		if find.InstanceID != nil {
+			ret.InstanceID = *find.InstanceID
+		}
 	} else if len(list) > 1 {
 		return nil, &common.Error{Code: common.Conflict, Err: fmt.Errorf("found %d policy with filter %+v, expect 1. ", len(list), find)}
 	} else {
@@ -71,27 +74,120 @@ func (s *PolicyService) FindPolicy(ctx context.Context, find *api.PolicyFind) (*
 	return ret, nil
 }
 
-func (s *PolicyService) findPolicy(ctx context.Context, tx *Tx, find *api.PolicyFind) (_ []*api.Policy, err error) {
-	// Build WHERE clause.
-	where, args := []string{"1 = 1"}, []interface{}{}
+// FindEffectivePolicy finds the policy taking effect for an environment.
+// It falls back to the global policy and then the default policy, see api.GetEffectivePolicy for the precedence order.
+func (s *PolicyService) FindEffectivePolicy(ctx context.Context, environmentID int, pType api.PolicyType) (*api.Policy, error) {
+	if err := api.ValidatePolicy(pType, ""); err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	return s.findEffectivePolicy(ctx, tx, environmentID, pType)
+}
+
+// ResolvePolicy finds the policy taking effect for an instance of the environment.
+// The instance policy takes precedence, otherwise it's the effective policy of the environment as FindEffectivePolicy.
+func (s *PolicyService) ResolvePolicy(ctx context.Context, environmentID int, instanceID int, pType api.PolicyType) (*api.Policy, error) {
+	if err := api.ValidatePolicy(pType, ""); err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	instancePolicy, err := s.findOnePolicy(ctx, tx, environmentID, &instanceID, pType)
+	if err != nil {
+		return nil, err
+	}
+	if instancePolicy != nil {
+		return instancePolicy, nil
+	}
+	return s.findEffectivePolicy(ctx, tx, environmentID, pType)
+}
+
+// findEffectivePolicy finds the environment policy, falling back to the global policy and then the default policy.
+func (s *PolicyService) findEffectivePolicy(ctx context.Context, tx *Tx, environmentID int, pType api.PolicyType) (*api.Policy, error) {
+	environmentPolicy, err := s.findOnePolicy(ctx, tx, environmentID, nil /* instanceID */, pType)
+	if err != nil {
+		return nil, err
+	}
+	var globalPolicy *api.Policy
+	if environmentPolicy == nil && environmentID != api.GlobalPolicyEnvironmentID {
+		globalPolicy, err = s.findOnePolicy(ctx, tx, api.GlobalPolicyEnvironmentID, nil /* instanceID */, pType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	policy, err := api.GetEffectivePolicy(environmentID, pType, environmentPolicy, globalPolicy)
+	if err != nil {
+		return nil, &common.Error{Code: common.Internal, Err: err}
+	}
+	return policy, nil
+}
+
+// findOnePolicy finds the stored policy of the type for the environment, or for the instance if instanceID isn't nil,
+// returns nil if not set.
+func (s *PolicyService) findOnePolicy(ctx context.Context, tx *Tx, environmentID int, instanceID *int, pType api.PolicyType) (*api.Policy, error) {
+	find := &api.PolicyFind{
+		EnvironmentID: &environmentID,
+		InstanceID:    instanceID,
+		Type:          &pType,
+	}
+	list, err := s.findPolicy(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	} else if len(list) > 1 {
+		return nil, &common.Error{Code: common.Conflict, Err: fmt.Errorf("found %d policy with filter %+v, expect 1. ", len(list), find)}
+	}
+	return list[0], nil
+}
+
+// findPolicyWhere builds the WHERE clause of the policies matching find.
+// The archived policies are deleted and never match.
+func findPolicyWhere(find *api.PolicyFind) ([]string, []interface{}) {
+	where, args := []string{"row_status = ?"}, []interface{}{api.Normal}
 	if v := find.ID; v != nil {
 		where, args = append(where, "id = ?"), append(args, *v)
 	}
 	if v := find.EnvironmentID; v != nil {
 		where, args = append(where, "environment_id = ?"), append(args, *v)
 	}
+	// The instance policies are only found by the instance, so that finding the environment policy stays unique.
+	instanceID := 0
+	if v := find.InstanceID; v != nil {
+		instanceID = *v
+	}
+	if find.ID == nil {
+		where, args = append(where, "instance_id = ?"), append(args, instanceID)
+	}
 	if v := find.Type; v != nil {
 		where, args = append(where, "type = ?"), append(args, *v)
 	}
+	return where, args
+}
 
+func (s *PolicyService) findPolicy(ctx context.Context, tx *Tx, find *api.PolicyFind) (_ []*api.Policy, err error) {
+	where, args := findPolicyWhere(find)
 	rows, err := tx.QueryContext(ctx, `
 		SELECT
 			id,
+			row_status,
 			creator_id,
 			created_ts,
 			updater_id,
 			updated_ts,
 			environment_id,
+			instance_id,
 			type,
 			payload
 		FROM policy
@@ -109,11 +205,13 @@ func (s *PolicyService) findPolicy(ctx context.Context, tx *Tx, find *api.Policy
 		var policy api.Policy
 		if err := rows.Scan(
 			&policy.ID,
+			&policy.RowStatus,
 			&policy.CreatorID,
 			&policy.CreatedTs,
 			&policy.UpdaterID,
 			&policy.UpdatedTs,
 			&policy.EnvironmentID,
+			&policy.InstanceID,
 			&policy.Type,
 			&policy.Payload,
 		); err != nil {
@@ -134,7 +232,7 @@ func (s *PolicyService) UpsertPolicy(ctx context.Context, upsert *api.PolicyUpse
 	// Validate policy.
 	if upsert.Type != "" {
 		if err := api.ValidatePolicy(upsert.Type, upsert.Payload); err != nil {
-			return nil, &common.Error{Code: common.Invalid, Err: err}
+			return nil, err
 		}
 	}
 	tx, err := s.db.BeginTx(ctx, nil)
@@ -157,6 +255,10 @@ func (s *PolicyService) UpsertPolicy(ctx context.Context, upsert *api.PolicyUpse
 
 // upsertPolicy updates an existing policy.
 func (s *PolicyService) upsertPolicy(ctx context.Context, tx *Tx, upsert *api.PolicyUpsert) (*api.Policy, error) {
+	instanceID := 0
+	if v := upsert.InstanceID; v != nil {
+		instanceID = *v
+	}
 	// Upsert row into policy.
 	// TODO(spinningbot): fix the query.
 	row, err := tx.QueryContext(ctx, `
@@ -164,17 +266,20 @@ func (s *PolicyService) upsertPolicy(ctx context.Context, tx *Tx, upsert *api.Po
 			creator_id,
 			updater_id,
 			environment_id,
+			instance_id,
 			type,
 			payload
 		)
-		VALUES (?, ?, ?, ?, ?)
-		ON CONFLICT(environment_id, type) DO UPDATE SET
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(environment_id, instance_id, type) DO UPDATE SET
+				row_status = excluded.row_status,
 				payload = excluded.payload
-		RETURNING id, creator_id, created_ts, updater_id, updated_ts, environment_id, type, payload
+		RETURNING id, row_status, creator_id, created_ts, updater_id, updated_ts, environment_id, instance_id, type, payload
 		`,
 		upsert.UpdaterID,
 		upsert.UpdaterID,
 		upsert.EnvironmentID,
+		instanceID,
 		upsert.Type,
 		upsert.Payload,
 	)
@@ -188,11 +293,13 @@ func (s *PolicyService) upsertPolicy(ctx context.Context, tx *Tx, upsert *api.Po
 	var policy api.Policy
 	if err := row.Scan(
 		&policy.ID,
+		&policy.RowStatus,
 		&policy.CreatorID,
 		&policy.CreatedTs,
 		&policy.UpdaterID,
 		&policy.UpdatedTs,
 		&policy.EnvironmentID,
+		&policy.InstanceID,
 		&policy.Type,
 		&policy.Payload,
 	); err != nil {
@@ -202,13 +309,45 @@ func (s *PolicyService) upsertPolicy(ctx context.Context, tx *Tx, upsert *api.Po
 	return &policy, nil
 }
 
-// GetBackupPlanPolicy will get the backup plan policy for an environment.
+// DeletePolicy deletes the policies matching find by archiving them, so the default policy takes effect again.
+// Returns ENOTFOUND if no matching record.
+func (s *PolicyService) DeletePolicy(ctx context.Context, find *api.PolicyFind) error {
+	// Validate policy type existence.
+	if find.Type != nil && *find.Type != "" {
+		if err := api.ValidatePolicy(*find.Type, ""); err != nil {
+			return err
+		}
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return FormatError(err)
+	}
+	defer tx.Rollback()
+
+	where, args := findPolicyWhere(find)
+	result, err := tx.ExecContext(ctx, `
+		UPDATE policy
+		SET row_status = ?
+		WHERE `+strings.Join(where, " AND "),
+		append([]interface{}{api.Archived}, args...)...,
+	)
+	if err != nil {
+		return FormatError(err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return &common.Error{Code: common.NotFound, Err: fmt.Errorf("policy not found: %+v", find)}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// GetBackupPlanPolicy will get the effective backup plan policy for an environment.
 func (s *PolicyService) GetBackupPlanPolicy(ctx context.Context, environmentID int) (*api.BackupPlanPolicy, error) {
-	pType := api.PolicyTypeBackupPlan
-	policy, err := s.FindPolicy(ctx, &api.PolicyFind{
-		EnvironmentID: &environmentID,
-		Type:          &pType,
-	})
+	policy, err := s.FindEffectivePolicy(ctx, environmentID, api.PolicyTypeBackupPlan)
 	if err != nil {
 		return nil, err
 	}
@@ -227,3 +366,21 @@ func (s *PolicyService) GetPipelineApprovalPolicy(ctx context.Context, environme
 	}
 	return api.UnmarshalPipelineApprovalPolicy(policy.Payload)
 }
+
+// GetAnomalyPolicy will get the anomaly policy for an environment.
+func (s *PolicyService) GetAnomalyPolicy(ctx context.Context, environmentID int) (*api.AnomalyPolicy, error) {
+	policy, err := s.FindEffectivePolicy(ctx, environmentID, api.PolicyTypeAnomaly)
+	if err != nil {
+		return nil, err
+	}
+	return api.UnmarshalAnomalyPolicy(policy.Payload)
+}
+
+// GetSchemaDriftPolicy will get the schema drift policy for an environment.
+func (s *PolicyService) GetSchemaDriftPolicy(ctx context.Context, environmentID int) (*api.SchemaDriftPolicy, error) {
+	policy, err := s.FindEffectivePolicy(ctx, environmentID, api.PolicyTypeSchemaDrift)
+	if err != nil {
+		return nil, err
+	}
+	return api.UnmarshalSchemaDriftPolicy(policy.Payload)
+}
@@ -0,0 +1,246 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/bytebase/bytebase/api"
+	"github.com/bytebase/bytebase/common"
+	"go.uber.org/zap"
+)
+
+func TestResolvePolicy(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(zap.NewNop(), filepath.Join(t.TempDir(), "bytebase_test.db"), "seed/test", true /* forceResetSeed */, false /* readonly */, "test")
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Db.Close()
+	s := NewPolicyService(zap.NewNop(), db, nil /* cache */)
+
+	// Instance 6004 is in environment 5004 from the test seed, which has the pipeline approval policy MANUAL_APPROVAL_ALWAYS.
+	environmentID, instanceID := 5004, 6004
+	environmentPolicy, err := s.ResolvePolicy(ctx, environmentID, instanceID, api.PolicyTypePipelineApproval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if environmentPolicy.ID == 0 || environmentPolicy.InstanceID != 0 {
+		t.Fatalf("ResolvePolicy() = {ID: %d, InstanceID: %d}, want the environment policy", environmentPolicy.ID, environmentPolicy.InstanceID)
+	}
+
+	payload := `{"value":"MANUAL_APPROVAL_ALWAYS","approverRoleList":["OWNER"]}`
+	for i := 0; i < 2; i++ {
+		instancePolicy, err := s.UpsertPolicy(ctx, &api.PolicyUpsert{
+			UpdaterID:     api.SystemBotID,
+			EnvironmentID: environmentID,
+			InstanceID:    &instanceID,
+			Type:          api.PolicyTypePipelineApproval,
+			Payload:       payload,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resolved, err := s.ResolvePolicy(ctx, environmentID, instanceID, api.PolicyTypePipelineApproval)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Upserting again updates the same instance policy.
+		if resolved.ID != instancePolicy.ID || resolved.InstanceID != instanceID || resolved.Payload != payload {
+			t.Errorf("ResolvePolicy() = {ID: %d, InstanceID: %d, Payload: %s}, want {ID: %d, InstanceID: %d, Payload: %s}",
+				resolved.ID, resolved.InstanceID, resolved.Payload, instancePolicy.ID, instanceID, payload)
+		}
+	}
+
+	// The environment-only calls are unaffected by the instance policy.
+	pType := api.PolicyTypePipelineApproval
+	policy, err := s.FindPolicy(ctx, &api.PolicyFind{EnvironmentID: &environmentID, Type: &pType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.ID != environmentPolicy.ID {
+		t.Errorf("FindPolicy() ID = %d, want the environment policy %d", policy.ID, environmentPolicy.ID)
+	}
+	// The other instances of the environment still fall back to the environment policy.
+	policy, err = s.ResolvePolicy(ctx, environmentID, instanceID+1, api.PolicyTypePipelineApproval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.ID != environmentPolicy.ID {
+		t.Errorf("ResolvePolicy() of another instance ID = %d, want the environment policy %d", policy.ID, environmentPolicy.ID)
+	}
+
+	// Neither the instance nor the environment sets the anomaly policy.
+	policy, err = s.ResolvePolicy(ctx, environmentID, instanceID, api.PolicyTypeAnomaly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultPayload, err := api.GetDefaultPolicy(api.PolicyTypeAnomaly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.ID != 0 || policy.Payload != defaultPayload {
+		t.Errorf("ResolvePolicy() = {ID: %d, Payload: %s}, want the default policy %s", policy.ID, policy.Payload, defaultPayload)
+	}
+
+	// The global policy takes effect when neither the instance nor the environment sets the policy.
+	globalPolicy, err := s.UpsertPolicy(ctx, &api.PolicyUpsert{
+		UpdaterID:     api.SystemBotID,
+		EnvironmentID: api.GlobalPolicyEnvironmentID,
+		Type:          api.PolicyTypeAnomaly,
+		Payload:       `{"enabled":false}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, err = s.ResolvePolicy(ctx, environmentID, instanceID, api.PolicyTypeAnomaly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.ID != globalPolicy.ID || policy.EnvironmentID != api.GlobalPolicyEnvironmentID {
+		t.Errorf("ResolvePolicy() = {ID: %d, EnvironmentID: %d}, want the global policy %d", policy.ID, policy.EnvironmentID, globalPolicy.ID)
+	}
+}
+
+func TestGetPolicyGlobalFallback(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(zap.NewNop(), filepath.Join(t.TempDir(), "bytebase_test.db"), "seed/test", true /* forceResetSeed */, false /* readonly */, "test")
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Db.Close()
+	s := NewPolicyService(zap.NewNop(), db, nil /* cache */)
+
+	// Environment 5004 from the test seed sets neither the anomaly policy nor the schema drift policy.
+	environmentID := 5004
+	for _, upsert := range []*api.PolicyUpsert{
+		{
+			UpdaterID:     api.SystemBotID,
+			EnvironmentID: api.GlobalPolicyEnvironmentID,
+			Type:          api.PolicyTypeAnomaly,
+			Payload:       `{"enabled":false}`,
+		},
+		{
+			UpdaterID:     api.SystemBotID,
+			EnvironmentID: api.GlobalPolicyEnvironmentID,
+			Type:          api.PolicyTypeSchemaDrift,
+			Payload:       `{"ignorePatternList":["^tmp_"]}`,
+		},
+	} {
+		if _, err := s.UpsertPolicy(ctx, upsert); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	anomalyPolicy, err := s.GetAnomalyPolicy(ctx, environmentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if anomalyPolicy.Enabled {
+		t.Errorf("GetAnomalyPolicy() Enabled = true, want the global policy false")
+	}
+	schemaDriftPolicy, err := s.GetSchemaDriftPolicy(ctx, environmentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schemaDriftPolicy.IgnorePatternList) != 1 || schemaDriftPolicy.IgnorePatternList[0] != "^tmp_" {
+		t.Errorf("GetSchemaDriftPolicy() IgnorePatternList = %v, want the global policy [^tmp_]", schemaDriftPolicy.IgnorePatternList)
+	}
+
+	// The environment policy takes precedence over the global policy.
+	if _, err := s.UpsertPolicy(ctx, &api.PolicyUpsert{
+		UpdaterID:     api.SystemBotID,
+		EnvironmentID: environmentID,
+		Type:          api.PolicyTypeAnomaly,
+		Payload:       `{"enabled":true}`,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	anomalyPolicy, err = s.GetAnomalyPolicy(ctx, environmentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !anomalyPolicy.Enabled {
+		t.Errorf("GetAnomalyPolicy() Enabled = false, want the environment policy true")
+	}
+}
+
+func TestDeletePolicy(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(zap.NewNop(), filepath.Join(t.TempDir(), "bytebase_test.db"), "seed/test", true /* forceResetSeed */, false /* readonly */, "test")
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Db.Close()
+	s := NewPolicyService(zap.NewNop(), db, nil /* cache */)
+
+	// Environment 5004 from the test seed has the backup plan policy DAILY, and instance 6004 is in it.
+	environmentID, instanceID := 5004, 6004
+	pType := api.PolicyTypeBackupPlan
+	defaultPayload, err := api.GetDefaultPolicy(pType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpsertPolicy(ctx, &api.PolicyUpsert{
+		UpdaterID:     api.SystemBotID,
+		EnvironmentID: environmentID,
+		InstanceID:    &instanceID,
+		Type:          pType,
+		Payload:       `{"schedule":"WEEKLY"}`,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting the instance policy falls back to the environment policy.
+	if err := s.DeletePolicy(ctx, &api.PolicyFind{EnvironmentID: &environmentID, InstanceID: &instanceID, Type: &pType}); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := s.ResolvePolicy(ctx, environmentID, instanceID, pType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.InstanceID != 0 || policy.Payload != `{"schedule":"DAILY"}` {
+		t.Errorf("ResolvePolicy() after deleting the instance policy = {InstanceID: %d, Payload: %s}, want the environment policy", policy.InstanceID, policy.Payload)
+	}
+
+	// Deleting the environment policy falls back to the default policy.
+	if err := s.DeletePolicy(ctx, &api.PolicyFind{EnvironmentID: &environmentID, Type: &pType}); err != nil {
+		t.Fatal(err)
+	}
+	policy, err = s.FindPolicy(ctx, &api.PolicyFind{EnvironmentID: &environmentID, Type: &pType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.ID != 0 || policy.Payload != defaultPayload {
+		t.Errorf("FindPolicy() after delete = {ID: %d, Payload: %s}, want the default policy %s", policy.ID, policy.Payload, defaultPayload)
+	}
+	policy, err = s.ResolvePolicy(ctx, environmentID, instanceID, pType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.ID != 0 || policy.Payload != defaultPayload {
+		t.Errorf("ResolvePolicy() after delete = {ID: %d, Payload: %s}, want the default policy %s", policy.ID, policy.Payload, defaultPayload)
+	}
+
+	// There is nothing left to delete.
+	if err := s.DeletePolicy(ctx, &api.PolicyFind{EnvironmentID: &environmentID, Type: &pType}); common.ErrorCode(err) != common.NotFound {
+		t.Errorf("DeletePolicy() of the deleted policy error = %v, want NotFound", err)
+	}
+
+	// Setting the policy again restores it.
+	if _, err := s.UpsertPolicy(ctx, &api.PolicyUpsert{
+		UpdaterID:     api.SystemBotID,
+		EnvironmentID: environmentID,
+		Type:          pType,
+		Payload:       `{"schedule":"WEEKLY"}`,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	policy, err = s.FindPolicy(ctx, &api.PolicyFind{EnvironmentID: &environmentID, Type: &pType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.RowStatus != api.Normal || policy.Payload != `{"schedule":"WEEKLY"}` {
+		t.Errorf("FindPolicy() after upsert = {RowStatus: %s, Payload: %s}, want the restored policy", policy.RowStatus, policy.Payload)
+	}
+}
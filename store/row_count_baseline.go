@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bytebase/bytebase/api"
+	"go.uber.org/zap"
+)
+
+var (
+	_ api.RowCountBaselineService = (*RowCountBaselineService)(nil)
+)
+
+// RowCountBaselineService represents a service for managing row count baseline.
+type RowCountBaselineService struct {
+	l  *zap.Logger
+	db *DB
+}
+
+// NewRowCountBaselineService returns a new instance of RowCountBaselineService.
+func NewRowCountBaselineService(logger *zap.Logger, db *DB) *RowCountBaselineService {
+	return &RowCountBaselineService{l: logger, db: db}
+}
+
+// FindRowCountBaselineList retrieves a list of row count baselines based on find.
+func (s *RowCountBaselineService) FindRowCountBaselineList(ctx context.Context, find *api.RowCountBaselineFind) ([]*api.RowCountBaseline, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	list, err := findRowCountBaselineList(ctx, tx, find)
+	if err != nil {
+		return []*api.RowCountBaseline{}, err
+	}
+
+	return list, nil
+}
+
+// UpsertRowCountBaselineList replaces the row count baselines of the database.
+func (s *RowCountBaselineService) UpsertRowCountBaselineList(ctx context.Context, upsert *api.RowCountBaselineUpsert) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return FormatError(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM row_count_baseline WHERE database_id = ?`, upsert.DatabaseID); err != nil {
+		return FormatError(err)
+	}
+	for tableName, rowCount := range upsert.RowCountMap {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO row_count_baseline (
+				database_id,
+				table_name,
+				row_count
+			)
+			VALUES (?, ?, ?)
+		`,
+			upsert.DatabaseID,
+			tableName,
+			rowCount,
+		); err != nil {
+			return FormatError(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+func findRowCountBaselineList(ctx context.Context, tx *Tx, find *api.RowCountBaselineFind) (_ []*api.RowCountBaseline, err error) {
+	// Build WHERE clause.
+	where, args := []string{"1 = 1"}, []interface{}{}
+	if v := find.DatabaseID; v != nil {
+		where, args = append(where, "database_id = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			database_id,
+			table_name,
+			row_count,
+			observed_ts
+		FROM row_count_baseline
+		WHERE `+strings.Join(where, " AND "),
+		args...,
+	)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	// Iterate over result set and deserialize rows into list.
+	list := make([]*api.RowCountBaseline, 0)
+	for rows.Next() {
+		var baseline api.RowCountBaseline
+		if err := rows.Scan(
+			&baseline.DatabaseID,
+			&baseline.TableName,
+			&baseline.RowCount,
+			&baseline.ObservedTs,
+		); err != nil {
+			return nil, FormatError(err)
+		}
+
+		list = append(list, &baseline)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return list, nil
+}
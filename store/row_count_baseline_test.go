@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/bytebase/bytebase/api"
+	"go.uber.org/zap"
+)
+
+func TestUpsertRowCountBaselineList(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(zap.NewNop(), filepath.Join(t.TempDir(), "bytebase_test.db"), "seed/test", true /* forceResetSeed */, false /* readonly */, "test")
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Db.Close()
+	s := NewRowCountBaselineService(zap.NewNop(), db)
+
+	// Database 7014 is from the test seed.
+	databaseID := 7014
+	find := &api.RowCountBaselineFind{DatabaseID: &databaseID}
+	for _, rowCountMap := range []map[string]int64{
+		{"t1": 10, "t2": 20},
+		// The baseline of the dropped table t1 is deleted.
+		{"t2": 30, "t3": 40},
+	} {
+		if err := s.UpsertRowCountBaselineList(ctx, &api.RowCountBaselineUpsert{
+			DatabaseID:  databaseID,
+			RowCountMap: rowCountMap,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		list, err := s.FindRowCountBaselineList(ctx, find)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]int64)
+		for _, baseline := range list {
+			if baseline.ObservedTs == 0 {
+				t.Errorf("expect ObservedTs of table %q to be set", baseline.TableName)
+			}
+			got[baseline.TableName] = baseline.RowCount
+		}
+		if len(got) != len(rowCountMap) {
+			t.Fatalf("got baselines %v, want %v", got, rowCountMap)
+		}
+		for tableName, rowCount := range rowCountMap {
+			if got[tableName] != rowCount {
+				t.Errorf("got baselines %v, want %v", got, rowCountMap)
+			}
+		}
+	}
+}
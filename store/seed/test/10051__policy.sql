@@ -136,5 +136,5 @@ VALUES
         'bb.policy.pipeline-approval',
         '{"value":"MANUAL_APPROVAL_ALWAYS"}'
     )
-    ON CONFLICT(environment_id, type) DO UPDATE SET
+    ON CONFLICT(environment_id, instance_id, type) DO UPDATE SET
 				payload = excluded.payload;